@@ -14,6 +14,14 @@ type APPError struct {
 	Status int `json:"-"`
 	// Message is the error message that may be displayed to end users
 	Message string `json:"message,omitempty"`
+	// Fields lists the individual fields that caused the error, if any
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes a problem with a single request or profile field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 var (
@@ -41,6 +49,11 @@ func New(status int, msg string) *APPError {
 	return &APPError{Status: status, Message: msg}
 }
 
+// NewFields generates an application error listing each failing field
+func NewFields(status int, msg string, fields []FieldError) *APPError {
+	return &APPError{Status: status, Message: msg, Fields: fields}
+}
+
 // Error returns the error message.
 func (e APPError) Error() string {
 	return e.Message
@@ -0,0 +1,161 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// errUnavailable is returned when the broker can't be reached or fails
+var errUnavailable = apperr.New(http.StatusBadGateway, "The broker is unavailable. Try again later.")
+
+// Service is the read-only view of the broker accounts of users support staff use
+type Service interface {
+	// Account returns the broker account, with its status and KYC results
+	Account(accountID string) (json.RawMessage, error)
+	Orders(accountID string) (json.RawMessage, error)
+	Positions(accountID string) (json.RawMessage, error)
+	Transfers(accountID string) (json.RawMessage, error)
+}
+
+// Closer closes the broker accounts of users who close their account with us
+type Closer interface {
+	Service
+	// Close closes a broker account. Accounts with open positions or a balance can't be closed.
+	Close(accountID string) error
+}
+
+// Clients returns the broker client of a tenant, as tenants may have their own Broker API credentials
+type Clients interface {
+	For(t *model.Tenant) Closer
+}
+
+// NewBroker creates a broker API client
+func NewBroker(cfg *config.BrokerConfig) *Broker {
+	return &Broker{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Broker is a client of the Alpaca Broker API
+type Broker struct {
+	cfg    *config.BrokerConfig
+	client *http.Client
+}
+
+// For returns the client of a tenant, which uses the Broker API endpoint and token of the tenant
+// if it has its own, or b for nil tenants
+func (b *Broker) For(t *model.Tenant) Closer {
+	if !ownCredentials(t) {
+		return b
+	}
+	cfg := *b.cfg
+	cfg.APIBase, cfg.Token = t.BrokerAPIBase, t.BrokerToken
+	return &Broker{cfg: &cfg, client: b.client}
+}
+
+// ownCredentials reports whether t has its own Broker API endpoint or token. They are only used
+// together, so that the configured token is never sent to the endpoint of a tenant or the reverse.
+func ownCredentials(t *model.Tenant) bool {
+	return t != nil && (t.BrokerAPIBase != "" || t.BrokerToken != "")
+}
+
+// APIBase returns the Broker API endpoint of the tenant of a request, or the configured one
+// outside of requests and for tenants without their own
+func APIBase(c context.Context) string {
+	if t := model.TenantOf(c); ownCredentials(t) {
+		return t.BrokerAPIBase
+	}
+	return os.Getenv("BROKER_API_BASE")
+}
+
+// Token returns the Broker API Authorization header value of the tenant of a request, or the
+// configured one outside of requests and for tenants without their own
+func Token(c context.Context) string {
+	if t := model.TenantOf(c); ownCredentials(t) {
+		return t.BrokerToken
+	}
+	return os.Getenv("BROKER_TOKEN")
+}
+
+// brokerError is the body of a failed Broker API request
+type brokerError struct {
+	Message string `json:"message"`
+}
+
+// Account returns the broker account, with its status and KYC results
+func (b *Broker) Account(accountID string) (json.RawMessage, error) {
+	return b.get("/v1/accounts/" + url.PathEscape(accountID))
+}
+
+// Orders returns all orders of the account, open and closed
+func (b *Broker) Orders(accountID string) (json.RawMessage, error) {
+	return b.get("/v1/trading/accounts/" + url.PathEscape(accountID) + "/orders?status=all")
+}
+
+// Positions returns the open positions of the account
+func (b *Broker) Positions(accountID string) (json.RawMessage, error) {
+	return b.get("/v1/trading/accounts/" + url.PathEscape(accountID) + "/positions")
+}
+
+// Transfers returns the deposits to and withdrawals from the account
+func (b *Broker) Transfers(accountID string) (json.RawMessage, error) {
+	return b.get("/v1/accounts/" + url.PathEscape(accountID) + "/transfers")
+}
+
+// Close closes the account
+func (b *Broker) Close(accountID string) error {
+	_, err := b.do(http.MethodPost, "/v1/accounts/"+url.PathEscape(accountID)+"/actions/close")
+	return err
+}
+
+// get returns the JSON body of a Broker API GET request
+func (b *Broker) get(path string) (json.RawMessage, error) {
+	body, err := b.do(http.MethodGet, path)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, errUnavailable
+	}
+	return body, nil
+}
+
+// do returns the body of a Broker API request. Failures are passed on with the status and
+// message of the broker, except for auth failures, which are our configuration's fault.
+func (b *Broker) do(method, path string) ([]byte, error) {
+	req, err := http.NewRequest(method, b.cfg.APIBase+path, nil)
+	if err != nil {
+		return nil, apperr.Generic
+	}
+	req.Header.Set("Authorization", b.cfg.Token)
+	res, err := b.client.Do(req)
+	if err != nil {
+		return nil, errUnavailable
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, errUnavailable
+	}
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden || res.StatusCode >= http.StatusInternalServerError {
+		return nil, errUnavailable
+	}
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		e := brokerError{}
+		if json.Unmarshal(body, &e) != nil || e.Message == "" {
+			e.Message = http.StatusText(res.StatusCode)
+		}
+		return nil, apperr.New(res.StatusCode, e.Message)
+	}
+	return body, nil
+}
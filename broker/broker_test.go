@@ -0,0 +1,90 @@
+package broker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	var gotPath, gotAuth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.RequestURI(), r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/v1/accounts/acc-1":
+			w.Write([]byte(`{"id":"acc-1","status":"ACTIVE"}`))
+		case "/v1/accounts/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":40410000,"message":"account not found"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+	b := NewBroker(&config.BrokerConfig{APIBase: ts.URL, Token: "Basic token"})
+
+	body, err := b.Account("acc-1")
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"id":"acc-1","status":"ACTIVE"}`, string(body))
+	assert.Equal(t, "Basic token", gotAuth)
+
+	_, err = b.Account("missing")
+	assert.Equal(t, http.StatusNotFound, err.(*apperr.APPError).Status)
+	assert.Equal(t, "account not found", err.(*apperr.APPError).Message)
+
+	_, err = b.Orders("acc-1")
+	assert.Equal(t, "/v1/trading/accounts/acc-1/orders?status=all", gotPath)
+	assert.Equal(t, errUnavailable, err, "auth failures are ours, not the client's")
+}
+
+func TestClose(t *testing.T) {
+	var gotMethod, gotPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod, gotPath = r.Method, r.URL.Path
+		if r.URL.Path == "/v1/accounts/open/actions/close" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message":"account has open positions"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	b := NewBroker(&config.BrokerConfig{APIBase: ts.URL})
+
+	assert.Nil(t, b.Close("acc-1"))
+	assert.Equal(t, http.MethodPost, gotMethod)
+	assert.Equal(t, "/v1/accounts/acc-1/actions/close", gotPath)
+
+	err := b.Close("open")
+	assert.Equal(t, http.StatusUnprocessableEntity, err.(*apperr.APPError).Status)
+	assert.Equal(t, "account has open positions", err.(*apperr.APPError).Message)
+}
+
+func TestFor(t *testing.T) {
+	var gotAuth string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Write([]byte(`{}`))
+	}))
+	defer ts.Close()
+	b := NewBroker(&config.BrokerConfig{APIBase: ts.URL, Token: "Basic default"})
+
+	assert.Equal(t, b, b.For(nil))
+	assert.Equal(t, b, b.For(&model.Tenant{Name: "acme"}), "tenants without credentials share the default ones")
+
+	_, err := b.For(&model.Tenant{BrokerAPIBase: ts.URL, BrokerToken: "Basic acme"}).Account("acc-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Basic acme", gotAuth)
+	_, err = b.For(&model.Tenant{BrokerAPIBase: ts.URL}).Account("acc-1")
+	assert.Nil(t, err)
+	assert.Empty(t, gotAuth, "the default token isn't sent to the broker API base of a tenant")
+	_, err = b.Account("acc-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Basic default", gotAuth)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/repository"
+	"github.com/alpacahq/ribbit-backend/repository/avatar"
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// anonymizeUsersCmd represents the anonymize_users command
+var anonymizeUsersCmd = &cobra.Command{
+	Use:   "anonymize_users",
+	Short: "anonymize_users erases the personal data of users closed or deleted more than CLOSURE_RETENTION ago",
+	Long: `anonymize_users erases the profile, sign-in methods, sessions, linked bank accounts, support notes and avatar
+of the users who closed their account, or were deleted, more than CLOSURE_RETENTION ago. Their ID, broker account,
+rewards and audit history are kept for our records. Run it periodically, e.g. daily.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("anonymize_users called")
+
+		db := config.GetConnection()
+		defer db.Close()
+		log, _ := zap.NewDevelopment()
+		defer log.Sync()
+
+		cipher, err := config.GetFieldCipher()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		store, err := config.GetStorage()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		userRepo := repository.NewUserRepo(db, log, cipher)
+		users, err := userRepo.Anonymize(time.Now().Add(-config.GetClosureConfig().Retention))
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		avatars := avatar.NewAvatarService(userRepo, repository.NewAccountRepo(db, log, secret.New(), cipher), nil, store, 0, log)
+		for _, u := range users {
+			avatars.Remove(u.Avatar)
+		}
+		fmt.Printf("Anonymized %d users\n", len(users))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(anonymizeUsersCmd)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/repository"
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// cleanupTokensCmd represents the cleanup_tokens command
+var cleanupTokensCmd = &cobra.Command{
+	Use:   "cleanup_tokens",
+	Short: "cleanup_tokens deletes expired refresh, revoked and verification tokens, passkey challenges and failed attempt counters",
+	Long: `cleanup_tokens deletes expired refresh tokens, revoked access tokens, verification tokens, passkey challenges
+and the failed attempt counters that are older than THROTTLE_WINDOW. Expired tokens and counters are ignored either way,
+so this only keeps the tables small. Run it periodically, e.g. daily.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("cleanup_tokens called")
+
+		db := config.GetConnection()
+		defer db.Close()
+		log, _ := zap.NewDevelopment()
+		defer log.Sync()
+
+		now := time.Now()
+		refreshTokens, err := repository.NewRefreshTokenRepo(db, log).DeleteExpired(now)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		revokedTokens, err := repository.NewRevocationRepo(db, log).DeleteExpired(now)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		cipher, err := config.GetFieldCipher()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		verifications, err := repository.NewAccountRepo(db, log, secret.New(), cipher).DeleteExpiredVerificationTokens(now)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		throttles, err := repository.NewThrottleRepo(db, log).DeleteStale(now.Add(-config.GetThrottleConfig().Window))
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		challenges, err := repository.NewPasskeyRepo(db, log).DeleteExpiredChallenges()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Printf("Deleted %d refresh tokens, %d revoked tokens, %d verification tokens, %d passkey challenges and %d failed attempt counters\n",
+			refreshTokens, revokedTokens, verifications, challenges, throttles)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupTokensCmd)
+}
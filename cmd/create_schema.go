@@ -23,13 +23,20 @@ var createSchemaCmd = &cobra.Command{
 		db := config.GetConnection()
 		log, _ := zap.NewDevelopment()
 		defer log.Sync()
-		accountRepo := repository.NewAccountRepo(db, log, secret.New())
+		cipher, err := config.GetFieldCipher()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		accountRepo := repository.NewAccountRepo(db, log, secret.New(), cipher)
 		roleRepo := repository.NewRoleRepo(db, log)
 
 		m := manager.NewManager(accountRepo, roleRepo, db)
 		models := manager.GetModels()
 		m.CreateSchema(models...)
 		m.CreateRoles()
+		if err := repository.NewAuditRepo(db, log).Protect(); err != nil {
+			log.Fatal(err.Error())
+		}
 	},
 }
 
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	exportAuditOut    string
+	exportAuditFilter model.AuditFilter
+	exportAuditFrom   string
+	exportAuditTo     string
+)
+
+// exportAuditCmd represents the export_audit command
+var exportAuditCmd = &cobra.Command{
+	Use:   "export_audit",
+	Short: "export_audit writes audit events to a JSON lines file",
+	Long: `export_audit writes the audit events matching the filters to a file, or to standard output,
+one JSON object per line and oldest first. --from and --to are RFC 3339 times, e.g. 2021-06-01T00:00:00Z.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(os.Stderr, "export_audit called")
+
+		db := config.GetConnection()
+		defer db.Close()
+		log, _ := zap.NewDevelopment()
+		defer log.Sync()
+
+		var err error
+		if exportAuditFilter.From, err = parseTime(exportAuditFrom); err != nil {
+			log.Fatal(err.Error())
+		}
+		if exportAuditFilter.To, err = parseTime(exportAuditTo); err != nil {
+			log.Fatal(err.Error())
+		}
+
+		var out io.Writer = os.Stdout
+		if exportAuditOut != "" {
+			f, err := os.Create(exportAuditOut)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+			defer f.Close()
+			out = f
+		}
+		w := bufio.NewWriter(out)
+		enc := json.NewEncoder(w)
+		count := 0
+		err = repository.NewAuditRepo(db, log).ForEach(&exportAuditFilter, func(e *model.AuditEvent) error {
+			count++
+			return enc.Encode(e)
+		})
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		if err := w.Flush(); err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d audit events\n", count)
+	},
+}
+
+// parseTime parses an RFC 3339 time, or returns the zero time for an empty string
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func init() {
+	exportAuditCmd.Flags().StringVarP(&exportAuditOut, "out", "o", "", "file to write to (defaults to standard output)")
+	exportAuditCmd.Flags().IntVar(&exportAuditFilter.ActorID, "actor", 0, "only events of this actor user ID")
+	exportAuditCmd.Flags().IntVar(&exportAuditFilter.TargetUserID, "target", 0, "only events on this target user ID")
+	exportAuditCmd.Flags().StringVar(&exportAuditFilter.Action, "action", "", "only events of this action, e.g. password.change")
+	exportAuditCmd.Flags().StringVar(&exportAuditFrom, "from", "", "only events at or after this time")
+	exportAuditCmd.Flags().StringVar(&exportAuditTo, "to", "", "only events before this time")
+	rootCmd.AddCommand(exportAuditCmd)
+}
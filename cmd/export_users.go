@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/mail"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository"
+	"github.com/alpacahq/ribbit-backend/repository/bulk"
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	exportUsersOut      string
+	exportUsersFormat   string
+	exportUsersColumns  []string
+	exportUsersRole     string
+	exportUsersVerified string
+	exportUsersActive   string
+	exportUsersFrom     string
+	exportUsersTo       string
+	exportUsersQuery    model.ListQuery
+)
+
+// exportUsersCmd represents the export_users command
+var exportUsersCmd = &cobra.Command{
+	Use:   "export_users",
+	Short: "export_users writes users to a CSV or JSON lines file",
+	Long: `export_users writes the users matching the filters to a file, or to standard output, by ID, as CSV with a header
+row or as a JSON object per line. --columns picks the columns, out of id, email, first_name, last_name, username,
+country_code, mobile, role, verified, active, account_id, account_number, account_status, referral_code, created_at,
+last_login and tenant_id. --from and --to are RFC 3339 times, e.g. 2021-06-01T00:00:00Z, of when users signed up.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(os.Stderr, "export_users called")
+
+		log, _ := zap.NewDevelopment()
+		defer log.Sync()
+
+		var err error
+		q := &exportUsersQuery
+		if exportUsersRole != "" {
+			role, ok := model.ParseRole(exportUsersRole)
+			if !ok {
+				log.Fatal(fmt.Sprintf("unknown role %q", exportUsersRole))
+			}
+			q.Role = role
+		}
+		if q.Verified, err = parseBool(exportUsersVerified); err != nil {
+			log.Fatal(err.Error())
+		}
+		if q.Active, err = parseBool(exportUsersActive); err != nil {
+			log.Fatal(err.Error())
+		}
+		if q.CreatedFrom, err = parseTime(exportUsersFrom); err != nil {
+			log.Fatal(err.Error())
+		}
+		if q.CreatedTo, err = parseTime(exportUsersTo); err != nil {
+			log.Fatal(err.Error())
+		}
+
+		var out io.Writer = os.Stdout
+		if exportUsersOut != "" {
+			f, err := os.Create(exportUsersOut)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+			defer f.Close()
+			out = f
+		}
+
+		db := config.GetConnection()
+		defer db.Close()
+		cipher, err := config.GetFieldCipher()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		svc := bulk.NewBulkService(repository.NewUserRepo(db, log, cipher), repository.NewAccountRepo(db, log, secret.New(), cipher),
+			mail.NewMail(config.GetMailConfig(), config.GetSiteConfig()))
+		count, err := svc.Export(out, fileFormat(exportUsersFormat, exportUsersOut), exportUsersColumns, q)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d users\n", count)
+	},
+}
+
+// parseBool parses a boolean flag that may be left out, returning nil for an empty string
+func parseBool(s string) (*bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+func init() {
+	f := exportUsersCmd.Flags()
+	f.StringVarP(&exportUsersOut, "out", "o", "", "file to write to (defaults to standard output)")
+	f.StringVar(&exportUsersFormat, "format", "", "csv or jsonl (defaults to the file extension, else csv)")
+	f.StringSliceVar(&exportUsersColumns, "columns", bulk.DefaultExportColumns, "comma separated columns to export")
+	f.StringVar(&exportUsersRole, "role", "", "only users with this role, e.g. admin")
+	f.StringVar(&exportUsersVerified, "verified", "", "only verified (true) or unverified (false) users")
+	f.StringVar(&exportUsersActive, "active", "", "only active (true) or inactive (false) users")
+	f.StringVar(&exportUsersQuery.AccountStatus, "account-status", "", "only users with this broker account status")
+	f.StringVar(&exportUsersQuery.Search, "q", "", "only users whose name, email or mobile number contains this")
+	f.StringVar(&exportUsersFrom, "from", "", "only users who signed up at or after this time")
+	f.StringVar(&exportUsersTo, "to", "", "only users who signed up before this time")
+	rootCmd.AddCommand(exportUsersCmd)
+}
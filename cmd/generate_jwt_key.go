@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	mw "github.com/alpacahq/ribbit-backend/middleware"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	jwtKeyAlgorithm string
+	jwtKeyDir       string
+	jwtKeyKeep      int
+)
+
+// generateJWTKeyCmd represents the generate_secret jwt_key command
+var generateJWTKeyCmd = &cobra.Command{
+	Use:   "jwt_key",
+	Short: "jwt_key adds a new RS256 or ES256 token signing key",
+	Long: `jwt_key adds a new RS256 or ES256 token signing key to the keys directory, as <kid>.pem.
+
+Every key in JWT_KEYS_DIR is accepted and published at /.well-known/jwks.json.
+To rotate keys without rejecting valid tokens:
+  1. run this command and deploy the new key with JWT_SIGNING_KID still set to the current key,
+     so every instance and every service reading the JWKS accepts it
+  2. set JWT_SIGNING_KID to the new key, or unset it to sign with the newest key
+  3. once tokens signed with the old key have expired (JWT_MAX_LIFETIME), remove it,
+     e.g. with --keep 2 on the next rotation`,
+	Run: func(cmd *cobra.Command, args []string) {
+		kid, data, err := mw.GenerateKey(jwtKeyAlgorithm)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.MkdirAll(jwtKeyDir, 0700); err != nil {
+			log.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(jwtKeyDir, kid+".pem"), data, 0600); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("\nCreated %s key %s\n", jwtKeyAlgorithm, kid)
+
+		if jwtKeyKeep > 0 {
+			files, err := filepath.Glob(filepath.Join(jwtKeyDir, "*.pem"))
+			if err != nil {
+				log.Fatal(err)
+			}
+			sort.Strings(files)
+			for i := 0; i < len(files)-jwtKeyKeep; i++ {
+				if err := os.Remove(files[i]); err != nil {
+					log.Fatal(err)
+				}
+				fmt.Printf("Removed %s\n", files[i])
+			}
+		}
+
+		fmt.Printf("\nJWT_KEYS_DIR=%s\nJWT_SIGNING_KID=%s\n\n", jwtKeyDir, kid)
+	},
+}
+
+func init() {
+	generateJWTKeyCmd.Flags().StringVarP(&jwtKeyAlgorithm, "algorithm", "a", "ES256", "RS256 or ES256")
+	generateJWTKeyCmd.Flags().StringVarP(&jwtKeyDir, "dir", "d", "keys", "directory holding the signing keys")
+	generateJWTKeyCmd.Flags().IntVarP(&jwtKeyKeep, "keep", "k", 0, "remove all but the newest keys, including the new one; 0 keeps every key")
+	generateSecretCmd.AddCommand(generateJWTKeyCmd)
+}
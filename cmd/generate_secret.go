@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
 
@@ -20,6 +21,18 @@ var generateSecretCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 		fmt.Printf("\nJWT_SECRET=%s\n\n", s)
+
+		key, err := secret.GenerateRandomBytes(32)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("PII_KEY=%s\n\n", base64.StdEncoding.EncodeToString(key))
+
+		signingKey, err := secret.GenerateRandomString(64)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("STORAGE_SIGNING_KEY=%s\n\n", signingKey)
 	},
 }
 
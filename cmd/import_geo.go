@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/geo"
+	"github.com/alpacahq/ribbit-backend/repository"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var importGeoDir string
+
+// importGeoCmd represents the import_geo command
+var importGeoCmd = &cobra.Command{
+	Use:   "import_geo",
+	Short: "import_geo loads countries, states and cities into the database",
+	Long: `import_geo loads countries.csv and, if present, uscities.csv (simplemaps layout) into
+the geo_countries, geo_states and geo_cities tables, replacing their contents.
+The API server builds its in-memory search index from these tables at startup.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("import_geo called")
+
+		db := config.GetConnection()
+		defer db.Close()
+		log, _ := zap.NewDevelopment()
+		defer log.Sync()
+
+		if importGeoDir == "" {
+			importGeoDir, _ = os.Getwd()
+		}
+		idx, err := geo.LoadDir(importGeoDir)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		countries, states, cities, err := repository.NewGeoRepo(db, log).Import(idx)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Printf("Imported %d countries, %d states and %d cities\n", countries, states, cities)
+	},
+}
+
+func init() {
+	importGeoCmd.Flags().StringVarP(&importGeoDir, "dir", "d", "", "directory containing countries.csv and uscities.csv (defaults to the working directory)")
+	rootCmd.AddCommand(importGeoCmd)
+}
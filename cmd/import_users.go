@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/mail"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository"
+	"github.com/alpacahq/ribbit-backend/repository/bulk"
+	"github.com/alpacahq/ribbit-backend/repository/tenant"
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	importUsersFile    string
+	importUsersErrors  string
+	importUsersRole    string
+	importUsersFormat  string
+	importUsersTenant  string
+	importUsersOptions bulk.ImportOptions
+)
+
+// importUsersCmd represents the import_users command
+var importUsersCmd = &cobra.Command{
+	Use:   "import_users",
+	Short: "import_users creates users from a CSV or JSON lines file",
+	Long: `import_users creates a user for every row of a CSV file with a header row, or of a JSON lines file. The columns are
+email (required), first_name, last_name, country_code, mobile and role, a role name such as admin. Rows that are invalid or
+name an existing user are skipped and reported with their line, as CSV, on standard error or in the --errors file.
+Imported users have no password: with --invite, they are emailed to set one with "Forgot password".
+With --tenant, the users belong to that tenant and are invited with its branding.
+--dry-run reports what would be imported without changing anything. The command exits with 1 if any row failed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintln(os.Stderr, "import_users called")
+
+		log, _ := zap.NewDevelopment()
+		defer log.Sync()
+
+		role, ok := model.ParseRole(importUsersRole)
+		if !ok {
+			log.Fatal(fmt.Sprintf("unknown role %q", importUsersRole))
+		}
+		importUsersOptions.Role = role
+		importUsersOptions.Format = fileFormat(importUsersFormat, importUsersFile)
+
+		var in io.Reader = os.Stdin
+		if importUsersFile != "-" {
+			f, err := os.Open(importUsersFile)
+			if err != nil {
+				log.Fatal(err.Error())
+			}
+			defer f.Close()
+			in = f
+		}
+
+		db := config.GetConnection()
+		defer db.Close()
+		cipher, err := config.GetFieldCipher()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		if importUsersTenant != "" {
+			t, err := repository.NewTenantRepo(db, log, cipher).FindByName(importUsersTenant)
+			if err != nil {
+				log.Fatal(fmt.Sprintf("tenant %q: %v", importUsersTenant, err))
+			}
+			t.Inherit(tenant.Default(config.GetSiteConfig(), config.GetMailConfig(), config.GetBrokerConfig()))
+			importUsersOptions.Tenant = t
+		}
+		svc := bulk.NewBulkService(repository.NewUserRepo(db, log, cipher), repository.NewAccountRepo(db, log, secret.New(), cipher),
+			mail.NewMail(config.GetMailConfig(), config.GetSiteConfig()))
+		res, err := svc.Import(in, &importUsersOptions)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		if len(res.Errors) > 0 {
+			if err := writeRowErrors(importUsersErrors, res.Errors); err != nil {
+				log.Fatal(err.Error())
+			}
+		}
+		verb := "Imported"
+		if importUsersOptions.DryRun {
+			verb = "Would import"
+		}
+		fmt.Fprintf(os.Stderr, "%s %d of %d users, invited %d, %d errors\n", verb, res.Imported, res.Rows, res.Invited, len(res.Errors))
+		if len(res.Errors) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// writeRowErrors writes the errors of an import as CSV to a file, or to standard error for an empty path
+func writeRowErrors(path string, rowErrors []bulk.RowError) error {
+	var out io.Writer = os.Stderr
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := csv.NewWriter(out)
+	w.Write([]string{"line", "email", "error", "imported"})
+	for _, e := range rowErrors {
+		w.Write([]string{strconv.Itoa(e.Line), e.Email, e.Error, strconv.FormatBool(e.Imported)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// fileFormat returns format, or guesses it from the extension of path, defaulting to CSV
+func fileFormat(format, path string) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		return bulk.FormatJSONL
+	}
+	return bulk.FormatCSV
+}
+
+func init() {
+	f := importUsersCmd.Flags()
+	f.StringVarP(&importUsersFile, "file", "f", "", "CSV or JSON lines file to import, - for standard input")
+	f.StringVar(&importUsersFormat, "format", "", "csv or jsonl (defaults to the file extension, else csv)")
+	f.StringVar(&importUsersRole, "role", model.RoleNames[model.UserRole], "role of the rows without one")
+	f.BoolVar(&importUsersOptions.Verified, "verified", false, "mark the users verified, for email addresses verified elsewhere")
+	f.BoolVar(&importUsersOptions.Invite, "invite", false, "email every imported user an invite")
+	f.BoolVar(&importUsersOptions.DryRun, "dry-run", false, "validate the rows without importing them")
+	f.StringVar(&importUsersTenant, "tenant", "", "name of the tenant of the users (defaults to the default tenant)")
+	f.StringVar(&importUsersErrors, "errors", "", "file to write the row errors to, as CSV (defaults to standard error)")
+	importUsersCmd.MarkFlagRequired("file")
+	rootCmd.AddCommand(importUsersCmd)
+}
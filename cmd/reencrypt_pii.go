@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/repository"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var reencryptBatchSize int
+
+// reencryptPIICmd represents the reencrypt_pii command
+var reencryptPIICmd = &cobra.Command{
+	Use:   "reencrypt_pii",
+	Short: "reencrypt_pii re-encrypts sensitive user fields with the current PII_KEY",
+	Long: `reencrypt_pii re-encrypts sensitive user fields with the current PII_KEY.
+Plaintext rows written before encryption was enabled are encrypted as well.
+
+To rotate keys, set PII_KEY and PII_KEY_ID to the new key, add the old key to
+PII_RETIRED_KEYS as id:key, run this command, then drop the old key from PII_RETIRED_KEYS.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("reencrypt_pii called")
+
+		db := config.GetConnection()
+		defer db.Close()
+		log, _ := zap.NewDevelopment()
+		defer log.Sync()
+		cipher, err := config.GetFieldCipher()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		userRepo := repository.NewUserRepo(db, log, cipher)
+
+		count, err := userRepo.ReencryptPII(reencryptBatchSize)
+		if err != nil {
+			log.Fatal(err.Error(), zap.Int("reencrypted", count))
+		}
+		fmt.Printf("Re-encrypted %d users\n", count)
+	},
+}
+
+func init() {
+	reencryptPIICmd.Flags().IntVarP(&reencryptBatchSize, "batch-size", "b", 500, "number of users read per batch")
+	rootCmd.AddCommand(reencryptPIICmd)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var saveTenantFlags model.Tenant
+
+// saveTenantCmd represents the save_tenant command
+var saveTenantCmd = &cobra.Command{
+	Use:   "save_tenant",
+	Short: "save_tenant creates or updates a white-label tenant",
+	Long: `save_tenant creates the tenant with the given name, or updates the settings given as flags of an existing one.
+Requests to the tenant's hosts, and requests with tokens of users who signed up on them, get the tenant's branding,
+email sender, links and broker credentials. Settings left empty are those of the default tenant, configured by
+SITE_NAME, SITE_LOGO_URL, SITE_PRIMARY_COLOR, LINK_DOMAIN, DEFAULT_EMAIL, BROKER_API_BASE and BROKER_TOKEN.
+The broker API base and token are set together, or both left empty.
+Running servers pick up changes within a minute.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("save_tenant called")
+
+		db := config.GetConnection()
+		defer db.Close()
+		log, _ := zap.NewDevelopment()
+		defer log.Sync()
+
+		cipher, err := config.GetFieldCipher()
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		repo := repository.NewTenantRepo(db, log, cipher)
+		t, err := repo.FindByName(saveTenantFlags.Name)
+		if e, ok := err.(*apperr.APPError); ok && e.Status == http.StatusNotFound {
+			t, err = &model.Tenant{Name: saveTenantFlags.Name}, nil
+		}
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+
+		f := cmd.Flags()
+		for flag, v := range map[string]struct{ dst, src *string }{
+			"logo-url":        {&t.LogoURL, &saveTenantFlags.LogoURL},
+			"primary-color":   {&t.PrimaryColor, &saveTenantFlags.PrimaryColor},
+			"link-domain":     {&t.LinkDomain, &saveTenantFlags.LinkDomain},
+			"mail-from-name":  {&t.MailFromName, &saveTenantFlags.MailFromName},
+			"mail-from-email": {&t.MailFromEmail, &saveTenantFlags.MailFromEmail},
+			"broker-api-base": {&t.BrokerAPIBase, &saveTenantFlags.BrokerAPIBase},
+			"broker-token":    {&t.BrokerToken, &saveTenantFlags.BrokerToken},
+		} {
+			if f.Changed(flag) {
+				*v.dst = *v.src
+			}
+		}
+		if f.Changed("hosts") {
+			t.Hosts = saveTenantFlags.Hosts
+		}
+		if (t.BrokerAPIBase == "") != (t.BrokerToken == "") {
+			log.Fatal("broker-api-base and broker-token have to be set together")
+		}
+		if err := repo.Save(t); err != nil {
+			log.Fatal(err.Error())
+		}
+		fmt.Printf("Saved tenant %s with ID %d\n", t.Name, t.ID)
+	},
+}
+
+func init() {
+	f := saveTenantCmd.Flags()
+	t := &saveTenantFlags
+	f.StringVar(&t.Name, "name", "", "name of the tenant, shown to its users")
+	f.StringSliceVar(&t.Hosts, "hosts", nil, "comma separated hostnames of the tenant, e.g. app.example.com")
+	f.StringVar(&t.LogoURL, "logo-url", "", "URL of the tenant's logo, shown in apps and emails")
+	f.StringVar(&t.PrimaryColor, "primary-color", "", "primary color of the tenant's apps, e.g. #1a73e8")
+	f.StringVar(&t.LinkDomain, "link-domain", "", "base URL of links to the tenant's app, e.g. https://example.com")
+	f.StringVar(&t.MailFromName, "mail-from-name", "", "sender name of the tenant's emails (defaults to the tenant's name)")
+	f.StringVar(&t.MailFromEmail, "mail-from-email", "", "sender address of the tenant's emails")
+	f.StringVar(&t.BrokerAPIBase, "broker-api-base", "", "Broker API endpoint of the tenant")
+	f.StringVar(&t.BrokerToken, "broker-token", "", `Broker API Authorization header value of the tenant, "Basic <token>"`)
+	saveTenantCmd.MarkFlagRequired("name")
+	rootCmd.AddCommand(saveTenantCmd)
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// BrokerConfig persists the Alpaca Broker API endpoint and credentials
+type BrokerConfig struct {
+	APIBase string `env:"BROKER_API_BASE" envDefault:"https://broker-api.sandbox.alpaca.markets"`
+	// Token is the Authorization header value, e.g. "Basic <token>"
+	Token string `env:"BROKER_TOKEN"`
+}
+
+// GetBrokerConfig returns a BrokerConfig pointer with the correct broker config values
+func GetBrokerConfig() *BrokerConfig {
+	c := BrokerConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// ClosureConfig persists how long the data of closed accounts is kept
+type ClosureConfig struct {
+	// Retention is how long after an account is closed, or deleted by an admin, the personal data of the
+	// user is anonymized by the anonymize_users command
+	Retention time.Duration `env:"CLOSURE_RETENTION" envDefault:"720h"`
+}
+
+// GetClosureConfig returns a ClosureConfig pointer with the correct closure config values
+func GetClosureConfig() *ClosureConfig {
+	c := ClosureConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
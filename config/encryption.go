@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// EncryptionConfig persists the keys used for encrypting sensitive user data at rest
+type EncryptionConfig struct {
+	KeyID string `env:"PII_KEY_ID" envDefault:"default"`
+	// Key is the base64 encoded 32 byte key new values are encrypted with
+	Key string `env:"PII_KEY"`
+	// RetiredKeys holds comma separated id:base64key pairs that are still needed to decrypt rows during key rotation
+	RetiredKeys string `env:"PII_RETIRED_KEYS"`
+}
+
+// GetEncryptionConfig returns an EncryptionConfig pointer with the correct encryption config values
+func GetEncryptionConfig() *EncryptionConfig {
+	c := EncryptionConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
+
+// KeyRing decodes the configured keys into a secret.KeyRing
+func (c *EncryptionConfig) KeyRing() (*secret.KeyRing, error) {
+	if c.Key == "" {
+		return nil, errors.New("Failed to set your environment variable PII_KEY. \n" +
+			"Please do so via \n" +
+			"go run ./entry generate_secret\n" +
+			"export PII_KEY=[the generated key]")
+	}
+	keys := map[string][]byte{}
+	current, err := base64.StdEncoding.DecodeString(c.Key)
+	if err != nil {
+		return nil, fmt.Errorf("PII_KEY is not valid base64: %v", err)
+	}
+	keys[c.KeyID] = current
+	for _, pair := range strings.Split(c.RetiredKeys, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("PII_RETIRED_KEYS entry %q must be in the form id:key", pair)
+		}
+		k, err := base64.StdEncoding.DecodeString(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("PII_RETIRED_KEYS key %q is not valid base64: %v", kv[0], err)
+		}
+		keys[kv[0]] = k
+	}
+	return secret.NewKeyRing(c.KeyID, keys)
+}
+
+// GetFieldCipher returns the cipher used by our repositories to encrypt sensitive columns
+func GetFieldCipher() (*secret.Cipher, error) {
+	ring, err := GetEncryptionConfig().KeyRing()
+	if err != nil {
+		return nil, err
+	}
+	return secret.NewCipher(ring), nil
+}
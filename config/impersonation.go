@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// ImpersonationConfig persists how support staff may sign in as users
+type ImpersonationConfig struct {
+	// TTL is how long impersonation tokens are valid. They can't be refreshed.
+	TTL time.Duration `env:"IMPERSONATION_TTL" envDefault:"15m"`
+	// WritableRoutes are the routes impersonation tokens may change data on, as a method and a
+	// path prefix below /v1, e.g. "POST /v1/watchlist". Every other route is read-only.
+	WritableRoutes []string `env:"IMPERSONATION_WRITABLE_ROUTES" envSeparator:","`
+}
+
+// GetImpersonationConfig returns a ImpersonationConfig pointer with the correct impersonation config values
+func GetImpersonationConfig() *ImpersonationConfig {
+	c := ImpersonationConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
@@ -33,8 +33,11 @@ func LoadJWT(env string) *JWT {
 
 	viper.AutomaticEnv()
 
+	jwt.KeysDir = viper.GetString("JWT_KEYS_DIR")
+	jwt.SigningKeyID = viper.GetString("JWT_SIGNING_KID")
+
 	jwt.Secret = viper.GetString("JWT_SECRET")
-	if jwt.Secret == "" {
+	if jwt.Secret == "" && jwt.KeysDir == "" {
 		if strings.HasPrefix(env, "test") {
 			// generate jwt secret and write into file
 			s, err := secret.GenerateRandomString(256)
@@ -50,19 +53,46 @@ func LoadJWT(env string) *JWT {
 			log.Fatalf("Failed to set your environment variable JWT_SECRET. \n" +
 				"Please do so via \n" +
 				"go run . generate_secret\n" +
-				"export JWT_SECRET=[the generated secret]")
+				"export JWT_SECRET=[the generated secret]\n" +
+				"or sign tokens with asymmetric keys via \n" +
+				"go run . generate_secret jwt_key\n" +
+				"export JWT_KEYS_DIR=[the keys directory]")
 		}
 	}
 
+	if viper.IsSet("JWT_REFRESH_DURATION") {
+		jwt.RefreshDuration = viper.GetInt("JWT_REFRESH_DURATION")
+	}
+	if viper.IsSet("JWT_MAX_REFRESH") {
+		jwt.MaxRefresh = viper.GetInt("JWT_MAX_REFRESH")
+	}
+	if viper.IsSet("JWT_SLIDING_REFRESH") {
+		jwt.SlidingRefresh = viper.GetBool("JWT_SLIDING_REFRESH")
+	}
+	if viper.IsSet("JWT_MAX_LIFETIME") {
+		jwt.MaxLifetime = viper.GetInt("JWT_MAX_LIFETIME")
+	}
+
 	return jwt
 }
 
-// JWT holds data necessary for JWT configuration
+// JWT holds data necessary for JWT configuration.
+// Durations are in minutes.
 type JWT struct {
-	Realm            string `default:"jwtrealm"`
-	Secret           string `default:""`
-	Duration         int    `default:"15"`
-	RefreshDuration  int    `default:"10"`
-	MaxRefresh       int    `default:"10"`
+	Realm    string `default:"jwtrealm"`
+	Secret   string `default:""`
+	Duration int    `default:"15"`
+	// RefreshDuration is how long an unused refresh token stays valid, 7 days by default
+	RefreshDuration int `default:"10080"`
+	// MaxRefresh is the absolute lifetime of a login, however often it is refreshed, 30 days by default
+	MaxRefresh int `default:"43200"`
+	// SlidingRefresh re-signs access tokens close to expiry on use, returning them in the New-Token header
+	SlidingRefresh bool `default:"true"`
+	// MaxLifetime caps how long sliding refresh can keep an access token alive after sign in, 12 hours by default
+	MaxLifetime      int    `default:"720"`
 	SigningAlgorithm string `default:"HS256"`
+	// KeysDir holds RS256/ES256 private keys as <kid>.pem; when set they replace Secret and SigningAlgorithm
+	KeysDir string
+	// SigningKeyID selects the key new tokens are signed with, the newest key by default
+	SigningKeyID string
 }
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// OAuthConfig persists the client IDs of our apps at the identity providers users can sign in with.
+// A provider without client IDs is turned off.
+type OAuthConfig struct {
+	// AppleClientIDs are the bundle IDs of the iOS apps and the service IDs of the web apps
+	AppleClientIDs []string `env:"APPLE_CLIENT_IDS" envSeparator:","`
+	// GoogleClientIDs are the OAuth client IDs of the apps
+	GoogleClientIDs []string `env:"GOOGLE_CLIENT_IDS" envSeparator:","`
+}
+
+// GetOAuthConfig returns an OAuthConfig pointer with the correct identity provider config values
+func GetOAuthConfig() *OAuthConfig {
+	c := OAuthConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// PasswordConfig persists the policy new passwords are checked against
+type PasswordConfig struct {
+	MinLength int `env:"PASSWORD_MIN_LENGTH" envDefault:"8"`
+	// MaxLength guards bcrypt, which ignores everything after 72 bytes
+	MaxLength int `env:"PASSWORD_MAX_LENGTH" envDefault:"72"`
+	// MinClasses is how many of lowercase letters, uppercase letters, digits and symbols a password needs
+	MinClasses int `env:"PASSWORD_MIN_CLASSES" envDefault:"3"`
+	// BreachedDir holds the breached password hashes as range files named by the first 5 hex characters
+	// of their SHA-1, with SUFFIX:COUNT lines, as downloaded from Have I Been Pwned. Empty turns the check off.
+	BreachedDir string `env:"PASSWORD_BREACHED_DIR"`
+	// BreachedMinCount is how often a password has to appear in breaches to be refused
+	BreachedMinCount int `env:"PASSWORD_BREACHED_MIN_COUNT" envDefault:"1"`
+	// History is the number of previous passwords users can't reuse, 0 turns the check off
+	History int `env:"PASSWORD_HISTORY" envDefault:"5"`
+}
+
+// GetPasswordConfig returns a PasswordConfig pointer with the correct password policy config values
+func GetPasswordConfig() *PasswordConfig {
+	c := PasswordConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
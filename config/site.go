@@ -13,6 +13,12 @@ import (
 // SiteConfig persists global configs needed for our application
 type SiteConfig struct {
 	ExternalURL string `env:"EXTERNAL_URL"  envDefault:"http://localhost:8080"`
+	// Name is shown to users, e.g. as the issuer in authenticator apps
+	Name string `env:"SITE_NAME" envDefault:"Ribbit"`
+	// LinkDomain is the base URL of links to the app, such as shareable profile links and links in emails
+	LinkDomain   string `env:"LINK_DOMAIN" envDefault:"https://alpaca.com"`
+	LogoURL      string `env:"SITE_LOGO_URL"`
+	PrimaryColor string `env:"SITE_PRIMARY_COLOR"`
 }
 
 // GetSiteConfig returns a SiteConfig pointer with the correct Site Config values
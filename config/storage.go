@@ -0,0 +1,68 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/storage"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// StorageConfig persists the configuration of our object storage for user uploads
+type StorageConfig struct {
+	// Driver is either local or s3
+	Driver    string        `env:"STORAGE_DRIVER" envDefault:"local"`
+	URLExpiry time.Duration `env:"STORAGE_URL_EXPIRY" envDefault:"1h"`
+
+	LocalDir string `env:"STORAGE_LOCAL_DIR" envDefault:"uploads"`
+	// SigningKey signs the URLs of local objects, which are served below EXTERNAL_URL/storage
+	SigningKey string `env:"STORAGE_SIGNING_KEY"`
+
+	S3Endpoint  string `env:"S3_ENDPOINT" envDefault:"https://s3.amazonaws.com"`
+	S3Region    string `env:"S3_REGION" envDefault:"us-east-1"`
+	S3Bucket    string `env:"S3_BUCKET"`
+	S3AccessKey string `env:"S3_ACCESS_KEY"`
+	S3SecretKey string `env:"S3_SECRET_KEY"`
+	// S3PathStyle addresses buckets as endpoint/bucket, as needed by most self-hosted S3 implementations
+	S3PathStyle bool `env:"S3_PATH_STYLE" envDefault:"false"`
+}
+
+// GetStorageConfig returns a StorageConfig pointer with the correct storage config values
+func GetStorageConfig() *StorageConfig {
+	c := StorageConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
+
+// GetStorage returns the storage service selected by STORAGE_DRIVER
+func GetStorage() (storage.Service, error) {
+	c := GetStorageConfig()
+	switch c.Driver {
+	case "local":
+		if c.SigningKey == "" {
+			return nil, errors.New("Failed to set your environment variable STORAGE_SIGNING_KEY. \n" +
+				"Please do so via \n" +
+				"go run ./entry generate_secret\n" +
+				"export STORAGE_SIGNING_KEY=[the generated key]")
+		}
+		return storage.NewLocal(c.LocalDir, GetSiteConfig().ExternalURL+"/storage", []byte(c.SigningKey)), nil
+	case "s3":
+		return storage.NewS3(c.S3Endpoint, c.S3Region, c.S3Bucket, c.S3AccessKey, c.S3SecretKey, c.S3PathStyle)
+	}
+	return nil, fmt.Errorf("unknown STORAGE_DRIVER %q, expected local or s3", c.Driver)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// ThrottleConfig persists the limits on failed sign ins and one-time passwords
+type ThrottleConfig struct {
+	// AccountLimit is the number of failed sign ins to an account before it is locked
+	AccountLimit int `env:"THROTTLE_ACCOUNT_LIMIT" envDefault:"5"`
+	// IPLimit is the number of failed attempts from a client IP before it is locked
+	IPLimit int `env:"THROTTLE_IP_LIMIT" envDefault:"50"`
+	// OTPLimit is the number of wrong one-time passwords after which outstanding ones are invalidated
+	OTPLimit int `env:"THROTTLE_OTP_LIMIT" envDefault:"5"`
+	// SMSLimit is the number of verification codes sent to a mobile number before further sends are refused
+	SMSLimit int `env:"THROTTLE_SMS_LIMIT" envDefault:"5"`
+	// PasskeyLimit is the number of passkey sign ins a client IP can begin before further ones are refused
+	PasskeyLimit int `env:"THROTTLE_PASSKEY_LIMIT" envDefault:"30"`
+	// Lockout is the first lockout, which doubles with every further failure up to MaxLockout
+	Lockout    time.Duration `env:"THROTTLE_LOCKOUT" envDefault:"1m"`
+	MaxLockout time.Duration `env:"THROTTLE_MAX_LOCKOUT" envDefault:"24h"`
+	// Window is how long failures are remembered after the last one
+	Window time.Duration `env:"THROTTLE_WINDOW" envDefault:"24h"`
+}
+
+// GetThrottleConfig returns a ThrottleConfig pointer with the correct throttle config values
+func GetThrottleConfig() *ThrottleConfig {
+	c := ThrottleConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// VerificationConfig persists the email verification policy
+type VerificationConfig struct {
+	// ResendCooldown is how long users have to wait before requesting another verification code
+	ResendCooldown time.Duration `env:"VERIFICATION_RESEND_COOLDOWN" envDefault:"1m"`
+	// UnverifiedRoutes are the path prefixes below /v1 that users can access before verifying
+	UnverifiedRoutes []string `env:"UNVERIFIED_ROUTES" envSeparator:"," envDefault:"/v1/profile,/v1/countries,/v1/clock,/v1/sessions,/v1/logout,/v1/2fa"`
+}
+
+// GetVerificationConfig returns a VerificationConfig pointer with the correct verification config values
+func GetVerificationConfig() *VerificationConfig {
+	c := VerificationConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"runtime"
+
+	"github.com/caarlos0/env/v6"
+	"github.com/joho/godotenv"
+)
+
+// WebAuthnConfig persists the relying party users register passkeys with
+type WebAuthnConfig struct {
+	// RPID is the domain passkeys are bound to, e.g. ribbitapp.co
+	RPID string `env:"WEBAUTHN_RP_ID" envDefault:"localhost"`
+	// RPName is the name authenticators show when creating a passkey
+	RPName string `env:"WEBAUTHN_RP_NAME" envDefault:"Ribbit"`
+	// Origins are the web and app origins ceremonies are accepted from
+	Origins []string `env:"WEBAUTHN_ORIGINS" envSeparator:"," envDefault:"http://localhost:8080"`
+}
+
+// GetWebAuthnConfig returns a WebAuthnConfig pointer with the correct relying party config values
+func GetWebAuthnConfig() *WebAuthnConfig {
+	c := WebAuthnConfig{}
+
+	_, b, _, _ := runtime.Caller(0)
+	d := path.Join(path.Dir(b))
+	projectRoot := filepath.Dir(d)
+	dotenvPath := path.Join(projectRoot, ".env")
+	_ = godotenv.Load(dotenvPath)
+
+	if err := env.Parse(&c); err != nil {
+		fmt.Printf("%+v\n", err)
+	}
+	return &c
+}
@@ -174,7 +174,7 @@ var doc = `{
 						}
 					},
 					"400": {
-						"description": "Client error",
+						"description": "Client error, e.g. a password that breaks the password policy or appeared in a data breach",
 						"schema": {
 							"$ref": "#/definitions/ErrorResponse"
 						}
@@ -257,6 +257,26 @@ var doc = `{
 				}
 			}
 		},
+    "/tenant": {
+			"get": {
+				"tags": [
+					"Onboarding"
+				],
+				"description": "Returns the branding of the tenant of the request host, or of the default tenant for hosts no tenant claims, for apps to show before sign in.",
+				"summary": "Tenant Branding",
+				"produces": [
+					"application/json"
+				],
+				"responses": {
+					"200": {
+						"description": "Success",
+						"schema": {
+							"$ref": "#/definitions/Tenant"
+						}
+					}
+				}
+			}
+		},
     "/verification/{otp}": {
 			"get": {
 				"tags": [
@@ -277,6 +297,13 @@ var doc = `{
             "required": true,
             "type": "string",
             "format": "byte"
+          },
+					{
+            "in": "query",
+            "name": "email",
+            "description": "the email address the OTP was sent to; links without it are verified by the OTP alone",
+            "required": false,
+            "type": "string"
           }
 				],
 				"responses": {
@@ -304,13 +331,13 @@ var doc = `{
 				}
 			}
 		},
-		"/refresh/{token}": {
+		"/oauth/{provider}": {
 			"post": {
 				"tags": [
 					"Onboarding"
 				],
-				"description": "Refresh your token",
-				"summary": "When token will be expired, it'll renew the token.",
+				"description": "Signs in with an ID token of Sign in with Apple (apple) or Google (google). Unknown accounts are linked to the user with the same email address, if the provider verified it, or else to a new user. Users with two-factor authentication get a challenge token instead, see /login/2fa.",
+				"summary": "Sign in with Apple or Google",
 				"produces": [
 					"application/json"
 				],
@@ -319,19 +346,182 @@ var doc = `{
 				],
 				"parameters": [
 					{
-						"name": "expires",
+						"in": "path",
+						"name": "provider",
+						"required": true,
+						"type": "string"
+					},
+					{
 						"in": "body",
+						"name": "body",
 						"required": true,
-						"description": "Next expiry",
 						"schema": {
-							"type": "string"
+							"type": "object",
+							"properties": {
+								"id_token": {
+									"type": "string"
+								},
+								"nonce": {
+									"type": "string"
+								}
+							}
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"401": {
+						"description": "Invalid ID token or unverified email address",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"409": {
+						"description": "An unverified account with the email address exists",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/passkeys/login/begin": {
+			"post": {
+				"tags": [
+					"Onboarding"
+				],
+				"description": "Returns the options for navigator.credentials.get(), with a challenge that is valid for 5 minutes.",
+				"summary": "Begin signing in with a passkey",
+				"produces": [
+					"application/json"
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					}
+				}
+			}
+		},
+		"/passkeys/login/finish": {
+			"post": {
+				"tags": [
+					"Onboarding"
+				],
+				"description": "Signs in with the credential returned by navigator.credentials.get(), with binary values base64url encoded. The authenticator verified the user, so there is no second factor to confirm.",
+				"summary": "Finish signing in with a passkey",
+				"produces": [
+					"application/json"
+				],
+				"consumes": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "body",
+						"name": "body",
+						"required": true,
+						"schema": {
+							"type": "object",
+							"properties": {
+								"id": {
+									"type": "string"
+								},
+								"response": {
+									"type": "object",
+									"properties": {
+										"clientDataJSON": {
+											"type": "string"
+										},
+										"authenticatorData": {
+											"type": "string"
+										},
+										"signature": {
+											"type": "string"
+										},
+										"userHandle": {
+											"type": "string"
+										}
+									}
+								}
+							}
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"401": {
+						"description": "Invalid passkey or expired challenge",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
 						}
+					}
+				}
+			}
+		},
+		"/verification/resend": {
+			"post": {
+				"tags": [
+					"Onboarding"
+				],
+				"description": "Emails a new verification code to a user who hasn't verified yet. A new code can be requested once per VERIFICATION_RESEND_COOLDOWN; unknown and verified emails get the same response.",
+				"summary": "Resend verification code",
+				"produces": [
+					"application/json"
+				],
+				"consumes": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "body",
+						"name": "body",
+						"required": true,
+						"schema": {
+							"type": "object",
+							"properties": {
+								"email": {
+									"type": "string"
+								}
+							}
+						}
+					}
+				],
+				"responses": {
+					"202": {
+						"description": "Accepted"
 					},
+					"429": {
+						"description": "Client error",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/refresh": {
+			"post": {
+				"tags": [
+					"Onboarding"
+				],
+				"description": "Exchanges a refresh token for a new access token and a new refresh token. Each refresh token can be used once; reusing one signs out every device of that login.",
+				"summary": "When token will be expired, it'll renew the token.",
+				"produces": [
+					"application/json"
+				],
+				"consumes": [
+					"application/json"
+				],
+				"parameters": [
 					{
-						"name": "token",
+						"name": "refresh_token",
 						"in": "body",
 						"required": true,
-						"description": "Valid token",
+						"description": "Refresh token returned by the last login or refresh",
 						"schema": {
 							"type": "string"
 						}
@@ -1339,55 +1529,886 @@ var doc = `{
 		"/forgot-password": {
 			"post": {
 				"tags": [
-					"Onboarding"
+					"Onboarding"
+				],
+				"description": "forgot password",
+				"summary": "Enter email to forgot password",
+				"produces": [
+					"application/json"
+				],
+				"consumes": [
+					"application/json"
+				],
+				"parameters": [
+					{
+            "in": "body",
+            "name": "email",
+            "description": "Enter user's email address.",
+            "schema": {
+              "type": "object",
+              "required": [
+                "email"
+              ],
+              "properties": {
+                "email": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+				],
+				"responses": {
+					"200": {
+						"description": "Success",
+						"schema": {
+							"$ref": "#/definitions/TokenResponse"
+						}
+					},
+					"400": {
+						"description": "Client error",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"422": {
+						"description": "Client error",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"500": {
+						"description": "Client error",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/recover-password": {
+			"post": {
+				"tags": [
+					"Onboarding"
+				],
+				"description": "recover password",
+				"summary": "recover password",
+				"produces": [
+					"application/json"
+				],
+				"consumes": [
+					"application/json"
+				],
+				"parameters": [
+					{
+            "in": "body",
+            "name": "Request payload",
+            "description": "Fill recover password data.",
+            "schema": {
+              "type": "object",
+              "required": [
+                "email",
+                "otp",
+                "password",
+                "confrim_password"
+              ],
+              "properties": {
+                "email": {
+                  "type": "string"
+                },
+                "otp": {
+                  "type": "string"
+                },
+                "password": {
+                  "type": "string"
+                },
+                "confrim_password": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+				],
+				"responses": {
+					"204": {
+						"description": "Success"
+					},
+					"400": {
+						"description": "Client error, e.g. a password that breaks the password policy, appeared in a data breach or was used recently",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"422": {
+						"description": "Client error",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"500": {
+						"description": "Client error",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/v1/permissions": {
+			"get": {
+				"tags": [
+					"Roles"
+				],
+				"description": "Lists the permissions roles can be granted, e.g. users:read.",
+				"summary": "List permissions",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the roles:manage permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/v1/roles": {
+			"get": {
+				"tags": [
+					"Roles"
+				],
+				"description": "Lists the roles with their permissions. The superadmin role has all permissions without being granted them.",
+				"summary": "List roles",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the roles:manage permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/v1/roles/{id}/permissions/{permission}": {
+			"put": {
+				"tags": [
+					"Roles"
+				],
+				"description": "Grants a permission to a role. Users with the role get it within 30 seconds.",
+				"summary": "Grant a permission",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					},
+					{
+						"in": "path",
+						"name": "permission",
+						"required": true,
+						"type": "string"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the roles:manage permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			},
+			"delete": {
+				"tags": [
+					"Roles"
+				],
+				"description": "Revokes a permission from a role.",
+				"summary": "Revoke a permission",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					},
+					{
+						"in": "path",
+						"name": "permission",
+						"required": true,
+						"type": "string"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the roles:manage permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/audit": {
+			"get": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Lists audit events newest first: actions of support staff, role changes, and profile, password, bank, transfer, order and position changes of users. Pass next as before to get the next page.",
+				"summary": "Query the audit log",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "query",
+						"name": "actor_id",
+						"required": false,
+						"type": "integer",
+						"description": "User who took the action"
+					},
+					{
+						"in": "query",
+						"name": "target_user_id",
+						"required": false,
+						"type": "integer",
+						"description": "User the action was taken on"
+					},
+					{
+						"in": "query",
+						"name": "action",
+						"required": false,
+						"type": "string",
+						"description": "e.g. password.change"
+					},
+					{
+						"in": "query",
+						"name": "from",
+						"required": false,
+						"type": "string",
+						"description": "Events at or after this time",
+						"format": "date-time"
+					},
+					{
+						"in": "query",
+						"name": "to",
+						"required": false,
+						"type": "string",
+						"description": "Events before this time",
+						"format": "date-time"
+					},
+					{
+						"in": "query",
+						"name": "before",
+						"required": false,
+						"type": "integer",
+						"description": "Events older than this event ID"
+					},
+					{
+						"in": "query",
+						"name": "limit",
+						"required": false,
+						"type": "integer",
+						"description": "At most 500, 50 by default"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the audit:read permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users": {
+			"get": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Searches users by email, mobile number or broker account number or ID. Returns at most 50 users.",
+				"summary": "Search users",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "query",
+						"name": "q",
+						"required": true,
+						"type": "string"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the users:read permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}": {
+			"get": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Returns a user with their internal notes and rewards.",
+				"summary": "View a user",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the users:read permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"404": {
+						"description": "User not found",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}/broker": {
+			"get": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Returns the broker account of a user with its status and KYC results.",
+				"summary": "View a broker account",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the kyc:review permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"404": {
+						"description": "User not found",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}/orders": {
+			"get": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Returns the orders of a user from the broker.",
+				"summary": "List orders",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the users:read permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"404": {
+						"description": "User not found",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}/positions": {
+			"get": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Returns the positions of a user from the broker.",
+				"summary": "List positions",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the users:read permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"404": {
+						"description": "User not found",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}/transfers": {
+			"get": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Returns the transfers of a user from the broker.",
+				"summary": "List transfers",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the users:read permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"404": {
+						"description": "User not found",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}/activate": {
+			"post": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Activates a verified user that was deactivated.",
+				"summary": "Activate a user",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the users:write permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"409": {
+						"description": "The user is not verified",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}/deactivate": {
+			"post": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Deactivates a verified user and signs them out everywhere. Staff can only deactivate users with a lower role.",
+				"summary": "Deactivate a user",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					},
+					{
+						"in": "body",
+						"name": "body",
+						"required": true,
+						"schema": {
+							"type": "object",
+							"required": [
+								"reason"
+							],
+							"properties": {
+								"reason": {
+									"type": "string"
+								}
+							}
+						}
+					}
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the users:write permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"409": {
+						"description": "The user is not verified",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}/verification": {
+			"post": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Emails an unverified user a new verification code.",
+				"summary": "Resend verification",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					}
+				],
+				"responses": {
+					"202": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the users:write permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"409": {
+						"description": "The user is verified already or has no email address",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}/rewards": {
+			"post": {
+				"tags": [
+					"Admin"
+				],
+				"description": "Grants a user a reward of up to 1000, paid out like referral rewards.",
+				"summary": "Grant a reward",
+				"produces": [
+					"application/json"
+				],
+				"parameters": [
+					{
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
+					},
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					},
+					{
+						"in": "body",
+						"name": "body",
+						"required": true,
+						"schema": {
+							"type": "object",
+							"required": [
+								"amount",
+								"reason"
+							],
+							"properties": {
+								"amount": {
+									"type": "number"
+								},
+								"reason": {
+									"type": "string"
+								}
+							}
+						}
+					}
+				],
+				"responses": {
+					"201": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Missing the rewards:grant permission",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"404": {
+						"description": "User not found",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+		"/admin/users/{id}/notes": {
+			"post": {
+				"tags": [
+					"Admin"
 				],
-				"description": "forgot password",
-				"summary": "Enter email to forgot password",
+				"description": "Adds an internal note on a user.",
+				"summary": "Add a note",
 				"produces": [
 					"application/json"
 				],
-				"consumes": [
-					"application/json"
-				],
 				"parameters": [
 					{
-            "in": "body",
-            "name": "email",
-            "description": "Enter user's email address.",
-            "schema": {
-              "type": "object",
-              "required": [
-                "email"
-              ],
-              "properties": {
-                "email": {
-                  "type": "string"
-                }
-              }
-            }
-          }
-				],
-				"responses": {
-					"200": {
-						"description": "Success",
-						"schema": {
-							"$ref": "#/definitions/TokenResponse"
-						}
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
 					},
-					"400": {
-						"description": "Client error",
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					},
+					{
+						"in": "body",
+						"name": "body",
+						"required": true,
 						"schema": {
-							"$ref": "#/definitions/ErrorResponse"
+							"type": "object",
+							"required": [
+								"body"
+							],
+							"properties": {
+								"body": {
+									"type": "string"
+								}
+							}
 						}
+					}
+				],
+				"responses": {
+					"201": {
+						"description": "Success"
 					},
-					"422": {
-						"description": "Client error",
+					"403": {
+						"description": "Missing the users:write permission",
 						"schema": {
 							"$ref": "#/definitions/ErrorResponse"
 						}
 					},
-					"500": {
-						"description": "Client error",
+					"404": {
+						"description": "User not found",
 						"schema": {
 							"$ref": "#/definitions/ErrorResponse"
 						}
@@ -1395,67 +2416,59 @@ var doc = `{
 				}
 			}
 		},
-		"/recover-password": {
+		"/admin/users/{id}/impersonate": {
 			"post": {
 				"tags": [
-					"Onboarding"
+					"Admin"
 				],
-				"description": "recover password",
-				"summary": "recover password",
+				"description": "Issues a short-lived token to act as a customer with a lower role; staff can't be impersonated. The token is read-only except on IMPERSONATION_WRITABLE_ROUTES, can't be refreshed, has no staff permissions, and every request made with it is recorded in the audit log. Responses to it carry the X-Impersonated-By header.",
+				"summary": "Impersonate a user",
 				"produces": [
 					"application/json"
 				],
-				"consumes": [
-					"application/json"
-				],
 				"parameters": [
 					{
-            "in": "body",
-            "name": "Request payload",
-            "description": "Fill recover password data.",
-            "schema": {
-              "type": "object",
-              "required": [
-                "email",
-                "otp",
-                "password",
-                "confrim_password"
-              ],
-              "properties": {
-                "email": {
-                  "type": "string"
-                },
-                "otp": {
-                  "type": "string"
-                },
-                "password": {
-                  "type": "string"
-                },
-                "confrim_password": {
-                  "type": "string"
-                }
-              }
-            }
-          }
-				],
-				"responses": {
-					"204": {
-						"description": "Success"
+						"in": "header",
+						"name": "Authorization",
+						"required": true,
+						"type": "string",
+						"format": "byte"
 					},
-					"400": {
-						"description": "Client error",
+					{
+						"in": "path",
+						"name": "id",
+						"required": true,
+						"type": "integer"
+					},
+					{
+						"in": "body",
+						"name": "body",
+						"required": true,
 						"schema": {
-							"$ref": "#/definitions/ErrorResponse"
+							"type": "object",
+							"required": [
+								"reason"
+							],
+							"properties": {
+								"reason": {
+									"type": "string"
+								}
+							}
 						}
+					}
+				],
+				"responses": {
+					"201": {
+						"description": "Success"
 					},
-					"422": {
-						"description": "Client error",
+					"403": {
+						"description": "Missing the users:impersonate permission, or the user is staff or doesn't have a lower role",
 						"schema": {
 							"$ref": "#/definitions/ErrorResponse"
 						}
 					},
-					"500": {
-						"description": "Client error",
+					"404": {
+						"description": "User not found",
 						"schema": {
 							"$ref": "#/definitions/ErrorResponse"
 						}
@@ -1468,7 +2481,7 @@ var doc = `{
 				"tags": [
 					"Users"
 				],
-				"description": "Get users",
+				"description": "Lists users, filtered, sorted and paged. total is the number of users matching the filters. Pass next_cursor as cursor to get the next page.",
 				"summary": "Get users",
 				"produces": [
 					"application/json"
@@ -1483,7 +2496,86 @@ var doc = `{
             "required": true,
             "type": "string",
             "format": "byte"
-          }
+          },
+					{
+						"in": "query",
+						"name": "role",
+						"required": false,
+						"type": "integer",
+						"description": "Role access level, e.g. 3 for users"
+					},
+					{
+						"in": "query",
+						"name": "verified",
+						"required": false,
+						"type": "boolean",
+						"description": "Verified users only, or unverified only"
+					},
+					{
+						"in": "query",
+						"name": "active",
+						"required": false,
+						"type": "boolean",
+						"description": "Active users only, or deactivated only"
+					},
+					{
+						"in": "query",
+						"name": "account_status",
+						"required": false,
+						"type": "string",
+						"description": "Broker account status, e.g. APPROVED"
+					},
+					{
+						"in": "query",
+						"name": "created_from",
+						"required": false,
+						"type": "string",
+						"description": "Users created at or after this time",
+						"format": "date-time"
+					},
+					{
+						"in": "query",
+						"name": "created_to",
+						"required": false,
+						"type": "string",
+						"description": "Users created before this time",
+						"format": "date-time"
+					},
+					{
+						"in": "query",
+						"name": "q",
+						"required": false,
+						"type": "string",
+						"description": "Searches names, emails and mobile numbers"
+					},
+					{
+						"in": "query",
+						"name": "sort",
+						"required": false,
+						"type": "string",
+						"description": "id, created_at, email, first_name or last_name, prefixed with - to sort descending"
+					},
+					{
+						"in": "query",
+						"name": "cursor",
+						"required": false,
+						"type": "string",
+						"description": "next_cursor of the previous page"
+					},
+					{
+						"in": "query",
+						"name": "limit",
+						"required": false,
+						"type": "integer",
+						"description": "Users per page"
+					},
+					{
+						"in": "query",
+						"name": "page",
+						"required": false,
+						"type": "integer",
+						"description": "Page number, when there is no cursor"
+					}
 				],
 				"responses": {
 					"200": {
@@ -1963,6 +3055,107 @@ var doc = `{
 				}
 			}
 		},
+    "/v1/account/close": {
+			"post": {
+				"tags": [
+					"Account"
+				],
+				"description": "Closes the broker account and then the account of the current user, signing them out everywhere. Open positions and pending transfers have to be settled first. Personal data is anonymized after CLOSURE_RETENTION. Users with two-factor authentication must have confirmed their second factor recently.",
+				"summary": "Close Account",
+				"produces": [
+					"application/json"
+				],
+				"consumes": [
+					"application/json"
+				],
+				"parameters": [
+          {
+            "in": "header",
+            "name": "Authorization",
+            "required": true,
+            "type": "string",
+            "format": "byte"
+          },
+          {
+            "in": "body",
+            "name": "body",
+            "required": false,
+            "schema": {
+              "type": "object",
+              "properties": {
+                "reason": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+				],
+				"responses": {
+					"200": {
+						"description": "Success"
+					},
+					"403": {
+						"description": "Second factor not confirmed recently",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"409": {
+						"description": "Open positions or pending transfers",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"502": {
+						"description": "The broker is unavailable",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
+    "/v1/account/export": {
+			"get": {
+				"tags": [
+					"Account"
+				],
+				"description": "Downloads a ZIP archive of the data of the current user: profile.json, orders.json, transfers.json, rewards.json and audit.jsonl. Users with two-factor authentication must have confirmed their second factor recently.",
+				"summary": "Export Account Data",
+				"produces": [
+					"application/zip"
+				],
+				"parameters": [
+          {
+            "in": "header",
+            "name": "Authorization",
+            "required": true,
+            "type": "string",
+            "format": "byte"
+          }
+				],
+				"responses": {
+					"200": {
+						"description": "Success",
+						"schema": {
+							"type": "file"
+						}
+					},
+					"403": {
+						"description": "Second factor not confirmed recently",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					},
+					"502": {
+						"description": "The broker is unavailable",
+						"schema": {
+							"$ref": "#/definitions/ErrorResponse"
+						}
+					}
+				}
+			}
+		},
     "/v1/clock": {
 			"get": {
 				"tags": [
@@ -3150,6 +4343,28 @@ var doc = `{
 		
   },
   "definitions": {
+    "Tenant": {
+      "type": "object",
+      "properties": {
+        "id": {
+          "type": "integer",
+          "description": "0 for the default tenant"
+        },
+        "name": {
+          "type": "string"
+        },
+        "logo_url": {
+          "type": "string"
+        },
+        "primary_color": {
+          "type": "string"
+        },
+        "link_domain": {
+          "type": "string",
+          "description": "base URL of links to the app"
+        }
+      }
+    },
     "ErrorResponse": {
       "type": "object",
       "properties": {
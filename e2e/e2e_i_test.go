@@ -10,6 +10,7 @@ import (
 
 	"github.com/alpacahq/ribbit-backend/config"
 	"github.com/alpacahq/ribbit-backend/e2e"
+	"github.com/alpacahq/ribbit-backend/geo"
 	"github.com/alpacahq/ribbit-backend/manager"
 	mw "github.com/alpacahq/ribbit-backend/middleware"
 	"github.com/alpacahq/ribbit-backend/mock"
@@ -17,6 +18,7 @@ import (
 	"github.com/alpacahq/ribbit-backend/repository"
 	"github.com/alpacahq/ribbit-backend/route"
 	"github.com/alpacahq/ribbit-backend/secret"
+	"github.com/alpacahq/ribbit-backend/storage"
 	"github.com/alpacahq/ribbit-backend/testhelper"
 
 	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
@@ -50,6 +52,7 @@ type E2ETestSuite struct {
 	m         *manager.Manager
 	r         *gin.Engine
 	v         *model.Verification
+	vEmail    string
 	authToken model.AuthToken
 }
 
@@ -87,7 +90,7 @@ func (suite *E2ETestSuite) SetupSuite() {
 
 	log, _ := zap.NewDevelopment()
 	defer log.Sync()
-	accountRepo := repository.NewAccountRepo(suite.db, log, secret.New())
+	accountRepo := repository.NewAccountRepo(suite.db, log, secret.New(), mock.Cipher())
 	roleRepo := repository.NewRoleRepo(suite.db, log)
 	suite.m = manager.NewManager(accountRepo, roleRepo, suite.db)
 
@@ -119,7 +122,7 @@ func (suite *E2ETestSuite) SetupSuite() {
 	}
 
 	// setup routes
-	rs := route.NewServices(suite.db, log, jwt, m, mobile, r)
+	rs := route.NewServices(suite.db, log, jwt, m, mobile, &mock.Magic{}, mock.Cipher(), geo.New(), storage.NewLocal(os.TempDir(), "http://localhost/storage", []byte("test")), r)
 	rs.SetupV1Routes()
 
 	// we can now test our routes in an end-to-end fashion by making http calls
@@ -167,5 +170,6 @@ func TestE2ETestSuiteIntegration(t *testing.T) {
 // our mock verification token is saved into suite.token for subsequent use
 func (suite *E2ETestSuite) sendVerification(email string, v *model.Verification) error {
 	suite.v = v
+	suite.vEmail = email
 	return nil
 }
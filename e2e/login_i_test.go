@@ -3,6 +3,7 @@ package e2e_test
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -58,8 +59,9 @@ func (suite *E2ETestSuite) TestRefreshToken() {
 	// delay by 1 second so that our re-generated JWT will have a 1 second difference
 	time.Sleep(1 * time.Second)
 
-	url := ts.URL + "/refresh/" + suite.authToken.RefreshToken
-	resp, err := http.Get(url)
+	url := ts.URL + "/refresh"
+	req := fmt.Sprintf(`{"refresh_token":%q}`, suite.authToken.RefreshToken)
+	resp, err := http.Post(url, "application/json", bytes.NewBufferString(req))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -81,4 +83,6 @@ func (suite *E2ETestSuite) TestRefreshToken() {
 	// because of a 1 second delay, our re-generated JWT will definitely be different
 	assert.NotEqual(t, suite.authToken.Token, refreshToken.Token)
 	assert.NotEqual(t, suite.authToken.Expires, refreshToken.Expires)
+	// refresh tokens are rotated on every use
+	assert.NotEqual(t, suite.authToken.RefreshToken, refreshToken.RefreshToken)
 }
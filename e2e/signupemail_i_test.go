@@ -58,7 +58,7 @@ func (suite *E2ETestSuite) TestVerification() {
 	ts := httptest.NewServer(suite.r)
 	defer ts.Close()
 
-	url := ts.URL + "/verification/" + v.Token
+	url := ts.URL + "/verification/" + v.Token + "?email=" + suite.vEmail
 	fmt.Println("This is our verification url", url)
 
 	resp, err := http.Get(url)
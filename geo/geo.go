@@ -0,0 +1,379 @@
+package geo
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Country is a country from countries.csv, identified by its ISO 3166-1 alpha-3 code
+type Country struct {
+	Name      string `json:"name"`
+	ShortCode string `json:"short_code"`
+	ISO2      string `json:"iso2,omitempty"`
+}
+
+// State is a first-level subdivision of a country
+type State struct {
+	Name      string `json:"name"`
+	ShortCode string `json:"short_code"`
+}
+
+// City is a city within a state
+type City struct {
+	Name  string   `json:"name"`
+	ASCII string   `json:"ascii"`
+	LAT   string   `json:"lat"`
+	LNG   string   `json:"lng"`
+	Zips  []string `json:"-"`
+}
+
+// Index is an in-memory index of our geography reference data.
+// It is built once at startup and is safe for concurrent reads afterwards.
+type Index struct {
+	countries []Country
+	byCode    map[string]Country           // alpha-3 and alpha-2 codes -> country
+	states    map[string][]State           // country code -> states
+	cities    map[string][]City            // country code + "/" + state code -> cities
+	zips      map[string]map[string]string // country code -> zip -> state code
+
+	countryNames prefixIndex
+	stateNames   map[string]prefixIndex
+	cityNames    map[string]prefixIndex
+	version      string
+}
+
+// New returns an empty index which only knows the built-in US states
+func New() *Index {
+	idx := &Index{
+		byCode: map[string]Country{},
+		states: map[string][]State{},
+		cities: map[string][]City{},
+		zips:   map[string]map[string]string{},
+	}
+	for _, s := range usStates {
+		idx.AddState("USA", s)
+	}
+	idx.Build()
+	return idx
+}
+
+// LoadDir builds an index from countries.csv and, if present, uscities.csv in dir
+func LoadDir(dir string) (*Index, error) {
+	idx := New()
+	f, err := os.Open(filepath.Join(dir, "countries.csv"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := idx.loadCountries(f); err != nil {
+		return nil, err
+	}
+
+	cities, err := os.Open(filepath.Join(dir, "uscities.csv"))
+	if os.IsNotExist(err) {
+		idx.Build()
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer cities.Close()
+	if err := idx.loadUSCities(cities); err != nil {
+		return nil, err
+	}
+	idx.Build()
+	return idx, nil
+}
+
+// loadCountries reads rows of short_code,name
+func (idx *Index) loadCountries(r io.Reader) error {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		if i == 0 || len(row) < 2 {
+			continue // header
+		}
+		idx.AddCountry(Country{ShortCode: strings.TrimSpace(row[0]), Name: strings.TrimSpace(row[1])})
+	}
+	return nil
+}
+
+// loadUSCities reads the simplemaps uscities.csv layout:
+// city, city_ascii, state_id, state_name, county_fips, county_name, lat, lng, ..., zips (space separated), ...
+func (idx *Index) loadUSCities(r io.Reader) error {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	zipsCol := -1
+	for i, h := range header {
+		if h == "zips" {
+			zipsCol = i
+		}
+	}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(row) < 8 {
+			continue
+		}
+		city := City{Name: row[0], ASCII: row[1], LAT: row[6], LNG: row[7]}
+		if zipsCol >= 0 && zipsCol < len(row) {
+			city.Zips = strings.Fields(row[zipsCol])
+		}
+		idx.AddState("USA", State{ShortCode: row[2], Name: row[3]})
+		idx.AddCity("USA", row[2], city)
+	}
+	return nil
+}
+
+// AddCountry adds c to the index, ignoring duplicates. The alpha-2 code is filled in if unset.
+func (idx *Index) AddCountry(c Country) {
+	if _, ok := idx.byCode[c.ShortCode]; ok {
+		return
+	}
+	if c.ISO2 == "" {
+		c.ISO2 = alpha3To2[c.ShortCode]
+	}
+	idx.byCode[c.ShortCode] = c
+	if c.ISO2 != "" {
+		idx.byCode[c.ISO2] = c
+	}
+	idx.countries = append(idx.countries, c)
+}
+
+// AddState adds s to country, ignoring duplicates
+func (idx *Index) AddState(country string, s State) {
+	for _, existing := range idx.states[country] {
+		if existing.ShortCode == s.ShortCode {
+			return
+		}
+	}
+	idx.states[country] = append(idx.states[country], s)
+}
+
+// AddCity adds c to a state of country and records its zip codes
+func (idx *Index) AddCity(country, state string, c City) {
+	key := country + "/" + state
+	idx.cities[key] = append(idx.cities[key], c)
+	if len(c.Zips) == 0 {
+		return
+	}
+	zips, ok := idx.zips[country]
+	if !ok {
+		zips = map[string]string{}
+		idx.zips[country] = zips
+	}
+	for _, z := range c.Zips {
+		zips[z] = state
+	}
+}
+
+// Build sorts the data and rebuilds the search indexes. It must be called after adding data.
+func (idx *Index) Build() {
+	h := sha1.New()
+	idx.countryNames = newPrefixIndex(len(idx.countries), func(i int) string { return idx.countries[i].Name })
+	for _, c := range idx.countries {
+		fmt.Fprintf(h, "c%s|%s|%s\n", c.ShortCode, c.ISO2, c.Name)
+	}
+
+	idx.stateNames = map[string]prefixIndex{}
+	for _, country := range stateKeys(idx.states) {
+		states := idx.states[country]
+		sort.SliceStable(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+		idx.stateNames[country] = newPrefixIndex(len(states), func(i int) string { return states[i].Name })
+		for _, s := range states {
+			fmt.Fprintf(h, "s%s|%s|%s\n", country, s.ShortCode, s.Name)
+		}
+	}
+
+	idx.cityNames = map[string]prefixIndex{}
+	for _, key := range cityKeys(idx.cities) {
+		cities := idx.cities[key]
+		sort.SliceStable(cities, func(i, j int) bool { return cities[i].Name < cities[j].Name })
+		idx.cityNames[key] = newPrefixIndex(len(cities), func(i int) string { return cities[i].Name })
+		for _, c := range cities {
+			fmt.Fprintf(h, "t%s|%s|%s|%s\n", key, c.Name, c.LAT, c.LNG)
+		}
+	}
+	idx.version = hex.EncodeToString(h.Sum(nil))
+}
+
+// Version returns a hash of the indexed data, suitable as an ETag
+func (idx *Index) Version() string {
+	return idx.version
+}
+
+// Countries returns all countries in file order
+func (idx *Index) Countries() []Country {
+	return idx.countries
+}
+
+// States returns the states of a country sorted by name
+func (idx *Index) States(country string) []State {
+	return idx.states[country]
+}
+
+// Cities returns the cities of a state sorted by name
+func (idx *Index) Cities(country, state string) []City {
+	return idx.cities[country+"/"+state]
+}
+
+// Country looks up a country by its ISO 3166-1 alpha-3 or alpha-2 code
+func (idx *Index) Country(code string) (Country, bool) {
+	c, ok := idx.byCode[strings.ToUpper(code)]
+	return c, ok
+}
+
+// SearchCountries returns the countries whose name starts with prefix (case insensitive),
+// paginated by offset and limit, along with the total number of matches.
+// A limit below 1 returns every match.
+func (idx *Index) SearchCountries(prefix string, offset, limit int) ([]Country, int) {
+	pos := idx.countryNames.search(prefix)
+	out := make([]Country, 0, len(pos))
+	for _, i := range page(pos, offset, limit) {
+		out = append(out, idx.countries[i])
+	}
+	return out, len(pos)
+}
+
+// SearchStates is like SearchCountries for the states of country
+func (idx *Index) SearchStates(country, prefix string, offset, limit int) ([]State, int) {
+	states := idx.states[country]
+	pos := idx.stateNames[country].search(prefix)
+	out := make([]State, 0, len(pos))
+	for _, i := range page(pos, offset, limit) {
+		out = append(out, states[i])
+	}
+	return out, len(pos)
+}
+
+// SearchCities is like SearchCountries for the cities of a state
+func (idx *Index) SearchCities(country, state, prefix string, offset, limit int) ([]City, int) {
+	key := country + "/" + state
+	cities := idx.cities[key]
+	pos := idx.cityNames[key].search(prefix)
+	out := make([]City, 0, len(pos))
+	for _, i := range page(pos, offset, limit) {
+		out = append(out, cities[i])
+	}
+	return out, len(pos)
+}
+
+// CountryExists reports whether code is a known ISO 3166-1 alpha-3 country code
+func (idx *Index) CountryExists(code string) bool {
+	c, ok := idx.byCode[code]
+	return ok && c.ShortCode == code
+}
+
+// StateExists reports whether state is a known state of country.
+// Countries we hold no state data for accept any state.
+func (idx *Index) StateExists(country, state string) bool {
+	states, ok := idx.states[country]
+	if !ok {
+		return true
+	}
+	for _, s := range states {
+		if s.ShortCode == state {
+			return true
+		}
+	}
+	return false
+}
+
+// ZipMatchesState reports whether zip belongs to state.
+// It returns true when we hold no zip data for the country or the zip is unknown.
+func (idx *Index) ZipMatchesState(country, state, zip string) bool {
+	zips, ok := idx.zips[country]
+	if !ok {
+		return true
+	}
+	if len(zip) > 5 {
+		zip = zip[:5]
+	}
+	s, ok := zips[zip]
+	return !ok || s == state
+}
+
+// prefixIndex holds lower cased names sorted alphabetically, each with the position
+// of its entry in the indexed slice, so prefix matches are found by binary search
+type prefixIndex struct {
+	names []string
+	pos   []int
+}
+
+func newPrefixIndex(n int, name func(i int) string) prefixIndex {
+	p := prefixIndex{names: make([]string, n), pos: make([]int, n)}
+	for i := 0; i < n; i++ {
+		p.names[i] = strings.ToLower(name(i))
+		p.pos[i] = i
+	}
+	sort.Sort(p)
+	return p
+}
+
+func (p prefixIndex) Len() int { return len(p.names) }
+func (p prefixIndex) Less(i, j int) bool {
+	return p.names[i] < p.names[j] || (p.names[i] == p.names[j] && p.pos[i] < p.pos[j])
+}
+func (p prefixIndex) Swap(i, j int) {
+	p.names[i], p.names[j] = p.names[j], p.names[i]
+	p.pos[i], p.pos[j] = p.pos[j], p.pos[i]
+}
+
+// search returns the positions of the entries starting with prefix, in slice order
+func (p prefixIndex) search(prefix string) []int {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	from := sort.SearchStrings(p.names, prefix)
+	to := from
+	for to < len(p.names) && strings.HasPrefix(p.names[to], prefix) {
+		to++
+	}
+	out := append([]int(nil), p.pos[from:to]...)
+	sort.Ints(out)
+	return out
+}
+
+func page(pos []int, offset, limit int) []int {
+	if offset >= len(pos) {
+		return nil
+	}
+	pos = pos[offset:]
+	if limit > 0 && limit < len(pos) {
+		pos = pos[:limit]
+	}
+	return pos
+}
+
+func stateKeys(m map[string][]State) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func cityKeys(m map[string][]City) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
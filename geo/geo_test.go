@@ -0,0 +1,88 @@
+package geo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const citiesCSV = `"city","city_ascii","state_id","state_name","county_fips","county_name","lat","lng","zips"
+"San Francisco","San Francisco","CA","California","06075","San Francisco","37.7562","-122.4430","94102 94103 94105"
+"Sacramento","Sacramento","CA","California","06067","Sacramento","38.5667","-121.4683","95814"
+"San Diego","San Diego","CA","California","06073","San Diego","32.8312","-117.1225","92101"
+"Austin","Austin","TX","Texas","48453","Travis","30.3005","-97.7522","73301 78701"
+`
+
+func testIndex(t *testing.T) *Index {
+	idx, err := LoadDir("..")
+	assert.Nil(t, err)
+	assert.Nil(t, idx.loadUSCities(strings.NewReader(citiesCSV)))
+	idx.Build()
+	return idx
+}
+
+func TestCountryLookup(t *testing.T) {
+	idx := testIndex(t)
+
+	c, ok := idx.Country("USA")
+	assert.True(t, ok)
+	assert.Equal(t, "US", c.ISO2)
+
+	c, ok = idx.Country("de")
+	assert.True(t, ok)
+	assert.Equal(t, "DEU", c.ShortCode)
+
+	_, ok = idx.Country("XXX")
+	assert.False(t, ok)
+
+	assert.True(t, idx.CountryExists("DEU"))
+	assert.False(t, idx.CountryExists("DE"))
+}
+
+func TestSearch(t *testing.T) {
+	idx := testIndex(t)
+
+	countries, total := idx.SearchCountries("united", 0, 0)
+	assert.True(t, total >= 3)
+	assert.Len(t, countries, total)
+	for _, c := range countries {
+		assert.True(t, strings.HasPrefix(strings.ToLower(c.Name), "united"))
+	}
+
+	page, total2 := idx.SearchCountries("united", 1, 1)
+	assert.Equal(t, total, total2)
+	assert.Equal(t, []Country{countries[1]}, page)
+
+	page, _ = idx.SearchCountries("united", total, 10)
+	assert.Empty(t, page)
+
+	cities, total := idx.SearchCities("USA", "CA", "san", 0, 0)
+	assert.Equal(t, 2, total)
+	assert.Equal(t, "San Diego", cities[0].Name)
+	assert.Equal(t, "San Francisco", cities[1].Name)
+
+	states, _ := idx.SearchStates("USA", "tex", 0, 0)
+	assert.Equal(t, []State{{Name: "Texas", ShortCode: "TX"}}, states)
+}
+
+func TestZipMatchesState(t *testing.T) {
+	idx := testIndex(t)
+	assert.True(t, idx.ZipMatchesState("USA", "CA", "94105"))
+	assert.True(t, idx.ZipMatchesState("USA", "CA", "94105-1234"))
+	assert.False(t, idx.ZipMatchesState("USA", "TX", "94105"))
+	assert.True(t, idx.ZipMatchesState("USA", "TX", "00000")) // unknown zip
+	assert.True(t, idx.StateExists("USA", "TX"))
+	assert.False(t, idx.StateExists("USA", "XX"))
+}
+
+func TestVersion(t *testing.T) {
+	idx := testIndex(t)
+	v := idx.Version()
+	assert.NotEmpty(t, v)
+	assert.Equal(t, v, testIndex(t).Version())
+
+	idx.AddCity("USA", "TX", City{Name: "Dallas"})
+	idx.Build()
+	assert.NotEqual(t, v, idx.Version())
+}
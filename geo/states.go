@@ -0,0 +1,17 @@
+package geo
+
+// usStates are the US states, DC and inhabited territories accepted by the broker, sorted by name
+var usStates = []State{
+	{"Alabama", "AL"}, {"Alaska", "AK"}, {"American Samoa", "AS"}, {"Arizona", "AZ"}, {"Arkansas", "AR"},
+	{"California", "CA"}, {"Colorado", "CO"}, {"Connecticut", "CT"}, {"Delaware", "DE"}, {"District of Columbia", "DC"},
+	{"Florida", "FL"}, {"Georgia", "GA"}, {"Guam", "GU"}, {"Hawaii", "HI"}, {"Idaho", "ID"},
+	{"Illinois", "IL"}, {"Indiana", "IN"}, {"Iowa", "IA"}, {"Kansas", "KS"}, {"Kentucky", "KY"},
+	{"Louisiana", "LA"}, {"Maine", "ME"}, {"Maryland", "MD"}, {"Massachusetts", "MA"}, {"Michigan", "MI"},
+	{"Minnesota", "MN"}, {"Mississippi", "MS"}, {"Missouri", "MO"}, {"Montana", "MT"}, {"Nebraska", "NE"},
+	{"Nevada", "NV"}, {"New Hampshire", "NH"}, {"New Jersey", "NJ"}, {"New Mexico", "NM"}, {"New York", "NY"},
+	{"North Carolina", "NC"}, {"North Dakota", "ND"}, {"Northern Mariana Islands", "MP"}, {"Ohio", "OH"}, {"Oklahoma", "OK"},
+	{"Oregon", "OR"}, {"Pennsylvania", "PA"}, {"Puerto Rico", "PR"}, {"Rhode Island", "RI"}, {"South Carolina", "SC"},
+	{"South Dakota", "SD"}, {"Tennessee", "TN"}, {"Texas", "TX"}, {"U.S. Virgin Islands", "VI"}, {"Utah", "UT"},
+	{"Vermont", "VT"}, {"Virginia", "VA"}, {"Washington", "WA"}, {"West Virginia", "WV"}, {"Wisconsin", "WI"},
+	{"Wyoming", "WY"},
+}
@@ -8,4 +8,8 @@ type Service interface {
 	SendWithDefaults(subject, toEmail, content string, HTMLContent string) error
 	SendVerificationEmail(toEmail string, v *model.Verification) error
 	SendForgotVerificationEmail(toEmail string, v *model.Verification) error
+	SendLockoutEmail(toEmail string) error
+	SendInviteEmail(toEmail string) error
+	// For returns the service sending the emails of a tenant
+	For(t *model.Tenant) Service
 }
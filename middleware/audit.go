@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditBody is the largest request body Audit reads fields from
+const maxAuditBody = 64 << 10
+
+// RequestID sets the request_id of every request to its X-Request-ID header, or a new random ID
+// if it has none or a malformed one, and returns it in the X-Request-ID response header
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if !validRequestID(id) {
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		c.Set("request_id", id)
+		c.Header("X-Request-ID", id)
+		c.Next()
+	}
+}
+
+// validRequestID accepts up to 64 printable ASCII characters, so IDs can't forge audit log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Audit records action taken by the current user on their own account in log, once the
+// request succeeded. The details of the event are the path parameters and fields, the
+// top-level fields of the JSON or form request body that are safe to keep. It must run after the JWT middleware.
+func Audit(log model.AuditLog, action string, fields ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		details := map[string]interface{}{}
+		for _, p := range c.Params {
+			details[p.Key] = p.Value
+		}
+		if len(fields) > 0 && c.Request.Body != nil {
+			for f, v := range bodyFields(c, fields) {
+				details[f] = v
+			}
+		}
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		if len(details) == 0 {
+			details = nil
+		}
+		// a failed write is logged by the audit repository, the response is sent already
+		log.Record(c, &model.AuditEvent{Action: action, TargetUserID: c.GetInt("id"), Details: details})
+	}
+}
+
+// bodyFields returns fields of the form or JSON request body, and leaves the body for the handler
+func bodyFields(c *gin.Context, fields []string) map[string]interface{} {
+	values := map[string]interface{}{}
+	switch c.ContentType() {
+	case gin.MIMEPOSTForm, gin.MIMEMultipartPOSTForm:
+		for _, f := range fields {
+			if v, ok := c.GetPostForm(f); ok {
+				values[f] = v
+			}
+		}
+		return values
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxAuditBody))
+	c.Request.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	var all map[string]interface{}
+	if json.Unmarshal(body, &all) == nil {
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				values[f] = v
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,112 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mw "github.com/alpacahq/ribbit-backend/middleware"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	r := ginHandler(mw.RequestID())
+	cases := []struct {
+		name   string
+		header string
+		wantID string
+	}{
+		{name: "Keeps the ID of the client", header: "abc-123", wantID: "abc-123"},
+		{name: "Replaces malformed IDs", header: "abc\n123"},
+		{name: "Generates an ID", header: ""},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/hello", nil)
+			req.Header.Set("X-Request-ID", tt.header)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			id := w.Header().Get("X-Request-ID")
+			if tt.wantID != "" {
+				assert.Equal(t, tt.wantID, id)
+			} else {
+				assert.Len(t, id, 32)
+			}
+		})
+	}
+}
+
+func TestAudit(t *testing.T) {
+	cases := []struct {
+		name        string
+		status      int
+		wantDetails map[string]interface{}
+	}{
+		{
+			name:        "Records successful requests",
+			status:      http.StatusOK,
+			wantDetails: map[string]interface{}{"order_id": "o-1", "symbol": "AAPL", "qty": float64(2)},
+		},
+		{
+			name:   "Ignores failed requests",
+			status: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []*model.AuditEvent
+			log := &mock.AuditLog{
+				RecordFn: func(c *gin.Context, e *model.AuditEvent) error {
+					events = append(events, e)
+					return nil
+				},
+			}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.PATCH("/orders/:order_id", func(c *gin.Context) { c.Set("id", 7) }, mw.Audit(log, model.AuditOrderReplace, "symbol", "qty"), func(c *gin.Context) {
+				var body map[string]interface{}
+				assert.Nil(t, c.ShouldBindJSON(&body))
+				assert.Equal(t, "secret", body["client_order_id"])
+				c.Status(tt.status)
+			})
+			req, _ := http.NewRequest("PATCH", "/orders/o-1", strings.NewReader(`{"symbol":"AAPL","qty":2,"client_order_id":"secret"}`))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+			if tt.wantDetails == nil {
+				assert.Empty(t, events)
+				return
+			}
+			if assert.Len(t, events, 1) {
+				assert.Equal(t, model.AuditOrderReplace, events[0].Action)
+				assert.Equal(t, 7, events[0].TargetUserID)
+				assert.Equal(t, tt.wantDetails, events[0].Details)
+			}
+		})
+	}
+}
+
+func TestAuditForm(t *testing.T) {
+	var events []*model.AuditEvent
+	log := &mock.AuditLog{
+		RecordFn: func(c *gin.Context, e *model.AuditEvent) error {
+			events = append(events, e)
+			return nil
+		},
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/transfer/bank/:bank_id/deposit", mw.Audit(log, model.AuditTransferCreate, "amount"), func(c *gin.Context) {
+		assert.Equal(t, "25.5", c.PostForm("amount"))
+	})
+	req, _ := http.NewRequest("POST", "/transfer/bank/b-1/deposit", strings.NewReader("amount=25.5"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, map[string]interface{}{"bank_id": "b-1", "amount": "25.5"}, events[0].Details)
+	}
+}
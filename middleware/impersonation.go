@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Impersonation flags the responses to impersonation tokens with the X-Impersonated-By header,
+// limits the tokens to reading, except on the writable routes, and records every request they make
+// in log. Writable routes are a method and a path prefix, e.g. "POST /v1/watchlist".
+// It must run after the JWT middleware.
+func Impersonation(log model.AuditLog, writable []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		admin := c.GetInt("impersonator")
+		if admin == 0 {
+			c.Next()
+			return
+		}
+		c.Header("X-Impersonated-By", strconv.Itoa(admin))
+		if !readOnly(c.Request.Method) && !writableRoute(c.Request.Method, c.Request.URL.Path, writable) {
+			c.AbortWithStatusJSON(http.StatusForbidden, apperr.New(http.StatusForbidden, "Impersonation is read-only."))
+		} else {
+			c.Next()
+		}
+		// a failed write is logged by the audit repository, the response is sent already
+		log.Record(c, &model.AuditEvent{
+			ActorID:      admin,
+			Action:       model.AuditImpersonatedRequest,
+			TargetUserID: c.GetInt("id"),
+			Details: map[string]interface{}{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"status": c.Writer.Status(),
+			},
+		})
+	}
+}
+
+func readOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// writableRoute reports whether path is below a prefix routes allow method on
+func writableRoute(method, path string, routes []string) bool {
+	for _, r := range routes {
+		parts := strings.Fields(r)
+		if len(parts) == 2 && strings.EqualFold(parts[0], method) && allowedPath(path, parts[1:]) {
+			return true
+		}
+	}
+	return false
+}
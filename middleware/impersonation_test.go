@@ -0,0 +1,81 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	mw "github.com/alpacahq/ribbit-backend/middleware"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImpersonation(t *testing.T) {
+	jwtMW := mw.NewJWT(&config.JWT{Realm: "testRealm", Secret: "jwtsecret", Duration: 60, SigningAlgorithm: "HS256"})
+	user := &model.User{ID: 2, Verified: true, Active: true, Role: &model.Role{AccessLevel: model.AdminRole}}
+	impersonation, _, err := jwtMW.GenerateImpersonationToken(user, 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, _, err := jwtMW.GenerateToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		token      string
+		method     string
+		path       string
+		wantStatus int
+		wantFlag   string
+		wantAudit  bool
+	}{
+		{name: "Reads as the user", token: impersonation, method: "GET", path: "/v1/profile", wantStatus: http.StatusOK, wantFlag: "1", wantAudit: true},
+		{name: "Can't write", token: impersonation, method: "PATCH", path: "/v1/profile", wantStatus: http.StatusForbidden, wantFlag: "1", wantAudit: true},
+		{name: "Writes on writable routes", token: impersonation, method: "POST", path: "/v1/watchlist", wantStatus: http.StatusOK, wantFlag: "1", wantAudit: true},
+		{name: "Has none of the permissions of the user", token: impersonation, method: "GET", path: "/admin/users", wantStatus: http.StatusForbidden},
+		{name: "Users' own tokens", token: own, method: "PATCH", path: "/v1/profile", wantStatus: http.StatusOK},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []*model.AuditEvent
+			log := &mock.AuditLog{
+				RecordFn: func(c *gin.Context, e *model.AuditEvent) error {
+					events = append(events, e)
+					return nil
+				},
+			}
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			v1 := r.Group("/v1", jwtMW.MWFunc(), mw.Impersonation(log, []string{"POST /v1/watchlist"}))
+			v1.GET("/profile", ok)
+			v1.PATCH("/profile", ok)
+			v1.POST("/watchlist", ok)
+			r.GET("/admin/users", jwtMW.MWFunc(), mw.RequirePermission(permissionStore{model.AdminRole: {model.PermUsersRead}}, model.PermUsersRead), ok)
+
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantFlag, w.Header().Get("X-Impersonated-By"))
+			if !tt.wantAudit {
+				assert.Empty(t, events)
+				return
+			}
+			if assert.Len(t, events, 1) {
+				assert.Equal(t, model.AuditImpersonatedRequest, events[0].Action)
+				assert.Equal(t, 1, events[0].ActorID)
+				assert.Equal(t, 2, events[0].TargetUserID)
+				assert.Equal(t, tt.wantStatus, events[0].Details["status"])
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/xid"
 )
 
 // NewJWT generates new JWT variable necessery for auth middleware
@@ -20,6 +21,12 @@ func NewJWT(c *config.JWT) *JWT {
 		Key:      []byte(c.Secret),
 		Duration: time.Duration(c.Duration) * time.Minute,
 		Algo:     c.SigningAlgorithm,
+
+		RefreshDuration: time.Duration(c.RefreshDuration) * time.Minute,
+		MaxRefresh:      time.Duration(c.MaxRefresh) * time.Minute,
+
+		Sliding:     c.SlidingRefresh,
+		MaxLifetime: time.Duration(c.MaxLifetime) * time.Minute,
 	}
 }
 
@@ -31,11 +38,79 @@ type JWT struct {
 	// Secret key used for signing.
 	Key []byte
 
+	// Keys replaces Key with asymmetric keys identified by kid, if set.
+	Keys *KeySet
+
 	// Duration for which the jwt token is valid.
 	Duration time.Duration
 
 	// JWT signing algorithm
 	Algo string
+
+	// Duration for which an unused refresh token is valid.
+	RefreshDuration time.Duration
+
+	// Absolute lifetime of a login, however often its refresh token is rotated.
+	MaxRefresh time.Duration
+
+	// Sliding re-signs tokens in their second half of validity, returning them in the New-Token header.
+	Sliding bool
+
+	// Absolute lifetime of an access token since sign in, however often it slides.
+	MaxLifetime time.Duration
+
+	// Sessions rejects tokens of revoked sessions, if set.
+	Sessions SessionStore
+
+	// Revocations rejects revoked tokens and tokens issued before a user's password or role changed, if set.
+	Revocations RevocationStore
+
+	// Tenants resolves the tenant of a token's user, replacing the tenant of the Host header, if set.
+	Tenants TenantStore
+}
+
+// SessionStore reports whether a login session is still active
+type SessionStore interface {
+	Active(string) (bool, error)
+}
+
+// RevocationStore reports whether the token with the given jti, issued to a user
+// who signed in at the given time, has been revoked
+type RevocationStore interface {
+	Revoked(string, int, time.Time) (bool, error)
+}
+
+// claims are the contents of our access tokens
+type claims struct {
+	id       int
+	username string
+	email    string
+	role     int8
+	verified bool
+	session  string
+	jti      string
+	authTime time.Time
+	expires  time.Time
+	// typ tells access tokens apart from tokens that aren't, such as two-factor challenges
+	typ string
+	// impersonator is the staff member a token was issued to, on behalf of the user id
+	impersonator int
+	// tenant is the tenant of the user, 0 for the default tenant
+	tenant int
+}
+
+// Types of the tokens we sign. Tokens without a type, or of another type, aren't access tokens.
+const (
+	typeAccess    = "access"
+	typeChallenge = "2fa"
+)
+
+// challengeDuration is how long users have to enter their second factor after their password
+const challengeDuration = 5 * time.Minute
+
+// RefreshLifetime returns how long refresh tokens and login families are valid
+func (j *JWT) RefreshLifetime() (time.Duration, time.Duration) {
+	return j.RefreshDuration, j.MaxRefresh
 }
 
 // MWFunc makes JWT implement the Middleware interface.
@@ -49,38 +124,97 @@ func (j *JWT) MWFunc() gin.HandlerFunc {
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-
-		id := int(claims["id"].(float64))
-		username := claims["u"].(string)
-		email := claims["e"].(string)
-		role := int8(claims["r"].(float64))
-
-		c.Set("id", id)
-		c.Set("username", username)
-		c.Set("email", email)
-		c.Set("role", role)
+		cl := parseClaims(token.Claims.(jwt.MapClaims))
+		if cl.typ != typeAccess {
+			j.reject(c, "Unauthorized")
+			return
+		}
 
-		// Generate new token
-		newToken := jwt.New(jwt.GetSigningMethod(j.Algo))
-		newClaims := newToken.Claims.(jwt.MapClaims)
+		if cl.session != "" && j.Sessions != nil {
+			active, err := j.Sessions.Active(cl.session)
+			if err != nil {
+				apperr.Response(c, err)
+				return
+			}
+			if !active {
+				j.reject(c, "Session has been revoked.")
+				return
+			}
+		}
+		if j.Revocations != nil {
+			revoked, err := j.Revocations.Revoked(cl.jti, cl.id, cl.authTime)
+			if err == nil && !revoked && cl.impersonator != 0 {
+				// signing out or demoting the staff member ends their impersonation too
+				revoked, err = j.Revocations.Revoked(cl.jti, cl.impersonator, cl.authTime)
+			}
+			if err != nil {
+				apperr.Response(c, err)
+				return
+			}
+			if revoked {
+				j.reject(c, "Token has been revoked.")
+				return
+			}
+		}
 
-		expire := time.Now().Add(j.Duration)
-		newClaims["id"] = id
-		newClaims["u"] = username
-		newClaims["e"] = email
-		newClaims["r"] = role
-		newClaims["exp"] = expire.Unix()
+		c.Set("id", cl.id)
+		c.Set("username", cl.username)
+		c.Set("email", cl.email)
+		c.Set("role", cl.role)
+		c.Set("verified", cl.verified)
+		c.Set("session_id", cl.session)
+		c.Set("jti", cl.jti)
+		c.Set("token_expires", cl.expires)
+		if cl.impersonator != 0 {
+			c.Set("impersonator", cl.impersonator)
+		}
+		if j.Tenants != nil {
+			t, err := j.Tenants.ByID(cl.tenant)
+			if err != nil {
+				j.reject(c, "Unauthorized")
+				return
+			}
+			c.Set("tenant", t)
+		}
 
-		newTokenString, err := newToken.SignedString(j.Key)
-		if err == nil {
-			c.Writer.Header().Set("New-Token", newTokenString)
+		if newToken, ok := j.slide(cl); ok {
+			c.Writer.Header().Set("New-Token", newToken)
 		}
 
 		c.Next()
 	}
 }
 
+func (j *JWT) reject(c *gin.Context, msg string) {
+	c.Header("WWW-Authenticate", "JWT realm="+j.Realm)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, apperr.New(http.StatusUnauthorized, msg))
+}
+
+// slide re-signs a token that is past half of its validity, unless sliding is disabled
+// or the token has reached its maximum lifetime. The new token never outlives MaxLifetime.
+func (j *JWT) slide(cl *claims) (string, bool) {
+	if !j.Sliding || cl.impersonator != 0 {
+		return "", false
+	}
+	now := time.Now()
+	if cl.expires.Sub(now) > j.Duration/2 {
+		return "", false
+	}
+	deadline := cl.authTime.Add(j.MaxLifetime)
+	if !now.Before(deadline) {
+		return "", false
+	}
+	cl.expires = now.Add(j.Duration)
+	if cl.expires.After(deadline) {
+		cl.expires = deadline
+	}
+	token, err := j.sign(cl)
+	if err != nil {
+		return "", false
+	}
+	return token, true
+}
+
 // ParseToken parses token from Authorization header
 func (j *JWT) ParseToken(c *gin.Context) (*jwt.Token, error) {
 
@@ -93,27 +227,178 @@ func (j *JWT) ParseToken(c *gin.Context) (*jwt.Token, error) {
 		return nil, apperr.New(http.StatusUnauthorized, "Unauthorized")
 	}
 
-	return jwt.Parse(parts[1], func(token *jwt.Token) (interface{}, error) {
+	return j.parse(parts[1])
+}
+
+func (j *JWT) parse(token string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if j.Keys != nil {
+			kid, _ := token.Header["kid"].(string)
+			k, ok := j.Keys.Key(kid)
+			if !ok || token.Method.Alg() != k.Algorithm {
+				return nil, apperr.Generic
+			}
+			return k.Public(), nil
+		}
 		if jwt.GetSigningMethod(j.Algo) != token.Method {
 			return nil, apperr.Generic
 		}
 		return j.Key, nil
 	})
-
 }
 
 // GenerateToken generates new JWT token and populates it with user data
 func (j *JWT) GenerateToken(u *model.User) (string, string, error) {
-	token := jwt.New(jwt.GetSigningMethod(j.Algo))
-	claims := token.Claims.(jwt.MapClaims)
-
-	expire := time.Now().Add(j.Duration)
-	claims["id"] = u.ID
-	claims["u"] = u.Username
-	claims["e"] = u.Email
-	claims["r"] = u.Role.AccessLevel
-	claims["exp"] = expire.Unix()
-
-	tokenString, err := token.SignedString(j.Key)
-	return tokenString, expire.Format(time.RFC3339), err
+	return j.GenerateSessionToken(u, "")
+}
+
+// GenerateSessionToken generates a JWT token for a login session, so that revoking the session revokes the token
+func (j *JWT) GenerateSessionToken(u *model.User, sessionID string) (string, string, error) {
+	now := time.Now()
+	cl := &claims{
+		id:       u.ID,
+		username: u.Username,
+		email:    u.Email,
+		verified: u.Verified,
+		session:  sessionID,
+		authTime: now,
+		expires:  now.Add(j.Duration),
+		tenant:   u.TenantID,
+		typ:      typeAccess,
+	}
+	if u.Role != nil {
+		cl.role = int8(u.Role.AccessLevel)
+	}
+	tokenString, err := j.sign(cl)
+	return tokenString, cl.expires.Format(time.RFC3339), err
+}
+
+// GenerateImpersonationToken generates a token for the staff member adminID to act as u for ttl.
+// It has no session and doesn't slide, so it can't be refreshed.
+func (j *JWT) GenerateImpersonationToken(u *model.User, adminID int, ttl time.Duration) (string, string, error) {
+	now := time.Now()
+	cl := &claims{
+		id:           u.ID,
+		username:     u.Username,
+		email:        u.Email,
+		verified:     u.Verified,
+		authTime:     now,
+		expires:      now.Add(ttl),
+		impersonator: adminID,
+		tenant:       u.TenantID,
+		typ:          typeAccess,
+	}
+	if u.Role != nil {
+		cl.role = int8(u.Role.AccessLevel)
+	}
+	tokenString, err := j.sign(cl)
+	return tokenString, cl.expires.Format(time.RFC3339), err
+}
+
+// GenerateChallengeToken generates a short-lived token proving that u entered their password,
+// to be exchanged for a session once they enter their second factor as well
+func (j *JWT) GenerateChallengeToken(u *model.User) (string, error) {
+	now := time.Now()
+	return j.sign(&claims{
+		id:       u.ID,
+		username: u.Username,
+		email:    u.Email,
+		authTime: now,
+		expires:  now.Add(challengeDuration),
+		typ:      typeChallenge,
+	})
+}
+
+// ParseChallengeToken returns the ID of the user a valid challenge token was issued to
+func (j *JWT) ParseChallengeToken(token string) (int, error) {
+	t, err := j.parse(token)
+	if err != nil || !t.Valid {
+		return 0, apperr.New(http.StatusUnauthorized, "Invalid or expired challenge token. Please sign in again.")
+	}
+	cl := parseClaims(t.Claims.(jwt.MapClaims))
+	if cl.typ != typeChallenge {
+		return 0, apperr.New(http.StatusUnauthorized, "Invalid or expired challenge token. Please sign in again.")
+	}
+	return cl.id, nil
+}
+
+// sign issues a token for cl with a new jti
+func (j *JWT) sign(cl *claims) (string, error) {
+	algo, key := j.Algo, interface{}(j.Key)
+	var kid string
+	if j.Keys != nil {
+		k := j.Keys.Signing()
+		algo, key, kid = k.Algorithm, k.private, k.ID
+	}
+	token := jwt.New(jwt.GetSigningMethod(algo))
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	mc := token.Claims.(jwt.MapClaims)
+
+	cl.jti = xid.New().String()
+	mc["id"] = cl.id
+	mc["u"] = cl.username
+	mc["e"] = cl.email
+	mc["r"] = cl.role
+	mc["v"] = cl.verified
+	mc["jti"] = cl.jti
+	mc["iat"] = time.Now().Unix()
+	mc["auth_time"] = cl.authTime.Unix()
+	mc["exp"] = cl.expires.Unix()
+	mc["typ"] = cl.typ
+	if cl.session != "" {
+		mc["sid"] = cl.session
+	}
+	if cl.impersonator != 0 {
+		mc["imp"] = cl.impersonator
+	}
+	if cl.tenant != 0 {
+		mc["tid"] = cl.tenant
+	}
+
+	return token.SignedString(key)
+}
+
+// JWKSHandler serves the public keys tokens are signed with, so other services can verify them.
+// The set is empty when tokens are signed with a shared secret.
+func (j *JWT) JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		set := JWKS{Keys: []JWK{}}
+		if j.Keys != nil {
+			set = j.Keys.JWKS()
+		}
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, set)
+	}
+}
+
+// parseClaims reads our claims from a validated token. Tokens issued before jti and
+// auth_time were added fall back to their iat, or to the zero time.
+func parseClaims(mc jwt.MapClaims) *claims {
+	cl := &claims{
+		id:       int(mc["id"].(float64)),
+		username: mc["u"].(string),
+		email:    mc["e"].(string),
+		role:     int8(mc["r"].(float64)),
+	}
+	cl.verified, _ = mc["v"].(bool)
+	cl.session, _ = mc["sid"].(string)
+	cl.jti, _ = mc["jti"].(string)
+	cl.typ, _ = mc["typ"].(string)
+	if imp, ok := mc["imp"].(float64); ok {
+		cl.impersonator = int(imp)
+	}
+	if tid, ok := mc["tid"].(float64); ok {
+		cl.tenant = int(tid)
+	}
+	if exp, ok := mc["exp"].(float64); ok {
+		cl.expires = time.Unix(int64(exp), 0)
+	}
+	if at, ok := mc["auth_time"].(float64); ok {
+		cl.authTime = time.Unix(int64(at), 0)
+	} else if iat, ok := mc["iat"].(float64); ok {
+		cl.authTime = time.Unix(int64(iat), 0)
+	}
+	return cl
 }
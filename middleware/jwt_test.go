@@ -1,10 +1,12 @@
 package middleware_test
 
 import (
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/alpacahq/ribbit-backend/config"
 	mw "github.com/alpacahq/ribbit-backend/middleware"
@@ -108,3 +110,172 @@ func TestGenerateToken(t *testing.T) {
 		})
 	}
 }
+
+type sessionStore map[string]bool
+
+func (s sessionStore) Active(id string) (bool, error) {
+	return s[id], nil
+}
+
+func TestMWFuncSessions(t *testing.T) {
+	jwtCfg := &config.JWT{Realm: "testRealm", Secret: "jwtsecret", Duration: 60, SigningAlgorithm: "HS256"}
+	jwtMW := mw.NewJWT(jwtCfg)
+	jwtMW.Sessions = sessionStore{"active": true, "revoked": false}
+	ts := httptest.NewServer(ginHandler(jwtMW.MWFunc()))
+	defer ts.Close()
+	u := &model.User{ID: 1, Username: "johndoe", Email: "johndoe@mail.com", Role: &model.Role{AccessLevel: model.UserRole}}
+
+	cases := []struct {
+		name       string
+		session    string
+		wantStatus int
+	}{
+		{name: "Active session", session: "active", wantStatus: http.StatusOK},
+		{name: "Revoked session", session: "revoked", wantStatus: http.StatusUnauthorized},
+		{name: "Token without session", wantStatus: http.StatusOK},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _, err := jwtMW.GenerateSessionToken(u, tt.session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest("GET", ts.URL+"/hello", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal("Cannot create http request")
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+type revocationStore map[string]bool
+
+func (s revocationStore) Revoked(jti string, _ int, _ time.Time) (bool, error) {
+	return s[jti], nil
+}
+
+func TestMWFuncRevocations(t *testing.T) {
+	jwtCfg := &config.JWT{Realm: "testRealm", Secret: "jwtsecret", Duration: 60, SigningAlgorithm: "HS256"}
+	jwtMW := mw.NewJWT(jwtCfg)
+	revoked := revocationStore{}
+	jwtMW.Revocations = revoked
+	r := ginHandler(jwtMW.MWFunc())
+	r.GET("/jti", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("jti"))
+	})
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	u := &model.User{ID: 1, Username: "johndoe", Email: "johndoe@mail.com", Role: &model.Role{AccessLevel: model.UserRole}}
+	token, _, err := jwtMW.GenerateToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string) *http.Response {
+		req, _ := http.NewRequest("GET", ts.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("Cannot create http request")
+		}
+		return res
+	}
+
+	res := get("/jti")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	jti, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.NotEmpty(t, jti)
+
+	revoked[string(jti)] = true
+	assert.Equal(t, http.StatusUnauthorized, get("/hello").StatusCode)
+}
+
+func TestSlidingRefresh(t *testing.T) {
+	u := &model.User{ID: 1, Username: "johndoe", Email: "johndoe@mail.com", Role: &model.Role{AccessLevel: model.UserRole}}
+	// tokens are issued valid for a minute; a 60 minute duration puts them past half of their validity
+	cases := []struct {
+		name        string
+		duration    int
+		sliding     bool
+		maxLifetime int
+		wantToken   bool
+	}{
+		{
+			name:        "Token in its first half of validity",
+			duration:    1,
+			sliding:     true,
+			maxLifetime: 720,
+		},
+		{
+			name:        "Token in its second half of validity",
+			duration:    60,
+			sliding:     true,
+			maxLifetime: 720,
+			wantToken:   true,
+		},
+		{
+			name:        "Sliding disabled",
+			duration:    60,
+			maxLifetime: 720,
+		},
+		{
+			name:     "Maximum lifetime reached",
+			duration: 60,
+			sliding:  true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			jwtCfg := &config.JWT{Secret: "jwtsecret", Duration: 1, SigningAlgorithm: "HS256", SlidingRefresh: tt.sliding, MaxLifetime: tt.maxLifetime}
+			token, _, err := mw.NewJWT(jwtCfg).GenerateToken(u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jwtCfg.Duration = tt.duration
+			ts := httptest.NewServer(ginHandler(mw.NewJWT(jwtCfg).MWFunc()))
+			defer ts.Close()
+			req, _ := http.NewRequest("GET", ts.URL+"/hello", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal("Cannot create http request")
+			}
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, tt.wantToken, res.Header.Get("New-Token") != "")
+		})
+	}
+}
+
+func TestChallengeToken(t *testing.T) {
+	jwtCfg := &config.JWT{Realm: "testRealm", Secret: "jwtsecret", Duration: 60, SigningAlgorithm: "HS256"}
+	jwtMW := mw.NewJWT(jwtCfg)
+	ts := httptest.NewServer(ginHandler(jwtMW.MWFunc()))
+	defer ts.Close()
+	u := &model.User{ID: 1, Username: "johndoe", Email: "johndoe@mail.com", Role: &model.Role{AccessLevel: model.UserRole}}
+
+	challenge, err := jwtMW.GenerateChallengeToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := jwtMW.ParseChallengeToken(challenge)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, id)
+
+	req, _ := http.NewRequest("GET", ts.URL+"/hello", nil)
+	req.Header.Set("Authorization", "Bearer "+challenge)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("Cannot create http request")
+	}
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "challenge tokens aren't access tokens")
+
+	access, _, err := jwtMW.GenerateToken(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwtMW.ParseChallengeToken(access)
+	assert.NotNil(t, err, "access tokens aren't challenge tokens")
+}
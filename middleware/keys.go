@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rs/xid"
+)
+
+// Key is an asymmetric key tokens are signed or verified with, identified by the kid header
+type Key struct {
+	ID        string
+	Algorithm string
+	private   crypto.Signer
+}
+
+// Public returns the public half of the key
+func (k *Key) Public() crypto.PublicKey {
+	return k.private.Public()
+}
+
+// KeySet holds every key tokens are accepted from and the one new tokens are signed with
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadKeySet reads the private keys in dir, stored as <kid>.pem. Tokens are signed with
+// the key signingID, or with the newest key if signingID is empty; kids generated by
+// GenerateKey sort by creation time.
+func LoadKeySet(dir, signingID string) (*KeySet, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	ks := &KeySet{keys: map[string]*Key{}}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		k, err := ParseKey(strings.TrimSuffix(filepath.Base(f), ".pem"), data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", f, err)
+		}
+		ks.keys[k.ID] = k
+		if signingID == "" || signingID == k.ID {
+			ks.signing = k
+		}
+	}
+	if len(ks.keys) == 0 {
+		return nil, fmt.Errorf("no signing keys found in %s", dir)
+	}
+	if ks.signing == nil {
+		return nil, fmt.Errorf("signing key %s not found in %s", signingID, dir)
+	}
+	return ks, nil
+}
+
+// ParseKey parses a PEM encoded RSA or P-256 private key
+func ParseKey(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	var private interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA keys must be at least 2048 bits")
+		}
+		return &Key{ID: id, Algorithm: "RS256", private: k}, nil
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("EC keys must use the P-256 curve")
+		}
+		return &Key{ID: id, Algorithm: "ES256", private: k}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", private)
+}
+
+// GenerateKey creates a new key for algorithm RS256 or ES256 and returns its kid and PKCS#8 PEM encoding
+func GenerateKey(algorithm string) (string, []byte, error) {
+	var private crypto.Signer
+	var err error
+	switch algorithm {
+	case "RS256":
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	default:
+		return "", nil, fmt.Errorf("unsupported algorithm %s, use RS256 or ES256", algorithm)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", nil, err
+	}
+	return xid.New().String(), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// Signing returns the key new tokens are signed with
+func (ks *KeySet) Signing() *Key {
+	return ks.signing
+}
+
+// Key returns the key with the given kid
+func (ks *KeySet) Key(id string) (*Key, bool) {
+	k, ok := ks.keys[id]
+	return k, ok
+}
+
+// JWKS returns the public keys of the set, for services verifying our tokens
+func (ks *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	set := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		k := ks.keys[id]
+		jwk := JWK{Use: "sig", Kid: k.ID, Alg: k.Algorithm}
+		switch pub := k.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64(pub.N.Bytes())
+			jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = "P-256"
+			jwk.X = b64(pad(pub.X.Bytes(), size))
+			jwk.Y = b64(pad(pub.Y.Bytes(), size))
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// pad left-pads b with zeros to size bytes, as JWK coordinates have a fixed length
+func pad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
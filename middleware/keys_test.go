@@ -0,0 +1,132 @@
+package middleware_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	mw "github.com/alpacahq/ribbit-backend/middleware"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeKey(t *testing.T, dir, algorithm string) string {
+	kid, data, err := mw.GenerateKey(algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return kid
+}
+
+func decodeInt(t *testing.T, s string) *big.Int {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return new(big.Int).SetBytes(b)
+}
+
+func TestKeySet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jwtkeys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = mw.LoadKeySet(dir, "")
+	assert.NotNil(t, err, "an empty directory has no signing key")
+
+	rsaKID := writeKey(t, dir, "RS256")
+	ecKID := writeKey(t, dir, "ES256")
+	_, err = mw.LoadKeySet(dir, "unknown")
+	assert.NotNil(t, err)
+
+	u := &model.User{ID: 1, Username: "johndoe", Email: "johndoe@mail.com", Role: &model.Role{AccessLevel: model.UserRole}}
+	jwtCfg := &config.JWT{Realm: "testRealm", Secret: "jwtsecret", Duration: 60, SigningAlgorithm: "HS256"}
+	hmacToken, _, _ := mw.NewJWT(jwtCfg).GenerateToken(u)
+
+	// sign with the older RSA key, then rotate to the newest key, which is the EC one
+	old := mw.NewJWT(jwtCfg)
+	old.Keys, err = mw.LoadKeySet(dir, rsaKID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, _, err := old.GenerateToken(u)
+	assert.Nil(t, err)
+
+	j := mw.NewJWT(jwtCfg)
+	j.Keys, err = mw.LoadKeySet(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ecKID, j.Keys.Signing().ID)
+	newToken, _, err := j.GenerateToken(u)
+	assert.Nil(t, err)
+
+	ts := httptest.NewServer(ginHandler(j.MWFunc()))
+	defer ts.Close()
+
+	for token, want := range map[string]int{
+		oldToken:  http.StatusOK,
+		newToken:  http.StatusOK,
+		hmacToken: http.StatusUnauthorized,
+	} {
+		req, _ := http.NewRequest("GET", ts.URL+"/hello", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, want, res.StatusCode)
+	}
+
+	r := ginHandler()
+	r.GET("/.well-known/jwks.json", j.JWKSHandler())
+	jwks := httptest.NewServer(r)
+	defer jwks.Close()
+	res, err := http.Get(jwks.URL + "/.well-known/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var set mw.JWKS
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, set.Keys, 2)
+
+	// verify both tokens with nothing but the published keys
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		switch k.Kty {
+		case "RSA":
+			keys[k.Kid] = &rsa.PublicKey{N: decodeInt(t, k.N), E: int(decodeInt(t, k.E).Int64())}
+		case "EC":
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: decodeInt(t, k.X), Y: decodeInt(t, k.Y)}
+		}
+	}
+	for _, token := range []string{oldToken, newToken} {
+		parsed, err := jwt.Parse(token, func(tk *jwt.Token) (interface{}, error) {
+			return keys[tk.Header["kid"].(string)], nil
+		})
+		assert.Nil(t, err)
+		assert.True(t, parsed.Valid)
+	}
+	assert.True(t, strings.HasPrefix(res.Header.Get("Cache-Control"), "public"))
+}
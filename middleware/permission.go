@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionStore reports whether a role has been granted a permission
+type PermissionStore interface {
+	Allowed(role model.AccessRole, permission string) (bool, error)
+}
+
+// RequirePermission limits a route to users whose role has permission. Impersonation tokens have no permissions.
+// It must run after the JWT middleware.
+func RequirePermission(store PermissionStore, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetInt("impersonator") != 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, apperr.New(http.StatusForbidden, "Forbidden"))
+			return
+		}
+		role, _ := c.Get("role")
+		level, _ := role.(int8)
+		ok, err := store.Allowed(model.AccessRole(level), permission)
+		if err != nil {
+			apperr.Response(c, err)
+			return
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, apperr.New(http.StatusForbidden, "Forbidden"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	mw "github.com/alpacahq/ribbit-backend/middleware"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type permissionStore map[model.AccessRole][]string
+
+func (s permissionStore) Allowed(role model.AccessRole, permission string) (bool, error) {
+	for _, p := range s[role] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	jwtCfg := &config.JWT{Realm: "testRealm", Secret: "jwtsecret", Duration: 60, SigningAlgorithm: "HS256"}
+	jwtMW := mw.NewJWT(jwtCfg)
+	store := permissionStore{model.AdminRole: {model.PermUsersRead}}
+
+	cases := []struct {
+		name       string
+		role       model.AccessRole
+		permission string
+		wantStatus int
+	}{
+		{
+			name:       "Granted",
+			role:       model.AdminRole,
+			permission: model.PermUsersRead,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Not granted",
+			role:       model.AdminRole,
+			permission: model.PermRolesManage,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Other role",
+			role:       model.UserRole,
+			permission: model.PermUsersRead,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &model.User{ID: 1, Verified: true, Active: true, Role: &model.Role{AccessLevel: tt.role}}
+			ts := httptest.NewServer(ginHandler(jwtMW.MWFunc(), mw.RequirePermission(store, tt.permission)))
+			defer ts.Close()
+			token, _, err := jwtMW.GenerateSessionToken(u, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest("GET", ts.URL+"/hello", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal("Cannot create http request")
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
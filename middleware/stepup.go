@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StepUpStore reports whether a user confirmed their second factor in a session within maxAge.
+// Users without two-factor authentication always pass.
+type StepUpStore interface {
+	SteppedUp(userID int, sessionID string, maxAge time.Duration) (bool, error)
+}
+
+// StepUp guards sensitive actions, such as moving money or linking bank accounts, by requiring
+// users with two-factor authentication to have confirmed their second factor within maxAge.
+// It must run after the JWT middleware.
+func StepUp(store StepUpStore, maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, err := store.SteppedUp(c.GetInt("id"), c.GetString("session_id"), maxAge)
+		if err != nil {
+			apperr.Response(c, err)
+			return
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, apperr.New(http.StatusForbidden, "Please confirm your two-factor code to continue."))
+			return
+		}
+		c.Next()
+	}
+}
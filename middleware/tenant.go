@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TenantStore resolves tenants, falling back to the default tenant for hosts no tenant claims and ID 0
+type TenantStore interface {
+	ByHost(string) (*model.Tenant, error)
+	ByID(int) (*model.Tenant, error)
+}
+
+// Tenant resolves the tenant of a request from its Host header, for model.TenantOf.
+// The JWT middleware replaces it with the tenant of the token's user.
+func Tenant(store TenantStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		t, err := store.ByHost(c.Request.Host)
+		if err != nil {
+			apperr.Response(c, err)
+			return
+		}
+		c.Set("tenant", t)
+		c.Next()
+	}
+}
@@ -0,0 +1,115 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/config"
+	mw "github.com/alpacahq/ribbit-backend/middleware"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// tenantStore has the tenants by ID, the default tenant being 0, and by host
+type tenantStore map[int]*model.Tenant
+
+func (s tenantStore) ByHost(host string) (*model.Tenant, error) {
+	for _, t := range s {
+		for _, h := range t.Hosts {
+			if h == host {
+				return t, nil
+			}
+		}
+	}
+	return s[0], nil
+}
+
+func (s tenantStore) ByID(id int) (*model.Tenant, error) {
+	if t, ok := s[id]; ok {
+		return t, nil
+	}
+	return nil, apperr.NotFound
+}
+
+func TestTenant(t *testing.T) {
+	store := tenantStore{
+		0: {Name: "Ribbit"},
+		1: {ID: 1, Name: "Acme", Hosts: []string{"acme.test"}},
+		2: {ID: 2, Name: "Globex", Hosts: []string{"globex.test"}},
+	}
+	jwtMW := mw.NewJWT(&config.JWT{Realm: "testRealm", Secret: "jwtsecret", Duration: 60, SigningAlgorithm: "HS256"})
+	jwtMW.Tenants = store
+
+	cases := []struct {
+		name       string
+		host       string
+		user       *model.User
+		wantStatus int
+		wantTenant string
+	}{
+		{
+			name:       "Unclaimed host",
+			host:       "localhost",
+			wantStatus: http.StatusOK,
+			wantTenant: "Ribbit",
+		},
+		{
+			name:       "Host of a tenant",
+			host:       "acme.test",
+			wantStatus: http.StatusOK,
+			wantTenant: "Acme",
+		},
+		{
+			name:       "Token of a user of another tenant",
+			host:       "acme.test",
+			user:       &model.User{ID: 1, TenantID: 2},
+			wantStatus: http.StatusOK,
+			wantTenant: "Globex",
+		},
+		{
+			name:       "Token of a user of the default tenant",
+			host:       "acme.test",
+			user:       &model.User{ID: 1},
+			wantStatus: http.StatusOK,
+			wantTenant: "Ribbit",
+		},
+		{
+			name:       "Token of a user of a removed tenant",
+			host:       "acme.test",
+			user:       &model.User{ID: 1, TenantID: 3},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(mw.Tenant(store))
+			if tt.user != nil {
+				r.Use(jwtMW.MWFunc())
+			}
+			r.GET("/hello", func(c *gin.Context) {
+				c.String(http.StatusOK, model.TenantOf(c).Name)
+			})
+			req, _ := http.NewRequest("GET", "/hello", nil)
+			req.Host = tt.host
+			if tt.user != nil {
+				token, _, err := jwtMW.GenerateToken(tt.user)
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, tt.wantTenant, w.Body.String())
+			}
+		})
+	}
+}
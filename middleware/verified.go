@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerificationStore reports whether a user has verified their email address or mobile number
+type VerificationStore interface {
+	Verified(userID int) (bool, error)
+}
+
+// RequireVerified limits users who haven't verified their email address or mobile number
+// to the routes below the allowed path prefixes. Tokens issued before the user verified are
+// checked against store, so users don't have to sign in again once they verified.
+// It must run after the JWT middleware.
+func RequireVerified(store VerificationStore, allowed []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("verified") || allowedPath(c.Request.URL.Path, allowed) {
+			c.Next()
+			return
+		}
+		verified, err := store.Verified(c.GetInt("id"))
+		if err != nil {
+			apperr.Response(c, err)
+			return
+		}
+		if !verified {
+			c.AbortWithStatusJSON(http.StatusForbidden, apperr.New(http.StatusForbidden, "Please verify your email address to continue."))
+			return
+		}
+		c.Next()
+	}
+}
+
+// allowedPath reports whether path is one of prefixes or below one of them
+func allowedPath(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		p = strings.TrimSuffix(strings.TrimSpace(p), "/")
+		if p != "" && (path == p || strings.HasPrefix(path, p+"/")) {
+			return true
+		}
+	}
+	return false
+}
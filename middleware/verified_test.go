@@ -0,0 +1,78 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	mw "github.com/alpacahq/ribbit-backend/middleware"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type verificationStore map[int]bool
+
+func (s verificationStore) Verified(userID int) (bool, error) {
+	return s[userID], nil
+}
+
+func TestRequireVerified(t *testing.T) {
+	jwtCfg := &config.JWT{Realm: "testRealm", Secret: "jwtsecret", Duration: 60, SigningAlgorithm: "HS256"}
+	jwtMW := mw.NewJWT(jwtCfg)
+	store := verificationStore{2: true}
+
+	cases := []struct {
+		name       string
+		user       *model.User
+		allowed    []string
+		wantStatus int
+	}{
+		{
+			name:       "Verified user",
+			user:       &model.User{ID: 1, Verified: true, Active: true},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Unverified user",
+			user:       &model.User{ID: 1},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Unverified user on an allowed route",
+			user:       &model.User{ID: 1},
+			allowed:    []string{"/hello/"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Unverified user on a route sharing an allowed prefix",
+			user:       &model.User{ID: 1},
+			allowed:    []string{"/hell"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "User verified after the token was issued",
+			user:       &model.User{ID: 2},
+			wantStatus: http.StatusOK,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.user.Role = &model.Role{AccessLevel: model.UserRole}
+			ts := httptest.NewServer(ginHandler(jwtMW.MWFunc(), mw.RequireVerified(store, tt.allowed)))
+			defer ts.Close()
+			token, _, err := jwtMW.GenerateSessionToken(tt.user, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest("GET", ts.URL+"/hello", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal("Cannot create http request")
+			}
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
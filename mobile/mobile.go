@@ -1,83 +1,148 @@
 package mobile
 
 import (
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/alpacahq/ribbit-backend/apperr"
 	"github.com/alpacahq/ribbit-backend/config"
 )
 
+const twilioVerifyURL = "https://verify.twilio.com/v2/Services/"
+
+// Twilio Verify error codes, see https://www.twilio.com/docs/api/errors
+const (
+	twilioNotFound        = 20404
+	twilioMaxCheckAttempt = 60202
+	twilioMaxSendAttempt  = 60203
+	twilioInvalidParam    = 60200
+)
+
+// Errors returned by the mobile service for the outcomes of sending and checking codes
+var (
+	// ErrInvalidCode is returned for a wrong code, the verification stays pending
+	ErrInvalidCode = apperr.New(http.StatusUnauthorized, "Invalid verification code.")
+	// ErrCodeExpired is returned when no code is pending, because it expired, was used or was never sent
+	ErrCodeExpired = apperr.New(http.StatusGone, "The verification code has expired. Please request a new one.")
+	// ErrMaxAttempts is returned once too many wrong codes were tried, the verification is canceled
+	ErrMaxAttempts = apperr.New(http.StatusTooManyRequests, "Too many wrong codes. Please request a new one.")
+	// ErrTooManySends is returned when too many codes were sent to the number
+	ErrTooManySends = apperr.New(http.StatusTooManyRequests, "Too many codes requested. Please try again later.")
+	// ErrInvalidNumber is returned for numbers Twilio can't send to
+	ErrInvalidNumber = apperr.New(http.StatusBadRequest, "Invalid mobile number.")
+)
+
 // NewMobile creates a new mobile service implementation
 func NewMobile(config *config.TwilioConfig) *Mobile {
-	return &Mobile{config}
+	return &Mobile{
+		config:  config,
+		baseURL: twilioVerifyURL,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
 }
 
 // Mobile provides a mobile service implementation
 type Mobile struct {
-	config *config.TwilioConfig
+	config  *config.TwilioConfig
+	baseURL string
+	client  *http.Client
+}
+
+// verification is the part of a Twilio Verify verification or verification check we use
+type verification struct {
+	Sid    string `json:"sid"`
+	Status string `json:"status"`
+}
+
+// twilioError is the body of a failed Twilio request
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
 }
 
 // GenerateSMSToken sends an sms token to the mobile numer
 func (m *Mobile) GenerateSMSToken(countryCode, mobile string) error {
-	apiURL := m.getTwilioVerifyURL()
 	data := url.Values{}
 	data.Set("To", countryCode+mobile)
 	data.Set("Channel", "sms")
-	resp, err := m.send(apiURL, data)
+	v, err := m.send("Verifications", data)
 	if err != nil {
 		return err
 	}
-
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
+	if v.Status != "pending" {
+		return fmt.Errorf("twilio: unexpected verification status %q", v.Status)
 	}
-	bodyString := string(bodyBytes)
-	fmt.Println(bodyString)
-	return err
+	return nil
 }
 
 // CheckCode verifies if the user-provided code is approved
 func (m *Mobile) CheckCode(countryCode, mobile, code string) error {
-	apiURL := m.getTwilioVerifyURL()
 	data := url.Values{}
 	data.Set("To", countryCode+mobile)
 	data.Set("Code", code)
-	resp, err := m.send(apiURL, data)
+	v, err := m.send("VerificationCheck", data)
 	if err != nil {
 		return err
 	}
-
-	// take a look at our response
-	fmt.Println(resp.StatusCode)
-	fmt.Println(resp.Body)
-	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		log.Fatal(err)
+	switch v.Status {
+	case "approved":
+		return nil
+	case "pending":
+		return ErrInvalidCode
+	case "max_attempts_reached":
+		return ErrMaxAttempts
 	}
-	bodyString := string(bodyBytes)
-	fmt.Println(bodyString)
-	return nil
-}
-
-func (m *Mobile) getTwilioVerifyURL() string {
-	return "https://verify.twilio.com/v2/Services/" + m.config.Verify + "/Verifications"
+	return ErrCodeExpired
 }
 
-func (m *Mobile) send(apiURL string, data url.Values) (*http.Response, error) {
-	u, _ := url.ParseRequestURI(apiURL)
-	urlStr := u.String()
-	// http client
-	client := &http.Client{}
-	r, _ := http.NewRequest("POST", urlStr, strings.NewReader(data.Encode())) // URL-encoded payload
+// send posts data to a Twilio Verify endpoint and parses the verification it returns
+func (m *Mobile) send(endpoint string, data url.Values) (*verification, error) {
+	body := data.Encode()
+	r, err := http.NewRequest("POST", m.baseURL+url.PathEscape(m.config.Verify)+"/"+endpoint, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
 	r.SetBasicAuth(m.config.Account, m.config.Token)
 	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	r.Header.Add("Content-Length", strconv.Itoa(len(data.Encode())))
+	r.Header.Add("Content-Length", strconv.Itoa(len(body)))
+
+	resp, err := m.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 
-	return client.Do(r)
+	if resp.StatusCode >= 300 {
+		var e twilioError
+		if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+			return nil, fmt.Errorf("twilio: status %d", resp.StatusCode)
+		}
+		return nil, twilioErr(e)
+	}
+	v := new(verification)
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("twilio: decoding response: %v", err)
+	}
+	return v, nil
+}
+
+// twilioErr maps a Twilio error to one of our errors
+func twilioErr(e twilioError) error {
+	switch e.Code {
+	case twilioNotFound:
+		// Twilio deletes verifications once they are approved, expired or canceled
+		return ErrCodeExpired
+	case twilioMaxCheckAttempt:
+		return ErrMaxAttempts
+	case twilioMaxSendAttempt:
+		return ErrTooManySends
+	case twilioInvalidParam:
+		return ErrInvalidNumber
+	}
+	return fmt.Errorf("twilio: %d %s", e.Code, e.Message)
 }
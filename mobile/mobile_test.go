@@ -0,0 +1,78 @@
+package mobile
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMobile(t *testing.T, wantPath string, status int, body string) (*Mobile, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, wantPath, r.URL.Path)
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "token", pass)
+		assert.Equal(t, "+6591919191", r.PostFormValue("To"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	m := NewMobile(&config.TwilioConfig{Account: "AC123", Token: "token", Verify: "VA123"})
+	m.baseURL = ts.URL + "/v2/Services/"
+	return m, ts.Close
+}
+
+func TestCheckCode(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{name: "Approved", status: http.StatusOK, body: `{"sid":"VE1","status":"approved","valid":true}`},
+		{name: "Wrong code", status: http.StatusOK, body: `{"sid":"VE1","status":"pending","valid":false}`, wantErr: ErrInvalidCode},
+		{name: "Canceled", status: http.StatusOK, body: `{"sid":"VE1","status":"canceled","valid":false}`, wantErr: ErrCodeExpired},
+		{name: "Max attempts status", status: http.StatusOK, body: `{"sid":"VE1","status":"max_attempts_reached","valid":false}`, wantErr: ErrMaxAttempts},
+		{name: "Expired", status: http.StatusNotFound, body: `{"code":20404,"message":"The requested resource was not found","status":404}`, wantErr: ErrCodeExpired},
+		{name: "Max attempts", status: http.StatusTooManyRequests, body: `{"code":60202,"message":"Max check attempts reached","status":429}`, wantErr: ErrMaxAttempts},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, done := testMobile(t, "/v2/Services/VA123/VerificationCheck", tt.status, tt.body)
+			defer done()
+			assert.Equal(t, tt.wantErr, m.CheckCode("+65", "91919191", "123456"))
+		})
+	}
+
+	t.Run("Unknown error", func(t *testing.T) {
+		m, done := testMobile(t, "/v2/Services/VA123/VerificationCheck", http.StatusInternalServerError, `{"code":20500,"message":"Internal Server Error"}`)
+		defer done()
+		err := m.CheckCode("+65", "91919191", "123456")
+		assert.EqualError(t, err, "twilio: 20500 Internal Server Error")
+	})
+}
+
+func TestGenerateSMSToken(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr error
+	}{
+		{name: "Sent", status: http.StatusCreated, body: `{"sid":"VE1","status":"pending"}`},
+		{name: "Too many sends", status: http.StatusTooManyRequests, body: `{"code":60203,"message":"Max send attempts reached","status":429}`, wantErr: ErrTooManySends},
+		{name: "Invalid number", status: http.StatusBadRequest, body: `{"code":60200,"message":"Invalid parameter","status":400}`, wantErr: ErrInvalidNumber},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			m, done := testMobile(t, "/v2/Services/VA123/Verifications", tt.status, tt.body)
+			defer done()
+			assert.Equal(t, tt.wantErr, m.GenerateSMSToken("+65", "91919191"))
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package mock
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLog mock, discards events unless RecordFn is set
+type AuditLog struct {
+	RecordFn func(*gin.Context, *model.AuditEvent) error
+}
+
+// Record mock
+func (a *AuditLog) Record(c *gin.Context, e *model.AuditEvent) error {
+	if a.RecordFn == nil {
+		return nil
+	}
+	return a.RecordFn(c, e)
+}
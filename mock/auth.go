@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"context"
+
 	"github.com/alpacahq/ribbit-backend/model"
 
 	"github.com/gin-gonic/gin"
@@ -8,10 +10,16 @@ import (
 
 // Auth mock
 type Auth struct {
-	UserFn func(*gin.Context) *model.AuthUser
+	UserFn   func(*gin.Context) *model.AuthUser
+	SignInFn func(context.Context, *model.User) (*model.LoginResponseWithToken, error)
 }
 
 // User mock
 func (a *Auth) User(c *gin.Context) *model.AuthUser {
 	return a.UserFn(c)
 }
+
+// SignIn mock
+func (a *Auth) SignIn(c context.Context, u *model.User) (*model.LoginResponseWithToken, error) {
+	return a.SignInFn(c, u)
+}
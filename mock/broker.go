@@ -0,0 +1,47 @@
+package mock
+
+import (
+	"encoding/json"
+
+	"github.com/alpacahq/ribbit-backend/broker"
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Broker mock
+type Broker struct {
+	AccountFn   func(string) (json.RawMessage, error)
+	OrdersFn    func(string) (json.RawMessage, error)
+	PositionsFn func(string) (json.RawMessage, error)
+	TransfersFn func(string) (json.RawMessage, error)
+	CloseFn     func(string) error
+}
+
+// Account mock
+func (b *Broker) Account(accountID string) (json.RawMessage, error) {
+	return b.AccountFn(accountID)
+}
+
+// Orders mock
+func (b *Broker) Orders(accountID string) (json.RawMessage, error) {
+	return b.OrdersFn(accountID)
+}
+
+// Positions mock
+func (b *Broker) Positions(accountID string) (json.RawMessage, error) {
+	return b.PositionsFn(accountID)
+}
+
+// Transfers mock
+func (b *Broker) Transfers(accountID string) (json.RawMessage, error) {
+	return b.TransfersFn(accountID)
+}
+
+// Close mock
+func (b *Broker) Close(accountID string) error {
+	return b.CloseFn(accountID)
+}
+
+// For mock, the client of every tenant is b
+func (b *Broker) For(*model.Tenant) broker.Closer {
+	return b
+}
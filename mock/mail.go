@@ -1,6 +1,9 @@
 package mock
 
-import "github.com/alpacahq/ribbit-backend/model"
+import (
+	"github.com/alpacahq/ribbit-backend/mail"
+	"github.com/alpacahq/ribbit-backend/model"
+)
 
 // Mail mock
 type Mail struct {
@@ -9,6 +12,9 @@ type Mail struct {
 	SendWithDefaultsFn            func(string, string, string, string) error
 	SendVerificationEmailFn       func(string, *model.Verification) error
 	SendForgotVerificationEmailFn func(string, *model.Verification) error
+	SendLockoutEmailFn            func(string) error
+	SendInviteEmailFn             func(string) error
+	ForFn                         func(*model.Tenant) mail.Service
 }
 
 // Send mock
@@ -29,3 +35,21 @@ func (m *Mail) SendVerificationEmail(toEmail string, v *model.Verification) erro
 func (m *Mail) SendForgotVerificationEmail(toEmail string, v *model.Verification) error {
 	return m.SendForgotVerificationEmailFn(toEmail, v)
 }
+
+// SendLockoutEmail mock
+func (m *Mail) SendLockoutEmail(toEmail string) error {
+	return m.SendLockoutEmailFn(toEmail)
+}
+
+// SendInviteEmail mock
+func (m *Mail) SendInviteEmail(toEmail string) error {
+	return m.SendInviteEmailFn(toEmail)
+}
+
+// For mock, returns m unless ForFn is set
+func (m *Mail) For(t *model.Tenant) mail.Service {
+	if m.ForFn == nil {
+		return m
+	}
+	return m.ForFn(t)
+}
@@ -1,13 +1,47 @@
 package mock
 
-import "github.com/alpacahq/ribbit-backend/model"
+import (
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/model"
+)
 
 // JWT mock
 type JWT struct {
-	GenerateTokenFn func(*model.User) (string, string, error)
+	GenerateTokenFn          func(*model.User) (string, string, error)
+	GenerateSessionTokenFn   func(*model.User, string) (string, string, error)
+	RefreshLifetimeFn        func() (time.Duration, time.Duration)
+	GenerateChallengeTokenFn func(*model.User) (string, error)
+	ParseChallengeTokenFn    func(string) (int, error)
 }
 
 // GenerateToken mock
 func (j *JWT) GenerateToken(u *model.User) (string, string, error) {
 	return j.GenerateTokenFn(u)
 }
+
+// GenerateSessionToken mock, falls back to GenerateTokenFn
+func (j *JWT) GenerateSessionToken(u *model.User, sessionID string) (string, string, error) {
+	if j.GenerateSessionTokenFn == nil {
+		return j.GenerateTokenFn(u)
+	}
+	return j.GenerateSessionTokenFn(u, sessionID)
+}
+
+// RefreshLifetime mock, defaults to one hour per token and a day per login
+func (j *JWT) RefreshLifetime() (time.Duration, time.Duration) {
+	if j.RefreshLifetimeFn == nil {
+		return time.Hour, 24 * time.Hour
+	}
+	return j.RefreshLifetimeFn()
+}
+
+// GenerateChallengeToken mock
+func (j *JWT) GenerateChallengeToken(u *model.User) (string, error) {
+	return j.GenerateChallengeTokenFn(u)
+}
+
+// ParseChallengeToken mock
+func (j *JWT) ParseChallengeToken(token string) (int, error) {
+	return j.ParseChallengeTokenFn(token)
+}
@@ -1,5 +1,12 @@
 package mock
 
+import (
+	"fmt"
+	"sync"
+
+	"github.com/alpacahq/ribbit-backend/mobile"
+)
+
 // Mobile mock
 type Mobile struct {
 	GenerateSMSTokenFn func(string, string) error
@@ -15,3 +22,84 @@ func (m *Mobile) GenerateSMSToken(countryCode, mobile string) error {
 func (m *Mobile) CheckCode(countryCode, mobile, code string) error {
 	return m.CheckCodeFn(countryCode, mobile, code)
 }
+
+// FakeMobile is an in-memory mobile.Service behaving like Twilio Verify. Tests read the sent
+// codes with Code, and simulate expiry with Expire or a failing provider with SendErr.
+type FakeMobile struct {
+	// MaxAttempts is the number of wrong codes after which a verification is canceled
+	MaxAttempts int
+	// MaxSends is the number of codes sent to a number before further sends fail, unlimited if 0
+	MaxSends int
+	// SendErr, if set, is returned by GenerateSMSToken
+	SendErr error
+
+	mu       sync.Mutex
+	next     int
+	codes    map[string]string
+	attempts map[string]int
+	sends    map[string]int
+}
+
+// NewFakeMobile creates a FakeMobile which cancels verifications after 5 wrong codes
+func NewFakeMobile() *FakeMobile {
+	return &FakeMobile{
+		MaxAttempts: 5,
+		codes:       map[string]string{},
+		attempts:    map[string]int{},
+		sends:       map[string]int{},
+	}
+}
+
+// GenerateSMSToken starts a verification, replacing any pending code for the number
+func (f *FakeMobile) GenerateSMSToken(countryCode, number string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.SendErr != nil {
+		return f.SendErr
+	}
+	to := countryCode + number
+	if f.MaxSends > 0 && f.sends[to] >= f.MaxSends {
+		return mobile.ErrTooManySends
+	}
+	f.sends[to]++
+	f.next++
+	f.codes[to] = fmt.Sprintf("%06d", f.next)
+	f.attempts[to] = 0
+	return nil
+}
+
+// CheckCode approves the pending code for the number
+func (f *FakeMobile) CheckCode(countryCode, number, code string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	to := countryCode + number
+	want, ok := f.codes[to]
+	if !ok {
+		return mobile.ErrCodeExpired
+	}
+	if code == want {
+		delete(f.codes, to)
+		return nil
+	}
+	f.attempts[to]++
+	if f.attempts[to] >= f.MaxAttempts {
+		delete(f.codes, to)
+		return mobile.ErrMaxAttempts
+	}
+	return mobile.ErrInvalidCode
+}
+
+// Code returns the pending code sent to the number, if any
+func (f *FakeMobile) Code(countryCode, number string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	code, ok := f.codes[countryCode+number]
+	return code, ok
+}
+
+// Expire drops the pending code for the number, as if it timed out
+func (f *FakeMobile) Expire(countryCode, number string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.codes, countryCode+number)
+}
@@ -1,30 +1,41 @@
 package mockdb
 
 import (
+	"time"
+
 	"github.com/alpacahq/ribbit-backend/model"
 )
 
 // Account database mock
 type Account struct {
-	ActivateFn                    func(*model.User) error
-	CreateFn                      func(*model.User) (*model.User, error)
-	CreateAndVerifyFn             func(*model.User) (*model.Verification, error)
-	CreateWithMobileFn            func(*model.User) error
-	CreateForgotTokenFn           func(*model.User) (*model.Verification, error)
-	CreateNewOTPFn                func(*model.User) (*model.Verification, error)
-	CreateWithMagicFn             func(*model.User) (int, error)
-	ChangePasswordFn              func(*model.User) error
-	ResetPasswordFn               func(*model.User) error
-	UpdateAvatarFn                func(*model.User) error
-	FindVerificationTokenFn       func(string) (*model.Verification, error)
-	FindVerificationTokenByUserFn func(*model.User) (*model.Verification, error)
-	DeleteVerificationTokenFn     func(*model.Verification) error
+	ActivateFn                        func(*model.User) error
+	DeactivateFn                      func(*model.User) error
+	CreateFn                          func(*model.User) (*model.User, error)
+	CreateAndVerifyFn                 func(*model.User) (*model.Verification, error)
+	CreateWithMobileFn                func(*model.User) error
+	CreateVerificationTokenFn         func(*model.User, string) (*model.Verification, error)
+	CreateWithMagicFn                 func(*model.User) (int, error)
+	ChangePasswordFn                  func(*model.User) error
+	ResetPasswordFn                   func(*model.User) error
+	UpdateAvatarFn                    func(*model.User) error
+	SignAgreementsFn                  func(*model.User) error
+	UseVerificationTokenFn            func(*model.User, string, string) (*model.Verification, error)
+	RedeemVerificationTokenFn         func(string, string) (*model.Verification, error)
+	FindVerificationTokenByUserFn     func(*model.User, string) (*model.Verification, error)
+	DeleteVerificationTokenFn         func(*model.Verification) error
+	DeleteVerificationTokensFn        func(*model.User) error
+	DeleteExpiredVerificationTokensFn func(time.Time) (int, error)
 }
 
 func (a *Account) Activate(usr *model.User) error {
 	return a.ActivateFn(usr)
 }
 
+// Deactivate mock
+func (a *Account) Deactivate(usr *model.User) error {
+	return a.DeactivateFn(usr)
+}
+
 // Create mock
 func (a *Account) Create(usr *model.User) (*model.User, error) {
 	return a.CreateFn(usr)
@@ -40,12 +51,9 @@ func (a *Account) CreateWithMobile(usr *model.User) error {
 	return a.CreateWithMobileFn(usr)
 }
 
-func (a *Account) CreateForgotToken(usr *model.User) (*model.Verification, error) {
-	return a.CreateForgotTokenFn(usr)
-}
-
-func (a *Account) CreateNewOTP(usr *model.User) (*model.Verification, error) {
-	return a.CreateNewOTPFn(usr)
+// CreateVerificationToken mock
+func (a *Account) CreateVerificationToken(usr *model.User, purpose string) (*model.Verification, error) {
+	return a.CreateVerificationTokenFn(usr, purpose)
 }
 
 func (a *Account) CreateWithMagic(usr *model.User) (int, error) {
@@ -61,20 +69,40 @@ func (a *Account) UpdateAvatar(usr *model.User) error {
 	return a.UpdateAvatarFn(usr)
 }
 
+// SignAgreements mock
+func (a *Account) SignAgreements(usr *model.User) error {
+	return a.SignAgreementsFn(usr)
+}
+
 func (a *Account) ResetPassword(usr *model.User) error {
 	return a.ResetPasswordFn(usr)
 }
 
-// FindVerificationToken mock
-func (a *Account) FindVerificationToken(token string) (*model.Verification, error) {
-	return a.FindVerificationTokenFn(token)
+// UseVerificationToken mock
+func (a *Account) UseVerificationToken(usr *model.User, purpose, token string) (*model.Verification, error) {
+	return a.UseVerificationTokenFn(usr, purpose, token)
 }
 
-func (a *Account) FindVerificationTokenByUser(usr *model.User) (*model.Verification, error) {
-	return a.FindVerificationTokenByUserFn(usr)
+// RedeemVerificationToken mock
+func (a *Account) RedeemVerificationToken(purpose, token string) (*model.Verification, error) {
+	return a.RedeemVerificationTokenFn(purpose, token)
+}
+
+func (a *Account) FindVerificationTokenByUser(usr *model.User, purpose string) (*model.Verification, error) {
+	return a.FindVerificationTokenByUserFn(usr, purpose)
 }
 
 // DeleteVerificationToken mock
 func (a *Account) DeleteVerificationToken(v *model.Verification) error {
 	return a.DeleteVerificationTokenFn(v)
 }
+
+// DeleteVerificationTokens mock
+func (a *Account) DeleteVerificationTokens(u *model.User) error {
+	return a.DeleteVerificationTokensFn(u)
+}
+
+// DeleteExpiredVerificationTokens mock
+func (a *Account) DeleteExpiredVerificationTokens(t time.Time) (int, error) {
+	return a.DeleteExpiredVerificationTokensFn(t)
+}
@@ -0,0 +1,27 @@
+package mockdb
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Audit database mock
+type Audit struct {
+	CreateFn  func(*model.AuditEvent) error
+	ListFn    func(*model.AuditFilter) ([]model.AuditEvent, error)
+	ForEachFn func(*model.AuditFilter, func(*model.AuditEvent) error) error
+}
+
+// Create mock
+func (a *Audit) Create(e *model.AuditEvent) error {
+	return a.CreateFn(e)
+}
+
+// List mock
+func (a *Audit) List(f *model.AuditFilter) ([]model.AuditEvent, error) {
+	return a.ListFn(f)
+}
+
+// ForEach mock
+func (a *Audit) ForEach(f *model.AuditFilter, fn func(*model.AuditEvent) error) error {
+	return a.ForEachFn(f, fn)
+}
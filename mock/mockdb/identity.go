@@ -0,0 +1,39 @@
+package mockdb
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Identity database mock
+type Identity struct {
+	FindFn   func(string, string) (*model.Identity, error)
+	ListFn   func(int) ([]model.Identity, error)
+	CreateFn func(*model.Identity) error
+	TouchFn  func(*model.Identity) error
+	DeleteFn func(int, int) error
+}
+
+// Find mock
+func (i *Identity) Find(provider, subject string) (*model.Identity, error) {
+	return i.FindFn(provider, subject)
+}
+
+// List mock
+func (i *Identity) List(userID int) ([]model.Identity, error) {
+	return i.ListFn(userID)
+}
+
+// Create mock
+func (i *Identity) Create(identity *model.Identity) error {
+	return i.CreateFn(identity)
+}
+
+// Touch mock
+func (i *Identity) Touch(identity *model.Identity) error {
+	return i.TouchFn(identity)
+}
+
+// Delete mock
+func (i *Identity) Delete(userID, id int) error {
+	return i.DeleteFn(userID, id)
+}
@@ -0,0 +1,21 @@
+package mockdb
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Note database mock
+type Note struct {
+	CreateFn func(*model.UserNote) error
+	ListFn   func(int) ([]model.UserNote, error)
+}
+
+// Create mock
+func (n *Note) Create(note *model.UserNote) error {
+	return n.CreateFn(note)
+}
+
+// List mock
+func (n *Note) List(userID int) ([]model.UserNote, error) {
+	return n.ListFn(userID)
+}
@@ -0,0 +1,57 @@
+package mockdb
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Passkey database mock
+type Passkey struct {
+	CreateFn                  func(*model.Passkey) error
+	ListFn                    func(int) ([]model.Passkey, error)
+	FindByCredentialIDFn      func(string) (*model.Passkey, error)
+	UsedFn                    func(*model.Passkey) error
+	DeleteFn                  func(int, int) error
+	CreateChallengeFn         func(*model.PasskeyChallenge) error
+	UseChallengeFn            func(string, string) (*model.PasskeyChallenge, error)
+	DeleteExpiredChallengesFn func() (int, error)
+}
+
+// Create mock
+func (p *Passkey) Create(pk *model.Passkey) error {
+	return p.CreateFn(pk)
+}
+
+// List mock
+func (p *Passkey) List(userID int) ([]model.Passkey, error) {
+	return p.ListFn(userID)
+}
+
+// FindByCredentialID mock
+func (p *Passkey) FindByCredentialID(id string) (*model.Passkey, error) {
+	return p.FindByCredentialIDFn(id)
+}
+
+// Used mock
+func (p *Passkey) Used(pk *model.Passkey) error {
+	return p.UsedFn(pk)
+}
+
+// Delete mock
+func (p *Passkey) Delete(userID, id int) error {
+	return p.DeleteFn(userID, id)
+}
+
+// CreateChallenge mock
+func (p *Passkey) CreateChallenge(c *model.PasskeyChallenge) error {
+	return p.CreateChallengeFn(c)
+}
+
+// UseChallenge mock
+func (p *Passkey) UseChallenge(challenge, ceremony string) (*model.PasskeyChallenge, error) {
+	return p.UseChallengeFn(challenge, ceremony)
+}
+
+// DeleteExpiredChallenges mock
+func (p *Passkey) DeleteExpiredChallenges() (int, error) {
+	return p.DeleteExpiredChallengesFn()
+}
@@ -0,0 +1,17 @@
+package mockdb
+
+// PasswordHistory database mock
+type PasswordHistory struct {
+	RecentFn func(int, int) ([]string, error)
+	AddFn    func(int, string, int) error
+}
+
+// Recent mock
+func (p *PasswordHistory) Recent(userID, n int) ([]string, error) {
+	return p.RecentFn(userID, n)
+}
+
+// Add mock
+func (p *PasswordHistory) Add(userID int, hash string, keep int) error {
+	return p.AddFn(userID, hash, keep)
+}
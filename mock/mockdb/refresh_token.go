@@ -0,0 +1,47 @@
+package mockdb
+
+import (
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// RefreshToken database mock
+type RefreshToken struct {
+	CreateFn        func(*model.IssuedRefreshToken) error
+	FindByHashFn    func(string) (*model.IssuedRefreshToken, error)
+	MarkUsedFn      func(*model.IssuedRefreshToken) (bool, error)
+	RevokeFamilyFn  func(string) error
+	RevokeUserFn    func(int) error
+	DeleteExpiredFn func(time.Time) (int, error)
+}
+
+// Create mock
+func (r *RefreshToken) Create(t *model.IssuedRefreshToken) error {
+	return r.CreateFn(t)
+}
+
+// FindByHash mock
+func (r *RefreshToken) FindByHash(hash string) (*model.IssuedRefreshToken, error) {
+	return r.FindByHashFn(hash)
+}
+
+// MarkUsed mock
+func (r *RefreshToken) MarkUsed(t *model.IssuedRefreshToken) (bool, error) {
+	return r.MarkUsedFn(t)
+}
+
+// RevokeFamily mock
+func (r *RefreshToken) RevokeFamily(familyID string) error {
+	return r.RevokeFamilyFn(familyID)
+}
+
+// RevokeUser mock
+func (r *RefreshToken) RevokeUser(userID int) error {
+	return r.RevokeUserFn(userID)
+}
+
+// DeleteExpired mock
+func (r *RefreshToken) DeleteExpired(t time.Time) (int, error) {
+	return r.DeleteExpiredFn(t)
+}
@@ -0,0 +1,35 @@
+package mockdb
+
+import (
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Revocation database mock
+type Revocation struct {
+	CreateFn           func(*model.RevokedToken) error
+	ListSinceFn        func(time.Time) ([]model.RevokedToken, error)
+	TokensValidAfterFn func(int) (time.Time, error)
+	DeleteExpiredFn    func(time.Time) (int, error)
+}
+
+// Create mock
+func (r *Revocation) Create(t *model.RevokedToken) error {
+	return r.CreateFn(t)
+}
+
+// ListSince mock
+func (r *Revocation) ListSince(t time.Time) ([]model.RevokedToken, error) {
+	return r.ListSinceFn(t)
+}
+
+// TokensValidAfter mock
+func (r *Revocation) TokensValidAfter(userID int) (time.Time, error) {
+	return r.TokensValidAfterFn(userID)
+}
+
+// DeleteExpired mock
+func (r *Revocation) DeleteExpired(t time.Time) (int, error) {
+	return r.DeleteExpiredFn(t)
+}
@@ -0,0 +1,33 @@
+package mockdb
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Role database mock
+type Role struct {
+	CreateRolesFn func() error
+	ListFn        func() ([]model.Role, error)
+	GrantFn       func(int, string) error
+	RevokeFn      func(int, string) error
+}
+
+// CreateRoles mock
+func (r *Role) CreateRoles() error {
+	return r.CreateRolesFn()
+}
+
+// List mock
+func (r *Role) List() ([]model.Role, error) {
+	return r.ListFn()
+}
+
+// Grant mock
+func (r *Role) Grant(roleID int, permission string) error {
+	return r.GrantFn(roleID, permission)
+}
+
+// Revoke mock
+func (r *Role) Revoke(roleID int, permission string) error {
+	return r.RevokeFn(roleID, permission)
+}
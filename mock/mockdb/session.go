@@ -0,0 +1,57 @@
+package mockdb
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Session database mock
+type Session struct {
+	CreateFn     func(*model.Session) error
+	ViewFn       func(string) (*model.Session, error)
+	ListByUserFn func(int) ([]model.Session, error)
+	TouchFn      func(*model.Session) error
+	RevokeFn     func(string) error
+	RevokeUserFn func(int) error
+	ActiveFn     func(string) (bool, error)
+	StepUpFn     func(string) error
+}
+
+// Create mock
+func (s *Session) Create(session *model.Session) error {
+	return s.CreateFn(session)
+}
+
+// View mock
+func (s *Session) View(id string) (*model.Session, error) {
+	return s.ViewFn(id)
+}
+
+// ListByUser mock
+func (s *Session) ListByUser(userID int) ([]model.Session, error) {
+	return s.ListByUserFn(userID)
+}
+
+// Touch mock
+func (s *Session) Touch(session *model.Session) error {
+	return s.TouchFn(session)
+}
+
+// Revoke mock
+func (s *Session) Revoke(id string) error {
+	return s.RevokeFn(id)
+}
+
+// RevokeUser mock
+func (s *Session) RevokeUser(userID int) error {
+	return s.RevokeUserFn(userID)
+}
+
+// StepUp mock
+func (s *Session) StepUp(id string) error {
+	return s.StepUpFn(id)
+}
+
+// Active mock
+func (s *Session) Active(id string) (bool, error) {
+	return s.ActiveFn(id)
+}
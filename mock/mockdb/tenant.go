@@ -0,0 +1,27 @@
+package mockdb
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Tenant database mock
+type Tenant struct {
+	ListFn       func() ([]model.Tenant, error)
+	FindByNameFn func(string) (*model.Tenant, error)
+	SaveFn       func(*model.Tenant) error
+}
+
+// List mock
+func (t *Tenant) List() ([]model.Tenant, error) {
+	return t.ListFn()
+}
+
+// FindByName mock
+func (t *Tenant) FindByName(name string) (*model.Tenant, error) {
+	return t.FindByNameFn(name)
+}
+
+// Save mock
+func (t *Tenant) Save(tenant *model.Tenant) error {
+	return t.SaveFn(tenant)
+}
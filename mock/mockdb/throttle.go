@@ -0,0 +1,41 @@
+package mockdb
+
+import (
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// Throttle database mock
+type Throttle struct {
+	FindAllFn     func([]string) ([]model.Throttle, error)
+	IncrementFn   func(string, time.Time) (*model.Throttle, error)
+	LockFn        func(string, time.Time) error
+	ResetFn       func(string) error
+	DeleteStaleFn func(time.Time) (int, error)
+}
+
+// FindAll mock
+func (t *Throttle) FindAll(keys []string) ([]model.Throttle, error) {
+	return t.FindAllFn(keys)
+}
+
+// Increment mock
+func (t *Throttle) Increment(key string, since time.Time) (*model.Throttle, error) {
+	return t.IncrementFn(key, since)
+}
+
+// Lock mock
+func (t *Throttle) Lock(key string, until time.Time) error {
+	return t.LockFn(key, until)
+}
+
+// Reset mock
+func (t *Throttle) Reset(key string) error {
+	return t.ResetFn(key)
+}
+
+// DeleteStale mock
+func (t *Throttle) DeleteStale(before time.Time) (int, error) {
+	return t.DeleteStaleFn(before)
+}
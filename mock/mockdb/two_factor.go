@@ -0,0 +1,51 @@
+package mockdb
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// TwoFactor database mock
+type TwoFactor struct {
+	FindFn                 func(int) (*model.TwoFactor, error)
+	SaveFn                 func(*model.TwoFactor) error
+	DeleteFn               func(int) error
+	UseStepFn              func(int, int64) (bool, error)
+	ReplaceRecoveryCodesFn func(int, []string) error
+	UnusedRecoveryCodesFn  func(int) ([]model.RecoveryCode, error)
+	UseRecoveryCodeFn      func(int) (bool, error)
+}
+
+// Find mock
+func (t *TwoFactor) Find(userID int) (*model.TwoFactor, error) {
+	return t.FindFn(userID)
+}
+
+// Save mock
+func (t *TwoFactor) Save(tf *model.TwoFactor) error {
+	return t.SaveFn(tf)
+}
+
+// Delete mock
+func (t *TwoFactor) Delete(userID int) error {
+	return t.DeleteFn(userID)
+}
+
+// UseStep mock
+func (t *TwoFactor) UseStep(userID int, step int64) (bool, error) {
+	return t.UseStepFn(userID, step)
+}
+
+// ReplaceRecoveryCodes mock
+func (t *TwoFactor) ReplaceRecoveryCodes(userID int, hashes []string) error {
+	return t.ReplaceRecoveryCodesFn(userID, hashes)
+}
+
+// UnusedRecoveryCodes mock
+func (t *TwoFactor) UnusedRecoveryCodes(userID int) ([]model.RecoveryCode, error) {
+	return t.UnusedRecoveryCodesFn(userID)
+}
+
+// UseRecoveryCode mock
+func (t *TwoFactor) UseRecoveryCode(id int) (bool, error) {
+	return t.UseRecoveryCodeFn(id)
+}
@@ -13,9 +13,12 @@ type User struct {
 	FindByMobileFn       func(string, string) (*model.User, error)
 	FindByTokenFn        func(string) (*model.User, error)
 	UpdateLoginFn        func(*model.User) error
-	ListFn               func(*model.ListQuery, *model.Pagination) ([]model.User, error)
+	ListFn               func(*model.ListQuery, *model.Pagination) ([]model.User, int, error)
 	DeleteFn             func(*model.User) error
+	CloseFn              func(*model.User) error
 	UpdateFn             func(*model.User) (*model.User, error)
+	CountByAvatarFn      func(string) (int, error)
+	SearchFn             func(string, int) ([]model.User, error)
 }
 
 // View mock
@@ -54,7 +57,7 @@ func (u *User) UpdateLogin(usr *model.User) error {
 }
 
 // List mock
-func (u *User) List(lq *model.ListQuery, p *model.Pagination) ([]model.User, error) {
+func (u *User) List(lq *model.ListQuery, p *model.Pagination) ([]model.User, int, error) {
 	return u.ListFn(lq, p)
 }
 
@@ -63,7 +66,22 @@ func (u *User) Delete(usr *model.User) error {
 	return u.DeleteFn(usr)
 }
 
+// Close mock
+func (u *User) Close(usr *model.User) error {
+	return u.CloseFn(usr)
+}
+
 // Update mock
 func (u *User) Update(usr *model.User) (*model.User, error) {
 	return u.UpdateFn(usr)
 }
+
+// CountByAvatar mock
+func (u *User) CountByAvatar(avatar string) (int, error) {
+	return u.CountByAvatarFn(avatar)
+}
+
+// Search mock
+func (u *User) Search(term string, limit int) ([]model.User, error) {
+	return u.SearchFn(term, limit)
+}
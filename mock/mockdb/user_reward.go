@@ -0,0 +1,21 @@
+package mockdb
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// UserReward database mock
+type UserReward struct {
+	CreateFn func(*model.UserReward) error
+	ListFn   func(int) ([]model.UserReward, error)
+}
+
+// Create mock
+func (r *UserReward) Create(ur *model.UserReward) error {
+	return r.CreateFn(ur)
+}
+
+// List mock
+func (r *UserReward) List(userID int) ([]model.UserReward, error) {
+	return r.ListFn(userID)
+}
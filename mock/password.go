@@ -0,0 +1,36 @@
+package mock
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// PasswordPolicy mock, accepts every password unless its functions are set
+type PasswordPolicy struct {
+	ValidateFn   func(*model.User, string) error
+	CheckReuseFn func(*model.User, string) error
+	RememberFn   func(*model.User) error
+}
+
+// Validate mock
+func (p *PasswordPolicy) Validate(u *model.User, password string) error {
+	if p.ValidateFn == nil {
+		return nil
+	}
+	return p.ValidateFn(u, password)
+}
+
+// CheckReuse mock
+func (p *PasswordPolicy) CheckReuse(u *model.User, password string) error {
+	if p.CheckReuseFn == nil {
+		return nil
+	}
+	return p.CheckReuseFn(u, password)
+}
+
+// Remember mock
+func (p *PasswordPolicy) Remember(u *model.User) error {
+	if p.RememberFn == nil {
+		return nil
+	}
+	return p.RememberFn(u)
+}
@@ -8,10 +8,12 @@ import (
 
 // RBAC Mock
 type RBAC struct {
-	EnforceRoleFn   func(*gin.Context, model.AccessRole) bool
-	EnforceUserFn   func(*gin.Context, int) bool
-	AccountCreateFn func(*gin.Context, int) bool
-	IsLowerRoleFn   func(*gin.Context, model.AccessRole) bool
+	EnforceRoleFn       func(*gin.Context, model.AccessRole) bool
+	EnforceUserFn       func(*gin.Context, int) bool
+	AccountCreateFn     func(*gin.Context, int) bool
+	IsLowerRoleFn       func(*gin.Context, model.AccessRole) bool
+	EnforcePermissionFn func(*gin.Context, string) bool
+	ImpersonatorFn      func(*gin.Context) int
 }
 
 // EnforceRole mock
@@ -33,3 +35,13 @@ func (a *RBAC) AccountCreate(c *gin.Context, roleID int) bool {
 func (a *RBAC) IsLowerRole(c *gin.Context, role model.AccessRole) bool {
 	return a.IsLowerRoleFn(c, role)
 }
+
+// EnforcePermission mock
+func (a *RBAC) EnforcePermission(c *gin.Context, permission string) bool {
+	return a.EnforcePermissionFn(c, permission)
+}
+
+// Impersonator mock
+func (a *RBAC) Impersonator(c *gin.Context) int {
+	return a.ImpersonatorFn(c)
+}
@@ -1,5 +1,7 @@
 package mock
 
+import "github.com/alpacahq/ribbit-backend/secret"
+
 // Password mock
 type Password struct {
 	HashPasswordFn        func(string) string
@@ -21,3 +23,9 @@ func (p *Password) HashMatchesPassword(hash, password string) bool {
 func (p *Password) HashRandomPassword() (string, error) {
 	return p.HashRandomPasswordFn()
 }
+
+// Cipher returns a field cipher with a fixed test key, so encrypted values round trip in tests
+func Cipher() *secret.Cipher {
+	ring, _ := secret.NewKeyRing("test", map[string][]byte{"test": []byte("0123456789abcdef0123456789abcdef")})
+	return secret.NewCipher(ring)
+}
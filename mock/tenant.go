@@ -0,0 +1,16 @@
+package mock
+
+import "github.com/alpacahq/ribbit-backend/model"
+
+// Tenants mock, resolves every ID to a tenant with that ID unless ByIDFn is set
+type Tenants struct {
+	ByIDFn func(int) (*model.Tenant, error)
+}
+
+// ByID mock
+func (t *Tenants) ByID(id int) (*model.Tenant, error) {
+	if t.ByIDFn == nil {
+		return &model.Tenant{ID: id}, nil
+	}
+	return t.ByIDFn(id)
+}
@@ -0,0 +1,32 @@
+package mock
+
+// Throttler mock, allows every attempt unless its functions are set
+type Throttler struct {
+	CheckFn func(...string) error
+	FailFn  func(string) (bool, error)
+	ResetFn func(string) error
+}
+
+// Check mock
+func (t *Throttler) Check(keys ...string) error {
+	if t.CheckFn == nil {
+		return nil
+	}
+	return t.CheckFn(keys...)
+}
+
+// Fail mock
+func (t *Throttler) Fail(key string) (bool, error) {
+	if t.FailFn == nil {
+		return false, nil
+	}
+	return t.FailFn(key)
+}
+
+// Reset mock
+func (t *Throttler) Reset(key string) error {
+	if t.ResetFn == nil {
+		return nil
+	}
+	return t.ResetFn(key)
+}
@@ -0,0 +1,20 @@
+package mock
+
+// TwoFactor mock
+type TwoFactor struct {
+	EnabledFn func(int) (bool, error)
+	VerifyFn  func(int, string) error
+}
+
+// Enabled mock, defaults to two-factor authentication being off
+func (t *TwoFactor) Enabled(userID int) (bool, error) {
+	if t.EnabledFn == nil {
+		return false, nil
+	}
+	return t.EnabledFn(userID)
+}
+
+// Verify mock
+func (t *TwoFactor) Verify(userID int, code string) error {
+	return t.VerifyFn(userID, code)
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	Register(&AuditEvent{})
+}
+
+// Actions support staff take in the back office
+const (
+	AuditUserSearch             = "user.search"
+	AuditUserView               = "user.view"
+	AuditUserBrokerAccount      = "user.broker_account"
+	AuditUserOrders             = "user.orders"
+	AuditUserPositions          = "user.positions"
+	AuditUserTransfers          = "user.transfers"
+	AuditUserActivate           = "user.activate"
+	AuditUserDeactivate         = "user.deactivate"
+	AuditUserResendVerification = "user.resend_verification"
+	AuditUserGrantReward        = "user.grant_reward"
+	AuditUserNote               = "user.note"
+	AuditUserImpersonate        = "user.impersonate"
+	// AuditImpersonatedRequest is a request made with an impersonation token
+	AuditImpersonatedRequest = "impersonation.request"
+)
+
+// Security-sensitive and financial actions users take on their own account, and role changes
+const (
+	AuditProfileUpdate  = "profile.update"
+	AuditPasswordChange = "password.change"
+	AuditBankLink       = "bank.link"
+	AuditBankUnlink     = "bank.unlink"
+	AuditTransferCreate = "transfer.create"
+	AuditTransferCancel = "transfer.cancel"
+	AuditOrderCreate    = "order.create"
+	AuditOrderReplace   = "order.replace"
+	AuditOrderCancel    = "order.cancel"
+	AuditOrderCancelAll = "order.cancel_all"
+	AuditPositionClose  = "position.close"
+	AuditAccountClose   = "account.close"
+	AuditDataExport     = "account.export"
+	AuditRolePermGrant  = "role.grant"
+	AuditRolePermRevoke = "role.revoke"
+)
+
+// AuditEvent records who did what, and to which user. The audit_events table is append-only.
+type AuditEvent struct {
+	tableName struct{} `pg:"audit_events"`
+
+	ID      int64 `json:"id"`
+	ActorID int   `json:"actor_id" pg:",notnull"`
+	// ImpersonatorID is the staff member who took the action while impersonating the actor, if any
+	ImpersonatorID int    `json:"impersonator_id,omitempty"`
+	Action         string `json:"action" pg:",notnull"`
+	// TargetUserID is the user the action was taken on, if any
+	TargetUserID int `json:"target_user_id,omitempty"`
+	// Details holds the parameters of the action, such as a search term or a reward amount
+	Details map[string]interface{} `json:"details,omitempty"`
+	// Changes holds the fields the action changed
+	Changes   map[string]AuditChange `json:"changes,omitempty"`
+	RequestID string                 `json:"request_id"`
+	IP        string                 `json:"ip"`
+	UserAgent string                 `json:"user_agent"`
+	CreatedAt time.Time              `json:"created_at" pg:",notnull"`
+}
+
+// AuditChange is the value of a field before and after an action
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+	// Redacted changes record that a personal field changed, without its values
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// redactedFields are the JSON fields of users whose values are kept out of the audit log: the
+// fields encrypted at rest and the user's name and contact details
+var redactedFields = map[string]bool{
+	"tax_id":       true,
+	"dob":          true,
+	"address":      true,
+	"unit_apt":     true,
+	"zip_code":     true,
+	"first_name":   true,
+	"last_name":    true,
+	"email":        true,
+	"mobile":       true,
+	"country_code": true,
+}
+
+// AuditFilter selects audit events. Zero fields match every event.
+type AuditFilter struct {
+	ActorID      int
+	TargetUserID int
+	Action       string
+	From         time.Time
+	To           time.Time
+	// BeforeID returns the events older than the event with this ID, to page through events newest first
+	BeforeID int64
+	Limit    int
+}
+
+// AuditRepo represents the audit log database interface. Events can't be changed or deleted.
+type AuditRepo interface {
+	Create(*AuditEvent) error
+	List(*AuditFilter) ([]AuditEvent, error)
+	ForEach(*AuditFilter, func(*AuditEvent) error) error
+}
+
+// AuditLog records actions taken in a request, with who took them and from where
+type AuditLog interface {
+	Record(*gin.Context, *AuditEvent) error
+}
+
+// Diff returns the JSON fields that differ between before and after, so values
+// their MarshalJSON masks or hides stay masked or hidden in the audit log.
+// Only the names of changed personal fields are returned.
+func Diff(before, after interface{}) (map[string]AuditChange, error) {
+	from, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := map[string]AuditChange{}
+	for k, v := range to {
+		if k != "updated_at" && !reflect.DeepEqual(from[k], v) {
+			changes[k] = AuditChange{From: from[k], To: v}
+		}
+	}
+	for k, v := range from {
+		if _, ok := to[k]; !ok {
+			changes[k] = AuditChange{From: v}
+		}
+	}
+	for k := range changes {
+		if redactedFields[k] {
+			changes[k] = AuditChange{Redacted: true}
+		}
+	}
+	return changes, nil
+}
+
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	return fields, json.Unmarshal(b, &fields)
+}
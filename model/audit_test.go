@@ -0,0 +1,23 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := &model.User{ID: 1, FirstName: "Jane", City: "Oakland", TaxID: "123456789", Password: "old", Email: "jane@mail.com"}
+	after := &model.User{ID: 1, FirstName: "Jane", City: "Berkeley", TaxID: "987654321", Password: "new", Email: "jane@example.com"}
+	after.UpdatedAt = after.UpdatedAt.AddDate(0, 0, 1)
+
+	changes, err := model.Diff(before, after)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]model.AuditChange{
+		"city":   {From: "Oakland", To: "Berkeley"},
+		"tax_id": {Redacted: true},
+		"email":  {Redacted: true},
+	}, changes, "hidden fields stay hidden and personal fields are only named")
+}
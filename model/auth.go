@@ -17,12 +17,17 @@ type LoginResponseWithToken struct {
 	Expires      string `json:"expires"`
 	RefreshToken string `json:"refresh_token"`
 	User         User   `json:"user"`
+	// TwoFactorRequired is set, with ChallengeToken and no tokens or user, when the password
+	// was correct but the user still has to enter their second factor
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 // RefreshToken holds authentication token details
 type RefreshToken struct {
-	Token   string `json:"token"`
-	Expires string `json:"expires"`
+	Token        string `json:"token"`
+	Expires      string `json:"expires"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 // AuthService represents authentication service interface
@@ -36,4 +41,6 @@ type RBACService interface {
 	EnforceUser(*gin.Context, int) bool
 	AccountCreate(*gin.Context, int) bool
 	IsLowerRole(*gin.Context, AccessRole) bool
+	EnforcePermission(*gin.Context, string) bool
+	Impersonator(*gin.Context) int
 }
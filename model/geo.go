@@ -0,0 +1,35 @@
+package model
+
+func init() {
+	Register(&GeoCountry{})
+	Register(&GeoState{})
+	Register(&GeoCity{})
+}
+
+// GeoCountry is a country of our geography reference data, imported with the import_geo command
+type GeoCountry struct {
+	ID     int    `json:"id"`
+	Alpha3 string `json:"alpha3" pg:",unique,notnull"`
+	Alpha2 string `json:"alpha2"`
+	Name   string `json:"name" pg:",notnull"`
+}
+
+// GeoState is a first-level subdivision of a GeoCountry
+type GeoState struct {
+	ID          int    `json:"id"`
+	CountryCode string `json:"country_code" pg:",notnull"`
+	Code        string `json:"code" pg:",notnull"`
+	Name        string `json:"name" pg:",notnull"`
+}
+
+// GeoCity is a city within a GeoState
+type GeoCity struct {
+	ID          int    `json:"id"`
+	CountryCode string `json:"country_code" pg:",notnull"`
+	StateCode   string `json:"state_code" pg:",notnull"`
+	Name        string `json:"name" pg:",notnull"`
+	ASCII       string `json:"ascii"`
+	Lat         string `json:"lat"`
+	Lng         string `json:"lng"`
+	Zips        string `json:"zips"` // space separated
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+)
+
+func init() {
+	Register(&Identity{})
+}
+
+// Identity links a user to an account at an external identity provider, such as Apple or Google.
+// A user can have several, next to their email address or mobile number.
+type Identity struct {
+	tableName struct{} `pg:"user_identities"`
+
+	ID     int `json:"id"`
+	UserID int `json:"-" pg:",notnull"`
+	// Provider and Subject identify the account at the provider
+	Provider string `json:"provider" pg:",notnull,unique:provider_subject"`
+	Subject  string `json:"-" pg:",notnull,unique:provider_subject"`
+	// Email is the email address the provider reported when the identity was linked
+	Email      string     `json:"email"`
+	CreatedAt  time.Time  `json:"created_at" pg:",notnull"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// IdentityRepo represents the linked identities database interface
+type IdentityRepo interface {
+	Find(provider, subject string) (*Identity, error)
+	List(userID int) ([]Identity, error)
+	Create(*Identity) error
+	Touch(*Identity) error
+	Delete(userID, id int) error
+}
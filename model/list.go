@@ -0,0 +1,75 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+)
+
+// UserSortColumns are the columns users can be sorted by, with the value of each for a user
+var UserSortColumns = map[string]func(*User) string{
+	"id":         func(u *User) string { return strconv.Itoa(u.ID) },
+	"created_at": func(u *User) string { return u.CreatedAt.Format(time.RFC3339Nano) },
+	"email":      func(u *User) string { return u.Email },
+	"first_name": func(u *User) string { return u.FirstName },
+	"last_name":  func(u *User) string { return u.LastName },
+}
+
+// ListQuery filters, sorts and pages through users. Zero fields don't filter.
+type ListQuery struct {
+	Role          AccessRole
+	Verified      *bool
+	Active        *bool
+	AccountStatus string
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	// Search matches names, emails and mobile numbers by substring
+	Search string
+	// Sort is one of UserSortColumns. Ties are sorted by id. Without a sort, users are listed by id, newest first.
+	Sort string
+	Desc bool
+	// Cursor continues the list after the last user of the previous page, instead of an offset
+	Cursor string
+}
+
+// UserCursor is a position in a list of users
+type UserCursor struct {
+	Value string
+	ID    int
+}
+
+// NextCursor returns the cursor of the page after the one ending with u
+func (q *ListQuery) NextCursor(u *User) string {
+	value, ok := UserSortColumns[q.Sort]
+	if !ok {
+		value = UserSortColumns["id"]
+	}
+	b, _ := json.Marshal([]interface{}{value(u), u.ID})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor returns the position Cursor continues after, or nil if there is no cursor
+func (q *ListQuery) ParseCursor() (*UserCursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	invalid := apperr.New(http.StatusBadRequest, "Invalid cursor.")
+	b, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var pos []interface{}
+	if err := json.Unmarshal(b, &pos); err != nil || len(pos) != 2 {
+		return nil, invalid
+	}
+	value, ok := pos[0].(string)
+	id, ok2 := pos[1].(float64)
+	if !ok || !ok2 {
+		return nil, invalid
+	}
+	return &UserCursor{Value: value, ID: int(id)}, nil
+}
@@ -21,12 +21,6 @@ type Pagination struct {
 	Offset int
 }
 
-// ListQuery holds company/location data used for list db queries
-type ListQuery struct {
-	Query string
-	ID    int
-}
-
 // BeforeInsert hooks into insert operations, setting createdAt and updatedAt to current time
 func (b *Base) BeforeInsert(ctx context.Context) (context.Context, error) {
 	now := time.Now()
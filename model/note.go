@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+func init() {
+	Register(&UserNote{})
+}
+
+// UserNote is an internal note support staff keep on a user, never shown to the user
+type UserNote struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id" pg:",notnull"`
+	AuthorID  int       `json:"author_id" pg:",notnull"`
+	Body      string    `json:"body" pg:",notnull"`
+	CreatedAt time.Time `json:"created_at" pg:",notnull"`
+}
+
+// NoteRepo represents the user notes database interface
+type NoteRepo interface {
+	Create(*UserNote) error
+	// List returns the notes on a user, newest first
+	List(userID int) ([]UserNote, error)
+}
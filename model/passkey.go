@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+)
+
+func init() {
+	Register(&Passkey{})
+	Register(&PasskeyChallenge{})
+}
+
+// Ceremonies a passkey challenge is issued for
+const (
+	PasskeyRegister = "register"
+	PasskeyLogin    = "login"
+)
+
+// PasskeyChallengeLifetime is how long a client has to complete a ceremony
+const PasskeyChallengeLifetime = 5 * time.Minute
+
+// Passkey is a WebAuthn credential a user signs in with, such as a fingerprint or face unlock on their phone
+type Passkey struct {
+	tableName struct{} `pg:"webauthn_credentials"`
+
+	ID     int `json:"id"`
+	UserID int `json:"-" pg:",notnull"`
+	// CredentialID is the base64url encoded credential ID chosen by the authenticator
+	CredentialID string `json:"-" pg:",unique,notnull"`
+	// PublicKey is the COSE encoded public key
+	PublicKey []byte `json:"-" pg:",notnull"`
+	// SignCount is the last signature counter, used to detect cloned authenticators
+	SignCount  int64      `json:"-" pg:",use_zero"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at" pg:",notnull"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// PasskeyChallenge is a random challenge of a registration or login ceremony, valid once
+type PasskeyChallenge struct {
+	tableName struct{} `pg:"webauthn_challenges"`
+
+	// Challenge is base64url encoded
+	Challenge string `pg:",pk"`
+	Ceremony  string `pg:",notnull"`
+	// UserID is set for registrations, logins don't know the user yet
+	UserID    int       `pg:",use_zero"`
+	ExpiresAt time.Time `pg:",notnull"`
+}
+
+// PasskeyRepo represents the passkey database interface
+type PasskeyRepo interface {
+	Create(*Passkey) error
+	List(userID int) ([]Passkey, error)
+	FindByCredentialID(string) (*Passkey, error)
+	Used(*Passkey) error
+	Delete(userID, id int) error
+	CreateChallenge(*PasskeyChallenge) error
+	UseChallenge(challenge, ceremony string) (*PasskeyChallenge, error)
+	DeleteExpiredChallenges() (int, error)
+}
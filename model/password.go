@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+)
+
+func init() {
+	Register(&PasswordHistory{})
+}
+
+// PasswordHistory is the hash of a password a user had, kept to block reusing it
+type PasswordHistory struct {
+	tableName struct{} `pg:"password_history"`
+
+	ID        int       `json:"-"`
+	UserID    int       `json:"-" pg:",notnull"`
+	Hash      string    `json:"-" pg:",notnull"`
+	CreatedAt time.Time `json:"-" pg:",notnull"`
+}
+
+// PasswordHistoryRepo represents the password history database interface
+type PasswordHistoryRepo interface {
+	// Recent returns the hashes of the last n passwords of a user, newest first
+	Recent(userID, n int) ([]string, error)
+	// Add stores a password hash of a user and forgets all but the last keep ones
+	Add(userID int, hash string, keep int) error
+}
+
+// PasswordPolicy checks the new passwords users choose
+type PasswordPolicy interface {
+	// Validate returns a 400 error if password is too weak, contains personal details of u or appeared in a breach
+	Validate(u *User, password string) error
+	// CheckReuse returns a 400 error if password is the current or a recent password of u
+	CheckReuse(u *User, password string) error
+	// Remember adds the current password hash of u to its history
+	Remember(u *User) error
+}
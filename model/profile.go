@@ -0,0 +1,138 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Profile sections, in the order the onboarding flow collects them
+const (
+	SectionIdentity    = "identity"
+	SectionContact     = "contact"
+	SectionEmployment  = "employment"
+	SectionDisclosures = "disclosures"
+	SectionAgreements  = "agreements"
+	SectionFunding     = "funding"
+)
+
+// ProfileSection holds the completion state of a single onboarding section
+type ProfileSection struct {
+	Name     string   `json:"name"`
+	Complete bool     `json:"complete"`
+	Missing  []string `json:"missing"`
+}
+
+// ProfileChecklist is the server computed onboarding completeness of a user,
+// based on the fields the broker account payload requires
+type ProfileChecklist struct {
+	Complete bool             `json:"complete"`
+	Percent  int              `json:"percent"`
+	Sections []ProfileSection `json:"sections"`
+}
+
+type requiredField struct {
+	name  string
+	value string
+}
+
+// CheckProfile computes the onboarding checklist for u
+func CheckProfile(u *User) *ProfileChecklist {
+	agreements := ""
+	if u.AgreementsSignedAt != nil {
+		agreements = u.AgreementsSignedAt.String()
+	}
+	sections := []struct {
+		name   string
+		fields []requiredField
+	}{
+		{SectionIdentity, []requiredField{
+			{"first_name", u.FirstName},
+			{"last_name", u.LastName},
+			{"dob", u.DOB},
+			{"tax_id_type", u.TaxIDType},
+			{"tax_id", u.TaxID},
+		}},
+		{SectionContact, []requiredField{
+			{"email", u.Email},
+			{"mobile", u.Mobile},
+			{"address", u.Address},
+			{"city", u.City},
+			{"state", u.State},
+			{"zip_code", u.ZipCode},
+		}},
+		{SectionEmployment, employmentFields(u)},
+		{SectionDisclosures, disclosureFields(u)},
+		{SectionAgreements, []requiredField{
+			{"agreements", agreements},
+		}},
+		{SectionFunding, []requiredField{
+			{"funding_source", u.FundingSource},
+			{"investing_experience", u.InvestingExperience},
+		}},
+	}
+
+	p := &ProfileChecklist{Complete: true}
+	total, done := 0, 0
+	for _, s := range sections {
+		section := ProfileSection{Name: s.name, Missing: []string{}}
+		for _, f := range s.fields {
+			total++
+			if strings.TrimSpace(f.value) == "" {
+				section.Missing = append(section.Missing, f.name)
+				continue
+			}
+			done++
+		}
+		section.Complete = len(section.Missing) == 0
+		p.Complete = p.Complete && section.Complete
+		p.Sections = append(p.Sections, section)
+	}
+	p.Percent = done * 100 / total
+	return p
+}
+
+// PercentString returns the completion percentage as stored in User.ProfileCompletion
+func (p *ProfileChecklist) PercentString() string {
+	return strconv.Itoa(p.Percent)
+}
+
+func employmentFields(u *User) []requiredField {
+	fields := []requiredField{{"employment_status", u.EmploymentStatus}}
+	if strings.EqualFold(u.EmploymentStatus, "employed") {
+		fields = append(fields,
+			requiredField{"employer_name", u.EmployerName},
+			requiredField{"occupation", u.Occupation},
+		)
+	}
+	return fields
+}
+
+func disclosureFields(u *User) []requiredField {
+	fields := []requiredField{
+		{"public_shareholder", u.PublicShareholder},
+		{"another_brokerage", u.AnotherBrokerage},
+	}
+	if IsYes(u.PublicShareholder) {
+		fields = append(fields,
+			requiredField{"shareholder_company_name", u.ShareholderCompanyName},
+			requiredField{"stock_symbol", u.StockSymbol},
+		)
+	}
+	if IsYes(u.AnotherBrokerage) {
+		fields = append(fields,
+			requiredField{"brokerage_firm_name", u.BrokerageFirmName},
+			requiredField{"brokerage_firm_employee_name", u.BrokerageFirmEmployeeName},
+			requiredField{"brokerage_firm_employee_relationship", u.BrokerageFirmEmployeeRelationship},
+		)
+	}
+	return fields
+}
+
+// IsYes reports whether a free-form yes/no profile answer is affirmative
+func IsYes(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "true", "y", "1":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func completeUser() *model.User {
+	signed := time.Now()
+	return &model.User{
+		FirstName:           "John",
+		LastName:            "Doe",
+		DOB:                 "1990-05-19",
+		TaxIDType:           "USA_SSN",
+		TaxID:               "123-45-6789",
+		Email:               "johndoe@mail.com",
+		Mobile:              "4155550100",
+		Address:             "760 Market Street",
+		City:                "San Francisco",
+		State:               "CA",
+		ZipCode:             "94102",
+		EmploymentStatus:    "unemployed",
+		PublicShareholder:   "no",
+		AnotherBrokerage:    "no",
+		AgreementsSignedAt:  &signed,
+		FundingSource:       "employment_income",
+		InvestingExperience: "none",
+	}
+}
+
+func TestCheckProfileComplete(t *testing.T) {
+	p := model.CheckProfile(completeUser())
+	assert.True(t, p.Complete)
+	assert.Equal(t, 100, p.Percent)
+	assert.Len(t, p.Sections, 6)
+}
+
+func TestCheckProfileMissingFields(t *testing.T) {
+	u := completeUser()
+	u.TaxID = ""
+	u.AgreementsSignedAt = nil
+	u.EmploymentStatus = "employed"
+	u.AnotherBrokerage = "yes"
+
+	p := model.CheckProfile(u)
+	assert.False(t, p.Complete)
+	missing := map[string][]string{}
+	for _, s := range p.Sections {
+		missing[s.Name] = s.Missing
+		assert.Equal(t, len(s.Missing) == 0, s.Complete)
+	}
+	assert.Equal(t, []string{"tax_id"}, missing[model.SectionIdentity])
+	assert.Equal(t, []string{}, missing[model.SectionContact])
+	assert.Equal(t, []string{"employer_name", "occupation"}, missing[model.SectionEmployment])
+	assert.Equal(t, []string{"brokerage_firm_name", "brokerage_firm_employee_name", "brokerage_firm_employee_relationship"}, missing[model.SectionDisclosures])
+	assert.Equal(t, []string{"agreements"}, missing[model.SectionAgreements])
+	assert.Less(t, p.Percent, 100)
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+func init() {
+	Register(&IssuedRefreshToken{})
+}
+
+// IssuedRefreshToken is a refresh token handed out to a client. Only the hash of the token is stored.
+// Every login starts a new family; each refresh rotates the token within its family.
+type IssuedRefreshToken struct {
+	tableName struct{} `pg:"refresh_tokens"`
+
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id" pg:",notnull"`
+	FamilyID  string    `json:"family_id" pg:",notnull"`
+	TokenHash string    `json:"-" pg:",unique,notnull"`
+	ExpiresAt time.Time `json:"expires_at" pg:",notnull"`
+	// FamilyExpiresAt caps the lifetime of the whole family, however often it is refreshed
+	FamilyExpiresAt time.Time  `json:"family_expires_at" pg:",notnull"`
+	UsedAt          *time.Time `json:"used_at,omitempty"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// Expired reports whether the token can no longer be used at t
+func (r *IssuedRefreshToken) Expired(t time.Time) bool {
+	return !t.Before(r.ExpiresAt) || !t.Before(r.FamilyExpiresAt)
+}
+
+// HashRefreshToken returns the value stored for a refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshTokenRepo represents the refresh token database interface
+type RefreshTokenRepo interface {
+	Create(*IssuedRefreshToken) error
+	FindByHash(string) (*IssuedRefreshToken, error)
+	MarkUsed(*IssuedRefreshToken) (bool, error)
+	RevokeFamily(string) error
+	RevokeUser(int) error
+	DeleteExpired(time.Time) (int, error)
+}
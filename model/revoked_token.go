@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+)
+
+func init() {
+	Register(&RevokedToken{})
+}
+
+// RevokedToken is an access token that was revoked before it expired, identified by its jti claim
+type RevokedToken struct {
+	tableName struct{} `pg:"revoked_tokens"`
+
+	JTI    string `json:"jti" pg:",pk"`
+	UserID int    `json:"user_id" pg:",notnull"`
+	// ExpiresAt is the expiry of the token, after which the row is no longer needed
+	ExpiresAt time.Time `json:"expires_at" pg:",notnull"`
+	RevokedAt time.Time `json:"revoked_at" pg:",notnull"`
+}
+
+// RevocationRepo represents the token revocation database interface
+type RevocationRepo interface {
+	Create(*RevokedToken) error
+	ListSince(time.Time) ([]RevokedToken, error)
+	TokensValidAfter(int) (time.Time, error)
+	DeleteExpired(time.Time) (int, error)
+}
+
+// TokenRevoker revokes access tokens before they expire
+type TokenRevoker interface {
+	Revoke(jti string, userID int, expires time.Time) error
+}
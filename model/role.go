@@ -1,7 +1,10 @@
 package model
 
+import "strings"
+
 func init() {
 	Register(&Role{})
+	Register(&RolePermission{})
 }
 
 // AccessRole represents access role type
@@ -18,14 +21,84 @@ const (
 	UserRole
 )
 
+// RoleNames are the names of the roles RoleRepo.CreateRoles creates
+var RoleNames = map[AccessRole]string{
+	SuperAdminRole: "superadmin",
+	AdminRole:      "admin",
+	UserRole:       "user",
+}
+
+// ParseRole returns the role with a name, ignoring case
+func ParseRole(name string) (AccessRole, bool) {
+	for role, n := range RoleNames {
+		if strings.EqualFold(n, name) {
+			return role, true
+		}
+	}
+	return 0, false
+}
+
+// Permissions granted to roles
+const (
+	// PermUsersRead allows looking up any user and their accounts
+	PermUsersRead = "users:read"
+	// PermUsersWrite allows changing, activating and deactivating any user
+	PermUsersWrite = "users:write"
+	// PermRewardsGrant allows granting rewards to users
+	PermRewardsGrant = "rewards:grant"
+	// PermKYCReview allows reviewing the KYC status of users
+	PermKYCReview = "kyc:review"
+	// PermRolesManage allows granting and revoking the permissions of roles
+	PermRolesManage = "roles:manage"
+	// PermAuditRead allows reading the audit log
+	PermAuditRead = "audit:read"
+	// PermUsersImpersonate allows signing in as users with a lower role, read-only
+	PermUsersImpersonate = "users:impersonate"
+)
+
+// Permissions are all permissions roles can be granted
+var Permissions = []string{PermUsersRead, PermUsersWrite, PermRewardsGrant, PermKYCReview, PermRolesManage, PermAuditRead, PermUsersImpersonate}
+
+// DefaultPermissions are the permissions RoleRepo.CreateRoles grants each role it creates.
+// SuperAdminRole has all permissions without being granted them.
+var DefaultPermissions = map[AccessRole][]string{
+	AdminRole: {PermUsersRead, PermUsersWrite, PermRewardsGrant, PermKYCReview, PermUsersImpersonate},
+}
+
+// ValidPermission reports whether p is one of Permissions
+func ValidPermission(p string) bool {
+	for _, v := range Permissions {
+		if v == p {
+			return true
+		}
+	}
+	return false
+}
+
 // Role model
 type Role struct {
 	ID          int        `json:"id"`
 	AccessLevel AccessRole `json:"access_level"`
 	Name        string     `json:"name"`
+	Permissions []string   `json:"permissions,omitempty" pg:"-"`
+}
+
+// RolePermission grants a permission to a role
+type RolePermission struct {
+	RoleID     int    `pg:",pk"`
+	Permission string `pg:",pk"`
 }
 
 // RoleRepo represents the database interface
 type RoleRepo interface {
 	CreateRoles() error
+	// List returns the roles with their permissions
+	List() ([]Role, error)
+	Grant(roleID int, permission string) error
+	Revoke(roleID int, permission string) error
+}
+
+// PermissionService checks the permissions granted to roles
+type PermissionService interface {
+	Allowed(role AccessRole, permission string) (bool, error)
 }
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+)
+
+func init() {
+	Register(&Session{})
+}
+
+// Session is a login on one device. Its ID is the family of the refresh tokens issued to it
+// and is carried by access tokens as the sid claim, so revoking a session ends both.
+type Session struct {
+	tableName struct{} `pg:"sessions"`
+
+	ID         string     `json:"id" pg:",pk"`
+	UserID     int        `json:"-" pg:",notnull"`
+	DeviceID   string     `json:"device_id,omitempty"`
+	DeviceName string     `json:"device_name,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" pg:",notnull"`
+	RevokedAt  *time.Time `json:"-"`
+	// StepUpAt is when the user last confirmed a second factor in this session
+	StepUpAt *time.Time `json:"-"`
+	// Current marks the session of the request listing sessions
+	Current bool `json:"current" pg:"-"`
+}
+
+// Active reports whether the session can still be used at t
+func (s *Session) Active(t time.Time) bool {
+	return s.RevokedAt == nil && t.Before(s.ExpiresAt)
+}
+
+// SessionRepo represents the session database interface
+type SessionRepo interface {
+	Create(*Session) error
+	View(string) (*Session, error)
+	ListByUser(int) ([]Session, error)
+	Touch(*Session) error
+	Revoke(string) error
+	RevokeUser(int) error
+	Active(string) (bool, error)
+	StepUp(string) error
+}
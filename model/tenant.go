@@ -0,0 +1,81 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+func init() {
+	Register(&Tenant{})
+}
+
+// Tenant is a white-label brand of the app, with its own hosts, branding, email sender and broker
+// credentials. Users belong to the tenant they signed up with; the default tenant, configured by
+// environment variables, has ID 0 and isn't stored.
+type Tenant struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" pg:",notnull,unique"`
+	// Hosts are the hostnames requests of the tenant are sent to, e.g. app.example.com
+	Hosts        []string `json:"-" pg:",array"`
+	LogoURL      string   `json:"logo_url"`
+	PrimaryColor string   `json:"primary_color"`
+	// LinkDomain is the base URL of links to the app, such as shareable profile links and links in emails
+	LinkDomain    string    `json:"link_domain"`
+	MailFromName  string    `json:"-"`
+	MailFromEmail string    `json:"-"`
+	BrokerAPIBase string    `json:"-"`
+	BrokerToken   string    `json:"-"`
+	CreatedAt     time.Time `json:"-" pg:",notnull"`
+	UpdatedAt     time.Time `json:"-" pg:",notnull"`
+}
+
+// Inherit sets the settings t leaves empty to those of def, usually the default tenant. The broker
+// credentials are inherited as a pair, so that the default token is never sent to a tenant's API base.
+func (t *Tenant) Inherit(def *Tenant) {
+	for _, f := range []struct{ v, def *string }{
+		{&t.LogoURL, &def.LogoURL},
+		{&t.PrimaryColor, &def.PrimaryColor},
+		{&t.LinkDomain, &def.LinkDomain},
+		{&t.MailFromEmail, &def.MailFromEmail},
+	} {
+		if *f.v == "" {
+			*f.v = *f.def
+		}
+	}
+	if t.BrokerAPIBase == "" && t.BrokerToken == "" {
+		t.BrokerAPIBase, t.BrokerToken = def.BrokerAPIBase, def.BrokerToken
+	}
+	if t.MailFromName == "" {
+		t.MailFromName = t.Name
+	}
+}
+
+// TenantRepo represents the tenants database interface
+type TenantRepo interface {
+	List() ([]Tenant, error)
+	FindByName(string) (*Tenant, error)
+	// Save creates t, or updates it if it has an ID
+	Save(t *Tenant) error
+}
+
+// TenantStore resolves tenants by ID, the default tenant being ID 0
+type TenantStore interface {
+	ByID(int) (*Tenant, error)
+}
+
+// TenantOf returns the tenant a request was resolved to by the tenant middleware, or nil outside of requests
+func TenantOf(c context.Context) *Tenant {
+	if c == nil {
+		return nil
+	}
+	t, _ := c.Value("tenant").(*Tenant)
+	return t
+}
+
+// TenantIDOf returns the ID of the tenant of a request, which is 0 for the default tenant
+func TenantIDOf(c context.Context) int {
+	if t := TenantOf(c); t != nil {
+		return t.ID
+	}
+	return 0
+}
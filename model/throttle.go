@@ -0,0 +1,70 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(&Throttle{})
+}
+
+// Kinds of throttled keys, each with its own limit
+const (
+	// ThrottleAccount counts failed sign ins to an account
+	ThrottleAccount = "account"
+	// ThrottleIP counts failed attempts of any kind from a client IP
+	ThrottleIP = "ip"
+	// ThrottleOTP counts wrong one-time passwords sent to an email address or mobile number
+	ThrottleOTP = "otp"
+	// ThrottleSMS counts the verification codes sent to a mobile number
+	ThrottleSMS = "sms"
+	// ThrottlePasskey counts the passkey sign ins begun from a client IP
+	ThrottlePasskey = "passkey"
+)
+
+// ThrottleKey returns the key of a throttled value, e.g. account:jane@example.com
+func ThrottleKey(kind, value string) string {
+	return kind + ":" + strings.ToLower(strings.TrimSpace(value))
+}
+
+// Throttle counts the recent failed attempts for a key, such as an account or an IP
+type Throttle struct {
+	tableName struct{} `pg:"throttles"`
+
+	Key      string `json:"key" pg:",pk"`
+	Failures int    `json:"failures" pg:",use_zero"`
+	// LockedUntil is set once the failures exceed the limit of the key
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	// UpdatedAt is the time of the last failure
+	UpdatedAt time.Time `json:"updated_at" pg:",notnull"`
+}
+
+// Locked reports whether attempts for the key are refused at the given time
+func (t *Throttle) Locked(at time.Time) bool {
+	return t.LockedUntil != nil && at.Before(*t.LockedUntil)
+}
+
+// Kind returns the kind of the key, e.g. account
+func (t *Throttle) Kind() string {
+	return strings.SplitN(t.Key, ":", 2)[0]
+}
+
+// ThrottleRepo represents the failed attempts database interface
+type ThrottleRepo interface {
+	FindAll([]string) ([]Throttle, error)
+	Increment(string, time.Time) (*Throttle, error)
+	Lock(string, time.Time) error
+	Reset(string) error
+	DeleteStale(time.Time) (int, error)
+}
+
+// Throttler limits failed attempts, locking keys with exponential backoff
+type Throttler interface {
+	// Check returns an error if any of the keys is locked
+	Check(keys ...string) error
+	// Fail counts a failed attempt for key and reports whether it locked the key
+	Fail(key string) (bool, error)
+	// Reset forgets the failed attempts for key, e.g. after a successful sign in
+	Reset(key string) error
+}
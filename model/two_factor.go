@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+)
+
+func init() {
+	Register(&TwoFactor{})
+	Register(&RecoveryCode{})
+}
+
+// TwoFactor holds a user's TOTP secret. It is pending until the user confirms
+// a code from their authenticator app, and only then required on login.
+type TwoFactor struct {
+	tableName struct{} `pg:"two_factors"`
+
+	UserID int `json:"-" pg:",pk"`
+	// Secret is stored encrypted
+	Secret      string     `json:"-" pg:",notnull"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	// LastStep is the TOTP time step last accepted, so codes cannot be replayed
+	LastStep  int64     `json:"-" pg:",use_zero"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Enabled reports whether the user confirmed their authenticator app
+func (t *TwoFactor) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
+
+// RecoveryCode is a single-use code replacing a TOTP code when the authenticator app is lost.
+// Only its hash is stored.
+type RecoveryCode struct {
+	tableName struct{} `pg:"recovery_codes"`
+
+	ID       int        `json:"-"`
+	UserID   int        `json:"-" pg:",notnull"`
+	CodeHash string     `json:"-" pg:",notnull"`
+	UsedAt   *time.Time `json:"-"`
+}
+
+// TOTPEnrollment is returned when a user starts setting up an authenticator app
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI to render as a QR code
+	URI string `json:"uri"`
+}
+
+// TwoFactorRepo represents the two-factor authentication database interface
+type TwoFactorRepo interface {
+	Find(int) (*TwoFactor, error)
+	Save(*TwoFactor) error
+	Delete(int) error
+	UseStep(int, int64) (bool, error)
+	ReplaceRecoveryCodes(int, []string) error
+	UnusedRecoveryCodes(int) ([]RecoveryCode, error)
+	UseRecoveryCode(int) (bool, error)
+}
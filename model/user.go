@@ -1,6 +1,8 @@
 package model
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -11,57 +13,64 @@ func init() {
 // User represents user domain model
 type User struct {
 	Base
-	ID                                int        `json:"id"`
-	FirstName                         string     `json:"first_name"`
-	LastName                          string     `json:"last_name"`
-	Username                          string     `json:"username"`
-	Password                          string     `json:"-"`
-	Email                             string     `json:"email"`
-	Mobile                            string     `json:"mobile"`
-	CountryCode                       string     `json:"country_code"`
-	Address                           string     `json:"address"`
-	LastLogin                         *time.Time `json:"last_login,omitempty"`
-	Verified                          bool       `json:"verified"`
-	Active                            bool       `json:"active"`
-	Token                             string     `json:"-"`
-	Role                              *Role      `json:"role,omitempty"`
-	RoleID                            int        `json:"-"`
-	AccountID                         string     `json:"account_id"`
-	AccountNumber                     string     `json:"account_number"`
-	AccountCurrency                   string     `json:"account_currency"`
-	AccountStatus                     string     `json:"account_status"`
-	DOB                               string     `json:"dob"`
-	City                              string     `json:"city"`
-	State                             string     `json:"state"`
-	Country                           string     `json:"country"`
-	TaxIDType                         string     `json:"tax_id_type"`
-	TaxID                             string     `json:"tax_id"`
-	FundingSource                     string     `json:"funding_source"`
-	EmploymentStatus                  string     `json:"employment_status"`
-	InvestingExperience               string     `json:"investing_experience"`
-	PublicShareholder                 string     `json:"public_shareholder"`
-	AnotherBrokerage                  string     `json:"another_brokerage"`
-	DeviceID                          string     `json:"device_id"`
-	ProfileCompletion                 string     `json:"profile_completion"`
-	BIO                               string     `json:"bio"`
-	FacebookURL                       string     `json:"facebook_url"`
-	TwitterURL                        string     `json:"twitter_url"`
-	InstagramURL                      string     `json:"instagram_url"`
-	PublicPortfolio                   string     `json:"public_portfolio"`
-	EmployerName                      string     `json:"employer_name"`
-	Occupation                        string     `json:"occupation"`
-	UnitApt                           string     `json:"unit_apt"`
-	ZipCode                           string     `json:"zip_code"`
-	StockSymbol                       string     `json:"stock_symbol"`
-	BrokerageFirmName                 string     `json:"brokerage_firm_name"`
-	BrokerageFirmEmployeeName         string     `json:"brokerage_firm_employee_name"`
-	BrokerageFirmEmployeeRelationship string     `json:"brokerage_firm_employee_relationship"`
-	ShareholderCompanyName            string     `json:"shareholder_company_name"`
-	Avatar                            string     `json:"avatar"`
-	ReferredBy                        string     `json:"referred_by"`
-	ReferralCode                      string     `json:"referral_code"`
-	WatchlistID                       string     `json:"watchlist_id"`
-	PerAccountLimit                   float64    `json:"per_account_limit"`
+	ID                                int               `json:"id"`
+	FirstName                         string            `json:"first_name"`
+	LastName                          string            `json:"last_name"`
+	Username                          string            `json:"username"`
+	Password                          string            `json:"-"`
+	Email                             string            `json:"email"`
+	Mobile                            string            `json:"mobile"`
+	CountryCode                       string            `json:"country_code"`
+	Address                           string            `json:"address"`
+	LastLogin                         *time.Time        `json:"last_login,omitempty"`
+	Verified                          bool              `json:"verified"`
+	Active                            bool              `json:"active"`
+	Token                             string            `json:"-"`
+	Role                              *Role             `json:"role,omitempty"`
+	RoleID                            int               `json:"-"`
+	AccountID                         string            `json:"account_id"`
+	AccountNumber                     string            `json:"account_number"`
+	AccountCurrency                   string            `json:"account_currency"`
+	AccountStatus                     string            `json:"account_status"`
+	DOB                               string            `json:"dob"`
+	City                              string            `json:"city"`
+	State                             string            `json:"state"`
+	Country                           string            `json:"country"`
+	TaxIDType                         string            `json:"tax_id_type"`
+	TaxID                             string            `json:"tax_id"`
+	FundingSource                     string            `json:"funding_source"`
+	EmploymentStatus                  string            `json:"employment_status"`
+	InvestingExperience               string            `json:"investing_experience"`
+	PublicShareholder                 string            `json:"public_shareholder"`
+	AnotherBrokerage                  string            `json:"another_brokerage"`
+	DeviceID                          string            `json:"device_id"`
+	ProfileCompletion                 string            `json:"profile_completion"`
+	BIO                               string            `json:"bio"`
+	FacebookURL                       string            `json:"facebook_url"`
+	TwitterURL                        string            `json:"twitter_url"`
+	InstagramURL                      string            `json:"instagram_url"`
+	PublicPortfolio                   string            `json:"public_portfolio"`
+	EmployerName                      string            `json:"employer_name"`
+	Occupation                        string            `json:"occupation"`
+	UnitApt                           string            `json:"unit_apt"`
+	ZipCode                           string            `json:"zip_code"`
+	StockSymbol                       string            `json:"stock_symbol"`
+	BrokerageFirmName                 string            `json:"brokerage_firm_name"`
+	BrokerageFirmEmployeeName         string            `json:"brokerage_firm_employee_name"`
+	BrokerageFirmEmployeeRelationship string            `json:"brokerage_firm_employee_relationship"`
+	ShareholderCompanyName            string            `json:"shareholder_company_name"`
+	Avatar                            string            `json:"avatar"`
+	AvatarURLs                        map[string]string `json:"avatar_urls,omitempty" pg:"-"`
+	ReferredBy                        string            `json:"referred_by"`
+	ReferralCode                      string            `json:"referral_code"`
+	WatchlistID                       string            `json:"watchlist_id"`
+	PerAccountLimit                   float64           `json:"per_account_limit"`
+	AgreementsSignedAt                *time.Time        `json:"agreements_signed_at,omitempty"`
+	AgreementsIP                      string            `json:"-"`
+	TokensValidAfter                  *time.Time        `json:"-"`
+	ClosedAt                          *time.Time        `json:"closed_at,omitempty"`
+	AnonymizedAt                      *time.Time        `json:"-"`
+	TenantID                          int               `json:"tenant_id,omitempty"`
 }
 
 // ReferralCodeVerifyResponse
@@ -72,18 +81,76 @@ type ReferralCodeVerifyResponse struct {
 	ReferralCode string `json:"referral_code"`
 }
 
+// MarshalJSON masks sensitive PII, so full values are never returned by the API
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User // prevents recursion into MarshalJSON
+	masked := user(u)
+	masked.TaxID = MaskTail(u.TaxID, 4)
+	masked.DOB = maskDOB(u.DOB)
+	return json.Marshal(masked)
+}
+
+// MaskTail replaces every character of s except the last n with '*'
+func MaskTail(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return strings.Repeat("*", len(r))
+	}
+	return strings.Repeat("*", len(r)-n) + string(r[len(r)-n:])
+}
+
+// maskDOB keeps the birth year of a YYYY-MM-DD date only, e.g. 1990-**-**
+func maskDOB(dob string) string {
+	r := []rune(dob)
+	for i := range r {
+		if i >= 4 && r[i] != '-' {
+			r[i] = '*'
+		}
+	}
+	return string(r)
+}
+
 // UpdateLastLogin updates last login field
 func (u *User) UpdateLastLogin() {
 	t := time.Now()
 	u.LastLogin = &t
 }
 
+// InvalidateTokens rejects the access and refresh tokens issued to the user so far,
+// e.g. after a password or role change. Token times have second precision.
+func (u *User) InvalidateTokens() {
+	t := time.Now().Truncate(time.Second)
+	u.TokensValidAfter = &t
+}
+
+// MarkVerified activates a user once they verified their email address or mobile number
+func (u *User) MarkVerified() {
+	u.Verified = true
+	u.Active = true
+}
+
+// Disabled reports whether the user was deactivated after verifying. Users are
+// inactive until they verify, and can only become inactive again through an admin.
+func (u *User) Disabled() bool {
+	return u.Verified && !u.Active
+}
+
 // Delete updates the deleted_at field
 func (u *User) Delete() {
 	t := time.Now()
 	u.DeletedAt = &t
 }
 
+// Close marks the account of a user closed at their request. Closed users are deleted and
+// signed out everywhere, and their personal data is anonymized after the retention period.
+func (u *User) Close() {
+	t := time.Now()
+	u.ClosedAt = &t
+	u.DeletedAt = &t
+	u.Active = false
+	u.InvalidateTokens()
+}
+
 // Update updates the updated_at field
 func (u *User) Update() {
 	t := time.Now()
@@ -99,32 +166,44 @@ type UserRepo interface {
 	FindByMobile(string, string) (*User, error)
 	FindByToken(string) (*User, error)
 	UpdateLogin(*User) error
-	List(*ListQuery, *Pagination) ([]User, error)
+	List(*ListQuery, *Pagination) ([]User, int, error)
 	Update(*User) (*User, error)
 	Delete(*User) error
+	Close(*User) error
+	CountByAvatar(string) (int, error)
+	// Search returns the users whose email, mobile number or broker account number or ID match term
+	Search(term string, limit int) ([]User, error)
 }
 
 // AccountRepo represents account database interface (the repository)
 type AccountRepo interface {
 	Create(*User) (*User, error)
 	CreateAndVerify(*User) (*Verification, error)
-	CreateForgotToken(*User) (*Verification, error)
-	CreateNewOTP(*User) (*Verification, error)
+	CreateVerificationToken(*User, string) (*Verification, error)
 	CreateWithMobile(*User) error
 	CreateWithMagic(*User) (int, error)
 	ResetPassword(*User) error
 	ChangePassword(*User) error
 	UpdateAvatar(*User) error
+	SignAgreements(*User) error
 	Activate(*User) error
-	FindVerificationToken(string) (*Verification, error)
-	FindVerificationTokenByUser(*User) (*Verification, error)
+	Deactivate(*User) error
+	UseVerificationToken(*User, string, string) (*Verification, error)
+	RedeemVerificationToken(string, string) (*Verification, error)
+	FindVerificationTokenByUser(*User, string) (*Verification, error)
 	DeleteVerificationToken(*Verification) error
+	DeleteVerificationTokens(*User) error
+	DeleteExpiredVerificationTokens(time.Time) (int, error)
 }
 
 // AuthUser represents data stored in JWT token for user
 type AuthUser struct {
-	ID       int
-	Username string
-	Email    string
-	Role     AccessRole
+	ID        int
+	Username  string
+	Email     string
+	Role      AccessRole
+	SessionID string
+	// TokenID and TokenExpires are the jti and expiry of the access token of the request
+	TokenID      string
+	TokenExpires time.Time
 }
@@ -15,3 +15,13 @@ type UserReward struct {
 	RewardTransferStatus bool    `json:"reward_transfer_status"`
 	ErrorResponse        string  `json:"error_response"`
 }
+
+// RewardTypeGrant is the type of rewards support staff grant
+const RewardTypeGrant = "grant"
+
+// UserRewardRepo represents the user rewards database interface
+type UserRewardRepo interface {
+	Create(*UserReward) error
+	// List returns the rewards of a user, newest first
+	List(userID int) ([]UserReward, error)
+}
@@ -1,6 +1,7 @@
 package model_test
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/alpacahq/ribbit-backend/model"
@@ -35,3 +36,31 @@ func TestUpdateDeletedAt(t *testing.T) {
 		t.Errorf("deleted_at is not changed")
 	}
 }
+
+func TestMarshalJSONMasksPII(t *testing.T) {
+	user := model.User{
+		FirstName: "TestGuy",
+		TaxID:     "123-45-6789",
+		DOB:       "1990-05-19",
+	}
+	b, err := json.Marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["tax_id"] != "*******6789" {
+		t.Errorf("tax_id was not masked, got %v", out["tax_id"])
+	}
+	if out["dob"] != "1990-**-**" {
+		t.Errorf("dob was not masked, got %v", out["dob"])
+	}
+	if out["first_name"] != "TestGuy" {
+		t.Errorf("first_name changed, got %v", out["first_name"])
+	}
+	if user.TaxID != "123-45-6789" {
+		t.Errorf("user was modified while masking")
+	}
+}
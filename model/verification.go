@@ -1,13 +1,45 @@
 package model
 
+import "time"
+
 func init() {
 	Register(&Verification{})
 }
 
-// Verification stores randomly generated tokens that can be redeemed
+// Purposes of verification tokens, a token is only accepted for the purpose it was issued for
+const (
+	PurposeEmailVerify   = "email_verify"
+	PurposePasswordReset = "password_reset"
+)
+
+// verificationLifetimes is how long a token of each purpose can be redeemed
+var verificationLifetimes = map[string]time.Duration{
+	PurposeEmailVerify:   24 * time.Hour,
+	PurposePasswordReset: 15 * time.Minute,
+}
+
+// Verification stores randomly generated tokens that can be redeemed once,
+// by the user they were issued to and for their purpose only
 type Verification struct {
 	Base
-	ID     int    `json:"id"`
-	Token  string `json:"token"`
-	UserID int    `json:"user_id"`
+	ID        int        `json:"id"`
+	Token     string     `json:"token"`
+	UserID    int        `json:"user_id"`
+	Purpose   string     `json:"purpose" pg:",notnull"`
+	ExpiresAt time.Time  `json:"expires_at" pg:",notnull"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// NewVerification returns a token of userID for purpose, expiring after the lifetime of the purpose
+func NewVerification(userID int, purpose, token string) *Verification {
+	lifetime, ok := verificationLifetimes[purpose]
+	if !ok {
+		lifetime = 15 * time.Minute
+	}
+	return &Verification{
+		UserID:    userID,
+		Token:     token,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(lifetime),
+	}
 }
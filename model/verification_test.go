@@ -0,0 +1,21 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+func TestNewVerification(t *testing.T) {
+	v := model.NewVerification(1, model.PurposePasswordReset, "123456")
+	if v.UserID != 1 || v.Purpose != model.PurposePasswordReset || v.Token != "123456" {
+		t.Errorf("unexpected verification %+v", v)
+	}
+	if d := time.Until(v.ExpiresAt); d <= 0 || d > 15*time.Minute {
+		t.Errorf("password reset token expires in %s", d)
+	}
+	if d := time.Until(model.NewVerification(1, model.PurposeEmailVerify, "123456").ExpiresAt); d <= time.Hour {
+		t.Errorf("email verification token expires in %s", d)
+	}
+}
@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned for a kid that isn't in the key set
+var ErrUnknownKey = errors.New("oidc: unknown signing key")
+
+// KeySource returns the public key an identity provider signed ID tokens with
+type KeySource interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// StaticKeys is a fixed set of public keys by kid, e.g. for tests
+type StaticKeys map[string]crypto.PublicKey
+
+// Key returns the key with the given kid
+func (s StaticKeys) Key(kid string) (crypto.PublicKey, error) {
+	if k, ok := s[kid]; ok {
+		return k, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// jwk is a public key in JSON Web Key format
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses the RSA and P-256 keys of a JSON Web Key Set, skipping other keys
+func ParseJWKS(data []byte) (StaticKeys, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	keys := StaticKeys{}
+	for _, k := range set.Keys {
+		switch {
+		case k.Kty == "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("oidc: key %s: %v", k.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("oidc: key %s: %v", k.Kid, err)
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("oidc: key %s: %v", k.Kid, err)
+			}
+			y, err := base64.RawURLEncoding.DecodeString(k.Y)
+			if err != nil {
+				return nil, fmt.Errorf("oidc: key %s: %v", k.Kid, err)
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+// NewRemoteKeys creates a key source fetching the JWKS published at url
+func NewRemoteKeys(url string) *RemoteKeys {
+	return &RemoteKeys{
+		url:     url,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		maxAge:  time.Hour,
+		minWait: time.Minute,
+		now:     time.Now,
+	}
+}
+
+// RemoteKeys caches the JWKS of an identity provider. Keys are fetched again after
+// an hour, or when a token names an unknown kid, which happens when the provider
+// rotates its keys; but no more than once a minute.
+type RemoteKeys struct {
+	url     string
+	client  *http.Client
+	maxAge  time.Duration
+	minWait time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	keys      StaticKeys
+	fetchedAt time.Time
+}
+
+// Key returns the key with the given kid
+func (r *RemoteKeys) Key(kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	if k, ok := r.keys[kid]; ok && now.Sub(r.fetchedAt) < r.maxAge {
+		return k, nil
+	}
+	if r.keys == nil || now.Sub(r.fetchedAt) >= r.minWait {
+		if err := r.fetch(); err != nil && r.keys == nil {
+			return nil, err
+		}
+	}
+	return r.keys.Key(kid)
+}
+
+// fetch replaces the cached keys, keeping the previous ones if the provider can't be reached
+func (r *RemoteKeys) fetch() error {
+	r.fetchedAt = r.now()
+	resp, err := r.client.Get(r.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: fetching %s: status %d", r.url, resp.StatusCode)
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	keys, err := ParseJWKS(body)
+	if err != nil {
+		return err
+	}
+	r.keys = keys
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package oidc verifies ID tokens of OpenID Connect identity providers, such as Sign in with Apple and Google
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// Provider names
+const (
+	Apple  = "apple"
+	Google = "google"
+)
+
+// Provider describes an identity provider and the client IDs of our apps registered with it
+type Provider struct {
+	Name    string
+	Issuers []string
+	JWKSURL string
+	// ClientIDs are the audiences tokens are accepted for, e.g. the iOS bundle ID and web service ID
+	ClientIDs []string
+}
+
+// AppleProvider returns Sign in with Apple for clientIDs
+func AppleProvider(clientIDs []string) Provider {
+	return Provider{
+		Name:      Apple,
+		Issuers:   []string{"https://appleid.apple.com"},
+		JWKSURL:   "https://appleid.apple.com/auth/keys",
+		ClientIDs: clientIDs,
+	}
+}
+
+// GoogleProvider returns Google Sign-In for clientIDs
+func GoogleProvider(clientIDs []string) Provider {
+	return Provider{
+		Name:      Google,
+		Issuers:   []string{"https://accounts.google.com", "accounts.google.com"},
+		JWKSURL:   "https://www.googleapis.com/oauth2/v3/certs",
+		ClientIDs: clientIDs,
+	}
+}
+
+// Claims are the verified claims of an ID token we use
+type Claims struct {
+	// Subject identifies the user at the provider and never changes
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// NewVerifier creates a verifier of ID tokens issued by p and signed with keys
+func NewVerifier(p Provider, keys KeySource) *Verifier {
+	return &Verifier{p, keys}
+}
+
+// Verifier verifies the ID tokens of one provider
+type Verifier struct {
+	provider Provider
+	keys     KeySource
+}
+
+// Verify checks the signature, issuer, audience and expiry of an ID token and returns its claims.
+// If nonce is set the token must carry it, either as is or, as Apple's SDKs send it, SHA-256 hashed.
+func (v *Verifier) Verify(idToken, nonce string) (*Claims, error) {
+	p := &jwt.Parser{ValidMethods: []string{"RS256", "ES256"}}
+	mc := jwt.MapClaims{}
+	_, err := p.ParseWithClaims(idToken, mc, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("oidc: %v", err)
+	}
+	iss, _ := mc["iss"].(string)
+	if !contains(v.provider.Issuers, iss) {
+		return nil, fmt.Errorf("oidc: unexpected issuer %q", iss)
+	}
+	if !v.audience(mc["aud"]) {
+		return nil, errors.New("oidc: token was issued for another client")
+	}
+	if _, ok := mc["exp"]; !ok {
+		return nil, errors.New("oidc: token has no expiry")
+	}
+	if nonce != "" && !matchNonce(mc["nonce"], nonce) {
+		return nil, errors.New("oidc: nonce mismatch")
+	}
+	c := &Claims{}
+	c.Subject, _ = mc["sub"].(string)
+	if c.Subject == "" {
+		return nil, errors.New("oidc: token has no subject")
+	}
+	c.Email, _ = mc["email"].(string)
+	c.Name, _ = mc["name"].(string)
+	// Google sends a boolean, Apple a string
+	switch ev := mc["email_verified"].(type) {
+	case bool:
+		c.EmailVerified = ev
+	case string:
+		c.EmailVerified = ev == "true"
+	}
+	return c, nil
+}
+
+// audience reports whether aud, a string or a list of strings, names one of our client IDs
+func (v *Verifier) audience(aud interface{}) bool {
+	switch a := aud.(type) {
+	case string:
+		return contains(v.provider.ClientIDs, a)
+	case []interface{}:
+		for _, s := range a {
+			if s, ok := s.(string); ok && contains(v.provider.ClientIDs, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchNonce(claim interface{}, nonce string) bool {
+	got, _ := claim.(string)
+	if got == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(nonce))
+	return got == nonce || strings.EqualFold(got, hex.EncodeToString(sum[:]))
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package oidc_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/oidc"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keys := oidc.StaticKeys{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey}
+	v := oidc.NewVerifier(oidc.AppleProvider([]string{"com.example.app"}), keys)
+
+	claims := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":            "https://appleid.apple.com",
+			"aud":            "com.example.app",
+			"sub":            "001234.abcd",
+			"email":          "jane@example.com",
+			"email_verified": "true",
+			"iat":            time.Now().Unix(),
+			"exp":            time.Now().Add(time.Hour).Unix(),
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+	sum := sha256.Sum256([]byte("n0nce"))
+
+	cases := []struct {
+		name    string
+		token   string
+		nonce   string
+		want    *oidc.Claims
+		wantErr bool
+	}{
+		{
+			name:  "RS256",
+			token: sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil)),
+			want:  &oidc.Claims{Subject: "001234.abcd", Email: "jane@example.com", EmailVerified: true},
+		},
+		{
+			name:  "ES256 with an audience list and a boolean email_verified",
+			token: sign(t, jwt.SigningMethodES256, "ec", ecKey, claims(func(c jwt.MapClaims) { c["aud"] = []string{"other", "com.example.app"}; c["email_verified"] = false })),
+			want:  &oidc.Claims{Subject: "001234.abcd", Email: "jane@example.com"},
+		},
+		{
+			name:  "Hashed nonce",
+			token: sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { c["nonce"] = hex.EncodeToString(sum[:]) })),
+			nonce: "n0nce",
+			want:  &oidc.Claims{Subject: "001234.abcd", Email: "jane@example.com", EmailVerified: true},
+		},
+		{
+			name:    "Missing nonce",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil)),
+			nonce:   "n0nce",
+			wantErr: true,
+		},
+		{
+			name:    "Other audience",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { c["aud"] = "com.example.other" })),
+			wantErr: true,
+		},
+		{
+			name:    "Other issuer",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { c["iss"] = "https://accounts.google.com" })),
+			wantErr: true,
+		},
+		{
+			name:    "Expired",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() })),
+			wantErr: true,
+		},
+		{
+			name:    "No expiry",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c jwt.MapClaims) { delete(c, "exp") })),
+			wantErr: true,
+		},
+		{
+			name:    "Signed with another key",
+			token:   sign(t, jwt.SigningMethodRS256, "rsa", otherKey, claims(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "Unknown kid",
+			token:   sign(t, jwt.SigningMethodRS256, "gone", rsaKey, claims(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "HS256",
+			token:   sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), claims(nil)),
+			wantErr: true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token, tt.nonce)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRemoteKeys(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	b64 := base64.RawURLEncoding.EncodeToString
+	kid := "k1"
+	fetches := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		fmt.Fprintf(w, `{"keys":[{"kty":"RSA","kid":%q,"alg":"RS256","n":%q,"e":%q},{"kty":"oct","kid":"skipped"}]}`,
+			kid, b64(key.N.Bytes()), b64(big.NewInt(int64(key.E)).Bytes()))
+	}))
+	defer ts.Close()
+
+	keys := oidc.NewRemoteKeys(ts.URL)
+	got, err := keys.Key("k1")
+	assert.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, got)
+	_, err = keys.Key("k1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches, "keys are cached")
+
+	kid = "k2"
+	_, err = keys.Key("k2")
+	assert.Equal(t, oidc.ErrUnknownKey, err, "unknown kids are fetched at most once a minute")
+	assert.Equal(t, 1, fetches)
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
 	"github.com/alpacahq/ribbit-backend/model"
@@ -18,8 +19,8 @@ import (
 )
 
 // NewAccountRepo returns an AccountRepo instance
-func NewAccountRepo(db orm.DB, log *zap.Logger, secret secret.Service) *AccountRepo {
-	return &AccountRepo{db, log, secret}
+func NewAccountRepo(db orm.DB, log *zap.Logger, secret secret.Service, cipher secret.FieldCipher) *AccountRepo {
+	return &AccountRepo{db, log, secret, cipher}
 }
 
 // AccountRepo represents the client for the user table
@@ -27,6 +28,7 @@ type AccountRepo struct {
 	db     orm.DB
 	log    *zap.Logger
 	Secret secret.Service
+	cipher secret.FieldCipher
 }
 
 // Create creates a new user in our database
@@ -41,13 +43,42 @@ func (a *AccountRepo) Create(u *model.User) (*model.User, error) {
 	if res.RowsReturned() != 0 {
 		return nil, apperr.New(http.StatusBadRequest, "User already exists.")
 	}
-	if err := a.db.Insert(u); err != nil {
-		a.log.Warn("AccountRepo error: ", zap.Error(err))
-		return nil, apperr.DB
+	if err := a.insert(u); err != nil {
+		return nil, err
 	}
 	return u, nil
 }
 
+// insert inserts u with its sensitive fields encrypted, setting the ID and timestamps of u
+func (a *AccountRepo) insert(u *model.User) error {
+	sealed, err := sealUser(a.cipher, u)
+	if err != nil {
+		a.log.Error("AccountRepo Error: failed to encrypt user", zap.Error(err))
+		return apperr.Generic
+	}
+	if err := a.db.Insert(sealed); err != nil {
+		a.log.Warn("AccountRepo error: ", zap.Error(err))
+		return apperr.DB
+	}
+	u.ID, u.CreatedAt, u.UpdatedAt = sealed.ID, sealed.CreatedAt, sealed.UpdatedAt
+	return nil
+}
+
+// update updates every column of u with its sensitive fields encrypted
+func (a *AccountRepo) update(u *model.User) error {
+	sealed, err := sealUser(a.cipher, u)
+	if err != nil {
+		a.log.Error("AccountRepo Error: failed to encrypt user", zap.Error(err))
+		return apperr.Generic
+	}
+	if err := a.db.Update(sealed); err != nil {
+		a.log.Warn("AccountRepo error: ", zap.Error(err))
+		return apperr.DB
+	}
+	u.UpdatedAt = sealed.UpdatedAt
+	return nil
+}
+
 func encodeToString(max int) string {
 	b := make([]byte, max)
 	var table = [...]byte{'1', '2', '3', '4', '5', '6', '7', '8', '9', '0'}
@@ -76,9 +107,8 @@ func (a *AccountRepo) CreateAndVerify(u *model.User) (*model.Verification, error
 	}
 	u.Password = a.Secret.HashPassword(u.Password)
 
-	if err := a.db.Insert(u); err != nil {
-		a.log.Warn("AccountRepo error: ", zap.Error(err))
-		return nil, apperr.DB
+	if err := a.insert(u); err != nil {
+		return nil, err
 	}
 
 	re, err := regexp.Compile(`[^a-z0-9]`)
@@ -86,14 +116,11 @@ func (a *AccountRepo) CreateAndVerify(u *model.User) (*model.Verification, error
 		log.Fatal(err)
 	}
 	u.ReferralCode = strings.ToUpper(re.ReplaceAllString(strings.Split(u.Email, "@")[0]+strconv.Itoa(u.ID), ""))
-	if err := a.db.Update(u); err != nil {
-		a.log.Warn("AccountRepo error: ", zap.Error(err))
-		return nil, apperr.DB
+	if err := a.update(u); err != nil {
+		return nil, err
 	}
 
-	v := new(model.Verification)
-	v.UserID = u.ID
-	v.Token = encodeToString(6)
+	v := model.NewVerification(u.ID, model.PurposeEmailVerify, encodeToString(6))
 	if err := a.db.Insert(v); err != nil {
 		a.log.Warn("AccountRepo error: ", zap.Error(err))
 		return nil, apperr.DB
@@ -101,21 +128,17 @@ func (a *AccountRepo) CreateAndVerify(u *model.User) (*model.Verification, error
 	return v, nil
 }
 
-func (a *AccountRepo) CreateForgotToken(u *model.User) (*model.Verification, error) {
-	v := new(model.Verification)
-	v.UserID = u.ID
-	v.Token = encodeToString(6)
-	if err := a.db.Insert(v); err != nil {
+// CreateVerificationToken generates a new token of u for purpose, invalidating the earlier ones for that purpose
+func (a *AccountRepo) CreateVerificationToken(u *model.User, purpose string) (*model.Verification, error) {
+	_, err := a.db.Model((*model.Verification)(nil)).
+		Set("deleted_at = ?", time.Now()).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL AND deleted_at IS NULL", u.ID, purpose).
+		Update()
+	if err != nil {
 		a.log.Warn("AccountRepo error: ", zap.Error(err))
 		return nil, apperr.DB
 	}
-	return v, nil
-}
-
-func (a *AccountRepo) CreateNewOTP(u *model.User) (*model.Verification, error) {
-	v := new(model.Verification)
-	v.UserID = u.ID
-	v.Token = encodeToString(6)
+	v := model.NewVerification(u.ID, purpose, encodeToString(6))
 	if err := a.db.Insert(v); err != nil {
 		a.log.Warn("AccountRepo error: ", zap.Error(err))
 		return nil, apperr.DB
@@ -143,9 +166,8 @@ func (a *AccountRepo) CreateWithMobile(u *model.User) error {
 	if err != nil {
 		return apperr.DB
 	}
-	if err := a.db.Insert(u); err != nil {
-		a.log.Warn("AccountRepo error: ", zap.Error(err))
-		return apperr.DB
+	if err := a.insert(u); err != nil {
+		return err
 	}
 	re, err := regexp.Compile(`[^a-z0-9]`)
 	if err != nil {
@@ -153,9 +175,8 @@ func (a *AccountRepo) CreateWithMobile(u *model.User) error {
 	}
 	u.ReferralCode = strings.ToUpper(re.ReplaceAllString(strings.Split(u.Email, "@")[0]+strconv.Itoa(u.ID), ""))
 
-	if err := a.db.Update(u); err != nil {
-		a.log.Warn("AccountRepo error: ", zap.Error(err))
-		return apperr.DB
+	if err := a.update(u); err != nil {
+		return err
 	}
 	return nil
 }
@@ -180,18 +201,16 @@ func (a *AccountRepo) CreateWithMagic(u *model.User) (int, error) {
 	if err != nil {
 		return 0, apperr.DB
 	}
-	if err := a.db.Insert(u); err != nil {
-		a.log.Warn("AccountRepo error: ", zap.Error(err))
-		return 0, apperr.DB
+	if err := a.insert(u); err != nil {
+		return 0, err
 	}
 	re, err := regexp.Compile(`[^a-z0-9]`)
 	if err != nil {
 		log.Fatal(err)
 	}
 	u.ReferralCode = strings.ToUpper(re.ReplaceAllString(strings.Split(u.Email, "@")[0]+strconv.Itoa(u.ID), ""))
-	if err := a.db.Update(u); err != nil {
-		a.log.Warn("AccountRepo error: ", zap.Error(err))
-		return 0, apperr.DB
+	if err := a.update(u); err != nil {
+		return 0, err
 	}
 	return u.ID, nil
 }
@@ -199,7 +218,8 @@ func (a *AccountRepo) CreateWithMagic(u *model.User) (int, error) {
 // ChangePassword changes user's password
 func (a *AccountRepo) ChangePassword(u *model.User) error {
 	u.Update()
-	_, err := a.db.Model(u).Column("password", "updated_at").WherePK().Update()
+	u.InvalidateTokens()
+	_, err := a.db.Model(u).Column("password", "tokens_valid_after", "updated_at").WherePK().Update()
 	if err != nil {
 		a.log.Warn("AccountRepo Error: ", zap.Error(err))
 	}
@@ -210,7 +230,8 @@ func (a *AccountRepo) ChangePassword(u *model.User) error {
 func (a *AccountRepo) ResetPassword(u *model.User) error {
 	u.Update()
 	u.Password = a.Secret.HashPassword(u.Password)
-	_, err := a.db.Model(u).Column("password", "updated_at").WherePK().Update()
+	u.InvalidateTokens()
+	_, err := a.db.Model(u).Column("password", "tokens_valid_after", "updated_at").WherePK().Update()
 	if err != nil {
 		a.log.Warn("AccountRepo Error: ", zap.Error(err))
 	}
@@ -227,6 +248,16 @@ func (a *AccountRepo) UpdateAvatar(u *model.User) error {
 	return err
 }
 
+// SignAgreements records when and from where the user accepted the broker agreements
+func (a *AccountRepo) SignAgreements(u *model.User) error {
+	u.Update()
+	_, err := a.db.Model(u).Column("agreements_signed_at", "agreements_ip", "updated_at").WherePK().Update()
+	if err != nil {
+		a.log.Warn("AccountRepo Error: ", zap.Error(err))
+	}
+	return err
+}
+
 // Activate changes user's password
 func (a *AccountRepo) Activate(u *model.User) error {
 	u.Update()
@@ -237,28 +268,48 @@ func (a *AccountRepo) Activate(u *model.User) error {
 	return err
 }
 
-// FindVerificationToken retrieves an existing verification token
-func (a *AccountRepo) FindVerificationToken(token string) (*model.Verification, error) {
+// Deactivate disables a user and signs them out everywhere
+func (a *AccountRepo) Deactivate(u *model.User) error {
+	u.Update()
+	u.Active = false
+	u.InvalidateTokens()
+	_, err := a.db.Model(u).Column("active", "tokens_valid_after", "updated_at").WherePK().Update()
+	if err != nil {
+		a.log.Warn("AccountRepo Error: ", zap.Error(err))
+	}
+	return err
+}
+
+// UseVerificationToken redeems an unexpired token of u for purpose, which can't be used again afterwards
+func (a *AccountRepo) UseVerificationToken(u *model.User, purpose, token string) (*model.Verification, error) {
 	var v = new(model.Verification)
-	sql := `SELECT * FROM verifications WHERE (token = ? and deleted_at IS NULL)`
-	_, err := a.db.QueryOne(v, sql, token)
+	sql := `UPDATE verifications SET used_at = now() WHERE (user_id = ? AND purpose = ? AND token = ? AND used_at IS NULL AND deleted_at IS NULL AND expires_at > now()) RETURNING *`
+	_, err := a.db.QueryOne(v, sql, u.ID, purpose, token)
 	if err != nil {
 		a.log.Warn("AccountRepo Error", zap.String("Error:", err.Error()))
-		return nil, apperr.New(http.StatusNotFound, "Invalid OTP")
+		return nil, apperr.New(http.StatusNotFound, "Invalid or expired OTP")
 	}
-	user := new(model.User)
-	user.ID = v.UserID
-	user.Active = true
-	user.Verified = true
-	a.Activate(user)
 	return v, nil
 }
 
-// FindVerificationTokenByUser retrieves an existing verification token
-func (a *AccountRepo) FindVerificationTokenByUser(user *model.User) (*model.Verification, error) {
+// RedeemVerificationToken redeems an unexpired token for purpose of whichever user it was issued to,
+// the latest one if several users were issued the same token
+func (a *AccountRepo) RedeemVerificationToken(purpose, token string) (*model.Verification, error) {
 	var v = new(model.Verification)
-	sql := `SELECT * FROM verifications WHERE (user_id = ? and deleted_at IS NULL)`
-	_, err := a.db.QueryOne(v, sql, user.ID)
+	sql := `UPDATE verifications SET used_at = now() WHERE id = (SELECT id FROM verifications WHERE (purpose = ? AND token = ? AND used_at IS NULL AND deleted_at IS NULL AND expires_at > now()) ORDER BY id DESC LIMIT 1) RETURNING *`
+	_, err := a.db.QueryOne(v, sql, purpose, token)
+	if err != nil {
+		a.log.Warn("AccountRepo Error", zap.String("Error:", err.Error()))
+		return nil, apperr.New(http.StatusNotFound, "Invalid or expired OTP")
+	}
+	return v, nil
+}
+
+// FindVerificationTokenByUser retrieves the latest outstanding token of user for purpose, expired or not
+func (a *AccountRepo) FindVerificationTokenByUser(user *model.User, purpose string) (*model.Verification, error) {
+	var v = new(model.Verification)
+	sql := `SELECT * FROM verifications WHERE (user_id = ? AND purpose = ? AND used_at IS NULL AND deleted_at IS NULL) ORDER BY id DESC LIMIT 1`
+	_, err := a.db.QueryOne(v, sql, user.ID, purpose)
 	if err != nil {
 		a.log.Warn("AccountRepo Error", zap.String("Error:", err.Error()))
 		return nil, nil
@@ -277,3 +328,26 @@ func (a *AccountRepo) DeleteVerificationToken(v *model.Verification) error {
 	}
 	return err
 }
+
+// DeleteVerificationTokens sets deleted_at for all outstanding verification tokens of a user
+func (a *AccountRepo) DeleteVerificationTokens(u *model.User) error {
+	_, err := a.db.Model((*model.Verification)(nil)).
+		Set("deleted_at = ?", time.Now()).
+		Where("user_id = ? AND deleted_at IS NULL", u.ID).
+		Update()
+	if err != nil {
+		a.log.Warn("AccountRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// DeleteExpiredVerificationTokens deletes the tokens that expired before t, used or not, returning how many were deleted
+func (a *AccountRepo) DeleteExpiredVerificationTokens(t time.Time) (int, error) {
+	res, err := a.db.Model((*model.Verification)(nil)).Where("expires_at < ?", t).Delete()
+	if err != nil {
+		a.log.Warn("AccountRepo Error", zap.Error(err))
+		return 0, apperr.DB
+	}
+	return res.RowsAffected(), nil
+}
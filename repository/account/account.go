@@ -2,6 +2,7 @@ package account
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
 	"github.com/alpacahq/ribbit-backend/model"
@@ -18,15 +19,19 @@ type Service struct {
 	userRepo    model.UserRepo
 	rbac        model.RBACService
 	secret      secret.Service
+	passwords   model.PasswordPolicy
+	audit       model.AuditLog
 }
 
 // NewAccountService creates a new account application service
-func NewAccountService(userRepo model.UserRepo, accountRepo model.AccountRepo, rbac model.RBACService, secret secret.Service) *Service {
+func NewAccountService(userRepo model.UserRepo, accountRepo model.AccountRepo, rbac model.RBACService, secret secret.Service, passwords model.PasswordPolicy, audit model.AuditLog) *Service {
 	return &Service{
 		accountRepo: accountRepo,
 		userRepo:    userRepo,
 		rbac:        rbac,
 		secret:      secret,
+		passwords:   passwords,
+		audit:       audit,
 	}
 }
 
@@ -42,7 +47,7 @@ func (s *Service) Create(c *gin.Context, u *model.User) error {
 
 // ChangePassword changes user's password
 func (s *Service) ChangePassword(c *gin.Context, oldPass, newPass string, id int) error {
-	if !s.rbac.EnforceUser(c, id) {
+	if !s.rbac.EnforceUser(c, id) || s.rbac.Impersonator(c) != 0 {
 		return apperr.New(http.StatusForbidden, "Forbidden")
 	}
 	u, err := s.userRepo.View(id)
@@ -52,8 +57,20 @@ func (s *Service) ChangePassword(c *gin.Context, oldPass, newPass string, id int
 	if !s.secret.HashMatchesPassword(u.Password, oldPass) {
 		return apperr.New(http.StatusBadGateway, "old password is not correct")
 	}
+	if err := s.passwords.Validate(u, newPass); err != nil {
+		return err
+	}
+	if err := s.passwords.CheckReuse(u, newPass); err != nil {
+		return err
+	}
 	u.Password = s.secret.HashPassword(newPass)
-	return s.accountRepo.ChangePassword(u)
+	if err := s.accountRepo.ChangePassword(u); err != nil {
+		return err
+	}
+	if err := s.passwords.Remember(u); err != nil {
+		return err
+	}
+	return s.audit.Record(c, &model.AuditEvent{Action: model.AuditPasswordChange, TargetUserID: id})
 }
 
 // UpdateAvatar changes user's avatar
@@ -91,6 +108,91 @@ func (s *Service) UpdateProfile(c *gin.Context, update *request.Update) (*model.
 	if err != nil {
 		return nil, err
 	}
+	before := *u
+	structs.Merge(u, update)
+	u.ProfileCompletion = model.CheckProfile(u).PercentString()
+	return s.update(c, &before, u)
+}
+
+// EditProfile applies the user's own profile update after checking that the merged
+// address and tax id are consistent with our geography data
+func (s *Service) EditProfile(c *gin.Context, update *request.Update, g request.Geography) (*model.User, error) {
+	if !s.rbac.EnforceUser(c, update.ID) {
+		return nil, apperr.New(http.StatusForbidden, "Forbidden")
+	}
+	u, err := s.userRepo.View(update.ID)
+	if err != nil {
+		return nil, err
+	}
+	before := *u
 	structs.Merge(u, update)
-	return s.userRepo.Update(u)
+	if fields := request.ValidateProfile(u, g); len(fields) > 0 {
+		return nil, apperr.NewFields(http.StatusUnprocessableEntity, "Invalid profile update.", fields)
+	}
+	u.ProfileCompletion = model.CheckProfile(u).PercentString()
+	return s.update(c, &before, u)
+}
+
+// update saves the profile of u and records the fields that changed since before in the audit log
+func (s *Service) update(c *gin.Context, before, u *model.User) (*model.User, error) {
+	u, err := s.userRepo.Update(u)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := model.Diff(before, u)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return u, nil
+	}
+	if err := s.audit.Record(c, &model.AuditEvent{Action: model.AuditProfileUpdate, TargetUserID: u.ID, Changes: changes}); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ProfileChecklist returns the server computed onboarding checklist of a user
+func (s *Service) ProfileChecklist(c *gin.Context, id int) (*model.ProfileChecklist, error) {
+	if !s.rbac.EnforceUser(c, id) {
+		return nil, apperr.New(http.StatusForbidden, "Forbidden")
+	}
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return nil, err
+	}
+	return model.CheckProfile(u), nil
+}
+
+// SignAgreements records the user's acceptance of the broker agreements
+func (s *Service) SignAgreements(c *gin.Context, id int) (*model.User, error) {
+	if !s.rbac.EnforceUser(c, id) {
+		return nil, apperr.New(http.StatusForbidden, "Forbidden")
+	}
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	u.AgreementsSignedAt = &now
+	u.AgreementsIP = c.ClientIP()
+	if err := s.accountRepo.SignAgreements(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// EnsureProfileComplete returns a 422 error listing every missing field if u cannot open a broker account yet
+func EnsureProfileComplete(u *model.User) error {
+	p := model.CheckProfile(u)
+	if p.Complete {
+		return nil
+	}
+	var fields []apperr.FieldError
+	for _, section := range p.Sections {
+		for _, f := range section.Missing {
+			fields = append(fields, apperr.FieldError{Field: f, Message: "is required to complete the " + section.Name + " section"})
+		}
+	}
+	return apperr.NewFields(http.StatusUnprocessableEntity, "Profile is incomplete.", fields)
 }
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
 	"github.com/alpacahq/ribbit-backend/model"
 	"github.com/alpacahq/ribbit-backend/repository"
 	"github.com/alpacahq/ribbit-backend/secret"
@@ -89,13 +90,31 @@ func (suite *AccountTestSuite) TestAccountCreateWithMobile() {
 	for _, tt := range cases {
 		suite.T().Run(tt.name, func(t *testing.T) {
 			log, _ := zap.NewDevelopment()
-			accountRepo := repository.NewAccountRepo(tt.db, log, secret.New())
+			accountRepo := repository.NewAccountRepo(tt.db, log, secret.New(), mock.Cipher())
 			err := accountRepo.CreateWithMobile(tt.user)
 			assert.Equal(t, tt.wantError, err)
 		})
 	}
 }
 
+func (suite *AccountTestSuite) TestCreateWithMagicSealed() {
+	t := suite.T()
+	log, _ := zap.NewDevelopment()
+	accountRepo := repository.NewAccountRepo(suite.db, log, secret.New(), mock.Cipher())
+	userRepo := repository.NewUserRepo(suite.db, log, mock.Cipher())
+	id, err := accountRepo.CreateWithMagic(&model.User{Email: "magic@example.org", TaxID: "123456789"})
+	assert.Nil(t, err)
+
+	var stored string
+	_, err = suite.db.QueryOne(pg.Scan(&stored), "SELECT tax_id FROM users WHERE id = ?", id)
+	assert.Nil(t, err)
+	assert.NotEqual(t, "123456789", stored, "sensitive fields are encrypted at rest")
+
+	u, err := userRepo.View(id)
+	assert.Nil(t, err)
+	assert.Equal(t, "123456789", u.TaxID)
+}
+
 func (suite *AccountTestSuite) TestAccountCreateAndVerify() {
 	cases := []struct {
 		name       string
@@ -128,7 +147,7 @@ func (suite *AccountTestSuite) TestAccountCreateAndVerify() {
 	for _, tt := range cases {
 		suite.T().Run(tt.name, func(t *testing.T) {
 			log, _ := zap.NewDevelopment()
-			accountRepo := repository.NewAccountRepo(tt.db, log, secret.New())
+			accountRepo := repository.NewAccountRepo(tt.db, log, secret.New(), mock.Cipher())
 			v, err := accountRepo.CreateAndVerify(tt.user)
 			assert.Equal(t, tt.wantError, err)
 			if v != nil {
@@ -191,7 +210,7 @@ func (suite *AccountTestSuite) TestAccountCreate() {
 	for _, tt := range cases {
 		suite.T().Run(tt.name, func(t *testing.T) {
 			log, _ := zap.NewDevelopment()
-			accountRepo := repository.NewAccountRepo(tt.db, log, secret.New())
+			accountRepo := repository.NewAccountRepo(tt.db, log, secret.New(), mock.Cipher())
 			u, err := accountRepo.Create(tt.user)
 			assert.Equal(t, tt.wantError, err)
 			if u != nil {
@@ -205,8 +224,8 @@ func (suite *AccountTestSuite) TestAccountCreate() {
 
 func (suite *AccountTestSuite) TestChangePasswordSuccess() {
 	log, _ := zap.NewDevelopment()
-	accountRepo := repository.NewAccountRepo(suite.db, log, secret.New())
-	userRepo := repository.NewUserRepo(suite.db, log)
+	accountRepo := repository.NewAccountRepo(suite.db, log, secret.New(), mock.Cipher())
+	userRepo := repository.NewUserRepo(suite.db, log, mock.Cipher())
 	currentPassword := secret.New().HashPassword("currentpassword")
 	user := &model.User{
 		Email:    "user3@example.org",
@@ -235,10 +254,13 @@ func (suite *AccountTestSuite) TestChangePasswordSuccess() {
 	assert.Nil(suite.T(), err)
 	assert.NotNil(suite.T(), v)
 
-	vRetrieved, err := accountRepo.FindVerificationToken(v.Token)
+	vRetrieved, err := accountRepo.UseVerificationToken(user2, model.PurposeEmailVerify, v.Token)
 	assert.Nil(suite.T(), err)
 	assert.Equal(suite.T(), v.Token, vRetrieved.Token)
 
+	_, err = accountRepo.UseVerificationToken(user2, model.PurposeEmailVerify, v.Token)
+	assert.NotNil(suite.T(), err, "tokens are single use")
+
 	err = accountRepo.DeleteVerificationToken(v)
 	assert.Nil(suite.T(), err)
 }
@@ -246,7 +268,7 @@ func (suite *AccountTestSuite) TestChangePasswordSuccess() {
 func (suite *AccountTestSuite) TestChangePasswordFailure() {
 	log, _ := zap.NewDevelopment()
 	defer log.Sync()
-	accountRepo := repository.NewAccountRepo(suite.dbErr, log, secret.New())
+	accountRepo := repository.NewAccountRepo(suite.dbErr, log, secret.New(), mock.Cipher())
 	user := &model.User{
 		Email:    "user5@example.org",
 		Password: secret.New().HashPassword("somepass"),
@@ -258,7 +280,7 @@ func (suite *AccountTestSuite) TestChangePasswordFailure() {
 func (suite *AccountTestSuite) TestDeleteVerificationTokenFailue() {
 	log, _ := zap.NewDevelopment()
 	defer log.Sync()
-	accountRepo := repository.NewAccountRepo(suite.dbErr, log, secret.New())
+	accountRepo := repository.NewAccountRepo(suite.dbErr, log, secret.New(), mock.Cipher())
 	v := &model.Verification{
 		UserID: 1,
 		Token:  uuid.NewV4().String(),
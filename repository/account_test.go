@@ -39,7 +39,7 @@ func (suite *AccountUnitTestSuite) SetupTest() {
 	}
 
 	log, _ := zap.NewDevelopment()
-	suite.accountRepo = repository.NewAccountRepo(db, log, &mock.Password{})
+	suite.accountRepo = repository.NewAccountRepo(db, log, &mock.Password{}, mock.Cipher())
 }
 
 func (suite *AccountUnitTestSuite) TearDownTest() {
@@ -213,37 +213,33 @@ func (suite *AccountUnitTestSuite) TestCreateWithMobileDBErrOnInsert() {
 	assert.Equal(t, apperr.DB, err)
 }
 
-func (suite *AccountUnitTestSuite) TestFindVerificationTokenSuccess() {
+func (suite *AccountUnitTestSuite) TestUseVerificationTokenSuccess() {
 	accountRepo := suite.accountRepo
 	t := suite.T()
 	mock := suite.mock
 
-	var v = new(model.Verification)
-	v.Token = "somerandomverificationtoken"
-	v.UserID = 1
-	mock.ExpectQuery(`SELECT * FROM verifications WHERE (token = ? and deleted_at IS NULL)`).
-		WithArgs("somerandomverificationtoken").
+	var v = model.NewVerification(1, model.PurposeEmailVerify, "123456")
+	mock.ExpectQuery(`UPDATE verifications SET used_at = now() WHERE (user_id = ? AND purpose = ? AND token = ? AND used_at IS NULL AND deleted_at IS NULL AND expires_at > now()) RETURNING *`).
+		WithArgs(1, model.PurposeEmailVerify, "123456").
 		Returns(mockgopg.NewResult(1, 1, v), nil)
 
-	vReturned, err := accountRepo.FindVerificationToken("somerandomverificationtoken")
+	vReturned, err := accountRepo.UseVerificationToken(&model.User{ID: 1}, model.PurposeEmailVerify, "123456")
 	assert.Equal(t, v.Token, vReturned.Token)
 	assert.Equal(t, v.UserID, vReturned.UserID)
 	assert.Nil(t, err)
 }
 
-func (suite *AccountUnitTestSuite) TestFindVerificationTokenFailure() {
+func (suite *AccountUnitTestSuite) TestUseVerificationTokenFailure() {
 	accountRepo := suite.accountRepo
 	t := suite.T()
 	mock := suite.mock
 
-	var v = new(model.Verification)
-	v.Token = "anotherverificationtoken"
-	v.UserID = 1
-	mock.ExpectQuery(`SELECT * FROM verifications WHERE (token = ? and deleted_at IS NULL)`).
-		WithArgs("anotherverificationtoken").
+	var v = model.NewVerification(1, model.PurposePasswordReset, "654321")
+	mock.ExpectQuery(`UPDATE verifications SET used_at = now() WHERE (user_id = ? AND purpose = ? AND token = ? AND used_at IS NULL AND deleted_at IS NULL AND expires_at > now()) RETURNING *`).
+		WithArgs(1, model.PurposePasswordReset, "654321").
 		Returns(mockgopg.NewResult(0, 0, v), apperr.NotFound)
 
-	vReturned, err := accountRepo.FindVerificationToken("anotherverificationtoken")
+	vReturned, err := accountRepo.UseVerificationToken(&model.User{ID: 1}, model.PurposePasswordReset, "654321")
 	assert.Nil(t, vReturned)
-	assert.Equal(t, apperr.NotFound, err)
+	assert.Equal(t, http.StatusNotFound, err.(*apperr.APPError).Status)
 }
@@ -0,0 +1,260 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/broker"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchLimit is the most users a search returns
+const searchLimit = 50
+
+// Verifier sends users a new email verification code
+type Verifier interface {
+	ResendVerification(c context.Context, email string) error
+}
+
+// ImpersonationTokens issues tokens for staff members to act as users
+type ImpersonationTokens interface {
+	GenerateImpersonationToken(u *model.User, adminID int, ttl time.Duration) (string, string, error)
+}
+
+// NewAdminService creates the back-office application service for support staff.
+// Impersonation tokens are valid for impersonationTTL.
+func NewAdminService(userRepo model.UserRepo, accountRepo model.AccountRepo, notes model.NoteRepo, rewards model.UserRewardRepo, audit model.AuditLog, brokers broker.Clients, tenants model.TenantStore, verifier Verifier, tokens ImpersonationTokens, impersonationTTL time.Duration) *Service {
+	return &Service{userRepo, accountRepo, notes, rewards, audit, brokers, tenants, verifier, tokens, impersonationTTL}
+}
+
+// Service represents the back-office application service. Every action is written to the audit log.
+type Service struct {
+	userRepo    model.UserRepo
+	accountRepo model.AccountRepo
+	notes       model.NoteRepo
+	rewards     model.UserRewardRepo
+	audit       model.AuditLog
+	brokers     broker.Clients
+	tenants     model.TenantStore
+	verifier    Verifier
+	tokens      ImpersonationTokens
+	ttl         time.Duration
+}
+
+// UserDetails is a user with what support staff need to help them
+type UserDetails struct {
+	User    *model.User        `json:"user"`
+	Notes   []model.UserNote   `json:"notes"`
+	Rewards []model.UserReward `json:"rewards"`
+}
+
+// Search returns the users whose email, mobile number or broker account number or ID match term
+func (s *Service) Search(c *gin.Context, term string) ([]model.User, error) {
+	if len(term) < 3 {
+		return nil, apperr.New(http.StatusBadRequest, "Search for at least 3 characters.")
+	}
+	users, err := s.userRepo.Search(term, searchLimit)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(c, model.AuditUserSearch, 0, map[string]interface{}{"term": term, "results": len(users)}); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// View returns a user with their notes and rewards
+func (s *Service) View(c *gin.Context, id int) (*UserDetails, error) {
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.notes.List(id)
+	if err != nil {
+		return nil, err
+	}
+	rewards, err := s.rewards.List(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(c, model.AuditUserView, id, nil); err != nil {
+		return nil, err
+	}
+	return &UserDetails{User: u, Notes: notes, Rewards: rewards}, nil
+}
+
+// BrokerAccount returns the broker account of a user, with its status and KYC results
+func (s *Service) BrokerAccount(c *gin.Context, id int) (json.RawMessage, error) {
+	return s.fromBroker(c, id, model.AuditUserBrokerAccount, broker.Service.Account)
+}
+
+// Orders returns the orders of a user
+func (s *Service) Orders(c *gin.Context, id int) (json.RawMessage, error) {
+	return s.fromBroker(c, id, model.AuditUserOrders, broker.Service.Orders)
+}
+
+// Positions returns the positions of a user
+func (s *Service) Positions(c *gin.Context, id int) (json.RawMessage, error) {
+	return s.fromBroker(c, id, model.AuditUserPositions, broker.Service.Positions)
+}
+
+// Transfers returns the transfers of a user
+func (s *Service) Transfers(c *gin.Context, id int) (json.RawMessage, error) {
+	return s.fromBroker(c, id, model.AuditUserTransfers, broker.Service.Transfers)
+}
+
+// Activate enables a verified user that was deactivated
+func (s *Service) Activate(c *gin.Context, id int) error {
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return err
+	}
+	if !u.Verified {
+		return apperr.New(http.StatusConflict, "The user has to verify their email address or mobile number first.")
+	}
+	u.Active = true
+	if err := s.accountRepo.Activate(u); err != nil {
+		return err
+	}
+	return s.record(c, model.AuditUserActivate, id, nil)
+}
+
+// Deactivate disables a user and signs them out everywhere. Unverified users can't sign in to
+// anything but verification yet, so only verified users are deactivated.
+func (s *Service) Deactivate(c *gin.Context, id int, reason string) error {
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return err
+	}
+	if !u.Verified {
+		return apperr.New(http.StatusConflict, "Only verified users can be deactivated.")
+	}
+	if !outranks(c, u) {
+		return apperr.New(http.StatusForbidden, "Forbidden")
+	}
+	if err := s.accountRepo.Deactivate(u); err != nil {
+		return err
+	}
+	return s.record(c, model.AuditUserDeactivate, id, map[string]interface{}{"reason": reason})
+}
+
+// ResendVerification emails an unverified user a new verification code
+func (s *Service) ResendVerification(c *gin.Context, id int) error {
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return err
+	}
+	if u.Verified {
+		return apperr.New(http.StatusConflict, "The user is verified already.")
+	}
+	if u.Email == "" {
+		return apperr.New(http.StatusConflict, "The user has no email address.")
+	}
+	if err := s.verifier.ResendVerification(c, u.Email); err != nil {
+		return err
+	}
+	return s.record(c, model.AuditUserResendVerification, id, nil)
+}
+
+// GrantReward grants a user a reward, which is paid out to their broker account like referral rewards
+func (s *Service) GrantReward(c *gin.Context, id int, amount float64, reason string) (*model.UserReward, error) {
+	if _, err := s.userRepo.View(id); err != nil {
+		return nil, err
+	}
+	r := &model.UserReward{
+		UserID:      id,
+		RewardValue: float32(amount),
+		RewardType:  model.RewardTypeGrant,
+	}
+	if err := s.rewards.Create(r); err != nil {
+		return nil, err
+	}
+	if err := s.record(c, model.AuditUserGrantReward, id, map[string]interface{}{"reward_id": r.ID, "amount": amount, "reason": reason}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// AddNote adds an internal note on a user
+func (s *Service) AddNote(c *gin.Context, id int, body string) (*model.UserNote, error) {
+	if _, err := s.userRepo.View(id); err != nil {
+		return nil, err
+	}
+	n := &model.UserNote{UserID: id, AuthorID: c.GetInt("id"), Body: body}
+	if err := s.notes.Create(n); err != nil {
+		return nil, err
+	}
+	if err := s.record(c, model.AuditUserNote, id, map[string]interface{}{"note_id": n.ID}); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Impersonation is a token to act as a user. Requests made with it are read-only unless
+// configured otherwise, and recorded in the audit log.
+type Impersonation struct {
+	Token   string `json:"token"`
+	Expires string `json:"expires"`
+}
+
+// Impersonate issues the current staff member a token to see exactly what a user with a lower role sees
+func (s *Service) Impersonate(c *gin.Context, id int, reason string) (*Impersonation, error) {
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return nil, err
+	}
+	if !outranks(c, u) {
+		return nil, apperr.New(http.StatusForbidden, "Forbidden")
+	}
+	if u.Role != nil && u.Role.AccessLevel <= model.AdminRole {
+		return nil, apperr.New(http.StatusForbidden, "Staff members can't be impersonated.")
+	}
+	token, expires, err := s.tokens.GenerateImpersonationToken(u, c.GetInt("id"), s.ttl)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(c, model.AuditUserImpersonate, id, map[string]interface{}{"reason": reason, "expires": expires}); err != nil {
+		return nil, err
+	}
+	return &Impersonation{Token: token, Expires: expires}, nil
+}
+
+// fromBroker returns what get returns for the broker account of a user, from the broker of the user's tenant
+func (s *Service) fromBroker(c *gin.Context, id int, action string, get func(broker.Service, string) (json.RawMessage, error)) (json.RawMessage, error) {
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.AccountID == "" {
+		return nil, apperr.New(http.StatusNotFound, "The user has no broker account.")
+	}
+	t, err := s.tenants.ByID(u.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	body, err := get(s.brokers.For(t), u.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(c, action, id, nil); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// outranks reports whether the current staff member has a higher role than u, which includes not being u
+func outranks(c *gin.Context, u *model.User) bool {
+	role, _ := c.Get("role")
+	level, _ := role.(int8)
+	return level != 0 && (u.Role == nil || level < int8(u.Role.AccessLevel))
+}
+
+// record writes an action of the current staff member to the audit log
+func (s *Service) record(c *gin.Context, action string, targetUserID int, details map[string]interface{}) error {
+	return s.audit.Record(c, &model.AuditEvent{Action: action, TargetUserID: targetUserID, Details: details})
+}
@@ -0,0 +1,209 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/admin"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// verifier counts the verification codes it sends
+type verifier struct{ sent int }
+
+func (v *verifier) ResendVerification(c context.Context, email string) error {
+	v.sent++
+	return nil
+}
+
+// tokens issues impersonation tokens naming the user and the staff member
+type tokens struct{}
+
+func (tokens) GenerateImpersonationToken(u *model.User, adminID int, ttl time.Duration) (string, string, error) {
+	return "token", time.Now().Add(ttl).Format(time.RFC3339), nil
+}
+
+func staff(role model.AccessRole) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/admin/users/2", nil)
+	c.Set("id", 1)
+	c.Set("role", int8(role))
+	return c
+}
+
+func TestActions(t *testing.T) {
+	users := map[int]*model.User{
+		2: {ID: 2, Email: "jane@mail.com", Verified: true, Active: true, AccountID: "acc-2", Role: &model.Role{AccessLevel: model.UserRole}},
+		3: {ID: 3, Email: "pending@mail.com"},
+		4: {ID: 4, Email: "admin@mail.com", Verified: true, Active: true, Role: &model.Role{AccessLevel: model.AdminRole}},
+	}
+	cases := []struct {
+		name       string
+		role       model.AccessRole
+		action     func(*admin.Service, *gin.Context) error
+		wantAudit  string
+		wantStatus int
+	}{
+		{
+			name:      "Deactivates a verified user",
+			role:      model.AdminRole,
+			action:    func(s *admin.Service, c *gin.Context) error { return s.Deactivate(c, 2, "fraud") },
+			wantAudit: model.AuditUserDeactivate,
+		},
+		{
+			name:       "Refuses to deactivate an unverified user",
+			role:       model.AdminRole,
+			action:     func(s *admin.Service, c *gin.Context) error { return s.Deactivate(c, 3, "fraud") },
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Refuses to deactivate staff of the same role",
+			role:       model.AdminRole,
+			action:     func(s *admin.Service, c *gin.Context) error { return s.Deactivate(c, 4, "fraud") },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:      "Superadmins deactivate admins",
+			role:      model.SuperAdminRole,
+			action:    func(s *admin.Service, c *gin.Context) error { return s.Deactivate(c, 4, "left") },
+			wantAudit: model.AuditUserDeactivate,
+		},
+		{
+			name:       "Refuses to activate an unverified user",
+			role:       model.AdminRole,
+			action:     func(s *admin.Service, c *gin.Context) error { return s.Activate(c, 3) },
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:      "Resends verification to an unverified user",
+			role:      model.AdminRole,
+			action:    func(s *admin.Service, c *gin.Context) error { return s.ResendVerification(c, 3) },
+			wantAudit: model.AuditUserResendVerification,
+		},
+		{
+			name:       "Refuses to resend verification to a verified user",
+			role:       model.AdminRole,
+			action:     func(s *admin.Service, c *gin.Context) error { return s.ResendVerification(c, 2) },
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "Reads orders from the broker",
+			role: model.AdminRole,
+			action: func(s *admin.Service, c *gin.Context) error {
+				_, err := s.Orders(c, 2)
+				return err
+			},
+			wantAudit: model.AuditUserOrders,
+		},
+		{
+			name: "Users without a broker account",
+			role: model.AdminRole,
+			action: func(s *admin.Service, c *gin.Context) error {
+				_, err := s.Positions(c, 3)
+				return err
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "Refuses short searches",
+			role: model.AdminRole,
+			action: func(s *admin.Service, c *gin.Context) error {
+				_, err := s.Search(c, "ja")
+				return err
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Grants rewards",
+			role: model.AdminRole,
+			action: func(s *admin.Service, c *gin.Context) error {
+				_, err := s.GrantReward(c, 2, 10, "goodwill")
+				return err
+			},
+			wantAudit: model.AuditUserGrantReward,
+		},
+		{
+			name: "Impersonates users",
+			role: model.AdminRole,
+			action: func(s *admin.Service, c *gin.Context) error {
+				_, err := s.Impersonate(c, 2, "ticket 42")
+				return err
+			},
+			wantAudit: model.AuditUserImpersonate,
+		},
+		{
+			name: "Refuses to impersonate staff of the same role",
+			role: model.AdminRole,
+			action: func(s *admin.Service, c *gin.Context) error {
+				_, err := s.Impersonate(c, 4, "ticket 42")
+				return err
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "Refuses to impersonate staff of a lower role",
+			role: model.SuperAdminRole,
+			action: func(s *admin.Service, c *gin.Context) error {
+				_, err := s.Impersonate(c, 4, "ticket 42")
+				return err
+			},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []*model.AuditEvent
+			userRepo := &mockdb.User{
+				ViewFn: func(id int) (*model.User, error) {
+					if u, ok := users[id]; ok {
+						cp := *u
+						return &cp, nil
+					}
+					return nil, apperr.NotFound
+				},
+			}
+			accountRepo := &mockdb.Account{
+				ActivateFn:   func(*model.User) error { return nil },
+				DeactivateFn: func(*model.User) error { return nil },
+			}
+			rewards := &mockdb.UserReward{
+				CreateFn: func(r *model.UserReward) error {
+					r.ID = 1
+					return nil
+				},
+			}
+			audit := &mock.AuditLog{
+				RecordFn: func(c *gin.Context, e *model.AuditEvent) error {
+					events = append(events, e)
+					return nil
+				},
+			}
+			brk := &mock.Broker{
+				OrdersFn: func(accountID string) (json.RawMessage, error) {
+					return json.RawMessage(`[]`), nil
+				},
+			}
+			s := admin.NewAdminService(userRepo, accountRepo, &mockdb.Note{}, rewards, audit, brk, &mock.Tenants{}, &verifier{}, tokens{}, 15*time.Minute)
+			err := tt.action(s, staff(tt.role))
+			if tt.wantStatus != 0 {
+				assert.Equal(t, tt.wantStatus, err.(*apperr.APPError).Status)
+				assert.Empty(t, events)
+				return
+			}
+			assert.Nil(t, err)
+			if assert.Len(t, events, 1) {
+				assert.Equal(t, tt.wantAudit, events[0].Action)
+			}
+		})
+	}
+}
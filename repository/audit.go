@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewAuditRepo returns an AuditRepo instance
+func NewAuditRepo(db orm.DB, log *zap.Logger) *AuditRepo {
+	return &AuditRepo{db, log}
+}
+
+// AuditRepo represents the client for the audit log
+type AuditRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// Create appends an event to the audit log
+func (r *AuditRepo) Create(e *model.AuditEvent) error {
+	if _, err := r.db.Model(e).Value("created_at", "now()").Returning("*").Insert(); err != nil {
+		r.log.Warn("AuditRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// List returns the events matching f, newest first
+func (r *AuditRepo) List(f *model.AuditFilter) ([]model.AuditEvent, error) {
+	var events []model.AuditEvent
+	q := r.filter(r.db.Model(&events), f).Order("id DESC")
+	if f.BeforeID > 0 {
+		q = q.Where("id < ?", f.BeforeID)
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	if err := q.Select(); err != nil {
+		r.log.Warn("AuditRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return events, nil
+}
+
+// ForEach calls fn with the events matching f, oldest first, without loading them all at once
+func (r *AuditRepo) ForEach(f *model.AuditFilter, fn func(*model.AuditEvent) error) error {
+	err := r.filter(r.db.Model((*model.AuditEvent)(nil)), f).Order("id ASC").ForEach(func(e *model.AuditEvent) error {
+		return fn(e)
+	})
+	if err != nil {
+		r.log.Warn("AuditRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// Protect makes the database refuse updates and deletes of events, so the audit log is append-only
+// even for code that doesn't go through AuditRepo
+func (r *AuditRepo) Protect() error {
+	_, err := r.db.Exec(`CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_events
+	FOR EACH STATEMENT EXECUTE PROCEDURE audit_events_append_only();`)
+	if err != nil {
+		r.log.Warn("AuditRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+func (r *AuditRepo) filter(q *orm.Query, f *model.AuditFilter) *orm.Query {
+	if f.ActorID > 0 {
+		q = q.Where("actor_id = ?", f.ActorID)
+	}
+	if f.TargetUserID > 0 {
+		q = q.Where("target_user_id = ?", f.TargetUserID)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+	return q
+}
@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultLimit is how many events List returns when no limit is asked for
+	DefaultLimit = 50
+	// MaxLimit is the most events List returns at once
+	MaxLimit = 500
+)
+
+// NewAuditService creates the audit log application service
+func NewAuditService(repo model.AuditRepo) *Service {
+	return &Service{repo}
+}
+
+// Service represents the audit log application service
+type Service struct {
+	repo model.AuditRepo
+}
+
+// Record appends e to the audit log, with the current user as the actor unless e has one, the staff
+// member impersonating them if any, and the request ID, IP and user agent of the request
+func (s *Service) Record(c *gin.Context, e *model.AuditEvent) error {
+	if e.ActorID == 0 {
+		e.ActorID = c.GetInt("id")
+	}
+	if e.ImpersonatorID == 0 {
+		e.ImpersonatorID = c.GetInt("impersonator")
+	}
+	e.RequestID = c.GetString("request_id")
+	e.IP = c.ClientIP()
+	e.UserAgent = c.Request.UserAgent()
+	return s.repo.Create(e)
+}
+
+// List returns a page of the events matching f, newest first
+func (s *Service) List(f *model.AuditFilter) ([]model.AuditEvent, error) {
+	if f.Limit <= 0 {
+		f.Limit = DefaultLimit
+	}
+	if f.Limit > MaxLimit {
+		f.Limit = MaxLimit
+	}
+	return s.repo.List(f)
+}
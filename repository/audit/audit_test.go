@@ -0,0 +1,82 @@
+package audit_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/audit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	var created *model.AuditEvent
+	repo := &mockdb.Audit{
+		CreateFn: func(e *model.AuditEvent) error {
+			created = e
+			return nil
+		},
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("PATCH", "/v1/profile", nil)
+	c.Request.Header.Set("User-Agent", "ribbit-ios/1.0")
+	c.Request.RemoteAddr = "203.0.113.7:5000"
+	c.Set("id", 3)
+	c.Set("request_id", "req-1")
+
+	s := audit.NewAuditService(repo)
+	assert.Nil(t, s.Record(c, &model.AuditEvent{Action: model.AuditProfileUpdate, TargetUserID: 3}))
+	assert.Equal(t, &model.AuditEvent{
+		ActorID:      3,
+		Action:       model.AuditProfileUpdate,
+		TargetUserID: 3,
+		RequestID:    "req-1",
+		IP:           "203.0.113.7",
+		UserAgent:    "ribbit-ios/1.0",
+	}, created)
+}
+
+func TestRecordImpersonated(t *testing.T) {
+	var created *model.AuditEvent
+	repo := &mockdb.Audit{
+		CreateFn: func(e *model.AuditEvent) error {
+			created = e
+			return nil
+		},
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/watchlist", nil)
+	c.Set("id", 3)
+	c.Set("impersonator", 1)
+
+	s := audit.NewAuditService(repo)
+	assert.Nil(t, s.Record(c, &model.AuditEvent{Action: model.AuditOrderCreate, TargetUserID: 3}))
+	assert.Equal(t, 3, created.ActorID)
+	assert.Equal(t, 1, created.ImpersonatorID, "the staff member acting as the user is recorded")
+}
+
+func TestList(t *testing.T) {
+	var limit int
+	repo := &mockdb.Audit{
+		ListFn: func(f *model.AuditFilter) ([]model.AuditEvent, error) {
+			limit = f.Limit
+			return nil, nil
+		},
+	}
+	s := audit.NewAuditService(repo)
+	cases := []struct {
+		limit     int
+		wantLimit int
+	}{
+		{limit: 0, wantLimit: audit.DefaultLimit},
+		{limit: 10, wantLimit: 10},
+		{limit: 100000, wantLimit: audit.MaxLimit},
+	}
+	for _, tt := range cases {
+		s.List(&model.AuditFilter{Limit: tt.limit})
+		assert.Equal(t, tt.wantLimit, limit)
+	}
+}
@@ -3,11 +3,15 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	mag "github.com/magiclabs/magic-admin-go"
@@ -22,110 +26,442 @@ import (
 	"github.com/alpacahq/ribbit-backend/model"
 	"github.com/alpacahq/ribbit-backend/request"
 	"github.com/alpacahq/ribbit-backend/secret"
+	"github.com/alpacahq/ribbit-backend/webauthn"
 
 	shortuuid "github.com/lithammer/shortuuid/v3"
 )
 
 // NewAuthService creates new auth service
-func NewAuthService(userRepo model.UserRepo, accountRepo model.AccountRepo, jwt JWT, m mail.Service, mob mobile.Service, mag magic.Service) *Service {
-	return &Service{userRepo, accountRepo, jwt, m, mob, mag}
+func NewAuthService(userRepo model.UserRepo, accountRepo model.AccountRepo, sessionRepo model.SessionRepo, tokenRepo model.RefreshTokenRepo, tfa SecondFactor, throttle model.Throttler, jwt JWT, m mail.Service, mob mobile.Service, mag magic.Service, resendCooldown time.Duration, passkeys model.PasskeyRepo, rp *webauthn.RelyingParty, passwords model.PasswordPolicy) *Service {
+	return &Service{userRepo, accountRepo, sessionRepo, tokenRepo, tfa, throttle, jwt, m, mob, mag, resendCooldown, passkeys, rp, passwords, nil}
 }
 
 // Service represents the auth application service
 type Service struct {
 	userRepo    model.UserRepo
 	accountRepo model.AccountRepo
+	sessionRepo model.SessionRepo
+	tokenRepo   model.RefreshTokenRepo
+	tfa         SecondFactor
+	throttle    model.Throttler
 	jwt         JWT
 	m           mail.Service
 	mob         mobile.Service
 	mag         magic.Service
+	// resendCooldown is how long users have to wait before requesting another verification code
+	resendCooldown time.Duration
+	passkeys       model.PasskeyRepo
+	// rp verifies passkey ceremonies
+	rp *webauthn.RelyingParty
+	// passwords checks new passwords on signup and recovery
+	passwords model.PasswordPolicy
+
+	// Tenants resolves the tenant emails to existing users are sent as, instead of the tenant of the request, if set.
+	Tenants model.TenantStore
 }
 
 // JWT represents jwt interface
 type JWT interface {
-	GenerateToken(*model.User) (string, string, error)
+	GenerateSessionToken(*model.User, string) (string, string, error)
+	GenerateChallengeToken(*model.User) (string, error)
+	ParseChallengeToken(string) (int, error)
+	// RefreshLifetime returns how long an unused refresh token is valid and the absolute lifetime of a login
+	RefreshLifetime() (time.Duration, time.Duration)
 }
 
-// Authenticate tries to authenticate the user provided by username and password
+// SecondFactor verifies the second factor of users with two-factor authentication
+type SecondFactor interface {
+	Enabled(int) (bool, error)
+	Verify(int, string) error
+}
+
+// errDisabled is returned to users who were deactivated by an admin, however they sign in
+var errDisabled = apperr.New(http.StatusForbidden, "Your account has been disabled.")
+
+// login starts a new session for u on the requesting device and issues its first tokens
+func (s *Service) login(c context.Context, u *model.User) (*model.AuthToken, error) {
+	return s.startSession(c, u, false)
+}
+
+// startSession starts a new session for u, unless u was disabled. stepUp records that the user just
+// confirmed their second factor.
+func (s *Service) startSession(c context.Context, u *model.User, stepUp bool) (*model.AuthToken, error) {
+	if u.Disabled() {
+		return nil, errDisabled
+	}
+	_, maxRefresh := s.jwt.RefreshLifetime()
+	now := time.Now()
+	session := &model.Session{
+		ID:        xid.New().String(),
+		UserID:    u.ID,
+		DeviceID:  u.DeviceID,
+		ExpiresAt: now.Add(maxRefresh),
+	}
+	if stepUp {
+		session.StepUpAt = &now
+	}
+	describeClient(c, session)
+	token, expire, err := s.jwt.GenerateSessionToken(u, session.ID)
+	if err != nil {
+		return nil, apperr.New(http.StatusUnauthorized, "Unauthorized")
+	}
+	u.UpdateLastLogin()
+	u.Token = "" // refresh tokens stored on the user before refresh_tokens existed are no longer accepted
+	if err := s.userRepo.UpdateLogin(u); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.issueRefreshToken(u.ID, session.ID, session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AuthToken{
+		Token:        token,
+		Expires:      expire,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// describeClient records the device, user agent and IP address of the request in c, if it is an HTTP request.
+// Clients name themselves with the X-Device-ID and X-Device-Name headers.
+func describeClient(c context.Context, session *model.Session) {
+	gc, ok := c.(*gin.Context)
+	if !ok || gc.Request == nil {
+		return
+	}
+	if id := gc.GetHeader("X-Device-ID"); id != "" {
+		session.DeviceID = id
+	}
+	if name := gc.GetHeader("X-Device-Name"); name != "" {
+		session.DeviceName = name
+	}
+	session.UserAgent = gc.Request.UserAgent()
+	session.IP = gc.ClientIP()
+}
+
+// clientKey returns the throttle key of the client IP of a request
+func clientKey(c context.Context) string {
+	return model.ThrottleKey(model.ThrottleIP, clientIP(c))
+}
+
+// clientIP returns the client IP of a request, or an empty string outside of requests
+func clientIP(c context.Context) string {
+	if gc, ok := c.(*gin.Context); ok && gc.Request != nil {
+		return gc.ClientIP()
+	}
+	return ""
+}
+
+// failed counts a failed attempt against key and the client IP and reports whether it locked key.
+// Errors are ignored, as the attempt fails either way.
+func (s *Service) failed(key, ip string) bool {
+	s.throttle.Fail(ip)
+	locked, _ := s.throttle.Fail(key)
+	return locked
+}
+
+// lockedOut tells a user by email that their account or one-time passwords were locked
+func (s *Service) lockedOut(c context.Context, u *model.User) {
+	if u != nil && u.Email != "" {
+		s.mailFor(c, u).SendLockoutEmail(u.Email)
+	}
+}
+
+// mailFor returns the mail service of the tenant of u, which needn't be the tenant of the request,
+// e.g. when support staff resend a verification email
+func (s *Service) mailFor(c context.Context, u *model.User) mail.Service {
+	if s.Tenants != nil {
+		if t, err := s.Tenants.ByID(u.TenantID); err == nil {
+			return s.m.For(t)
+		}
+	}
+	return s.m.For(model.TenantOf(c))
+}
+
+// issueRefreshToken creates a refresh token in family, valid until familyExpires at the latest
+func (s *Service) issueRefreshToken(userID int, family string, familyExpires time.Time) (string, error) {
+	b, err := secret.GenerateRandomBytes(32)
+	if err != nil {
+		return "", apperr.Generic
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	ttl, _ := s.jwt.RefreshLifetime()
+	expires := time.Now().Add(ttl)
+	if expires.After(familyExpires) {
+		expires = familyExpires
+	}
+	err = s.tokenRepo.Create(&model.IssuedRefreshToken{
+		UserID:          userID,
+		FamilyID:        family,
+		TokenHash:       model.HashRefreshToken(token),
+		ExpiresAt:       expires,
+		FamilyExpiresAt: familyExpires,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Authenticate tries to authenticate the user provided by username and password.
+// Failed attempts are throttled per account and per client IP.
 func (s *Service) Authenticate(c context.Context, email, password string) (*model.LoginResponseWithToken, error) {
+	account, ip := model.ThrottleKey(model.ThrottleAccount, email), clientKey(c)
+	if err := s.throttle.Check(account, ip); err != nil {
+		return nil, err
+	}
 	u, err := s.userRepo.FindByEmail(email)
 	if err != nil {
+		s.failed(account, ip)
 		return nil, apperr.New(http.StatusUnauthorized, "Invalid credentials. Please check and submit again.")
 	}
 	if !secret.New().HashMatchesPassword(u.Password, password) {
+		if s.failed(account, ip) {
+			s.lockedOut(c, u)
+		}
 		return nil, apperr.New(http.StatusUnauthorized, "Invalid credentials. Please check and submit again.")
 	}
-	// user must be active and verified. Active is enabled/disabled by superadmin user. Verified depends on user verifying via /verification/:token or /mobile/verify
-	// if !u.Active || !u.Verified {
-	// 	return nil, apperr.New(http.StatusUnauthorized, "User already exists.")
-	// }
-	token, expire, err := s.jwt.GenerateToken(u)
-	if err != nil {
-		return nil, apperr.New(http.StatusUnauthorized, "Invalid credentials. Please check and submit again.")
+	if err := s.throttle.Reset(account); err != nil {
+		return nil, err
 	}
-	u.UpdateLastLogin()
-	u.Token = xid.New().String()
-	if err := s.userRepo.UpdateLogin(u); err != nil {
+	response, err := s.SignIn(c, u)
+	if err != nil {
 		return nil, err
 	}
-
-	response := &model.LoginResponseWithToken{
-		Token:        token,
-		Expires:      expire,
-		RefreshToken: u.Token,
-		User:         *u,
+	if !u.Verified && response.Token != "" {
+		// best effort, users can ask for another code with ResendVerification
+		s.sendVerification(c, u)
 	}
+	return response, nil
+}
 
-	if !u.Active {
-		v, _ := s.accountRepo.FindVerificationTokenByUser(u)
-		if v != nil {
-			err = s.accountRepo.DeleteVerificationToken(v)
-			if err != nil {
-				return response, err
-			}
-			v2, err := s.accountRepo.CreateNewOTP(u)
-			if err != nil { // user exists
-				return response, apperr.New(http.StatusInternalServerError, "Invalid credentials. Please check and submit again.")
-			}
-
-			err = s.m.SendVerificationEmail(email, v2)
-			if err != nil {
-				return response, err
-			}
+// SignIn starts a session for a user whose first factor was checked, such as a password or an ID token
+// of an identity provider. Users with two-factor authentication get a challenge token instead.
+func (s *Service) SignIn(c context.Context, u *model.User) (*model.LoginResponseWithToken, error) {
+	// unverified users can sign in, but only access the routes allowed by the verification policy
+	if u.Disabled() {
+		return nil, errDisabled
+	}
+	enabled, err := s.tfa.Enabled(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		// no session until the second factor is verified with VerifyTwoFactor
+		challenge, err := s.jwt.GenerateChallengeToken(u)
+		if err != nil {
+			return nil, apperr.Generic
 		}
+		return &model.LoginResponseWithToken{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, nil
 	}
+	t, err := s.login(c, u)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResponseWithToken{
+		Token:        t.Token,
+		Expires:      t.Expires,
+		RefreshToken: t.RefreshToken,
+		User:         *u,
+	}, nil
+}
 
-	return response, nil
+// VerifyTwoFactor completes a login of a user with two-factor authentication,
+// exchanging the challenge token returned by Authenticate and a TOTP or recovery code for a session
+func (s *Service) VerifyTwoFactor(c context.Context, challenge, code string) (*model.LoginResponseWithToken, error) {
+	id, err := s.jwt.ParseChallengeToken(challenge)
+	if err != nil {
+		return nil, err
+	}
+	account, ip := model.ThrottleKey(model.ThrottleAccount, strconv.Itoa(id)), clientKey(c)
+	if err := s.throttle.Check(account, ip); err != nil {
+		return nil, err
+	}
+	if err := s.tfa.Verify(id, code); err != nil {
+		if s.failed(account, ip) {
+			u, _ := s.userRepo.View(id)
+			s.lockedOut(c, u)
+		}
+		return nil, err
+	}
+	if err := s.throttle.Reset(account); err != nil {
+		return nil, err
+	}
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return nil, apperr.New(http.StatusUnauthorized, "Invalid or expired challenge token. Please sign in again.")
+	}
+	t, err := s.startSession(c, u, true)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResponseWithToken{
+		Token:        t.Token,
+		Expires:      t.Expires,
+		RefreshToken: t.RefreshToken,
+		User:         *u,
+	}, nil
 }
 
-// Refresh refreshes jwt token and puts new claims inside
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// A refresh token can only be used once: presenting a used token again means it was
+// stolen, so its whole family is revoked and every client of that login must sign in again.
 func (s *Service) Refresh(c context.Context, refreshToken string) (*model.RefreshToken, error) {
-	user, err := s.userRepo.FindByToken(refreshToken)
+	t, err := s.tokenRepo.FindByHash(model.HashRefreshToken(refreshToken))
 	if err != nil {
 		return nil, err
 	}
-	// this is our re-generated JWT
-	token, expire, err := s.jwt.GenerateToken(user)
+	if t.RevokedAt != nil {
+		return nil, apperr.New(http.StatusUnauthorized, "Refresh token has been revoked.")
+	}
+	if t.UsedAt != nil {
+		return nil, s.refreshReused(t)
+	}
+	if t.Expired(time.Now()) {
+		return nil, apperr.New(http.StatusUnauthorized, "Refresh token has expired.")
+	}
+	ok, err := s.tokenRepo.MarkUsed(t)
+	if err != nil {
+		return nil, err
+	}
+	if !ok { // a concurrent request rotated the same token
+		return nil, s.refreshReused(t)
+	}
+
+	session, err := s.sessionRepo.View(t.FamilyID)
+	if err != nil || !session.Active(time.Now()) {
+		return nil, apperr.New(http.StatusUnauthorized, "Session has been revoked.")
+	}
+	describeClient(c, session)
+	if err := s.sessionRepo.Touch(session); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.View(t.UserID)
+	if err != nil {
+		return nil, apperr.New(http.StatusUnauthorized, "Invalid refresh token.")
+	}
+	if user.TokensValidAfter != nil && session.CreatedAt.Before(*user.TokensValidAfter) {
+		// the password or role changed since this session signed in
+		if err := s.revokeSession(session.ID); err != nil {
+			return nil, err
+		}
+		return nil, apperr.New(http.StatusUnauthorized, "Session has been revoked.")
+	}
+	token, expire, err := s.jwt.GenerateSessionToken(user, session.ID)
 	if err != nil {
 		return nil, apperr.Generic
 	}
+	next, err := s.issueRefreshToken(t.UserID, t.FamilyID, t.FamilyExpiresAt)
+	if err != nil {
+		return nil, err
+	}
 	return &model.RefreshToken{
-		Token:   token,
-		Expires: expire,
+		Token:        token,
+		Expires:      expire,
+		RefreshToken: next,
 	}, nil
 }
 
-// Verify verifies the (verification) token and deletes it
-func (s *Service) Verify(c context.Context, token string) error {
-	v, err := s.accountRepo.FindVerificationToken(token)
+func (s *Service) refreshReused(t *model.IssuedRefreshToken) error {
+	if err := s.revokeSession(t.FamilyID); err != nil {
+		return err
+	}
+	return apperr.New(http.StatusUnauthorized, "Refresh token has already been used. Please sign in again.")
+}
+
+func (s *Service) revokeSession(id string) error {
+	if err := s.tokenRepo.RevokeFamily(id); err != nil {
+		return err
+	}
+	return s.sessionRepo.Revoke(id)
+}
+
+// Verify redeems the email verification token of the user with email, activating the user.
+// Links sent before verification links had the email are redeemed by their token alone.
+func (s *Service) Verify(c context.Context, email, token string) error {
+	if email == "" {
+		return s.verifyToken(c, token)
+	}
+	key, ip := model.ThrottleKey(model.ThrottleOTP, email), clientKey(c)
+	if err := s.throttle.Check(key, ip); err != nil {
+		return err
+	}
+	u, err := s.userRepo.FindByEmail(email)
 	if err != nil {
+		s.throttle.Fail(ip)
+		return apperr.New(http.StatusNotFound, "Invalid or expired OTP")
+	}
+	if _, err := s.accountRepo.UseVerificationToken(u, model.PurposeEmailVerify, token); err != nil {
+		if s.failed(key, ip) {
+			s.lockedOut(c, u)
+		}
 		return err
 	}
-	err = s.accountRepo.DeleteVerificationToken(v)
+	if err := s.throttle.Reset(key); err != nil {
+		return err
+	}
+	u.MarkVerified()
+	return s.accountRepo.Activate(u)
+}
+
+// verifyToken redeems an email verification token without knowing whose it is,
+// so failed attempts can only be throttled by client
+func (s *Service) verifyToken(c context.Context, token string) error {
+	ip := clientKey(c)
+	if err := s.throttle.Check(ip); err != nil {
+		return err
+	}
+	v, err := s.accountRepo.RedeemVerificationToken(model.PurposeEmailVerify, token)
 	if err != nil {
+		s.throttle.Fail(ip)
 		return err
 	}
-	return nil
+	u, err := s.userRepo.View(v.UserID)
+	if err != nil {
+		return err
+	}
+	u.MarkVerified()
+	return s.accountRepo.Activate(u)
+}
+
+// ResendVerification emails a new verification code to the user with email, unless the user
+// is verified already or was sent a code within the cooldown. Unknown emails are ignored,
+// so that the response doesn't tell which emails have an account.
+func (s *Service) ResendVerification(c context.Context, email string) error {
+	u, err := s.userRepo.FindByEmail(email)
+	if err != nil || u.Verified {
+		return nil
+	}
+	return s.sendVerification(c, u)
+}
+
+// sendVerification emails u a new verification code, invalidating the previous one
+func (s *Service) sendVerification(c context.Context, u *model.User) error {
+	if v, _ := s.accountRepo.FindVerificationTokenByUser(u, model.PurposeEmailVerify); v != nil {
+		if wait := s.resendCooldown - time.Since(v.CreatedAt); wait > 0 {
+			return apperr.New(http.StatusTooManyRequests, fmt.Sprintf("Please wait %d seconds before requesting another code.", int(math.Ceil(wait.Seconds()))))
+		}
+	}
+	v, err := s.accountRepo.CreateVerificationToken(u, model.PurposeEmailVerify)
+	if err != nil {
+		return err
+	}
+	return s.mailFor(c, u).SendVerificationEmail(u.Email, v)
+}
+
+// Verified reports whether a user has verified their email address or mobile number
+func (s *Service) Verified(userID int) (bool, error) {
+	u, err := s.userRepo.View(userID)
+	if err != nil {
+		return false, err
+	}
+	return u.Verified, nil
 }
 
 func encodeToString(max int) string {
@@ -147,12 +483,12 @@ func (s *Service) Forgot(c *gin.Context, email string) error {
 	if err != nil { // user exists
 		return apperr.New(http.StatusNotFound, "User doesn't exist.")
 	}
-	v, err := s.accountRepo.CreateForgotToken(u)
+	v, err := s.accountRepo.CreateVerificationToken(u, model.PurposePasswordReset)
 	if err != nil { // user exists
 		return apperr.New(http.StatusInternalServerError, "Failed to generate verification process.")
 	}
 
-	err = s.m.SendForgotVerificationEmail(email, v)
+	err = s.mailFor(c, u).SendForgotVerificationEmail(email, v)
 	if err != nil {
 		apperr.Response(c, err)
 		return err
@@ -162,24 +498,44 @@ func (s *Service) Forgot(c *gin.Context, email string) error {
 
 // Verify OTP and recover password
 func (s *Service) RecoverPassword(c *gin.Context, email string, otp string, password string) error {
+	key, ip := model.ThrottleKey(model.ThrottleOTP, email), clientKey(c)
+	if err := s.throttle.Check(key, ip); err != nil {
+		return err
+	}
 	u, err := s.userRepo.FindByEmail(email)
 	if err != nil { // user exists
+		s.throttle.Fail(ip)
 		return apperr.New(http.StatusNotFound, "User doesn't exist.")
 	}
+	// a weak password doesn't use up the otp, a reused one does, so that the history can't be probed without it
+	if err := s.passwords.Validate(u, password); err != nil {
+		return err
+	}
 
-	v, err := s.accountRepo.FindVerificationToken(otp)
+	_, err = s.accountRepo.UseVerificationToken(u, model.PurposePasswordReset, otp)
 	if err != nil {
+		if s.failed(key, ip) {
+			s.lockedOut(c, u)
+			// the user has to request a new otp once the lockout ends
+			if err := s.accountRepo.DeleteVerificationTokens(u); err != nil {
+				return err
+			}
+		}
 		return err
 	}
-	err = s.accountRepo.DeleteVerificationToken(v)
-	if err != nil {
+	if err := s.throttle.Reset(key); err != nil {
+		return err
+	}
+	if err := s.passwords.CheckReuse(u, password); err != nil {
 		return err
 	}
-
 	u.Password = password
 	if err := s.accountRepo.ResetPassword(u); err != nil {
 		return apperr.New(http.StatusInternalServerError, "Failed to change password, please try again.")
 	}
+	if err := s.passwords.Remember(u); err != nil {
+		return err
+	}
 
 	return apperr.New(http.StatusAccepted, "Password changed.")
 }
@@ -195,19 +551,37 @@ func (s *Service) RefVerify(c context.Context, referralCode string) (*model.Refe
 }
 
 // MobileVerify verifies the mobile verification code, i.e. (6-digit) code
-func (s *Service) MobileVerify(c context.Context, countryCode, mobile, code string, signup bool) (*model.AuthToken, error) {
-	// send code to twilio
-	err := s.mob.CheckCode(countryCode, mobile, code)
+func (s *Service) MobileVerify(c context.Context, countryCode, number, code string, signup bool) (*model.LoginResponseWithToken, error) {
+	key, ip := model.ThrottleKey(model.ThrottleOTP, countryCode+number), clientKey(c)
+	if err := s.throttle.Check(key, ip); err != nil {
+		return nil, err
+	}
+	// send code to twilio, which stops accepting a code after its own attempt limit
+	err := s.mob.CheckCode(countryCode, number, code)
+	if err == mobile.ErrInvalidCode || err == mobile.ErrMaxAttempts {
+		if s.failed(key, ip) {
+			u, _ := s.userRepo.FindByMobile(countryCode, number)
+			s.lockedOut(c, u)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
-	u, err := s.userRepo.FindByMobile(countryCode, mobile)
+	if err := s.throttle.Reset(key); err != nil {
+		return nil, err
+	}
+	if err := s.throttle.Reset(model.ThrottleKey(model.ThrottleSMS, countryCode+number)); err != nil {
+		return nil, err
+	}
+	u, err := s.userRepo.FindByMobile(countryCode, number)
 	if err != nil {
 		return nil, err
 	}
+	if u.Disabled() { // before verifying the number again would reactivate the user
+		return nil, errDisabled
+	}
 	if signup { // signup case, make user verified and active
-		u.Verified = true
-		u.Active = true
+		u.MarkVerified()
 	} else { // login case, update user's last_login attribute
 		u.UpdateLastLogin()
 	}
@@ -216,21 +590,8 @@ func (s *Service) MobileVerify(c context.Context, countryCode, mobile, code stri
 		return nil, err
 	}
 
-	// generate jwt and return
-	token, expire, err := s.jwt.GenerateToken(u)
-	if err != nil {
-		return nil, apperr.New(http.StatusUnauthorized, "Unauthorized")
-	}
-	u.UpdateLastLogin()
-	u.Token = xid.New().String()
-	if err := s.userRepo.UpdateLogin(u); err != nil {
-		return nil, err
-	}
-	return &model.AuthToken{
-		Token:        token,
-		Expires:      expire,
-		RefreshToken: u.Token,
-	}, nil
+	// users with two-factor authentication get a challenge token instead of a session
+	return s.SignIn(c, u)
 }
 
 // User returns user data stored in jwt token
@@ -240,10 +601,13 @@ func (s *Service) User(c *gin.Context) *model.AuthUser {
 	email := c.GetString("email")
 	role := c.MustGet("role").(int8)
 	return &model.AuthUser{
-		ID:       id,
-		Username: user,
-		Email:    email,
-		Role:     model.AccessRole(role),
+		ID:           id,
+		Username:     user,
+		Email:        email,
+		Role:         model.AccessRole(role),
+		SessionID:    c.GetString("session_id"),
+		TokenID:      c.GetString("jti"),
+		TokenExpires: c.GetTime("token_expires"),
 	}
 }
 
@@ -253,36 +617,36 @@ func (s *Service) Signup(c *gin.Context, e *request.EmailSignup, password string
 	if err == nil { // user already exists
 		return nil, apperr.New(http.StatusConflict, "User already exists.")
 	}
-	u := shortuuid.New()
-	v, err := s.accountRepo.CreateAndVerify(&model.User{Email: e.Email, Password: password, ReferralCode: u})
+	user := &model.User{Email: e.Email, Password: password, ReferralCode: shortuuid.New(), TenantID: model.TenantIDOf(c)}
+	if err := s.passwords.Validate(user, password); err != nil {
+		return nil, err
+	}
+	v, err := s.accountRepo.CreateAndVerify(user)
 	if err != nil {
 		return nil, err
 	}
-	err = s.m.SendVerificationEmail(e.Email, v)
+	if err := s.passwords.Remember(user); err != nil {
+		return nil, err
+	}
+	err = s.m.For(model.TenantOf(c)).SendVerificationEmail(e.Email, v)
 	if err != nil {
 		apperr.Response(c, err)
 		return nil, err
 	}
 	newUser, err2 := s.userRepo.View(v.UserID)
 	if err2 == nil { // user already exists
-		fmt.Println(newUser)
 		// user must be active and verified. Active is enabled/disabled by superadmin user. Verified depends on user verifying via /verification/:token or /mobile/verify
 		// if !newUser.Active || !newUser.Verified {
 		// 	return nil, apperr.Unauthorized
 		// }
-		token, expire, err := s.jwt.GenerateToken(newUser)
+		t, err := s.login(c, newUser)
 		if err != nil {
-			return nil, apperr.New(http.StatusUnauthorized, "Unauthorized")
-		}
-		newUser.UpdateLastLogin()
-		newUser.Token = xid.New().String()
-		if err := s.userRepo.UpdateLogin(newUser); err != nil {
 			return nil, err
 		}
 		return &model.LoginResponseWithToken{
-			Token:        token,
-			Expires:      expire,
-			RefreshToken: newUser.Token,
+			Token:        t.Token,
+			Expires:      t.Expires,
+			RefreshToken: t.RefreshToken,
 			User:         *newUser,
 		}, nil
 	}
@@ -301,13 +665,14 @@ func (s *Service) Mobile(c *gin.Context, m *request.MobileSignup) error {
 	user := &model.User{
 		CountryCode: m.CountryCode,
 		Mobile:      m.Mobile,
+		TenantID:    model.TenantIDOf(c),
 	}
 	err = s.accountRepo.CreateWithMobile(user)
 	if err != nil {
 		return err
 	}
 	// generate sms token
-	err = s.mob.GenerateSMSToken(m.CountryCode, m.Mobile)
+	err = s.sendSMS(c, m.CountryCode, m.Mobile)
 	if err != nil {
 		apperr.Response(c, err)
 		return err
@@ -315,6 +680,28 @@ func (s *Service) Mobile(c *gin.Context, m *request.MobileSignup) error {
 	return nil
 }
 
+// MobileResend sends a new verification code to the mobile number of an existing user. Unknown numbers
+// are ignored, so that the response doesn't tell which numbers have an account.
+func (s *Service) MobileResend(c context.Context, countryCode, mobile string) error {
+	if _, err := s.userRepo.FindByMobile(countryCode, mobile); err != nil {
+		return nil
+	}
+	return s.sendSMS(c, countryCode, mobile)
+}
+
+// sendSMS sends a verification code, counting it against the send limit of the number
+func (s *Service) sendSMS(c context.Context, countryCode, mobile string) error {
+	key := model.ThrottleKey(model.ThrottleSMS, countryCode+mobile)
+	if err := s.throttle.Check(key, clientKey(c)); err != nil {
+		return err
+	}
+	if err := s.mob.GenerateSMSToken(countryCode, mobile); err != nil {
+		return err
+	}
+	_, err := s.throttle.Fail(key)
+	return err
+}
+
 // Magic returns any error from creating a new user in our database with a magic link
 func (s *Service) Magic(c *gin.Context, m *request.MagicSignup) (*model.LoginResponseWithToken, error) {
 	// Validate magic token
@@ -361,21 +748,7 @@ func (s *Service) Magic(c *gin.Context, m *request.MagicSignup) (*model.LoginRes
 	// find by email
 	if user, err := s.userRepo.FindByEmail(issuer.Email); err == nil { // user already exists
 		// fmt.Println(user)
-		token, expire, err := s.jwt.GenerateToken(user)
-		if err != nil {
-			return nil, apperr.New(http.StatusUnauthorized, "Unauthorized")
-		}
-		user.UpdateLastLogin()
-		user.Token = xid.New().String()
-		if err := s.userRepo.UpdateLogin(user); err != nil {
-			return nil, err
-		}
-		return &model.LoginResponseWithToken{
-			Token:        token,
-			Expires:      expire,
-			RefreshToken: user.Token,
-			User:         *user,
-		}, nil
+		return s.SignIn(c, user)
 	} else {
 		u := shortuuid.New()
 		user := &model.User{
@@ -383,6 +756,7 @@ func (s *Service) Magic(c *gin.Context, m *request.MagicSignup) (*model.LoginRes
 			Verified:     true,
 			Active:       true,
 			ReferralCode: u,
+			TenantID:     model.TenantIDOf(c),
 		}
 		userID, err := s.accountRepo.CreateWithMagic(user)
 		if err != nil {
@@ -395,21 +769,7 @@ func (s *Service) Magic(c *gin.Context, m *request.MagicSignup) (*model.LoginRes
 		newUser, err := s.userRepo.View(userID)
 		if err == nil { // user already exists
 			// fmt.Println(newUser)
-			token, expire, err := s.jwt.GenerateToken(newUser)
-			if err != nil {
-				return nil, apperr.New(http.StatusUnauthorized, "Unauthorized")
-			}
-			newUser.UpdateLastLogin()
-			newUser.Token = xid.New().String()
-			if err := s.userRepo.UpdateLogin(newUser); err != nil {
-				return nil, err
-			}
-			return &model.LoginResponseWithToken{
-				Token:        token,
-				Expires:      expire,
-				RefreshToken: newUser.Token,
-				User:         *newUser,
-			}, nil
+			return s.SignIn(c, newUser)
 		}
 	}
 
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/request"
+	"github.com/alpacahq/ribbit-backend/secret"
+	"github.com/alpacahq/ribbit-backend/webauthn"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPasskeys is the number of passkeys a user can register
+const maxPasskeys = 10
+
+var errInvalidPasskey = apperr.New(http.StatusUnauthorized, "Invalid passkey.")
+
+// BeginPasskeyRegistration starts registering a passkey for the current user
+func (s *Service) BeginPasskeyRegistration(c *gin.Context) (*webauthn.CreationOptions, error) {
+	u, err := s.userRepo.View(s.User(c).ID)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := s.passkeys.List(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(passkeys) >= maxPasskeys {
+		return nil, apperr.New(http.StatusConflict, "You can register up to "+strconv.Itoa(maxPasskeys)+" passkeys.")
+	}
+	challenge, err := s.passkeyChallenge(model.PasskeyRegister, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([][]byte, 0, len(passkeys))
+	for _, p := range passkeys {
+		if id, err := webauthn.DecodeID(p.CredentialID); err == nil {
+			exclude = append(exclude, id)
+		}
+	}
+	name := u.Email
+	if name == "" {
+		name = u.CountryCode + u.Mobile
+	}
+	displayName := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if displayName == "" {
+		displayName = name
+	}
+	return s.rp.CreationOptions(challenge, userHandle(u.ID), name, displayName, exclude, model.PasskeyChallengeLifetime), nil
+}
+
+// FinishPasskeyRegistration stores the passkey the current user's authenticator created
+func (s *Service) FinishPasskeyRegistration(c *gin.Context, p *request.PasskeyCredential) (*model.Passkey, error) {
+	clientData, attestation, err := decodePair(p.Response.ClientDataJSON, p.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := webauthn.Challenge(clientData)
+	if err != nil {
+		return nil, apperr.BadRequest
+	}
+	ch, err := s.passkeys.UseChallenge(challenge, model.PasskeyRegister)
+	if err != nil {
+		return nil, err
+	}
+	if ch.UserID != s.User(c).ID {
+		return nil, errInvalidPasskey
+	}
+	cred, err := s.rp.VerifyRegistration(challenge, clientData, attestation)
+	if err != nil {
+		return nil, errInvalidPasskey
+	}
+	name := strings.TrimSpace(p.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	passkey := &model.Passkey{
+		UserID:       ch.UserID,
+		CredentialID: webauthn.EncodeID(cred.ID),
+		PublicKey:    cred.PublicKey,
+		SignCount:    int64(cred.SignCount),
+		Name:         name,
+	}
+	if err := s.passkeys.Create(passkey); err != nil {
+		return nil, err
+	}
+	return passkey, nil
+}
+
+// BeginPasskeyLogin starts a passwordless login with any passkey of the user
+func (s *Service) BeginPasskeyLogin(c context.Context) (*webauthn.RequestOptions, error) {
+	// anyone can begin, so every challenge stored counts against the begin limit of the client
+	key := model.ThrottleKey(model.ThrottlePasskey, clientIP(c))
+	if err := s.throttle.Check(key, clientKey(c)); err != nil {
+		return nil, err
+	}
+	challenge, err := s.passkeyChallenge(model.PasskeyLogin, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.throttle.Fail(key); err != nil {
+		return nil, err
+	}
+	return s.rp.RequestOptions(challenge, model.PasskeyChallengeLifetime), nil
+}
+
+// FinishPasskeyLogin signs a user in with a passkey. The authenticator verified the user, e.g. with
+// a fingerprint, on a device they own, so there is no second factor to ask for.
+func (s *Service) FinishPasskeyLogin(c context.Context, p *request.PasskeyCredential) (*model.LoginResponseWithToken, error) {
+	ip := clientKey(c)
+	if err := s.throttle.Check(ip); err != nil {
+		return nil, err
+	}
+	clientData, authData, err := decodePair(p.Response.ClientDataJSON, p.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := webauthn.DecodeID(p.Response.Signature)
+	if err != nil {
+		return nil, apperr.BadRequest
+	}
+	challenge, err := webauthn.Challenge(clientData)
+	if err != nil {
+		return nil, apperr.BadRequest
+	}
+	if _, err := s.passkeys.UseChallenge(challenge, model.PasskeyLogin); err != nil {
+		return nil, err
+	}
+	id, err := webauthn.DecodeID(p.ID)
+	if err != nil {
+		return nil, apperr.BadRequest
+	}
+	passkey, err := s.passkeys.FindByCredentialID(webauthn.EncodeID(id))
+	if err != nil {
+		s.throttle.Fail(ip)
+		return nil, errInvalidPasskey
+	}
+	if p.Response.UserHandle != "" && p.Response.UserHandle != webauthn.EncodeID(userHandle(passkey.UserID)) {
+		s.throttle.Fail(ip)
+		return nil, errInvalidPasskey
+	}
+	cred := &webauthn.Credential{PublicKey: passkey.PublicKey, SignCount: uint32(passkey.SignCount)}
+	count, err := s.rp.VerifyAssertion(cred, challenge, clientData, authData, signature)
+	if err != nil {
+		s.throttle.Fail(ip)
+		return nil, errInvalidPasskey
+	}
+	passkey.SignCount = int64(count)
+	if err := s.passkeys.Used(passkey); err != nil {
+		return nil, err
+	}
+	u, err := s.userRepo.View(passkey.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.throttle.Reset(model.ThrottleKey(model.ThrottlePasskey, clientIP(c))); err != nil {
+		return nil, err
+	}
+	t, err := s.login(c, u)
+	if err != nil {
+		return nil, err
+	}
+	return &model.LoginResponseWithToken{
+		Token:        t.Token,
+		Expires:      t.Expires,
+		RefreshToken: t.RefreshToken,
+		User:         *u,
+	}, nil
+}
+
+// Passkeys returns the passkeys of the current user
+func (s *Service) Passkeys(c *gin.Context) ([]model.Passkey, error) {
+	return s.passkeys.List(s.User(c).ID)
+}
+
+// DeletePasskey removes a passkey of the current user
+func (s *Service) DeletePasskey(c *gin.Context, id int) error {
+	return s.passkeys.Delete(s.User(c).ID, id)
+}
+
+// passkeyChallenge stores a new random challenge for a ceremony and returns it base64url encoded
+func (s *Service) passkeyChallenge(ceremony string, userID int) (string, error) {
+	b, err := secret.GenerateRandomBytes(32)
+	if err != nil {
+		return "", apperr.Generic
+	}
+	challenge := webauthn.EncodeID(b)
+	err = s.passkeys.CreateChallenge(&model.PasskeyChallenge{
+		Challenge: challenge,
+		Ceremony:  ceremony,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(model.PasskeyChallengeLifetime),
+	})
+	if err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// userHandle is the WebAuthn user handle of a user, which authenticators return on login
+func userHandle(userID int) []byte {
+	return []byte(strconv.Itoa(userID))
+}
+
+// decodePair decodes the client data and the attestation object or authenticator data of a credential
+func decodePair(clientData, data string) ([]byte, []byte, error) {
+	cd, err := webauthn.DecodeID(clientData)
+	if err != nil || len(cd) == 0 {
+		return nil, nil, apperr.BadRequest
+	}
+	d, err := webauthn.DecodeID(data)
+	if err != nil || len(d) == 0 {
+		return nil, nil, apperr.BadRequest
+	}
+	return cd, d, nil
+}
@@ -0,0 +1,203 @@
+package avatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/storage"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxUploadSize is the largest avatar we accept
+	MaxUploadSize = 5 << 20
+	// maxPixels guards against images that decode to huge bitmaps
+	maxPixels = 40000000
+	keyPrefix = "avatars/"
+	// legacyDir holds avatars uploaded before object storage, named <user id>-<file name>
+	legacyDir = "public/users"
+)
+
+// ThumbnailSizes are the square sizes, in pixels, generated for every avatar
+var ThumbnailSizes = []int{64, 128, 256}
+
+// Service represents the avatar application service
+type Service struct {
+	userRepo    model.UserRepo
+	accountRepo model.AccountRepo
+	rbac        model.RBACService
+	store       storage.Service
+	expiry      time.Duration
+	log         *zap.Logger
+}
+
+// NewAvatarService creates a new avatar application service.
+// URLs to avatars are signed and valid for expiry.
+func NewAvatarService(userRepo model.UserRepo, accountRepo model.AccountRepo, rbac model.RBACService, store storage.Service, expiry time.Duration, log *zap.Logger) *Service {
+	return &Service{
+		userRepo:    userRepo,
+		accountRepo: accountRepo,
+		rbac:        rbac,
+		store:       store,
+		expiry:      expiry,
+		log:         log,
+	}
+}
+
+// Upload stores a JPEG or PNG image with its thumbnails as the user's avatar and deletes the previous one.
+// Files are named after the hash of their content, so identical uploads share their objects.
+func (s *Service) Upload(c *gin.Context, id int, data []byte) (*model.User, error) {
+	if !s.rbac.EnforceUser(c, id) {
+		return nil, apperr.New(http.StatusForbidden, "Forbidden")
+	}
+	if len(data) > MaxUploadSize {
+		return nil, apperr.New(http.StatusBadRequest, "The uploaded file is too big. Please choose an file that's less than 5MB in size")
+	}
+	var ext, contentType string
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		ext, contentType = ".jpg", "image/jpeg"
+	case "image/png":
+		ext, contentType = ".png", "image/png"
+	default:
+		return nil, apperr.New(http.StatusBadRequest, "The provided file format is not allowed. Please upload a JPEG or PNG image")
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > maxPixels {
+		return nil, apperr.New(http.StatusUnprocessableEntity, "The uploaded image is malformed or too large.")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, apperr.New(http.StatusUnprocessableEntity, "The uploaded image is malformed or too large.")
+	}
+
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	base := keyPrefix + hex.EncodeToString(sum[:])
+	// the original is re-encoded as well, which strips metadata such as EXIF locations
+	objects := map[string]image.Image{base + ext: img}
+	for _, size := range ThumbnailSizes {
+		objects[base+"_"+strconv.Itoa(size)+ext] = thumbnail(img, size)
+	}
+	for key, im := range objects {
+		var buf bytes.Buffer
+		if ext == ".png" {
+			err = png.Encode(&buf, im)
+		} else {
+			err = jpeg.Encode(&buf, im, &jpeg.Options{Quality: 85})
+		}
+		if err == nil {
+			err = s.store.Put(key, buf.Bytes(), contentType)
+		}
+		if err != nil {
+			s.log.Error("AvatarService Error: failed to store avatar", zap.String("key", key), zap.Error(err))
+			return nil, apperr.New(http.StatusInternalServerError, "Couldn't save your avatar. Try again later.")
+		}
+	}
+
+	old := u.Avatar
+	u.Avatar = base + ext
+	if err := s.accountRepo.UpdateAvatar(u); err != nil {
+		return nil, err
+	}
+	if old != u.Avatar {
+		s.Remove(old)
+	}
+	u.AvatarURLs = s.URLs(u.Avatar)
+	return u, nil
+}
+
+// Delete removes the user's avatar
+func (s *Service) Delete(c *gin.Context, id int) error {
+	if !s.rbac.EnforceUser(c, id) {
+		return apperr.New(http.StatusForbidden, "Forbidden")
+	}
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return err
+	}
+	old := u.Avatar
+	u.Avatar = ""
+	if err := s.accountRepo.UpdateAvatar(u); err != nil {
+		return err
+	}
+	s.Remove(old)
+	return nil
+}
+
+// URLs returns signed URLs to an avatar and its thumbnails, keyed by "original" and thumbnail size.
+// Avatars uploaded before object storage have no thumbnails and are served from /file/users.
+func (s *Service) URLs(avatar string) map[string]string {
+	if avatar == "" {
+		return nil
+	}
+	if !strings.HasPrefix(avatar, keyPrefix) {
+		return map[string]string{"original": "/file/users/" + avatar}
+	}
+	urls := map[string]string{}
+	ext := filepath.Ext(avatar)
+	base := strings.TrimSuffix(avatar, ext)
+	keys := map[string]string{"original": avatar}
+	for _, size := range ThumbnailSizes {
+		keys[strconv.Itoa(size)] = base + "_" + strconv.Itoa(size) + ext
+	}
+	for name, key := range keys {
+		url, err := s.store.SignedURL(key, s.expiry)
+		if err != nil {
+			s.log.Warn("AvatarService Error: failed to sign url", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		urls[name] = url
+	}
+	return urls
+}
+
+// Remove deletes a replaced or erased avatar unless another user still references the same content.
+// Failures are logged only: the user's avatar has already been updated.
+func (s *Service) Remove(avatar string) {
+	if avatar == "" {
+		return
+	}
+	if !strings.HasPrefix(avatar, keyPrefix) {
+		name := filepath.Base(avatar)
+		if name != avatar || name == "." || name == ".." {
+			return
+		}
+		if err := os.Remove(filepath.Join(legacyDir, name)); err != nil && !os.IsNotExist(err) {
+			s.log.Warn("AvatarService Error: failed to delete legacy avatar", zap.String("avatar", avatar), zap.Error(err))
+		}
+		return
+	}
+	if n, err := s.userRepo.CountByAvatar(avatar); err != nil || n > 0 {
+		return
+	}
+	ext := filepath.Ext(avatar)
+	base := strings.TrimSuffix(avatar, ext)
+	keys := []string{avatar}
+	for _, size := range ThumbnailSizes {
+		keys = append(keys, base+"_"+strconv.Itoa(size)+ext)
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(key); err != nil {
+			s.log.Warn("AvatarService Error: failed to delete avatar", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
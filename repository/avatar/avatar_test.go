@@ -0,0 +1,85 @@
+package avatar_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/avatar"
+	"github.com/alpacahq/ribbit-backend/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func pngImage(w, h int, c color.Color) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestUploadAndDelete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "avatars")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	store := storage.NewLocal(dir, "http://localhost/storage", []byte("key"))
+
+	user := &model.User{ID: 1}
+	references := 0
+	userRepo := &mockdb.User{
+		ViewFn:          func(int) (*model.User, error) { u := *user; return &u, nil },
+		CountByAvatarFn: func(string) (int, error) { return references, nil },
+	}
+	accountRepo := &mockdb.Account{
+		UpdateAvatarFn: func(u *model.User) error { user.Avatar = u.Avatar; return nil },
+	}
+	rbac := &mock.RBAC{EnforceUserFn: func(*gin.Context, int) bool { return true }}
+	log, _ := zap.NewDevelopment()
+	svc := avatar.NewAvatarService(userRepo, accountRepo, rbac, store, time.Hour, log)
+
+	u, err := svc.Upload(nil, 1, pngImage(300, 200, color.RGBA{R: 255, A: 255}))
+	assert.Nil(t, err)
+	first := u.Avatar
+	assert.Regexp(t, `^avatars/[0-9a-f]{64}\.png$`, first)
+	assert.Len(t, u.AvatarURLs, len(avatar.ThumbnailSizes)+1)
+
+	thumb, err := store.Get(first[:len(first)-4] + "_64.png")
+	assert.Nil(t, err)
+	img, err := png.Decode(bytes.NewReader(thumb))
+	assert.Nil(t, err)
+	assert.Equal(t, image.Rect(0, 0, 64, 64), img.Bounds())
+	r, _, _, _ := img.At(32, 32).RGBA()
+	assert.Equal(t, uint32(0xffff), r)
+
+	// the previous avatar is kept while another user still references it
+	references = 1
+	_, err = svc.Upload(nil, 1, pngImage(100, 100, color.RGBA{B: 255, A: 255}))
+	assert.Nil(t, err)
+	_, err = store.Get(first)
+	assert.Nil(t, err)
+
+	references = 0
+	second := user.Avatar
+	assert.Nil(t, svc.Delete(nil, 1))
+	assert.Equal(t, "", user.Avatar)
+	_, err = store.Get(second)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	_, err = svc.Upload(nil, 1, []byte("GIF89a not an image we accept"))
+	assert.NotNil(t, err)
+}
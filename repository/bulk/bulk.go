@@ -0,0 +1,260 @@
+package bulk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+
+	"github.com/alpacahq/ribbit-backend/model"
+
+	mailer "github.com/alpacahq/ribbit-backend/mail"
+)
+
+// File formats of imports and exports
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// NewBulkService creates the bulk user import and export application service
+func NewBulkService(userRepo model.UserRepo, accountRepo model.AccountRepo, m mailer.Service) *Service {
+	return &Service{userRepo, accountRepo, m}
+}
+
+// Service represents the bulk user import and export application service, used by admin commands
+type Service struct {
+	userRepo    model.UserRepo
+	accountRepo model.AccountRepo
+	mail        mailer.Service
+}
+
+// Row is a user to import. CSV files have a header row naming the columns, in any order.
+type Row struct {
+	Email       string `json:"email"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	CountryCode string `json:"country_code"`
+	Mobile      string `json:"mobile"`
+	// Role is a role name, e.g. admin. Rows without one get the default role.
+	Role string `json:"role"`
+}
+
+// rowColumns are the CSV columns of a Row
+var rowColumns = map[string]func(*Row) *string{
+	"email":        func(r *Row) *string { return &r.Email },
+	"first_name":   func(r *Row) *string { return &r.FirstName },
+	"last_name":    func(r *Row) *string { return &r.LastName },
+	"country_code": func(r *Row) *string { return &r.CountryCode },
+	"mobile":       func(r *Row) *string { return &r.Mobile },
+	"role":         func(r *Row) *string { return &r.Role },
+}
+
+// ImportOptions are how users are imported
+type ImportOptions struct {
+	Format string
+	// Role is the role of rows without one
+	Role model.AccessRole
+	// Verified marks the users verified and active, for email addresses verified elsewhere
+	Verified bool
+	// Invite emails every imported user how to set their password
+	Invite bool
+	// DryRun validates the rows and reports what would be imported, without importing anything
+	DryRun bool
+	// Tenant is the tenant the users belong to and are invited by, nil for the default tenant
+	Tenant *model.Tenant
+}
+
+// RowError is why a row wasn't imported, or, with Imported set, what failed after importing it
+type RowError struct {
+	Line     int    `json:"line"`
+	Email    string `json:"email"`
+	Error    string `json:"error"`
+	Imported bool   `json:"imported"`
+}
+
+// ImportResult reports on an import. Rows are numbered by line, the CSV header being line 1.
+type ImportResult struct {
+	Rows     int
+	Imported int
+	Invited  int
+	Errors   []RowError
+}
+
+// Import creates a user for every valid row of r, skipping rows that are invalid or name an existing
+// user. Errors of single rows are reported in the result; an error is returned only when r can't be read.
+func (s *Service) Import(r io.Reader, opts *ImportOptions) (*ImportResult, error) {
+	res := &ImportResult{}
+	seen := map[string]int{}
+	m := s.mail.For(opts.Tenant)
+	return res, readRows(r, opts.Format, func(line int, row *Row, err error) {
+		res.Rows++
+		if err == nil {
+			err = s.importRow(row, opts, seen, line)
+		}
+		if err != nil {
+			res.Errors = append(res.Errors, RowError{Line: line, Email: emailOf(row), Error: err.Error()})
+			return
+		}
+		res.Imported++
+		if !opts.Invite || opts.DryRun {
+			return
+		}
+		if err := m.SendInviteEmail(row.Email); err != nil {
+			res.Errors = append(res.Errors, RowError{Line: line, Email: row.Email, Error: "couldn't send the invite: " + err.Error(), Imported: true})
+			return
+		}
+		res.Invited++
+	})
+}
+
+// importRow validates a row and creates its user unless it's a dry run. seen holds the email
+// addresses and mobile numbers of the rows so far, with their lines.
+func (s *Service) importRow(row *Row, opts *ImportOptions, seen map[string]int, line int) error {
+	role := opts.Role
+	if row.Role != "" {
+		var ok bool
+		if role, ok = model.ParseRole(row.Role); !ok {
+			return fmt.Errorf("unknown role %q", row.Role)
+		}
+	}
+	if row.Email == "" {
+		return fmt.Errorf("email is required")
+	}
+	if a, err := mail.ParseAddress(row.Email); err != nil || a.Address != row.Email {
+		return fmt.Errorf("invalid email %q", row.Email)
+	}
+	if (row.Mobile == "") != (row.CountryCode == "") {
+		return fmt.Errorf("mobile and country_code go together")
+	}
+
+	keys := []string{strings.ToLower(row.Email)}
+	if row.Mobile != "" {
+		keys = append(keys, row.CountryCode+row.Mobile)
+	}
+	for _, k := range keys {
+		if prev, ok := seen[k]; ok {
+			return fmt.Errorf("duplicate of line %d", prev)
+		}
+	}
+	for _, k := range keys {
+		seen[k] = line
+	}
+	if _, err := s.userRepo.FindByEmail(row.Email); err == nil {
+		return fmt.Errorf("a user with this email already exists")
+	}
+	if row.Mobile != "" {
+		if _, err := s.userRepo.FindByMobile(row.CountryCode, row.Mobile); err == nil {
+			return fmt.Errorf("a user with this mobile number already exists")
+		}
+	}
+	if opts.DryRun {
+		return nil
+	}
+
+	_, err := s.accountRepo.Create(&model.User{
+		Email:       row.Email,
+		FirstName:   row.FirstName,
+		LastName:    row.LastName,
+		CountryCode: row.CountryCode,
+		Mobile:      row.Mobile,
+		RoleID:      int(role),
+		Verified:    opts.Verified,
+		Active:      opts.Verified,
+		TenantID:    tenantID(opts.Tenant),
+	})
+	return err
+}
+
+// readRows calls fn with every row of r and its line, or why the row couldn't be read
+func readRows(r io.Reader, format string, fn func(line int, row *Row, err error)) error {
+	switch format {
+	case FormatCSV:
+		return readCSV(r, fn)
+	case FormatJSONL:
+		return readJSONL(r, fn)
+	}
+	return fmt.Errorf("unknown format %q, expected %s or %s", format, FormatCSV, FormatJSONL)
+}
+
+func readCSV(r io.Reader, fn func(int, *Row, error)) error {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fields := make([]func(*Row) *string, len(header))
+	hasEmail := false
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(h))
+		f, ok := rowColumns[name]
+		if !ok {
+			return fmt.Errorf("unknown column %q", h)
+		}
+		fields[i] = f
+		hasEmail = hasEmail || name == "email"
+	}
+	if !hasEmail {
+		return fmt.Errorf("the email column is required")
+	}
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return err
+			}
+			fn(line, nil, err)
+			continue
+		}
+		row := new(Row)
+		for i, v := range record {
+			*fields[i](row) = strings.TrimSpace(v)
+		}
+		fn(line, row, nil)
+	}
+}
+
+func readJSONL(r io.Reader, fn func(int, *Row, error)) error {
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		row := new(Row)
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(row); err != nil {
+			fn(line, nil, err)
+			continue
+		}
+		row.Email = strings.TrimSpace(row.Email)
+		fn(line, row, nil)
+	}
+	return sc.Err()
+}
+
+func tenantID(t *model.Tenant) int {
+	if t == nil {
+		return 0
+	}
+	return t.ID
+}
+
+func emailOf(row *Row) string {
+	if row == nil {
+		return ""
+	}
+	return row.Email
+}
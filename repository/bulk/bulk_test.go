@@ -0,0 +1,180 @@
+package bulk_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/bulk"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// existing is the email address and mobile number of a user in the database
+var existing = &model.User{ID: 1, Email: "taken@mail.com", CountryCode: "+65", Mobile: "91919191"}
+
+func service(created *[]*model.User, invited *[]string) *bulk.Service {
+	userRepo := &mockdb.User{
+		FindByEmailFn: func(email string) (*model.User, error) {
+			if email == existing.Email {
+				return existing, nil
+			}
+			return nil, apperr.NotFound
+		},
+		FindByMobileFn: func(cc, mobile string) (*model.User, error) {
+			if cc+mobile == existing.CountryCode+existing.Mobile {
+				return existing, nil
+			}
+			return nil, apperr.NotFound
+		},
+	}
+	accountRepo := &mockdb.Account{
+		CreateFn: func(u *model.User) (*model.User, error) {
+			*created = append(*created, u)
+			return u, nil
+		},
+	}
+	m := &mock.Mail{
+		SendInviteEmailFn: func(email string) error {
+			*invited = append(*invited, email)
+			return nil
+		},
+	}
+	return bulk.NewBulkService(userRepo, accountRepo, m)
+}
+
+func TestImport(t *testing.T) {
+	csv := `email,first_name,last_name,country_code,mobile,role
+jane@mail.com,Jane,Doe,+65,81818181,
+ops@mail.com,Ops,Team,,,Admin
+not-an-email,A,B,,,
+JANE@mail.com,Jane,Again,,,
+taken@mail.com,Taken,User,,,
+mobile@mail.com,No,Code,,81818182,
+role@mail.com,Bad,Role,,,owner
+other@mail.com,Same,Mobile,+65,91919191,
+`
+	cases := []struct {
+		name         string
+		opts         bulk.ImportOptions
+		wantCreated  int
+		wantInvited  int
+		wantImported int
+	}{
+		{
+			name:         "Imports valid rows with their roles",
+			opts:         bulk.ImportOptions{Format: bulk.FormatCSV, Role: model.UserRole, Invite: true},
+			wantCreated:  2,
+			wantInvited:  2,
+			wantImported: 2,
+		},
+		{
+			name:         "Dry runs import nothing",
+			opts:         bulk.ImportOptions{Format: bulk.FormatCSV, Role: model.UserRole, Invite: true, DryRun: true},
+			wantImported: 2,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var created []*model.User
+			var invited []string
+			res, err := service(&created, &invited).Import(strings.NewReader(csv), &tt.opts)
+			assert.Nil(t, err)
+			assert.Equal(t, 8, res.Rows)
+			assert.Equal(t, tt.wantImported, res.Imported)
+			assert.Len(t, created, tt.wantCreated)
+			assert.Len(t, invited, tt.wantInvited)
+			lines := map[int]string{}
+			for _, e := range res.Errors {
+				lines[e.Line] = e.Error
+			}
+			assert.Equal(t, map[int]string{
+				4: `invalid email "not-an-email"`,
+				5: "duplicate of line 2",
+				6: "a user with this email already exists",
+				7: "mobile and country_code go together",
+				8: `unknown role "owner"`,
+				9: "a user with this mobile number already exists",
+			}, lines)
+			if tt.wantCreated > 0 {
+				assert.Equal(t, int(model.UserRole), created[0].RoleID)
+				assert.Equal(t, int(model.AdminRole), created[1].RoleID)
+				assert.False(t, created[0].Verified)
+			}
+		})
+	}
+}
+
+func TestImportJSONL(t *testing.T) {
+	var created []*model.User
+	var invited []string
+	jsonl := `{"email":"jane@mail.com","first_name":"Jane"}
+
+{"email":"joe@mail.com","password":"secret"}
+`
+	opts := &bulk.ImportOptions{Format: bulk.FormatJSONL, Role: model.UserRole, Verified: true, Tenant: &model.Tenant{ID: 2}}
+	res, err := service(&created, &invited).Import(strings.NewReader(jsonl), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res.Rows)
+	assert.Equal(t, 1, res.Imported)
+	if assert.Len(t, res.Errors, 1) {
+		assert.Equal(t, 3, res.Errors[0].Line)
+		assert.Contains(t, res.Errors[0].Error, "unknown field")
+	}
+	if assert.Len(t, created, 1) {
+		assert.True(t, created[0].Verified)
+		assert.True(t, created[0].Active)
+		assert.Equal(t, 2, created[0].TenantID)
+	}
+}
+
+func TestImportUnknownColumn(t *testing.T) {
+	var created []*model.User
+	var invited []string
+	_, err := service(&created, &invited).Import(strings.NewReader("email,password\njane@mail.com,secret\n"), &bulk.ImportOptions{Format: bulk.FormatCSV})
+	assert.EqualError(t, err, `unknown column "password"`)
+	assert.Empty(t, created)
+}
+
+func TestExport(t *testing.T) {
+	var queries []model.ListQuery
+	users := make([]model.User, 501)
+	for i := range users {
+		users[i] = model.User{ID: i + 1, Email: "user@mail.com", Verified: true, Role: &model.Role{AccessLevel: model.UserRole}}
+	}
+	userRepo := &mockdb.User{
+		ListFn: func(q *model.ListQuery, p *model.Pagination) ([]model.User, int, error) {
+			queries = append(queries, *q)
+			if q.Cursor == "" {
+				return users[:p.Limit], len(users), nil
+			}
+			return users[p.Limit:], len(users), nil
+		},
+	}
+	s := bulk.NewBulkService(userRepo, &mockdb.Account{}, &mock.Mail{})
+
+	verified := true
+	buf := new(bytes.Buffer)
+	n, err := s.Export(buf, bulk.FormatCSV, []string{"id", "email", "role", "verified"}, &model.ListQuery{Verified: &verified, Sort: "email"})
+	assert.Nil(t, err)
+	assert.Equal(t, 501, n)
+	if assert.Len(t, queries, 2) {
+		assert.Equal(t, "id", queries[0].Sort, "exports page through users by ID")
+		assert.Equal(t, &verified, queries[0].Verified)
+		assert.NotEmpty(t, queries[1].Cursor)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 502)
+	assert.Equal(t, "id,email,role,verified", lines[0])
+	assert.Equal(t, "1,user@mail.com,user,true", lines[1])
+
+	buf.Reset()
+	queries = nil
+	_, err = s.Export(buf, bulk.FormatJSONL, []string{"id", "tax_id"}, &model.ListQuery{})
+	assert.EqualError(t, err, `unknown column "tax_id"`)
+	assert.Empty(t, queries)
+}
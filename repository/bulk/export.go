@@ -0,0 +1,124 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// exportBatch is how many users Export reads at once
+const exportBatch = 500
+
+// ExportColumns are the columns users can be exported with, and the value of each for a user.
+// Sensitive fields, like tax IDs, can't be exported.
+var ExportColumns = map[string]func(*model.User) interface{}{
+	"id":             func(u *model.User) interface{} { return u.ID },
+	"email":          func(u *model.User) interface{} { return u.Email },
+	"first_name":     func(u *model.User) interface{} { return u.FirstName },
+	"last_name":      func(u *model.User) interface{} { return u.LastName },
+	"username":       func(u *model.User) interface{} { return u.Username },
+	"country_code":   func(u *model.User) interface{} { return u.CountryCode },
+	"mobile":         func(u *model.User) interface{} { return u.Mobile },
+	"role":           func(u *model.User) interface{} { return roleName(u) },
+	"verified":       func(u *model.User) interface{} { return u.Verified },
+	"active":         func(u *model.User) interface{} { return u.Active },
+	"account_id":     func(u *model.User) interface{} { return u.AccountID },
+	"account_number": func(u *model.User) interface{} { return u.AccountNumber },
+	"account_status": func(u *model.User) interface{} { return u.AccountStatus },
+	"referral_code":  func(u *model.User) interface{} { return u.ReferralCode },
+	"created_at":     func(u *model.User) interface{} { return u.CreatedAt.UTC().Format(time.RFC3339) },
+	"last_login":     func(u *model.User) interface{} { return formatTime(u.LastLogin) },
+	"tenant_id":      func(u *model.User) interface{} { return u.TenantID },
+}
+
+// DefaultExportColumns are the columns users are exported with unless others are asked for
+var DefaultExportColumns = []string{"id", "email", "first_name", "last_name", "country_code", "mobile", "role", "verified", "active", "account_status", "created_at"}
+
+// Export writes the users lq filters for to w, by ID, as CSV with a header row or as a JSON object per line.
+// It returns how many users were written.
+func (s *Service) Export(w io.Writer, format string, columns []string, lq *model.ListQuery) (int, error) {
+	if len(columns) == 0 {
+		columns = DefaultExportColumns
+	}
+	for _, c := range columns {
+		if _, ok := ExportColumns[c]; !ok {
+			return 0, fmt.Errorf("unknown column %q", c)
+		}
+	}
+	var write func(*model.User) error
+	bw := bufio.NewWriter(w)
+	flush := bw.Flush
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(bw)
+		if err := cw.Write(columns); err != nil {
+			return 0, err
+		}
+		write = func(u *model.User) error {
+			record := make([]string, len(columns))
+			for i, c := range columns {
+				record[i] = fmt.Sprint(ExportColumns[c](u))
+			}
+			return cw.Write(record)
+		}
+		flush = func() error {
+			if cw.Flush(); cw.Error() != nil {
+				return cw.Error()
+			}
+			return bw.Flush()
+		}
+	case FormatJSONL:
+		enc := json.NewEncoder(bw)
+		write = func(u *model.User) error {
+			obj := make(map[string]interface{}, len(columns))
+			for _, c := range columns {
+				obj[c] = ExportColumns[c](u)
+			}
+			return enc.Encode(obj)
+		}
+	default:
+		return 0, fmt.Errorf("unknown format %q, expected %s or %s", format, FormatCSV, FormatJSONL)
+	}
+
+	q := *lq
+	q.Sort, q.Desc, q.Cursor = "id", false, ""
+	count := 0
+	for {
+		users, _, err := s.userRepo.List(&q, &model.Pagination{Limit: exportBatch})
+		if err != nil {
+			return count, err
+		}
+		for i := range users {
+			if err := write(&users[i]); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(users) < exportBatch {
+			break
+		}
+		q.Cursor = q.NextCursor(&users[len(users)-1])
+	}
+	return count, flush()
+}
+
+// roleName returns the name of the role of a user, or an empty string when it's unknown
+func roleName(u *model.User) string {
+	if u.Role == nil {
+		return ""
+	}
+	return model.RoleNames[u.Role.AccessLevel]
+}
+
+// formatTime formats t as RFC 3339, or returns an empty string for nil
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,178 @@
+package closure
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/broker"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pendingTransfers are the statuses of broker transfers that haven't settled yet
+var pendingTransfers = map[string]bool{
+	"QUEUED":           true,
+	"APPROVAL_PENDING": true,
+	"PENDING":          true,
+	"SENT_TO_CLEARING": true,
+	"APPROVED":         true,
+}
+
+// profile is a user's own profile in their data export. It has the user's JSON fields without the
+// masking of User.MarshalJSON, as users are entitled to the full values of their own data.
+type profile model.User
+
+// NewClosureService creates the account closure and data export application service
+func NewClosureService(userRepo model.UserRepo, rewards model.UserRewardRepo, auditRepo model.AuditRepo, audit model.AuditLog, brokers broker.Clients, tenants model.TenantStore) *Service {
+	return &Service{userRepo, rewards, auditRepo, audit, brokers, tenants}
+}
+
+// Service represents the account closure and data export application service
+type Service struct {
+	userRepo  model.UserRepo
+	rewards   model.UserRewardRepo
+	auditRepo model.AuditRepo
+	audit     model.AuditLog
+	brokers   broker.Clients
+	tenants   model.TenantStore
+}
+
+// Close closes the broker account of the current user and then their account with us. Users have to
+// close their positions and wait for pending transfers first. Their personal data is anonymized after
+// the retention period, see UserRepo.Anonymize.
+func (s *Service) Close(c *gin.Context, reason string) error {
+	id := c.GetInt("id")
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return err
+	}
+	if u.AccountID != "" {
+		brk, err := s.broker(u)
+		if err != nil {
+			return err
+		}
+		if err := checkSettled(brk, u.AccountID); err != nil {
+			return err
+		}
+		if err := brk.Close(u.AccountID); err != nil {
+			return err
+		}
+	}
+	if err := s.userRepo.Close(u); err != nil {
+		return err
+	}
+	return s.audit.Record(c, &model.AuditEvent{
+		Action:       model.AuditAccountClose,
+		TargetUserID: id,
+		Details:      map[string]interface{}{"reason": reason},
+	})
+}
+
+// checkSettled returns a conflict unless the broker account has no open positions and pending transfers
+func checkSettled(brk broker.Service, accountID string) error {
+	body, err := brk.Positions(accountID)
+	if err != nil {
+		return err
+	}
+	var positions []json.RawMessage
+	if err := json.Unmarshal(body, &positions); err != nil {
+		return apperr.Generic
+	}
+	if len(positions) > 0 {
+		return apperr.New(http.StatusConflict, "Close your positions before closing your account.")
+	}
+	body, err = brk.Transfers(accountID)
+	if err != nil {
+		return err
+	}
+	var transfers []struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &transfers); err != nil {
+		return apperr.Generic
+	}
+	for _, t := range transfers {
+		if pendingTransfers[t.Status] {
+			return apperr.New(http.StatusConflict, "Wait for your pending transfers to complete before closing your account.")
+		}
+	}
+	return nil
+}
+
+// broker returns the broker client of the tenant of u
+func (s *Service) broker(u *model.User) (broker.Closer, error) {
+	t, err := s.tenants.ByID(u.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	return s.brokers.For(t), nil
+}
+
+// Export returns a ZIP archive of the current user's data: their profile, orders, transfers and
+// rewards as JSON, and their audit history as JSON lines, oldest first
+func (s *Service) Export(c *gin.Context) ([]byte, error) {
+	id := c.GetInt("id")
+	u, err := s.userRepo.View(id)
+	if err != nil {
+		return nil, err
+	}
+	orders, transfers := json.RawMessage(`[]`), json.RawMessage(`[]`)
+	if u.AccountID != "" {
+		brk, err := s.broker(u)
+		if err != nil {
+			return nil, err
+		}
+		if orders, err = brk.Orders(u.AccountID); err != nil {
+			return nil, err
+		}
+		if transfers, err = brk.Transfers(u.AccountID); err != nil {
+			return nil, err
+		}
+	}
+	rewards, err := s.rewards.List(id)
+	if err != nil {
+		return nil, err
+	}
+	if rewards == nil {
+		rewards = []model.UserReward{}
+	}
+
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name, v := range map[string]interface{}{
+		"profile.json":   profile(*u),
+		"orders.json":    orders,
+		"transfers.json": transfers,
+		"rewards.json":   rewards,
+	} {
+		f, err := zw.Create(name)
+		if err != nil {
+			return nil, apperr.Generic
+		}
+		if err := json.NewEncoder(f).Encode(v); err != nil {
+			return nil, apperr.Generic
+		}
+	}
+	f, err := zw.Create("audit.jsonl")
+	if err != nil {
+		return nil, apperr.Generic
+	}
+	enc := json.NewEncoder(f)
+	if err := s.auditRepo.ForEach(&model.AuditFilter{TargetUserID: id}, func(e *model.AuditEvent) error {
+		return enc.Encode(e)
+	}); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, apperr.Generic
+	}
+
+	if err := s.audit.Record(c, &model.AuditEvent{Action: model.AuditDataExport, TargetUserID: id}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
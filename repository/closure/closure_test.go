@@ -0,0 +1,191 @@
+package closure_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/closure"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func current(id int) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/account/close", nil)
+	c.Set("id", id)
+	return c
+}
+
+func TestClose(t *testing.T) {
+	cases := []struct {
+		name       string
+		user       *model.User
+		positions  string
+		transfers  string
+		closeErr   error
+		wantBroker bool
+		wantStatus int
+		wantClosed bool
+	}{
+		{
+			name:       "Closes a settled account",
+			user:       &model.User{ID: 2, AccountID: "acc-2", Active: true},
+			positions:  `[]`,
+			transfers:  `[{"status":"COMPLETE"},{"status":"CANCELED"}]`,
+			wantBroker: true,
+			wantClosed: true,
+		},
+		{
+			name:       "Closes users without a broker account",
+			user:       &model.User{ID: 3, Active: true},
+			wantClosed: true,
+		},
+		{
+			name:       "Refuses with open positions",
+			user:       &model.User{ID: 2, AccountID: "acc-2"},
+			positions:  `[{"symbol":"AAPL","qty":"1"}]`,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Refuses with pending transfers",
+			user:       &model.User{ID: 2, AccountID: "acc-2"},
+			positions:  `[]`,
+			transfers:  `[{"status":"COMPLETE"},{"status":"QUEUED"}]`,
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "Keeps the account when the broker refuses",
+			user:       &model.User{ID: 2, AccountID: "acc-2"},
+			positions:  `[]`,
+			transfers:  `[]`,
+			closeErr:   apperr.New(http.StatusUnprocessableEntity, "account has a balance"),
+			wantBroker: true,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []*model.AuditEvent
+			brokerClosed, closed := false, false
+			userRepo := &mockdb.User{
+				ViewFn: func(id int) (*model.User, error) { return tt.user, nil },
+				CloseFn: func(u *model.User) error {
+					u.Close()
+					closed = true
+					return nil
+				},
+			}
+			brk := &mock.Broker{
+				PositionsFn: func(string) (json.RawMessage, error) { return json.RawMessage(tt.positions), nil },
+				TransfersFn: func(string) (json.RawMessage, error) { return json.RawMessage(tt.transfers), nil },
+				CloseFn: func(string) error {
+					brokerClosed = true
+					return tt.closeErr
+				},
+			}
+			audit := &mock.AuditLog{
+				RecordFn: func(c *gin.Context, e *model.AuditEvent) error {
+					events = append(events, e)
+					return nil
+				},
+			}
+			s := closure.NewClosureService(userRepo, &mockdb.UserReward{}, &mockdb.Audit{}, audit, brk, &mock.Tenants{})
+			err := s.Close(current(tt.user.ID), "moving")
+			assert.Equal(t, tt.wantBroker, brokerClosed)
+			assert.Equal(t, tt.wantClosed, closed)
+			if tt.wantStatus != 0 {
+				assert.Equal(t, tt.wantStatus, err.(*apperr.APPError).Status)
+				assert.Empty(t, events)
+				return
+			}
+			assert.Nil(t, err)
+			assert.False(t, tt.user.Active)
+			assert.NotNil(t, tt.user.ClosedAt)
+			if assert.Len(t, events, 1) {
+				assert.Equal(t, model.AuditAccountClose, events[0].Action)
+				assert.Equal(t, tt.user.ID, events[0].TargetUserID)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	var recorded []string
+	var filter *model.AuditFilter
+	var tenantID int
+	userRepo := &mockdb.User{
+		ViewFn: func(id int) (*model.User, error) {
+			return &model.User{ID: id, Email: "jane@mail.com", AccountID: "acc-2", TenantID: 3, TaxID: "123456789", DOB: "1990-04-23"}, nil
+		},
+	}
+	brk := &mock.Broker{
+		OrdersFn:    func(string) (json.RawMessage, error) { return json.RawMessage(`[{"id":"o-1"}]`), nil },
+		TransfersFn: func(string) (json.RawMessage, error) { return json.RawMessage(`[{"id":"t-1"}]`), nil },
+	}
+	rewards := &mockdb.UserReward{
+		ListFn: func(int) ([]model.UserReward, error) { return nil, nil },
+	}
+	auditRepo := &mockdb.Audit{
+		ForEachFn: func(f *model.AuditFilter, fn func(*model.AuditEvent) error) error {
+			filter = f
+			for _, e := range []model.AuditEvent{{ID: 1, Action: model.AuditPasswordChange}, {ID: 2, Action: model.AuditBankLink}} {
+				if err := fn(&e); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	audit := &mock.AuditLog{
+		RecordFn: func(c *gin.Context, e *model.AuditEvent) error {
+			recorded = append(recorded, e.Action)
+			return nil
+		},
+	}
+	tenants := &mock.Tenants{
+		ByIDFn: func(id int) (*model.Tenant, error) {
+			tenantID = id
+			return &model.Tenant{ID: id}, nil
+		},
+	}
+	s := closure.NewClosureService(userRepo, rewards, auditRepo, audit, brk, tenants)
+
+	data, err := s.Export(current(2))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{model.AuditDataExport}, recorded)
+	assert.Equal(t, 2, filter.TargetUserID)
+	assert.Equal(t, 3, tenantID, "orders and transfers come from the broker of the user's tenant")
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(b)
+	}
+	assert.Len(t, files, 5)
+	assert.Contains(t, files["profile.json"], `"email":"jane@mail.com"`)
+	assert.Contains(t, files["profile.json"], `"tax_id":"123456789"`, "users get their own data unmasked")
+	assert.Contains(t, files["profile.json"], `"dob":"1990-04-23"`)
+	assert.JSONEq(t, `[{"id":"o-1"}]`, files["orders.json"])
+	assert.JSONEq(t, `[{"id":"t-1"}]`, files["transfers.json"])
+	assert.JSONEq(t, `[]`, files["rewards.json"])
+	assert.Equal(t, 2, bytes.Count([]byte(files["audit.jsonl"]), []byte("\n")))
+}
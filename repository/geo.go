@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/alpacahq/ribbit-backend/geo"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+const geoBatchSize = 1000
+
+// geoIndexes support prefix searches on names directly in Postgres
+var geoIndexes = []string{
+	`CREATE UNIQUE INDEX IF NOT EXISTS geo_states_country_code_code_idx ON geo_states (country_code, code)`,
+	`CREATE INDEX IF NOT EXISTS geo_countries_name_prefix_idx ON geo_countries (lower(name) text_pattern_ops)`,
+	`CREATE INDEX IF NOT EXISTS geo_states_name_prefix_idx ON geo_states (country_code, lower(name) text_pattern_ops)`,
+	`CREATE INDEX IF NOT EXISTS geo_cities_name_prefix_idx ON geo_cities (country_code, state_code, lower(name) text_pattern_ops)`,
+}
+
+// NewGeoRepo returns a Geo Repo instance
+func NewGeoRepo(db *pg.DB, log *zap.Logger) *GeoRepo {
+	return &GeoRepo{db, log}
+}
+
+// GeoRepo represents the client for the geography reference tables
+type GeoRepo struct {
+	db  *pg.DB
+	log *zap.Logger
+}
+
+// Import replaces the geography tables with the contents of idx in a single transaction
+func (g *GeoRepo) Import(idx *geo.Index) (countries, states, cities int, err error) {
+	err = g.db.RunInTransaction(func(tx *pg.Tx) error {
+		for _, m := range []interface{}{&model.GeoCountry{}, &model.GeoState{}, &model.GeoCity{}} {
+			if err := tx.CreateTable(m, &orm.CreateTableOptions{IfNotExists: true}); err != nil {
+				return err
+			}
+		}
+		for _, sql := range geoIndexes {
+			if _, err := tx.Exec(sql); err != nil {
+				return err
+			}
+		}
+		for _, table := range []string{"geo_cities", "geo_states", "geo_countries"} {
+			if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+				return err
+			}
+		}
+
+		var cs []model.GeoCountry
+		var ss []model.GeoState
+		var ts []model.GeoCity
+		for _, c := range idx.Countries() {
+			cs = append(cs, model.GeoCountry{Alpha3: c.ShortCode, Alpha2: c.ISO2, Name: c.Name})
+			for _, s := range idx.States(c.ShortCode) {
+				ss = append(ss, model.GeoState{CountryCode: c.ShortCode, Code: s.ShortCode, Name: s.Name})
+				for _, t := range idx.Cities(c.ShortCode, s.ShortCode) {
+					ts = append(ts, model.GeoCity{
+						CountryCode: c.ShortCode,
+						StateCode:   s.ShortCode,
+						Name:        t.Name,
+						ASCII:       t.ASCII,
+						Lat:         t.LAT,
+						Lng:         t.LNG,
+						Zips:        strings.Join(t.Zips, " "),
+					})
+				}
+			}
+		}
+		for i := 0; i < len(cs); i += geoBatchSize {
+			batch := cs[i:min(i+geoBatchSize, len(cs))]
+			if err := tx.Insert(&batch); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < len(ss); i += geoBatchSize {
+			batch := ss[i:min(i+geoBatchSize, len(ss))]
+			if err := tx.Insert(&batch); err != nil {
+				return err
+			}
+		}
+		for i := 0; i < len(ts); i += geoBatchSize {
+			batch := ts[i:min(i+geoBatchSize, len(ts))]
+			if err := tx.Insert(&batch); err != nil {
+				return err
+			}
+		}
+		countries, states, cities = len(cs), len(ss), len(ts)
+		return nil
+	})
+	if err != nil {
+		g.log.Warn("GeoRepo Error", zap.Error(err))
+	}
+	return countries, states, cities, err
+}
+
+// Load builds an in-memory index from the geography tables.
+// It returns an index without countries if nothing has been imported yet.
+func (g *GeoRepo) Load() (*geo.Index, error) {
+	var cs []model.GeoCountry
+	if err := g.db.Model(&cs).Order("id").Select(); err != nil {
+		g.log.Warn("GeoRepo Error", zap.Error(err))
+		return nil, err
+	}
+	var ss []model.GeoState
+	if err := g.db.Model(&ss).Order("id").Select(); err != nil {
+		g.log.Warn("GeoRepo Error", zap.Error(err))
+		return nil, err
+	}
+	var ts []model.GeoCity
+	if err := g.db.Model(&ts).Order("id").Select(); err != nil {
+		g.log.Warn("GeoRepo Error", zap.Error(err))
+		return nil, err
+	}
+
+	idx := geo.New()
+	for _, c := range cs {
+		idx.AddCountry(geo.Country{ShortCode: c.Alpha3, ISO2: c.Alpha2, Name: c.Name})
+	}
+	for _, s := range ss {
+		idx.AddState(s.CountryCode, geo.State{ShortCode: s.Code, Name: s.Name})
+	}
+	for _, t := range ts {
+		idx.AddCity(t.CountryCode, t.StateCode, geo.City{
+			Name:  t.Name,
+			ASCII: t.ASCII,
+			LAT:   t.Lat,
+			LNG:   t.Lng,
+			Zips:  strings.Fields(t.Zips),
+		})
+	}
+	idx.Build()
+	return idx, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// uniqueViolation is the PostgreSQL error code of a unique constraint violation
+const uniqueViolation = "23505"
+
+// NewIdentityRepo returns an IdentityRepo instance
+func NewIdentityRepo(db orm.DB, log *zap.Logger) *IdentityRepo {
+	return &IdentityRepo{db, log}
+}
+
+// IdentityRepo represents the client for the user_identities table
+type IdentityRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// Find returns the identity of the account subject at provider
+func (r *IdentityRepo) Find(provider, subject string) (*model.Identity, error) {
+	i := new(model.Identity)
+	err := r.db.Model(i).Where("provider = ? AND subject = ?", provider, subject).Select()
+	if err == pg.ErrNoRows {
+		return nil, apperr.NotFound
+	}
+	if err != nil {
+		r.log.Warn("IdentityRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return i, nil
+}
+
+// List returns the identities linked to a user, oldest first
+func (r *IdentityRepo) List(userID int) ([]model.Identity, error) {
+	var identities []model.Identity
+	err := r.db.Model(&identities).Where("user_id = ?", userID).Order("id ASC").Select()
+	if err != nil {
+		r.log.Warn("IdentityRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return identities, nil
+}
+
+// Create links an identity to a user. It fails with 409 if the identity is linked to a user already.
+func (r *IdentityRepo) Create(i *model.Identity) error {
+	_, err := r.db.Model(i).Value("created_at", "now()").Returning("*").Insert()
+	if pgErr, ok := err.(pg.Error); ok && pgErr.Field('C') == uniqueViolation {
+		return apperr.New(http.StatusConflict, "This account is already linked to another user.")
+	}
+	if err != nil {
+		r.log.Warn("IdentityRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// Touch records that the identity was just used to sign in
+func (r *IdentityRepo) Touch(i *model.Identity) error {
+	_, err := r.db.Model(i).Set("last_used_at = now()").WherePK().Returning("last_used_at").Update()
+	if err != nil {
+		r.log.Warn("IdentityRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// Delete unlinks the identity id of a user
+func (r *IdentityRepo) Delete(userID, id int) error {
+	res, err := r.db.Model((*model.Identity)(nil)).Where("id = ? AND user_id = ?", id, userID).Delete()
+	if err != nil {
+		r.log.Warn("IdentityRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	if res.RowsAffected() == 0 {
+		return apperr.NotFound
+	}
+	return nil
+}
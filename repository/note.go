@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewNoteRepo returns a NoteRepo instance
+func NewNoteRepo(db orm.DB, log *zap.Logger) *NoteRepo {
+	return &NoteRepo{db, log}
+}
+
+// NoteRepo represents the client for the internal notes on users
+type NoteRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// Create stores a note on a user
+func (r *NoteRepo) Create(n *model.UserNote) error {
+	if _, err := r.db.Model(n).Value("created_at", "now()").Returning("*").Insert(); err != nil {
+		r.log.Warn("NoteRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// List returns the notes on a user, newest first
+func (r *NoteRepo) List(userID int) ([]model.UserNote, error) {
+	var notes []model.UserNote
+	if err := r.db.Model(&notes).Where("user_id = ?", userID).Order("id DESC").Select(); err != nil {
+		r.log.Warn("NoteRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return notes, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewPasskeyRepo returns a PasskeyRepo instance
+func NewPasskeyRepo(db orm.DB, log *zap.Logger) *PasskeyRepo {
+	return &PasskeyRepo{db, log}
+}
+
+// PasskeyRepo represents the client for WebAuthn credentials and challenges
+type PasskeyRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// Create stores a new passkey of a user
+func (r *PasskeyRepo) Create(p *model.Passkey) error {
+	_, err := r.db.Model(p).Value("created_at", "now()").Returning("*").Insert()
+	if pgErr, ok := err.(pg.Error); ok && pgErr.Field('C') == uniqueViolation {
+		return apperr.New(http.StatusConflict, "This passkey is already registered.")
+	}
+	if err != nil {
+		r.log.Warn("PasskeyRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// List returns the passkeys of a user, oldest first
+func (r *PasskeyRepo) List(userID int) ([]model.Passkey, error) {
+	var passkeys []model.Passkey
+	err := r.db.Model(&passkeys).Where("user_id = ?", userID).Order("id ASC").Select()
+	if err != nil {
+		r.log.Warn("PasskeyRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return passkeys, nil
+}
+
+// FindByCredentialID returns the passkey with a base64url encoded credential ID
+func (r *PasskeyRepo) FindByCredentialID(id string) (*model.Passkey, error) {
+	p := new(model.Passkey)
+	err := r.db.Model(p).Where("credential_id = ?", id).Select()
+	if err == pg.ErrNoRows {
+		return nil, apperr.NotFound
+	}
+	if err != nil {
+		r.log.Warn("PasskeyRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return p, nil
+}
+
+// Used stores the signature counter of a passkey that was just signed in with
+func (r *PasskeyRepo) Used(p *model.Passkey) error {
+	_, err := r.db.Model(p).Set("sign_count = ?sign_count, last_used_at = now()").WherePK().Returning("last_used_at").Update()
+	if err != nil {
+		r.log.Warn("PasskeyRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// Delete removes the passkey id of a user
+func (r *PasskeyRepo) Delete(userID, id int) error {
+	res, err := r.db.Model((*model.Passkey)(nil)).Where("id = ? AND user_id = ?", id, userID).Delete()
+	if err != nil {
+		r.log.Warn("PasskeyRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	if res.RowsAffected() == 0 {
+		return apperr.NotFound
+	}
+	return nil
+}
+
+// CreateChallenge stores the challenge of a ceremony
+func (r *PasskeyRepo) CreateChallenge(c *model.PasskeyChallenge) error {
+	if err := r.db.Insert(c); err != nil {
+		r.log.Warn("PasskeyRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// UseChallenge deletes an unexpired challenge of a ceremony and returns it, so each challenge is answered once
+func (r *PasskeyRepo) UseChallenge(challenge, ceremony string) (*model.PasskeyChallenge, error) {
+	c := new(model.PasskeyChallenge)
+	_, err := r.db.QueryOne(c, `DELETE FROM webauthn_challenges WHERE (challenge = ? AND ceremony = ? AND expires_at > now()) RETURNING *`, challenge, ceremony)
+	if err == pg.ErrNoRows {
+		return nil, apperr.New(http.StatusUnauthorized, "Invalid or expired challenge.")
+	}
+	if err != nil {
+		r.log.Warn("PasskeyRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return c, nil
+}
+
+// DeleteExpiredChallenges removes the challenges of abandoned ceremonies
+func (r *PasskeyRepo) DeleteExpiredChallenges() (int, error) {
+	res, err := r.db.Model((*model.PasskeyChallenge)(nil)).Where("expires_at <= now()").Delete()
+	if err != nil {
+		r.log.Warn("PasskeyRepo Error", zap.Error(err))
+		return 0, apperr.DB
+	}
+	return res.RowsAffected(), nil
+}
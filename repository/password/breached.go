@@ -0,0 +1,62 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RangeSource returns the breached password hashes that start with a 5 character SHA-1 prefix, as
+// SUFFIX:COUNT lines. Only the prefix of a hash is looked up, so a source never learns the password.
+type RangeSource interface {
+	Range(prefix string) (io.ReadCloser, error)
+}
+
+// Dir is a RangeSource over a directory of range files named by their prefix, e.g. 21BD1 or 21BD1.txt
+type Dir string
+
+// Range opens the range file of prefix. A missing file is an empty range, so partial datasets work.
+func (d Dir) Range(prefix string) (io.ReadCloser, error) {
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		f, err := os.Open(filepath.Join(string(d), name))
+		if err == nil {
+			return f, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return ioutil.NopCloser(strings.NewReader("")), nil
+}
+
+// BreachCount returns how often password appeared in the breaches of src
+func BreachCount(src RangeSource, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	r, err := src.Range(hash[:5])
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	suffix := hash[5:]
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		i := strings.IndexByte(line, ':')
+		if i < 0 || !strings.EqualFold(line[:i], suffix) {
+			continue
+		}
+		count, err := strconv.Atoi(line[i+1:])
+		if err != nil {
+			return 1, nil
+		}
+		return count, nil
+	}
+	return 0, s.Err()
+}
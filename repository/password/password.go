@@ -0,0 +1,137 @@
+package password
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/secret"
+)
+
+// minPersonal is the shortest personal detail a password may not contain, so short names don't rule out too much
+const minPersonal = 3
+
+var (
+	errBreached = apperr.New(http.StatusBadRequest, "This password has appeared in a data breach. Please choose another one.")
+	errPersonal = apperr.New(http.StatusBadRequest, "Password must not contain your name, username, email or mobile number.")
+	errReused   = apperr.New(http.StatusBadRequest, "You have used this password recently. Please choose another one.")
+)
+
+// NewPolicy creates a password policy with the limits of cfg, reading breached passwords from cfg.BreachedDir
+func NewPolicy(history model.PasswordHistoryRepo, sec secret.Service, cfg *config.PasswordConfig) *Policy {
+	p := &Policy{
+		history: history,
+		secret:  sec,
+		cfg:     cfg,
+	}
+	if cfg.BreachedDir != "" {
+		p.breached = Dir(cfg.BreachedDir)
+	}
+	return p
+}
+
+// Policy checks the new passwords users choose
+type Policy struct {
+	history  model.PasswordHistoryRepo
+	secret   secret.Service
+	cfg      *config.PasswordConfig
+	breached RangeSource
+}
+
+// Validate returns a 400 error if password is too short or long, mixes too few character classes,
+// contains personal details of u or appeared in a breach
+func (p *Policy) Validate(u *model.User, password string) error {
+	if utf8.RuneCountInString(password) < p.cfg.MinLength {
+		return apperr.New(http.StatusBadRequest, "Password must be at least "+strconv.Itoa(p.cfg.MinLength)+" characters long.")
+	}
+	if len(password) > p.cfg.MaxLength {
+		return apperr.New(http.StatusBadRequest, "Password must be at most "+strconv.Itoa(p.cfg.MaxLength)+" characters long.")
+	}
+	if classes(password) < p.cfg.MinClasses {
+		return apperr.New(http.StatusBadRequest, "Password must contain "+strconv.Itoa(p.cfg.MinClasses)+
+			" of lowercase letters, uppercase letters, digits and symbols.")
+	}
+	lower := strings.ToLower(password)
+	for _, v := range personal(u) {
+		if strings.Contains(lower, v) {
+			return errPersonal
+		}
+	}
+	if p.breached == nil {
+		return nil
+	}
+	count, err := BreachCount(p.breached, password)
+	if err != nil {
+		return apperr.New(http.StatusInternalServerError, "Failed to check password, please try again.")
+	}
+	if count >= p.cfg.BreachedMinCount {
+		return errBreached
+	}
+	return nil
+}
+
+// CheckReuse returns a 400 error if password is the current or one of the last cfg.History passwords of u
+func (p *Policy) CheckReuse(u *model.User, password string) error {
+	if p.cfg.History <= 0 || u.ID == 0 {
+		return nil
+	}
+	hashes, err := p.history.Recent(u.ID, p.cfg.History)
+	if err != nil {
+		return err
+	}
+	if u.Password != "" {
+		hashes = append(hashes, u.Password)
+	}
+	for _, h := range hashes {
+		if p.secret.HashMatchesPassword(h, password) {
+			return errReused
+		}
+	}
+	return nil
+}
+
+// Remember adds the current password hash of u to its history
+func (p *Policy) Remember(u *model.User) error {
+	if p.cfg.History <= 0 || u.Password == "" {
+		return nil
+	}
+	return p.history.Add(u.ID, u.Password, p.cfg.History)
+}
+
+// classes counts the character classes in password
+func classes(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// personal returns the lowercased personal details of u a password may not contain
+func personal(u *model.User) []string {
+	values := []string{u.Username, u.FirstName, u.LastName, u.Mobile}
+	if i := strings.LastIndexByte(u.Email, '@'); i > 0 {
+		values = append(values, u.Email[:i])
+	}
+	var personal []string
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); utf8.RuneCountInString(v) >= minPersonal {
+			personal = append(personal, v)
+		}
+	}
+	return personal
+}
@@ -0,0 +1,143 @@
+package password_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/password"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// breachedDir writes a range file listing the breached passwords with their counts
+func breachedDir(t *testing.T, breached map[string]int) string {
+	dir, err := ioutil.TempDir("", "breached")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	ranges := map[string][]string{}
+	for p, count := range breached {
+		sum := sha1.Sum([]byte(p))
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		ranges[hash[:5]] = append(ranges[hash[:5]], hash[5:]+":"+string(rune('0'+count)))
+	}
+	for prefix, lines := range ranges {
+		// other suffixes of the range and windows line endings, as in the downloaded dataset
+		lines = append([]string{"0000000000000000000000000000000000A:3"}, lines...)
+		if err := ioutil.WriteFile(filepath.Join(dir, prefix+".txt"), []byte(strings.Join(lines, "\r\n")), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &config.PasswordConfig{
+		MinLength:        8,
+		MaxLength:        72,
+		MinClasses:       3,
+		BreachedDir:      breachedDir(t, map[string]int{"Summer2020!": 5, "Winter2020!": 1}),
+		BreachedMinCount: 2,
+	}
+	policy := password.NewPolicy(nil, nil, cfg)
+	u := &model.User{Email: "johndoe@mail.com", FirstName: "Al", LastName: "Smithers", Mobile: "91919191"}
+	cases := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{name: "Strong", password: "c0rrect-Horse"},
+		{name: "Too short", password: "Ab1!", wantErr: true},
+		{name: "Too long", password: strings.Repeat("Ab1!", 19), wantErr: true},
+		{name: "Too few classes", password: "correcthorse1", wantErr: true},
+		{name: "Contains email", password: "JohnDoe#2020", wantErr: true},
+		{name: "Contains last name", password: "smithers-2020A", wantErr: true},
+		{name: "Contains mobile", password: "Call91919191", wantErr: true},
+		{name: "Short first name is allowed", password: "Always-2020"},
+		{name: "Breached", password: "Summer2020!", wantErr: true},
+		{name: "Breached less often than the minimum", password: "Winter2020!"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(u, tt.password)
+			if !tt.wantErr {
+				assert.Nil(t, err)
+				return
+			}
+			if assert.NotNil(t, err) {
+				assert.Equal(t, http.StatusBadRequest, err.(*apperr.APPError).Status)
+			}
+		})
+	}
+
+	t.Run("Without a dataset", func(t *testing.T) {
+		cfg := *cfg
+		cfg.BreachedDir = ""
+		assert.Nil(t, password.NewPolicy(nil, nil, &cfg).Validate(u, "Summer2020!"))
+	})
+}
+
+func TestBreachCount(t *testing.T) {
+	dir := password.Dir(breachedDir(t, map[string]int{"Summer2020!": 5}))
+	count, err := password.BreachCount(dir, "Summer2020!")
+	assert.Nil(t, err)
+	assert.Equal(t, 5, count)
+
+	// no range file for the prefix
+	count, err = password.BreachCount(dir, "c0rrect-Horse")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestHistory(t *testing.T) {
+	history := map[int][]string{}
+	repo := &mockdb.PasswordHistory{
+		RecentFn: func(userID, n int) ([]string, error) {
+			hashes := history[userID]
+			if len(hashes) > n {
+				hashes = hashes[:n]
+			}
+			return hashes, nil
+		},
+		AddFn: func(userID int, hash string, keep int) error {
+			hashes := append([]string{hash}, history[userID]...)
+			if len(hashes) > keep {
+				hashes = hashes[:keep]
+			}
+			history[userID] = hashes
+			return nil
+		},
+	}
+	sec := &mock.Password{
+		HashMatchesPasswordFn: func(hash, password string) bool {
+			return hash == "hash:"+password
+		},
+	}
+	policy := password.NewPolicy(repo, sec, &config.PasswordConfig{History: 2})
+	u := &model.User{ID: 1}
+	for _, p := range []string{"first", "second", "third"} {
+		assert.Nil(t, policy.CheckReuse(u, p))
+		u.Password = "hash:" + p
+		assert.Nil(t, policy.Remember(u))
+	}
+
+	assert.NotNil(t, policy.CheckReuse(u, "third"), "current password")
+	assert.NotNil(t, policy.CheckReuse(u, "second"))
+	assert.Nil(t, policy.CheckReuse(u, "first"), "forgotten after 2 passwords")
+	assert.Nil(t, policy.CheckReuse(&model.User{ID: 2}, "third"), "other user")
+
+	off := password.NewPolicy(repo, sec, &config.PasswordConfig{})
+	assert.Nil(t, off.CheckReuse(u, "third"))
+}
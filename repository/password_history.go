@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewPasswordHistoryRepo returns a PasswordHistoryRepo instance
+func NewPasswordHistoryRepo(db orm.DB, log *zap.Logger) *PasswordHistoryRepo {
+	return &PasswordHistoryRepo{db, log}
+}
+
+// PasswordHistoryRepo represents the client for the password history table
+type PasswordHistoryRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// Recent returns the hashes of the last n passwords of a user, newest first
+func (r *PasswordHistoryRepo) Recent(userID, n int) ([]string, error) {
+	var hashes []string
+	err := r.db.Model((*model.PasswordHistory)(nil)).
+		Column("hash").
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(n).
+		Select(&hashes)
+	if err != nil {
+		r.log.Warn("PasswordHistoryRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return hashes, nil
+}
+
+// Add stores a password hash of a user and forgets all but the last keep ones
+func (r *PasswordHistoryRepo) Add(userID int, hash string, keep int) error {
+	h := &model.PasswordHistory{UserID: userID, Hash: hash}
+	if _, err := r.db.Model(h).Value("created_at", "now()").Insert(); err != nil {
+		r.log.Warn("PasswordHistoryRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	_, err := r.db.Model((*model.PasswordHistory)(nil)).
+		Where("user_id = ?", userID).
+		Where("id NOT IN (SELECT id FROM password_history WHERE user_id = ? ORDER BY id DESC LIMIT ?)", userID, keep).
+		Delete()
+	if err != nil {
+		r.log.Warn("PasswordHistoryRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package permission
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultInterval is how often services reload the permissions of roles
+const DefaultInterval = 30 * time.Second
+
+// NewPermissionService creates a permission service backed by repo. Grants and revocations
+// made on other instances are picked up within interval. Grants and revocations are recorded in audit.
+func NewPermissionService(repo model.RoleRepo, audit model.AuditLog, interval time.Duration) *Service {
+	return &Service{
+		repo:     repo,
+		audit:    audit,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Service keeps the permissions of roles in memory, so checking them doesn't query Postgres on every request
+type Service struct {
+	repo     model.RoleRepo
+	audit    model.AuditLog
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	granted map[model.AccessRole]map[string]bool
+	loaded  time.Time
+}
+
+// Allowed reports whether role has permission. The superadmin role has all permissions.
+func (s *Service) Allowed(role model.AccessRole, permission string) (bool, error) {
+	if role == model.SuperAdminRole {
+		return true, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := s.now(); s.granted == nil || now.Sub(s.loaded) >= s.interval {
+		roles, err := s.repo.List()
+		if err != nil {
+			return false, err
+		}
+		s.granted = map[model.AccessRole]map[string]bool{}
+		for _, r := range roles {
+			if s.granted[r.AccessLevel] == nil {
+				s.granted[r.AccessLevel] = map[string]bool{}
+			}
+			for _, p := range r.Permissions {
+				s.granted[r.AccessLevel][p] = true
+			}
+		}
+		s.loaded = now
+	}
+	return s.granted[role][permission], nil
+}
+
+// Roles returns the roles with their permissions
+func (s *Service) Roles() ([]model.Role, error) {
+	return s.repo.List()
+}
+
+// Grant grants permission to the role roleID
+func (s *Service) Grant(c *gin.Context, roleID int, permission string) error {
+	if err := s.check(roleID, permission); err != nil {
+		return err
+	}
+	if err := s.repo.Grant(roleID, permission); err != nil {
+		return err
+	}
+	s.reload()
+	return s.audit.Record(c, &model.AuditEvent{Action: model.AuditRolePermGrant, Details: map[string]interface{}{"role_id": roleID, "permission": permission}})
+}
+
+// Revoke revokes permission from the role roleID
+func (s *Service) Revoke(c *gin.Context, roleID int, permission string) error {
+	if err := s.check(roleID, permission); err != nil {
+		return err
+	}
+	if err := s.repo.Revoke(roleID, permission); err != nil {
+		return err
+	}
+	s.reload()
+	return s.audit.Record(c, &model.AuditEvent{Action: model.AuditRolePermRevoke, Details: map[string]interface{}{"role_id": roleID, "permission": permission}})
+}
+
+// check returns an error if permission is unknown, or the role roleID doesn't exist or is the superadmin role
+func (s *Service) check(roleID int, permission string) error {
+	if !model.ValidPermission(permission) {
+		return apperr.New(http.StatusBadRequest, "Unknown permission.")
+	}
+	roles, err := s.repo.List()
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if r.ID != roleID {
+			continue
+		}
+		if r.AccessLevel == model.SuperAdminRole {
+			return apperr.New(http.StatusBadRequest, "The superadmin role has all permissions.")
+		}
+		return nil
+	}
+	return apperr.New(http.StatusNotFound, "Role not found.")
+}
+
+// reload makes the next check load the permissions again, so changes apply on this instance right away
+func (s *Service) reload() {
+	s.mu.Lock()
+	s.granted = nil
+	s.mu.Unlock()
+}
@@ -0,0 +1,73 @@
+package permission_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/permission"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissions(t *testing.T) {
+	roles := []model.Role{
+		{ID: 1, AccessLevel: model.SuperAdminRole, Name: "superadmin"},
+		{ID: 2, AccessLevel: model.AdminRole, Name: "admin", Permissions: []string{model.PermUsersRead}},
+		{ID: 3, AccessLevel: model.UserRole, Name: "user"},
+	}
+	loads := 0
+	repo := &mockdb.Role{
+		ListFn: func() ([]model.Role, error) {
+			loads++
+			return roles, nil
+		},
+		GrantFn: func(roleID int, p string) error {
+			roles[roleID-1].Permissions = append(roles[roleID-1].Permissions, p)
+			return nil
+		},
+	}
+	var events []*model.AuditEvent
+	audit := &mock.AuditLog{
+		RecordFn: func(c *gin.Context, e *model.AuditEvent) error {
+			events = append(events, e)
+			return nil
+		},
+	}
+	svc := permission.NewPermissionService(repo, audit, permission.DefaultInterval)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	ok, err := svc.Allowed(model.AdminRole, model.PermUsersRead)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, _ = svc.Allowed(model.AdminRole, model.PermRolesManage)
+	assert.False(t, ok)
+	ok, _ = svc.Allowed(model.SuperAdminRole, model.PermRolesManage)
+	assert.True(t, ok, "superadmin has all permissions")
+	ok, _ = svc.Allowed(model.UserRole, model.PermUsersRead)
+	assert.False(t, ok)
+	assert.Equal(t, 1, loads, "permissions are cached")
+
+	assert.Nil(t, svc.Grant(c, 2, model.PermRolesManage))
+	ok, _ = svc.Allowed(model.AdminRole, model.PermRolesManage)
+	assert.True(t, ok, "grants apply right away")
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, model.AuditRolePermGrant, events[0].Action)
+	}
+
+	status := func(err error) int {
+		if e, ok := err.(*apperr.APPError); ok {
+			return e.Status
+		}
+		return 0
+	}
+	assert.Equal(t, http.StatusBadRequest, status(svc.Grant(c, 2, "users:delete")))
+	assert.Equal(t, http.StatusBadRequest, status(svc.Grant(c, 1, model.PermUsersRead)))
+	assert.Equal(t, http.StatusNotFound, status(svc.Grant(c, 4, model.PermUsersRead)))
+	assert.Len(t, events, 1, "refused grants aren't recorded")
+}
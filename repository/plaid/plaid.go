@@ -12,6 +12,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/alpacahq/ribbit-backend/broker"
 	"github.com/alpacahq/ribbit-backend/request"
 
 	"github.com/alpacahq/ribbit-backend/model"
@@ -22,9 +23,6 @@ import (
 	"github.com/plaid/plaid-go/plaid"
 )
 
-var baseURL string = os.Getenv("BROKER_API_BASE")
-var token string = os.Getenv("BROKER_TOKEN")
-
 var (
 	PLAID_CLIENT_ID     = os.Getenv("PLAID_CLIENT_ID")
 	PLAID_SECRET        = os.Getenv("PLAID_SECRET")
@@ -80,6 +78,14 @@ type JWT interface {
 	GenerateToken(*model.User) (string, string, error)
 }
 
+// clientName returns the app name Plaid Link shows, the name of the current tenant
+func clientName(c context.Context) string {
+	if t := model.TenantOf(c); t != nil && t.Name != "" {
+		return t.Name
+	}
+	return "Ribbit"
+}
+
 func (s *Service) CreateLinkToken(c context.Context, accountID string, name string) (*model.PlaidAuthToken, error) {
 	countryCodes := strings.Split(PLAID_COUNTRY_CODES, ",")
 	products := strings.Split(PLAID_PRODUCTS, ",")
@@ -88,7 +94,7 @@ func (s *Service) CreateLinkToken(c context.Context, accountID string, name stri
 		User: &plaid.LinkTokenUser{
 			ClientUserID: accountID,
 		},
-		ClientName:   "Ribbit",
+		ClientName:   clientName(c),
 		Products:     products,
 		CountryCodes: countryCodes,
 		Language:     "en",
@@ -158,12 +164,12 @@ func (s *Service) SetAccessToken(c context.Context, id int, accountID string, e
 	})
 	attachAccountBody := bytes.NewBuffer(attachAccount)
 
-	req, err := http.NewRequest("POST", baseURL+"/v1/accounts/"+accountID+"/ach_relationships", attachAccountBody)
+	req, err := http.NewRequest("POST", broker.APIBase(c)+"/v1/accounts/"+accountID+"/ach_relationships", attachAccountBody)
 	if err != nil {
 		return nil, errors.New("Something went wrong. Try again later.")
 	}
 
-	req.Header.Add("Authorization", token)
+	req.Header.Add("Authorization", broker.Token(c))
 	cardBody, err := client.Do(req)
 
 	if err != nil {
@@ -7,15 +7,17 @@ import (
 )
 
 // NewRBACService creates new RBAC service
-func NewRBACService(userRepo model.UserRepo) *RBACService {
+func NewRBACService(userRepo model.UserRepo, permissions model.PermissionService) *RBACService {
 	return &RBACService{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		permissions: permissions,
 	}
 }
 
 // RBACService is RBAC application service
 type RBACService struct {
-	userRepo model.UserRepo
+	userRepo    model.UserRepo
+	permissions model.PermissionService
 }
 
 // EnforceRole authorizes request by AccessRole
@@ -30,8 +32,9 @@ func (s *RBACService) EnforceUser(c *gin.Context, ID int) bool {
 	return (c.GetInt("id") == ID) || s.isAdmin(c)
 }
 
+// isAdmin reports whether the requesting user is an admin. Impersonation tokens never are.
 func (s *RBACService) isAdmin(c *gin.Context) bool {
-	return !(c.MustGet("role").(int8) > int8(model.AdminRole))
+	return s.Impersonator(c) == 0 && !(c.MustGet("role").(int8) > int8(model.AdminRole))
 }
 
 // AccountCreate performs auth check when creating a new account
@@ -46,3 +49,18 @@ func (s *RBACService) AccountCreate(c *gin.Context, roleID int) bool {
 func (s *RBACService) IsLowerRole(c *gin.Context, r model.AccessRole) bool {
 	return !(c.MustGet("role").(int8) >= int8(r))
 }
+
+// EnforcePermission authorizes request by a permission granted to the requesting user's role.
+// Impersonation tokens have no permissions.
+func (s *RBACService) EnforcePermission(c *gin.Context, permission string) bool {
+	if s.Impersonator(c) != 0 {
+		return false
+	}
+	ok, err := s.permissions.Allowed(model.AccessRole(c.MustGet("role").(int8)), permission)
+	return err == nil && ok
+}
+
+// Impersonator returns the ID of the staff member impersonating the requesting user, or 0
+func (s *RBACService) Impersonator(c *gin.Context) int {
+	return c.GetInt("impersonator")
+}
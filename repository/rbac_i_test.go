@@ -8,9 +8,11 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/alpacahq/ribbit-backend/mock"
 	"github.com/alpacahq/ribbit-backend/model"
 	"github.com/alpacahq/ribbit-backend/repository"
 	"github.com/alpacahq/ribbit-backend/repository/account"
+	"github.com/alpacahq/ribbit-backend/repository/permission"
 	"github.com/alpacahq/ribbit-backend/secret"
 
 	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
@@ -75,16 +77,15 @@ func (suite *RBACTestSuite) TestRBAC() {
 
 	// create a user in our test database, which is superadmin
 	log, _ := zap.NewDevelopment()
-	userRepo := repository.NewUserRepo(suite.db, log)
-	accountRepo := repository.NewAccountRepo(suite.db, log, secret.New())
-	rbac := repository.NewRBACService(userRepo)
-
+	userRepo := repository.NewUserRepo(suite.db, log, mock.Cipher())
+	accountRepo := repository.NewAccountRepo(suite.db, log, secret.New(), mock.Cipher())
 	// ensure that our roles table is populated with default roles
 	roleRepo := repository.NewRoleRepo(suite.db, log)
+	rbac := repository.NewRBACService(userRepo, permission.NewPermissionService(roleRepo, &mock.AuditLog{}, permission.DefaultInterval))
 	err := roleRepo.CreateRoles()
 	assert.Nil(suite.T(), err)
 
-	accountService := account.NewAccountService(userRepo, accountRepo, rbac, secret.New())
+	accountService := account.NewAccountService(userRepo, accountRepo, rbac, secret.New(), &mock.PasswordPolicy{}, &mock.AuditLog{})
 	err = accountService.Create(c, &model.User{
 		CountryCode: "+65",
 		Mobile:      "91919191",
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewRefreshTokenRepo returns a RefreshTokenRepo instance
+func NewRefreshTokenRepo(db orm.DB, log *zap.Logger) *RefreshTokenRepo {
+	return &RefreshTokenRepo{db, log}
+}
+
+// RefreshTokenRepo represents the client for the refresh_tokens table
+type RefreshTokenRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// Create stores a newly issued refresh token
+func (r *RefreshTokenRepo) Create(t *model.IssuedRefreshToken) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	if err := r.db.Insert(t); err != nil {
+		r.log.Warn("RefreshTokenRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// FindByHash returns the refresh token with the given hash
+func (r *RefreshTokenRepo) FindByHash(hash string) (*model.IssuedRefreshToken, error) {
+	t := new(model.IssuedRefreshToken)
+	err := r.db.Model(t).Where("token_hash = ?", hash).Select()
+	if err == pg.ErrNoRows {
+		return nil, apperr.New(http.StatusUnauthorized, "Invalid refresh token.")
+	}
+	if err != nil {
+		r.log.Warn("RefreshTokenRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return t, nil
+}
+
+// MarkUsed marks t as rotated. It reports false if t was already used or revoked,
+// so two concurrent refreshes with the same token cannot both succeed.
+func (r *RefreshTokenRepo) MarkUsed(t *model.IssuedRefreshToken) (bool, error) {
+	now := time.Now()
+	res, err := r.db.Model(t).
+		Set("used_at = ?", now).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", t.ID).
+		Update()
+	if err != nil {
+		r.log.Warn("RefreshTokenRepo Error", zap.Error(err))
+		return false, apperr.DB
+	}
+	if res.RowsAffected() == 0 {
+		return false, nil
+	}
+	t.UsedAt = &now
+	return true, nil
+}
+
+// RevokeFamily revokes every token of a login family
+func (r *RefreshTokenRepo) RevokeFamily(familyID string) error {
+	_, err := r.db.Model((*model.IssuedRefreshToken)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update()
+	if err != nil {
+		r.log.Warn("RefreshTokenRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// RevokeUser revokes every refresh token of a user
+func (r *RefreshTokenRepo) RevokeUser(userID int) error {
+	_, err := r.db.Model((*model.IssuedRefreshToken)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update()
+	if err != nil {
+		r.log.Warn("RefreshTokenRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// DeleteExpired removes tokens whose family expired before t
+func (r *RefreshTokenRepo) DeleteExpired(t time.Time) (int, error) {
+	res, err := r.db.Model((*model.IssuedRefreshToken)(nil)).Where("family_expires_at < ?", t).Delete()
+	if err != nil {
+		r.log.Warn("RefreshTokenRepo Error", zap.Error(err))
+		return 0, apperr.DB
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewRevocationRepo returns a RevocationRepo instance
+func NewRevocationRepo(db orm.DB, log *zap.Logger) *RevocationRepo {
+	return &RevocationRepo{db, log}
+}
+
+// RevocationRepo represents the client for revoked access tokens and token epochs
+type RevocationRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// Create stores a revoked token, revoking it again is a no-op
+func (r *RevocationRepo) Create(t *model.RevokedToken) error {
+	if t.RevokedAt.IsZero() {
+		t.RevokedAt = time.Now()
+	}
+	_, err := r.db.Model(t).OnConflict("DO NOTHING").Insert()
+	if err != nil {
+		r.log.Warn("RevocationRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// ListSince returns the unexpired tokens revoked at or after t
+func (r *RevocationRepo) ListSince(t time.Time) ([]model.RevokedToken, error) {
+	var tokens []model.RevokedToken
+	err := r.db.Model(&tokens).
+		Where("revoked_at >= ? AND expires_at > ?", t, time.Now()).
+		Select()
+	if err != nil {
+		r.log.Warn("RevocationRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return tokens, nil
+}
+
+// TokensValidAfter returns the time before which the user's tokens are no longer accepted,
+// or the zero time if they never changed their password or role
+func (r *RevocationRepo) TokensValidAfter(userID int) (time.Time, error) {
+	u := new(model.User)
+	err := r.db.Model(u).Column("tokens_valid_after").Where("id = ?", userID).Select()
+	if err == pg.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		r.log.Warn("RevocationRepo Error", zap.Error(err))
+		return time.Time{}, apperr.DB
+	}
+	if u.TokensValidAfter == nil {
+		return time.Time{}, nil
+	}
+	return *u.TokensValidAfter, nil
+}
+
+// DeleteExpired removes revoked tokens that expired before t
+func (r *RevocationRepo) DeleteExpired(t time.Time) (int, error) {
+	res, err := r.db.Model((*model.RevokedToken)(nil)).Where("expires_at < ?", t).Delete()
+	if err != nil {
+		r.log.Warn("RevocationRepo Error", zap.Error(err))
+		return 0, apperr.DB
+	}
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,115 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// DefaultInterval is how often stores sync with Postgres
+const DefaultInterval = 30 * time.Second
+
+// NewStore creates a revocation store backed by repo. Revocations made on other
+// instances, and password or role changes, are picked up within interval.
+func NewStore(repo model.RevocationRepo, interval time.Duration) *Store {
+	return &Store{
+		repo:     repo,
+		interval: interval,
+		revoked:  map[string]time.Time{},
+		epochs:   map[int]epoch{},
+		now:      time.Now,
+	}
+}
+
+// Store keeps revoked tokens and token epochs in memory, so the JWT middleware
+// doesn't query Postgres on every request
+type Store struct {
+	repo     model.RevocationRepo
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	revoked map[string]time.Time // jti to token expiry
+	synced  time.Time
+	epochs  map[int]epoch
+}
+
+type epoch struct {
+	validAfter time.Time
+	fetched    time.Time
+}
+
+// Revoked reports whether the token jti has been revoked, or was issued to userID at
+// authTime, before their password or role last changed
+func (s *Store) Revoked(jti string, userID int, authTime time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if err := s.sync(now); err != nil {
+		return false, err
+	}
+	if _, ok := s.revoked[jti]; ok && jti != "" {
+		return true, nil
+	}
+	e, ok := s.epochs[userID]
+	if !ok || now.Sub(e.fetched) >= s.interval {
+		validAfter, err := s.repo.TokensValidAfter(userID)
+		if err != nil {
+			return false, err
+		}
+		e = epoch{validAfter: validAfter, fetched: now}
+		s.epochs[userID] = e
+	}
+	return authTime.Before(e.validAfter), nil
+}
+
+// Revoke rejects the token jti from now on. expires is the expiry of the token.
+func (s *Store) Revoke(jti string, userID int, expires time.Time) error {
+	if jti == "" {
+		return nil
+	}
+	err := s.repo.Create(&model.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expires,
+	})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.revoked[jti] = expires
+	s.mu.Unlock()
+	return nil
+}
+
+// sync loads tokens revoked since the last sync and forgets expired ones.
+// Consecutive syncs overlap by an interval, so rows committed late are not missed.
+func (s *Store) sync(now time.Time) error {
+	if !s.synced.IsZero() && now.Sub(s.synced) < s.interval {
+		return nil
+	}
+	var since time.Time
+	if !s.synced.IsZero() {
+		since = s.synced.Add(-s.interval)
+	}
+	tokens, err := s.repo.ListSince(since)
+	if err != nil {
+		return err
+	}
+	for _, t := range tokens {
+		s.revoked[t.JTI] = t.ExpiresAt
+	}
+	for jti, expires := range s.revoked {
+		if !now.Before(expires) {
+			delete(s.revoked, jti)
+		}
+	}
+	for id, e := range s.epochs {
+		if now.Sub(e.fetched) >= s.interval {
+			delete(s.epochs, id)
+		}
+	}
+	s.synced = now
+	return nil
+}
@@ -0,0 +1,80 @@
+package revocation_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/revocation"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Now()
+	syncs, epochs := 0, 0
+	repo := &mockdb.Revocation{
+		CreateFn: func(*model.RevokedToken) error {
+			return nil
+		},
+		ListSinceFn: func(time.Time) ([]model.RevokedToken, error) {
+			syncs++
+			return []model.RevokedToken{
+				{JTI: "stored", UserID: 1, ExpiresAt: now.Add(time.Hour)},
+				{JTI: "expired", UserID: 1, ExpiresAt: now.Add(-time.Hour)},
+			}, nil
+		},
+		TokensValidAfterFn: func(id int) (time.Time, error) {
+			epochs++
+			if id == 2 {
+				return now, nil
+			}
+			return time.Time{}, nil
+		},
+	}
+	s := revocation.NewStore(repo, time.Hour)
+
+	revoked, err := s.Revoked("stored", 1, now)
+	assert.Nil(t, err)
+	assert.True(t, revoked)
+
+	revoked, _ = s.Revoked("expired", 1, now)
+	assert.False(t, revoked)
+
+	revoked, _ = s.Revoked("fresh", 1, now)
+	assert.False(t, revoked)
+	assert.Nil(t, s.Revoke("fresh", 1, now.Add(time.Hour)))
+	revoked, _ = s.Revoked("fresh", 1, now)
+	assert.True(t, revoked, "revocations apply immediately on the instance making them")
+
+	// tokens issued before the user's password or role changed
+	revoked, _ = s.Revoked("other", 2, now.Add(-time.Minute))
+	assert.True(t, revoked)
+	revoked, _ = s.Revoked("other", 2, now)
+	assert.False(t, revoked)
+
+	assert.Equal(t, 1, syncs, "syncs at most once per interval")
+	assert.Equal(t, 2, epochs, "epochs are cached per user")
+}
+
+func TestStoreResync(t *testing.T) {
+	var since []time.Time
+	repo := &mockdb.Revocation{
+		ListSinceFn: func(t time.Time) ([]model.RevokedToken, error) {
+			since = append(since, t)
+			return nil, nil
+		},
+		TokensValidAfterFn: func(int) (time.Time, error) {
+			return time.Time{}, nil
+		},
+	}
+	s := revocation.NewStore(repo, 10*time.Millisecond)
+	s.Revoked("a", 1, time.Now())
+	time.Sleep(20 * time.Millisecond)
+	s.Revoked("a", 1, time.Now())
+
+	assert.Len(t, since, 2)
+	assert.True(t, since[0].IsZero(), "the first sync loads every unexpired revocation")
+	assert.False(t, since[1].IsZero())
+}
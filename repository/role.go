@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
 	"github.com/alpacahq/ribbit-backend/model"
 
 	"github.com/go-pg/pg/v9"
@@ -18,12 +21,88 @@ type RoleRepo struct {
 	log *zap.Logger
 }
 
-// CreateRoles creates role objects in our database
+// CreateRoles creates role objects in our database that don't exist yet, and grants new roles their
+// default permissions. Existing roles keep the permissions granted and revoked since, unless no role
+// was granted any yet, e.g. on the first run since permissions were added.
 func (r *RoleRepo) CreateRoles() error {
-	role := new(model.Role)
-	sql := `INSERT INTO roles (id, access_level, name) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`
-	r.db.Query(role, sql, 1, model.SuperAdminRole, "superadmin")
-	r.db.Query(role, sql, 2, model.AdminRole, "admin")
-	r.db.Query(role, sql, 3, model.UserRole, "user")
+	seeded, err := r.db.Model((*model.RolePermission)(nil)).Count()
+	if err != nil {
+		r.log.Warn("RoleRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	sql := `INSERT INTO roles (id, access_level, name) SELECT ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM roles WHERE access_level = ?) ON CONFLICT DO NOTHING RETURNING id`
+	for _, level := range []model.AccessRole{model.SuperAdminRole, model.AdminRole, model.UserRole} {
+		role := new(model.Role)
+		res, err := r.db.Query(role, sql, int(level), level, model.RoleNames[level], level)
+		if err != nil {
+			r.log.Warn("RoleRepo Error", zap.Error(err))
+			return apperr.DB
+		}
+		if res.RowsReturned() == 0 {
+			if seeded > 0 {
+				continue
+			}
+			// the role exists, but its ID needn't be its access level
+			if err := r.db.Model(role).Column("id").Where("access_level = ?", level).Order("id ASC").Limit(1).Select(); err != nil {
+				r.log.Warn("RoleRepo Error", zap.Error(err))
+				return apperr.DB
+			}
+		}
+		for _, p := range model.DefaultPermissions[level] {
+			if err := r.Grant(role.ID, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// List returns the roles with their permissions
+func (r *RoleRepo) List() ([]model.Role, error) {
+	var roles []model.Role
+	if err := r.db.Model(&roles).Order("id ASC").Select(); err != nil {
+		r.log.Warn("RoleRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	var grants []model.RolePermission
+	if err := r.db.Model(&grants).Order("permission ASC").Select(); err != nil {
+		r.log.Warn("RoleRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	for i := range roles {
+		for _, g := range grants {
+			if g.RoleID == roles[i].ID {
+				roles[i].Permissions = append(roles[i].Permissions, g.Permission)
+			}
+		}
+	}
+	return roles, nil
+}
+
+// Grant grants permission to the role roleID
+func (r *RoleRepo) Grant(roleID int, permission string) error {
+	n, err := r.db.Model((*model.Role)(nil)).Where("id = ?", roleID).Count()
+	if err != nil {
+		r.log.Warn("RoleRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	if n == 0 {
+		return apperr.New(http.StatusNotFound, "Role not found.")
+	}
+	_, err = r.db.Model(&model.RolePermission{RoleID: roleID, Permission: permission}).OnConflict("DO NOTHING").Insert()
+	if err != nil {
+		r.log.Warn("RoleRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// Revoke revokes permission from the role roleID
+func (r *RoleRepo) Revoke(roleID int, permission string) error {
+	_, err := r.db.Model((*model.RolePermission)(nil)).Where("role_id = ? AND permission = ?", roleID, permission).Delete()
+	if err != nil {
+		r.log.Warn("RoleRepo Error", zap.Error(err))
+		return apperr.DB
+	}
 	return nil
 }
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewSessionRepo returns a SessionRepo instance
+func NewSessionRepo(db orm.DB, log *zap.Logger) *SessionRepo {
+	return &SessionRepo{db, log}
+}
+
+// SessionRepo represents the client for the sessions table
+type SessionRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// Create stores a new session
+func (r *SessionRepo) Create(s *model.Session) error {
+	now := time.Now()
+	s.CreatedAt = now
+	s.LastSeenAt = now
+	if err := r.db.Insert(s); err != nil {
+		r.log.Warn("SessionRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// View returns a single session by ID
+func (r *SessionRepo) View(id string) (*model.Session, error) {
+	s := &model.Session{ID: id}
+	err := r.db.Select(s)
+	if err == pg.ErrNoRows {
+		return nil, apperr.New(http.StatusNotFound, "Session not found.")
+	}
+	if err != nil {
+		r.log.Warn("SessionRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return s, nil
+}
+
+// ListByUser returns the active sessions of a user, most recently seen first
+func (r *SessionRepo) ListByUser(userID int) ([]model.Session, error) {
+	var sessions []model.Session
+	err := r.db.Model(&sessions).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Select()
+	if err != nil {
+		r.log.Warn("SessionRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return sessions, nil
+}
+
+// Touch records that the session was just used from its current IP and user agent
+func (r *SessionRepo) Touch(s *model.Session) error {
+	s.LastSeenAt = time.Now()
+	_, err := r.db.Model(s).Column("last_seen_at", "ip", "user_agent").WherePK().Update()
+	if err != nil {
+		r.log.Warn("SessionRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// Revoke ends a session
+func (r *SessionRepo) Revoke(id string) error {
+	_, err := r.db.Model((*model.Session)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update()
+	if err != nil {
+		r.log.Warn("SessionRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// RevokeUser ends every session of a user
+func (r *SessionRepo) RevokeUser(userID int) error {
+	_, err := r.db.Model((*model.Session)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update()
+	if err != nil {
+		r.log.Warn("SessionRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// StepUp records that the user just confirmed a second factor in the session
+func (r *SessionRepo) StepUp(id string) error {
+	_, err := r.db.Model((*model.Session)(nil)).
+		Set("step_up_at = ?", time.Now()).
+		Where("id = ?", id).
+		Update()
+	if err != nil {
+		r.log.Warn("SessionRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// Active reports whether a session exists, has not expired and has not been revoked
+func (r *SessionRepo) Active(id string) (bool, error) {
+	n, err := r.db.Model((*model.Session)(nil)).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, time.Now()).
+		Count()
+	if err != nil {
+		r.log.Warn("SessionRepo Error", zap.Error(err))
+		return false, apperr.DB
+	}
+	return n > 0, nil
+}
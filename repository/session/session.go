@@ -0,0 +1,81 @@
+package session
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewSessionService creates a new session application service
+func NewSessionService(sessionRepo model.SessionRepo, tokenRepo model.RefreshTokenRepo, revoker model.TokenRevoker, auth model.AuthService) *Service {
+	return &Service{
+		sessionRepo: sessionRepo,
+		tokenRepo:   tokenRepo,
+		revoker:     revoker,
+		auth:        auth,
+	}
+}
+
+// Service represents the session application service
+type Service struct {
+	sessionRepo model.SessionRepo
+	tokenRepo   model.RefreshTokenRepo
+	revoker     model.TokenRevoker
+	auth        model.AuthService
+}
+
+// List returns the active sessions of the current user, marking the one making the request
+func (s *Service) List(c *gin.Context) ([]model.Session, error) {
+	u := s.auth.User(c)
+	sessions, err := s.sessionRepo.ListByUser(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == u.SessionID
+	}
+	return sessions, nil
+}
+
+// Revoke ends one of the current user's sessions and invalidates its tokens
+func (s *Service) Revoke(c *gin.Context, id string) error {
+	u := s.auth.User(c)
+	session, err := s.sessionRepo.View(id)
+	if err != nil {
+		return err
+	}
+	if session.UserID != u.ID { // don't reveal other users' sessions
+		return apperr.New(http.StatusNotFound, "Session not found.")
+	}
+	if err := s.tokenRepo.RevokeFamily(id); err != nil {
+		return err
+	}
+	return s.sessionRepo.Revoke(id)
+}
+
+// RevokeAll logs the current user out everywhere, including the session making the request
+func (s *Service) RevokeAll(c *gin.Context) error {
+	u := s.auth.User(c)
+	if err := s.tokenRepo.RevokeUser(u.ID); err != nil {
+		return err
+	}
+	return s.sessionRepo.RevokeUser(u.ID)
+}
+
+// Logout revokes the access token of the request and ends its session
+func (s *Service) Logout(c *gin.Context) error {
+	u := s.auth.User(c)
+	if err := s.revoker.Revoke(u.TokenID, u.ID, u.TokenExpires); err != nil {
+		return err
+	}
+	if u.SessionID == "" {
+		return nil
+	}
+	if err := s.tokenRepo.RevokeFamily(u.SessionID); err != nil {
+		return err
+	}
+	return s.sessionRepo.Revoke(u.SessionID)
+}
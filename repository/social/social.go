@@ -0,0 +1,148 @@
+package social
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/oidc"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidToken = apperr.New(http.StatusUnauthorized, "Invalid ID token.")
+
+// Verifier verifies the ID tokens of an identity provider
+type Verifier interface {
+	Verify(idToken, nonce string) (*oidc.Claims, error)
+}
+
+// Authenticator signs users in and returns the user of a request
+type Authenticator interface {
+	model.AuthService
+	SignIn(context.Context, *model.User) (*model.LoginResponseWithToken, error)
+}
+
+// NewSocialService creates a new social login application service for the providers in verifiers
+func NewSocialService(identities model.IdentityRepo, userRepo model.UserRepo, accountRepo model.AccountRepo, auth Authenticator, verifiers map[string]Verifier) *Service {
+	return &Service{identities, userRepo, accountRepo, auth, verifiers}
+}
+
+// Service represents the social login application service
+type Service struct {
+	identities  model.IdentityRepo
+	userRepo    model.UserRepo
+	accountRepo model.AccountRepo
+	auth        Authenticator
+	verifiers   map[string]Verifier
+}
+
+// Login signs a user in with an ID token of provider. Unknown identities are linked to the user
+// with the same email address, if the provider verified it, or else to a new user.
+func (s *Service) Login(c context.Context, provider, idToken, nonce string) (*model.LoginResponseWithToken, error) {
+	claims, err := s.verify(provider, idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	i, err := s.identities.Find(provider, claims.Subject)
+	if err == nil {
+		u, err := s.userRepo.View(i.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.identities.Touch(i); err != nil {
+			return nil, err
+		}
+		return s.auth.SignIn(c, u)
+	}
+	if err != apperr.NotFound {
+		return nil, err
+	}
+
+	// a new identity, link it by email
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, apperr.New(http.StatusUnauthorized, "The email address of this account is not verified.")
+	}
+	u, err := s.userRepo.FindByEmail(claims.Email)
+	if err == nil && !u.Verified {
+		// whoever signed up with this email address never proved they own it, so linking
+		// would hand the account to them; the owner has to verify it first
+		return nil, apperr.New(http.StatusConflict, "An account with this email address exists. Please sign in with your password and verify your email address first.")
+	}
+	if err != nil {
+		if u, err = s.signup(c, claims); err != nil {
+			return nil, err
+		}
+	}
+	i = &model.Identity{UserID: u.ID, Provider: provider, Subject: claims.Subject, Email: claims.Email}
+	if err := s.identities.Create(i); err != nil {
+		return nil, err
+	}
+	return s.auth.SignIn(c, u)
+}
+
+// signup creates a verified user of the current tenant for the email address of claims, without a usable password
+func (s *Service) signup(c context.Context, claims *oidc.Claims) (*model.User, error) {
+	id, err := s.accountRepo.CreateWithMagic(&model.User{
+		Email:    claims.Email,
+		Verified: true,
+		Active:   true,
+		TenantID: model.TenantIDOf(c),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.userRepo.View(id)
+}
+
+// Identities returns the identities linked to the current user
+func (s *Service) Identities(c *gin.Context) ([]model.Identity, error) {
+	return s.identities.List(s.auth.User(c).ID)
+}
+
+// Link links an identity of provider to the current user
+func (s *Service) Link(c *gin.Context, provider, idToken, nonce string) (*model.Identity, error) {
+	claims, err := s.verify(provider, idToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	i := &model.Identity{UserID: s.auth.User(c).ID, Provider: provider, Subject: claims.Subject, Email: claims.Email}
+	if err := s.identities.Create(i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// Unlink removes an identity of the current user. Users keep signing in with their
+// email address or mobile number, so the last identity can only go if they have one.
+func (s *Service) Unlink(c *gin.Context, id int) error {
+	au := s.auth.User(c)
+	identities, err := s.identities.List(au.ID)
+	if err != nil {
+		return err
+	}
+	if len(identities) == 1 && identities[0].ID == id {
+		u, err := s.userRepo.View(au.ID)
+		if err != nil {
+			return err
+		}
+		if u.Email == "" && u.Mobile == "" {
+			return apperr.New(http.StatusConflict, "This is the only way to sign in to your account.")
+		}
+	}
+	return s.identities.Delete(au.ID, id)
+}
+
+// verify returns the claims of an ID token of provider
+func (s *Service) verify(provider, idToken, nonce string) (*oidc.Claims, error) {
+	v, ok := s.verifiers[provider]
+	if !ok {
+		return nil, apperr.New(http.StatusNotFound, "Unknown identity provider.")
+	}
+	claims, err := v.Verify(idToken, nonce)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	return claims, nil
+}
@@ -0,0 +1,117 @@
+package social_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/oidc"
+	"github.com/alpacahq/ribbit-backend/repository/social"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// verifier accepts the ID tokens in its map
+type verifier map[string]*oidc.Claims
+
+func (v verifier) Verify(idToken, nonce string) (*oidc.Claims, error) {
+	if c, ok := v[idToken]; ok {
+		return c, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+func TestLogin(t *testing.T) {
+	tokens := verifier{
+		"known":      {Subject: "g-1", Email: "known@mail.com", EmailVerified: true},
+		"existing":   {Subject: "g-2", Email: "jane@mail.com", EmailVerified: true},
+		"unverified": {Subject: "g-3", Email: "pending@mail.com", EmailVerified: true},
+		"new":        {Subject: "g-4", Email: "new@mail.com", EmailVerified: true},
+		"noemail":    {Subject: "g-5", Email: "hidden@mail.com"},
+	}
+	users := map[string]*model.User{
+		"jane@mail.com":    {ID: 2, Email: "jane@mail.com", Verified: true, Active: true},
+		"pending@mail.com": {ID: 3, Email: "pending@mail.com"},
+	}
+	userRepo := &mockdb.User{
+		ViewFn: func(id int) (*model.User, error) {
+			return &model.User{ID: id, Verified: true, Active: true}, nil
+		},
+		FindByEmailFn: func(email string) (*model.User, error) {
+			if u, ok := users[email]; ok {
+				return u, nil
+			}
+			return nil, apperr.NotFound
+		},
+	}
+
+	cases := []struct {
+		name       string
+		provider   string
+		token      string
+		wantUserID int
+		wantLinked bool
+		wantSignup bool
+		wantStatus int
+	}{
+		{name: "Linked identity", provider: oidc.Google, token: "known", wantUserID: 1},
+		{name: "Links to the user with the verified email", provider: oidc.Google, token: "existing", wantUserID: 2, wantLinked: true},
+		{name: "Refuses users who never verified the email", provider: oidc.Google, token: "unverified", wantStatus: http.StatusConflict},
+		{name: "Signs up new users", provider: oidc.Google, token: "new", wantUserID: 4, wantLinked: true, wantSignup: true},
+		{name: "Refuses unverified emails", provider: oidc.Google, token: "noemail", wantStatus: http.StatusUnauthorized},
+		{name: "Invalid token", provider: oidc.Google, token: "forged", wantStatus: http.StatusUnauthorized},
+		{name: "Unknown provider", provider: "facebook", token: "known", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var linked *model.Identity
+			signedUp := false
+			identities := &mockdb.Identity{
+				FindFn: func(provider, subject string) (*model.Identity, error) {
+					if provider == oidc.Google && subject == "g-1" {
+						return &model.Identity{ID: 1, UserID: 1, Provider: provider, Subject: subject}, nil
+					}
+					return nil, apperr.NotFound
+				},
+				TouchFn: func(*model.Identity) error {
+					return nil
+				},
+				CreateFn: func(i *model.Identity) error {
+					linked = i
+					return nil
+				},
+			}
+			accountRepo := &mockdb.Account{
+				CreateWithMagicFn: func(u *model.User) (int, error) {
+					signedUp = u.Verified && u.Active
+					return 4, nil
+				},
+			}
+			auth := &mock.Auth{
+				SignInFn: func(_ context.Context, u *model.User) (*model.LoginResponseWithToken, error) {
+					return &model.LoginResponseWithToken{Token: "jwt", User: *u}, nil
+				},
+			}
+			s := social.NewSocialService(identities, userRepo, accountRepo, auth, map[string]social.Verifier{oidc.Google: tokens})
+			r, err := s.Login(context.Background(), tt.provider, tt.token, "")
+			if tt.wantStatus != 0 {
+				assert.Equal(t, tt.wantStatus, err.(*apperr.APPError).Status)
+				assert.Nil(t, linked)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantUserID, r.User.ID)
+			assert.Equal(t, tt.wantSignup, signedUp)
+			if tt.wantLinked {
+				assert.Equal(t, &model.Identity{UserID: tt.wantUserID, Provider: oidc.Google, Subject: tokens[tt.token].Subject, Email: tokens[tt.token].Email}, linked)
+			} else {
+				assert.Nil(t, linked)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewTenantRepo returns a TenantRepo instance. Broker tokens are encrypted with cipher at rest.
+func NewTenantRepo(db orm.DB, log *zap.Logger, cipher secret.FieldCipher) *TenantRepo {
+	return &TenantRepo{db, log, cipher}
+}
+
+// TenantRepo represents the client for the tenants table
+type TenantRepo struct {
+	db     orm.DB
+	log    *zap.Logger
+	cipher secret.FieldCipher
+}
+
+// List returns all tenants, by ID
+func (r *TenantRepo) List() ([]model.Tenant, error) {
+	var tenants []model.Tenant
+	if err := r.db.Model(&tenants).Order("id").Select(); err != nil {
+		r.log.Warn("TenantRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	for i := range tenants {
+		if err := r.open(&tenants[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tenants, nil
+}
+
+// FindByName returns the tenant with a name
+func (r *TenantRepo) FindByName(name string) (*model.Tenant, error) {
+	t := new(model.Tenant)
+	if err := r.db.Model(t).Where("name = ?", name).Select(); err == pg.ErrNoRows {
+		return nil, apperr.New(http.StatusNotFound, "Tenant not found.")
+	} else if err != nil {
+		r.log.Warn("TenantRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return t, r.open(t)
+}
+
+// Save creates t, or updates it if it has an ID
+func (r *TenantRepo) Save(t *model.Tenant) error {
+	token, err := r.cipher.Encrypt(t.BrokerToken)
+	if err != nil {
+		r.log.Error("TenantRepo Error: failed to encrypt broker token", zap.Error(err))
+		return apperr.Generic
+	}
+	sealed := *t
+	sealed.BrokerToken = token
+	if t.ID == 0 {
+		_, err = r.db.Model(&sealed).Value("created_at", "now()").Value("updated_at", "now()").Returning("id").Insert()
+	} else {
+		_, err = r.db.Model(&sealed).Value("updated_at", "now()").ExcludeColumn("created_at").WherePK().Update()
+	}
+	if err != nil {
+		r.log.Warn("TenantRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	t.ID = sealed.ID
+	return nil
+}
+
+func (r *TenantRepo) open(t *model.Tenant) error {
+	token, err := r.cipher.Decrypt(t.BrokerToken)
+	if err != nil {
+		r.log.Error("TenantRepo Error: failed to decrypt broker token", zap.Int("id", t.ID), zap.Error(err))
+		return apperr.Generic
+	}
+	t.BrokerToken = token
+	return nil
+}
@@ -0,0 +1,119 @@
+package tenant
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// DefaultInterval is how often stores reload tenants from Postgres
+const DefaultInterval = time.Minute
+
+// Default returns the default tenant, of the hosts no tenant claims and the users who signed up on them
+func Default(sc *config.SiteConfig, mc *config.MailConfig, bc *config.BrokerConfig) *model.Tenant {
+	return &model.Tenant{
+		Name:          sc.Name,
+		LogoURL:       sc.LogoURL,
+		PrimaryColor:  sc.PrimaryColor,
+		LinkDomain:    sc.LinkDomain,
+		MailFromName:  mc.Name,
+		MailFromEmail: mc.Email,
+		BrokerAPIBase: bc.APIBase,
+		BrokerToken:   bc.Token,
+	}
+}
+
+// NewStore creates a tenant store backed by repo. Tenants leave the settings they don't have to def,
+// and changes made with save_tenant are picked up within interval.
+func NewStore(repo model.TenantRepo, def *model.Tenant, interval time.Duration) *Store {
+	return &Store{
+		repo:     repo,
+		def:      def,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Store keeps the tenants in memory, so that resolving the tenant of a request doesn't query Postgres
+type Store struct {
+	repo     model.TenantRepo
+	def      *model.Tenant
+	interval time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	byID   map[int]*model.Tenant
+	byHost map[string]*model.Tenant
+	synced time.Time
+}
+
+// Default returns the default tenant
+func (s *Store) Default() *model.Tenant {
+	return s.def
+}
+
+// ByHost returns the tenant of a host, which may have a port, or the default tenant if none claims it
+func (s *Store) ByHost(host string) (*model.Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.sync(); err != nil {
+		return nil, err
+	}
+	if t, ok := s.byHost[normalizeHost(host)]; ok {
+		return t, nil
+	}
+	return s.def, nil
+}
+
+// ByID returns a tenant, or the default tenant for ID 0
+func (s *Store) ByID(id int) (*model.Tenant, error) {
+	if id == 0 {
+		return s.def, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.sync(); err != nil {
+		return nil, err
+	}
+	if t, ok := s.byID[id]; ok {
+		return t, nil
+	}
+	return nil, apperr.New(http.StatusNotFound, "Tenant not found.")
+}
+
+// sync reloads the tenants once they are older than the interval
+func (s *Store) sync() error {
+	now := s.now()
+	if !s.synced.IsZero() && now.Sub(s.synced) < s.interval {
+		return nil
+	}
+	tenants, err := s.repo.List()
+	if err != nil {
+		return err
+	}
+	byID, byHost := make(map[int]*model.Tenant, len(tenants)), map[string]*model.Tenant{}
+	for i := range tenants {
+		t := &tenants[i]
+		t.Inherit(s.def)
+		byID[t.ID] = t
+		for _, h := range t.Hosts {
+			byHost[normalizeHost(h)] = t
+		}
+	}
+	s.byID, s.byHost, s.synced = byID, byHost, now
+	return nil
+}
+
+// normalizeHost strips the port and trailing dot of a host and lower cases it
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
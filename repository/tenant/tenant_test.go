@@ -0,0 +1,63 @@
+package tenant_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/tenant"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	loads := 0
+	repo := &mockdb.Tenant{
+		ListFn: func() ([]model.Tenant, error) {
+			loads++
+			return []model.Tenant{
+				{ID: 1, Name: "Acme", Hosts: []string{"App.Acme.com"}, MailFromEmail: "hello@acme.com", BrokerAPIBase: "https://broker.acme.com", BrokerToken: "Basic acme"},
+				{ID: 2, Name: "Globex", Hosts: []string{"globex.io"}, LinkDomain: "https://globex.io"},
+				{ID: 3, Name: "Initech", Hosts: []string{"initech.com"}, BrokerAPIBase: "https://broker.initech.com"},
+			}, nil
+		},
+	}
+	def := tenant.Default(
+		&config.SiteConfig{Name: "Ribbit", LinkDomain: "https://alpaca.com"},
+		&config.MailConfig{Name: "Ribbit App", Email: "ribbitapp@ribbitapp.co"},
+		&config.BrokerConfig{APIBase: "https://broker-api.sandbox.alpaca.markets", Token: "Basic default"},
+	)
+	s := tenant.NewStore(repo, def, time.Hour)
+
+	acme, err := s.ByHost("app.acme.com:443")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, acme.ID)
+	assert.Equal(t, "Acme", acme.MailFromName, "tenants send as themselves")
+	assert.Equal(t, "hello@acme.com", acme.MailFromEmail)
+	assert.Equal(t, "Basic acme", acme.BrokerToken)
+	assert.Equal(t, "https://alpaca.com", acme.LinkDomain, "settings tenants leave empty are the default ones")
+
+	globex, err := s.ByID(2)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://globex.io", globex.LinkDomain)
+	assert.Equal(t, "ribbitapp@ribbitapp.co", globex.MailFromEmail)
+	assert.Equal(t, "https://broker-api.sandbox.alpaca.markets", globex.BrokerAPIBase)
+	assert.Equal(t, "Basic default", globex.BrokerToken)
+
+	initech, err := s.ByID(3)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://broker.initech.com", initech.BrokerAPIBase)
+	assert.Empty(t, initech.BrokerToken, "the default token isn't sent to the broker API base of a tenant")
+
+	other, err := s.ByHost("localhost:8080")
+	assert.Nil(t, err)
+	assert.Equal(t, def, other, "hosts no tenant claims are the default tenant's")
+	zero, _ := s.ByID(0)
+	assert.Equal(t, def, zero)
+
+	_, err = s.ByID(4)
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, loads, "tenants are loaded once per interval")
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewThrottleRepo returns a ThrottleRepo instance
+func NewThrottleRepo(db orm.DB, log *zap.Logger) *ThrottleRepo {
+	return &ThrottleRepo{db, log}
+}
+
+// ThrottleRepo represents the client for failed attempt counters
+type ThrottleRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// FindAll returns the counters of the given keys that exist
+func (r *ThrottleRepo) FindAll(keys []string) ([]model.Throttle, error) {
+	var throttles []model.Throttle
+	err := r.db.Model(&throttles).WhereIn("key IN (?)", keys).Select()
+	if err != nil {
+		r.log.Warn("ThrottleRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return throttles, nil
+}
+
+// Increment atomically counts a failed attempt for key. Failures before since are
+// forgotten, so the count restarts at one when the previous failure is older than that.
+func (r *ThrottleRepo) Increment(key string, since time.Time) (*model.Throttle, error) {
+	t := &model.Throttle{Key: key, Failures: 1, UpdatedAt: time.Now()}
+	_, err := r.db.Model(t).
+		OnConflict("(key) DO UPDATE").
+		Set("failures = CASE WHEN throttle.updated_at < ? THEN 1 ELSE throttle.failures + 1 END", since).
+		Set("updated_at = EXCLUDED.updated_at").
+		Returning("*").
+		Insert()
+	if err != nil {
+		r.log.Warn("ThrottleRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return t, nil
+}
+
+// Lock refuses attempts for key until the given time
+func (r *ThrottleRepo) Lock(key string, until time.Time) error {
+	_, err := r.db.Model((*model.Throttle)(nil)).
+		Set("locked_until = ?", until).
+		Where("key = ?", key).
+		Update()
+	if err != nil {
+		r.log.Warn("ThrottleRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// Reset deletes the counter of key
+func (r *ThrottleRepo) Reset(key string) error {
+	_, err := r.db.Model((*model.Throttle)(nil)).Where("key = ?", key).Delete()
+	if err != nil {
+		r.log.Warn("ThrottleRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// DeleteStale deletes the unlocked counters whose last failure was before t, returning how many were deleted
+func (r *ThrottleRepo) DeleteStale(t time.Time) (int, error) {
+	res, err := r.db.Model((*model.Throttle)(nil)).
+		Where("updated_at < ? AND (locked_until IS NULL OR locked_until < ?)", t, time.Now()).
+		Delete()
+	if err != nil {
+		r.log.Warn("ThrottleRepo Error", zap.Error(err))
+		return 0, apperr.DB
+	}
+	return res.RowsAffected(), nil
+}
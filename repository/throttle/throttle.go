@@ -0,0 +1,108 @@
+package throttle
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/model"
+)
+
+// NewLimiter creates a limiter of failed attempts with the limits of cfg
+func NewLimiter(repo model.ThrottleRepo, cfg *config.ThrottleConfig) *Limiter {
+	return &Limiter{
+		repo: repo,
+		cfg:  cfg,
+		now:  time.Now,
+	}
+}
+
+// Limiter locks keys once they exceed the limit of their kind. Every further
+// failure after a lockout doubles the next one, up to the configured maximum.
+type Limiter struct {
+	repo model.ThrottleRepo
+	cfg  *config.ThrottleConfig
+	now  func() time.Time
+}
+
+// Check returns a 429 error if any of the keys is locked
+func (l *Limiter) Check(keys ...string) error {
+	throttles, err := l.repo.FindAll(keys)
+	if err != nil {
+		return err
+	}
+	now := l.now()
+	var until time.Time
+	for _, t := range throttles {
+		if t.Locked(now) && t.LockedUntil.After(until) {
+			until = *t.LockedUntil
+		}
+	}
+	if until.IsZero() {
+		return nil
+	}
+	return apperr.New(http.StatusTooManyRequests, fmt.Sprintf("Too many failed attempts. Please try again in %s.", wait(until.Sub(now))))
+}
+
+// Fail counts a failed attempt for key and reports whether it locked the key
+func (l *Limiter) Fail(key string) (bool, error) {
+	now := l.now()
+	t, err := l.repo.Increment(key, now.Add(-l.cfg.Window))
+	if err != nil {
+		return false, err
+	}
+	limit := l.limit(t.Kind())
+	if t.Failures < limit {
+		return false, nil
+	}
+	if err := l.repo.Lock(key, now.Add(l.lockout(t.Failures-limit))); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Reset forgets the failed attempts for key
+func (l *Limiter) Reset(key string) error {
+	return l.repo.Reset(key)
+}
+
+func (l *Limiter) limit(kind string) int {
+	switch kind {
+	case model.ThrottleIP:
+		return l.cfg.IPLimit
+	case model.ThrottleOTP:
+		return l.cfg.OTPLimit
+	case model.ThrottleSMS:
+		return l.cfg.SMSLimit
+	case model.ThrottlePasskey:
+		return l.cfg.PasskeyLimit
+	}
+	return l.cfg.AccountLimit
+}
+
+// lockout doubles the first lockout for each failure past the limit
+func (l *Limiter) lockout(extra int) time.Duration {
+	d := l.cfg.Lockout
+	for i := 0; i < extra && d < l.cfg.MaxLockout; i++ {
+		d *= 2
+	}
+	if d > l.cfg.MaxLockout {
+		d = l.cfg.MaxLockout
+	}
+	return d
+}
+
+// wait describes how long a client has to wait, rounded up
+func wait(d time.Duration) string {
+	minutes := int(math.Ceil(d.Minutes()))
+	switch {
+	case minutes <= 1:
+		return "a minute"
+	case minutes < 120:
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return fmt.Sprintf("%d hours", int(math.Ceil(d.Hours())))
+}
@@ -0,0 +1,98 @@
+package throttle_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/throttle"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	throttles := map[string]*model.Throttle{}
+	repo := &mockdb.Throttle{
+		FindAllFn: func(keys []string) ([]model.Throttle, error) {
+			var found []model.Throttle
+			for _, k := range keys {
+				if t, ok := throttles[k]; ok {
+					found = append(found, *t)
+				}
+			}
+			return found, nil
+		},
+		IncrementFn: func(key string, since time.Time) (*model.Throttle, error) {
+			t, ok := throttles[key]
+			if !ok || t.UpdatedAt.Before(since) {
+				t = &model.Throttle{Key: key}
+				throttles[key] = t
+			}
+			t.Failures++
+			t.UpdatedAt = time.Now()
+			return t, nil
+		},
+		LockFn: func(key string, until time.Time) error {
+			throttles[key].LockedUntil = &until
+			return nil
+		},
+		ResetFn: func(key string) error {
+			delete(throttles, key)
+			return nil
+		},
+	}
+	l := throttle.NewLimiter(repo, &config.ThrottleConfig{
+		AccountLimit: 3,
+		IPLimit:      10,
+		OTPLimit:     2,
+		SMSLimit:     1,
+		Lockout:      time.Minute,
+		MaxLockout:   4 * time.Minute,
+		Window:       time.Hour,
+	})
+	account := model.ThrottleKey(model.ThrottleAccount, " Jane@Example.com")
+	ip := model.ThrottleKey(model.ThrottleIP, "10.0.0.1")
+	assert.Equal(t, "account:jane@example.com", account)
+
+	for i := 1; i <= 3; i++ {
+		assert.Nil(t, l.Check(account, ip))
+		locked, err := l.Fail(account)
+		assert.Nil(t, err)
+		assert.Equal(t, i == 3, locked, "failure %d", i)
+	}
+	err := l.Check(account, ip)
+	assert.Equal(t, http.StatusTooManyRequests, err.(*apperr.APPError).Status)
+	assert.Equal(t, "Too many failed attempts. Please try again in a minute.", err.(*apperr.APPError).Message)
+	assert.Nil(t, l.Check(ip), "other keys are not locked")
+
+	lockout := func() time.Duration {
+		return time.Until(*throttles[account].LockedUntil).Round(time.Minute)
+	}
+	assert.Equal(t, time.Minute, lockout())
+	l.Fail(account)
+	assert.Equal(t, 2*time.Minute, lockout(), "backoff doubles")
+	l.Fail(account)
+	l.Fail(account)
+	assert.Equal(t, 4*time.Minute, lockout(), "backoff is capped")
+
+	assert.Nil(t, l.Reset(account))
+	assert.Nil(t, l.Check(account, ip))
+
+	otp := model.ThrottleKey(model.ThrottleOTP, "+15551234567")
+	l.Fail(otp)
+	locked, _ := l.Fail(otp)
+	assert.True(t, locked, "otp keys have their own limit")
+
+	locked, _ = l.Fail(model.ThrottleKey(model.ThrottleSMS, "+15551234567"))
+	assert.True(t, locked, "sms keys have their own limit")
+
+	throttles[otp].UpdatedAt = time.Now().Add(-2 * time.Hour)
+	throttles[otp].LockedUntil = nil
+	locked, _ = l.Fail(otp)
+	assert.False(t, locked, "failures outside the window are forgotten")
+	assert.Equal(t, 1, throttles[otp].Failures)
+}
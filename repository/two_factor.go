@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewTwoFactorRepo returns a TwoFactorRepo instance
+func NewTwoFactorRepo(db orm.DB, log *zap.Logger, cipher secret.FieldCipher) *TwoFactorRepo {
+	return &TwoFactorRepo{db, log, cipher}
+}
+
+// TwoFactorRepo represents the client for TOTP secrets and recovery codes
+type TwoFactorRepo struct {
+	db     orm.DB
+	log    *zap.Logger
+	cipher secret.FieldCipher
+}
+
+// Find returns the two-factor settings of a user, with the secret decrypted
+func (r *TwoFactorRepo) Find(userID int) (*model.TwoFactor, error) {
+	t := &model.TwoFactor{UserID: userID}
+	err := r.db.Select(t)
+	if err == pg.ErrNoRows {
+		return nil, apperr.New(http.StatusNotFound, "Two-factor authentication is not set up.")
+	}
+	if err != nil {
+		r.log.Warn("TwoFactorRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	if t.Secret, err = r.cipher.Decrypt(t.Secret); err != nil {
+		r.log.Error("TwoFactorRepo Error: failed to decrypt secret", zap.Int("user_id", userID), zap.Error(err))
+		return nil, apperr.Generic
+	}
+	return t, nil
+}
+
+// Save creates or replaces the two-factor settings of a user, encrypting the secret
+func (r *TwoFactorRepo) Save(t *model.TwoFactor) error {
+	sealed := *t
+	var err error
+	if sealed.Secret, err = r.cipher.Encrypt(t.Secret); err != nil {
+		r.log.Error("TwoFactorRepo Error: failed to encrypt secret", zap.Error(err))
+		return apperr.Generic
+	}
+	if sealed.CreatedAt.IsZero() {
+		sealed.CreatedAt = time.Now()
+	}
+	_, err = r.db.Model(&sealed).
+		OnConflict("(user_id) DO UPDATE").
+		Set("secret = EXCLUDED.secret, confirmed_at = EXCLUDED.confirmed_at, last_step = EXCLUDED.last_step, created_at = EXCLUDED.created_at").
+		Insert()
+	if err != nil {
+		r.log.Warn("TwoFactorRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	t.CreatedAt = sealed.CreatedAt
+	return nil
+}
+
+// Delete turns off two-factor authentication for a user and removes their recovery codes
+func (r *TwoFactorRepo) Delete(userID int) error {
+	if _, err := r.db.Model((*model.RecoveryCode)(nil)).Where("user_id = ?", userID).Delete(); err != nil {
+		r.log.Warn("TwoFactorRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	if _, err := r.db.Model((*model.TwoFactor)(nil)).Where("user_id = ?", userID).Delete(); err != nil {
+		r.log.Warn("TwoFactorRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// UseStep records that a code of TOTP time step was accepted. It reports false if
+// that step or a later one was already used, so concurrent requests cannot replay a code.
+func (r *TwoFactorRepo) UseStep(userID int, step int64) (bool, error) {
+	res, err := r.db.Model((*model.TwoFactor)(nil)).
+		Set("last_step = ?", step).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update()
+	if err != nil {
+		r.log.Warn("TwoFactorRepo Error", zap.Error(err))
+		return false, apperr.DB
+	}
+	return res.RowsAffected() > 0, nil
+}
+
+// ReplaceRecoveryCodes replaces a user's recovery codes with new hashes
+func (r *TwoFactorRepo) ReplaceRecoveryCodes(userID int, hashes []string) error {
+	if _, err := r.db.Model((*model.RecoveryCode)(nil)).Where("user_id = ?", userID).Delete(); err != nil {
+		r.log.Warn("TwoFactorRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	codes := make([]model.RecoveryCode, len(hashes))
+	for i, h := range hashes {
+		codes[i] = model.RecoveryCode{UserID: userID, CodeHash: h}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	if err := r.db.Insert(&codes); err != nil {
+		r.log.Warn("TwoFactorRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// UnusedRecoveryCodes returns the recovery codes of a user that were not used yet
+func (r *TwoFactorRepo) UnusedRecoveryCodes(userID int) ([]model.RecoveryCode, error) {
+	var codes []model.RecoveryCode
+	err := r.db.Model(&codes).Where("user_id = ? AND used_at IS NULL", userID).Select()
+	if err != nil {
+		r.log.Warn("TwoFactorRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode marks a recovery code as used. It reports false if it already was.
+func (r *TwoFactorRepo) UseRecoveryCode(id int) (bool, error) {
+	res, err := r.db.Model((*model.RecoveryCode)(nil)).
+		Set("used_at = ?", time.Now()).
+		Where("id = ? AND used_at IS NULL", id).
+		Update()
+	if err != nil {
+		r.log.Warn("TwoFactorRepo Error", zap.Error(err))
+		return false, apperr.DB
+	}
+	return res.RowsAffected() > 0, nil
+}
@@ -0,0 +1,279 @@
+package twofactor
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecoveryCodeCount is the number of recovery codes generated for a user
+const RecoveryCodeCount = 10
+
+// recoveryAlphabet avoids characters that are easily confused when written down
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+var errInvalidCode = apperr.New(http.StatusUnauthorized, "Invalid two-factor code.")
+
+// NewVerifier creates a verifier of second factors, as used by login
+func NewVerifier(tfRepo model.TwoFactorRepo, sec secret.Service) *Verifier {
+	return &Verifier{
+		tfRepo: tfRepo,
+		secret: sec,
+		now:    time.Now,
+	}
+}
+
+// Verifier checks TOTP and recovery codes
+type Verifier struct {
+	tfRepo model.TwoFactorRepo
+	secret secret.Service
+	now    func() time.Time
+}
+
+// NewTwoFactorService creates a new two-factor authentication application service.
+// issuer names the account in authenticator apps.
+func NewTwoFactorService(v *Verifier, userRepo model.UserRepo, sessionRepo model.SessionRepo, auth model.AuthService, throttle model.Throttler, issuer string) *Service {
+	return &Service{
+		Verifier:    v,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		auth:        auth,
+		throttle:    throttle,
+		issuer:      issuer,
+	}
+}
+
+// Service represents the two-factor authentication application service
+type Service struct {
+	*Verifier
+	userRepo    model.UserRepo
+	sessionRepo model.SessionRepo
+	auth        model.AuthService
+	throttle    model.Throttler
+	issuer      string
+}
+
+// Enroll starts setting up an authenticator app for the current user, replacing a pending setup
+func (s *Service) Enroll(c *gin.Context) (*model.TOTPEnrollment, error) {
+	au := s.auth.User(c)
+	if tf, err := s.tfRepo.Find(au.ID); err == nil && tf.Enabled() {
+		return nil, apperr.New(http.StatusConflict, "Two-factor authentication is already enabled.")
+	}
+	u, err := s.userRepo.View(au.ID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := secret.GenerateTOTPSecret()
+	if err != nil {
+		return nil, apperr.Generic
+	}
+	if err := s.tfRepo.Save(&model.TwoFactor{UserID: u.ID, Secret: key}); err != nil {
+		return nil, err
+	}
+	account := u.Email
+	if account == "" {
+		account = u.CountryCode + u.Mobile
+	}
+	return &model.TOTPEnrollment{
+		Secret: key,
+		URI:    secret.TOTPURI(s.issuer, account, key),
+	}, nil
+}
+
+// Confirm enables two-factor authentication once the user enters a code from their app.
+// It returns the recovery codes, which are shown to the user this one time only.
+func (s *Service) Confirm(c *gin.Context, code string) ([]string, error) {
+	au := s.auth.User(c)
+	tf, err := s.tfRepo.Find(au.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled() {
+		return nil, apperr.New(http.StatusConflict, "Two-factor authentication is already enabled.")
+	}
+	step, ok := secret.ValidateTOTP(tf.Secret, code, s.now())
+	if !ok {
+		return nil, errInvalidCode
+	}
+	now := s.now()
+	tf.ConfirmedAt = &now
+	tf.LastStep = step
+	if err := s.tfRepo.Save(tf); err != nil {
+		return nil, err
+	}
+	if err := s.markSession(au.SessionID); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(au.ID)
+}
+
+// Disable turns off two-factor authentication, after checking a current code
+func (s *Service) Disable(c *gin.Context, code string) error {
+	au := s.auth.User(c)
+	if err := s.verify(c, au.ID, code); err != nil {
+		return err
+	}
+	return s.tfRepo.Delete(au.ID)
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes, after checking a current code
+func (s *Service) RegenerateRecoveryCodes(c *gin.Context, code string) ([]string, error) {
+	au := s.auth.User(c)
+	if err := s.verify(c, au.ID, code); err != nil {
+		return nil, err
+	}
+	return s.newRecoveryCodes(au.ID)
+}
+
+// StepUp confirms the second factor in the current session, unlocking sensitive actions for a while
+func (s *Service) StepUp(c *gin.Context, code string) error {
+	au := s.auth.User(c)
+	if err := s.verify(c, au.ID, code); err != nil {
+		return err
+	}
+	return s.markSession(au.SessionID)
+}
+
+// verify checks a code of a signed in user, counting wrong codes against the user and the client IP
+// as login does, so that a stolen access token can't be used to guess codes
+func (s *Service) verify(c *gin.Context, userID int, code string) error {
+	key, ip := model.ThrottleKey(model.ThrottleOTP, strconv.Itoa(userID)), model.ThrottleKey(model.ThrottleIP, clientIP(c))
+	if err := s.throttle.Check(key, ip); err != nil {
+		return err
+	}
+	if err := s.Verify(userID, code); err != nil {
+		// errors are ignored, as the attempt fails either way
+		s.throttle.Fail(ip)
+		s.throttle.Fail(key)
+		return err
+	}
+	return s.throttle.Reset(key)
+}
+
+// clientIP returns the client IP of a request, or an empty string outside of requests
+func clientIP(c *gin.Context) string {
+	if c == nil || c.Request == nil {
+		return ""
+	}
+	return c.ClientIP()
+}
+
+// Enabled reports whether a user has confirmed two-factor authentication
+func (s *Verifier) Enabled(userID int) (bool, error) {
+	tf, err := s.tfRepo.Find(userID)
+	if err != nil {
+		if e, ok := err.(*apperr.APPError); ok && e.Status == http.StatusNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return tf.Enabled(), nil
+}
+
+// Verify checks a TOTP code or an unused recovery code of a user with two-factor authentication.
+// Each TOTP code and each recovery code is accepted once.
+func (s *Verifier) Verify(userID int, code string) error {
+	tf, err := s.tfRepo.Find(userID)
+	if err != nil {
+		return err
+	}
+	if !tf.Enabled() {
+		return apperr.New(http.StatusBadRequest, "Two-factor authentication is not enabled.")
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == secret.TOTPDigits {
+		step, ok := secret.ValidateTOTP(tf.Secret, code, s.now())
+		if !ok {
+			return errInvalidCode
+		}
+		fresh, err := s.tfRepo.UseStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return errInvalidCode
+		}
+		return nil
+	}
+	return s.useRecoveryCode(userID, normalizeRecoveryCode(code))
+}
+
+// SteppedUp reports whether a user without two-factor authentication, or whose session
+// confirmed the second factor within maxAge, may perform a sensitive action
+func (s *Service) SteppedUp(userID int, sessionID string, maxAge time.Duration) (bool, error) {
+	enabled, err := s.Enabled(userID)
+	if err != nil || !enabled {
+		return !enabled, err
+	}
+	if sessionID == "" {
+		return false, nil
+	}
+	session, err := s.sessionRepo.View(sessionID)
+	if err != nil {
+		return false, nil
+	}
+	return session.UserID == userID && session.StepUpAt != nil && s.now().Sub(*session.StepUpAt) < maxAge, nil
+}
+
+func (s *Service) markSession(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	return s.sessionRepo.StepUp(sessionID)
+}
+
+func (s *Verifier) useRecoveryCode(userID int, code string) error {
+	if len(code) != 10 {
+		return errInvalidCode
+	}
+	codes, err := s.tfRepo.UnusedRecoveryCodes(userID)
+	if err != nil {
+		return err
+	}
+	for _, rc := range codes {
+		if !s.secret.HashMatchesPassword(rc.CodeHash, code) {
+			continue
+		}
+		used, err := s.tfRepo.UseRecoveryCode(rc.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return errInvalidCode
+		}
+		return nil
+	}
+	return errInvalidCode
+}
+
+// newRecoveryCodes replaces a user's recovery codes and returns them formatted as xxxxx-xxxxx
+func (s *Verifier) newRecoveryCodes(userID int) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b, err := secret.GenerateRandomBytes(10)
+		if err != nil {
+			return nil, apperr.Generic
+		}
+		for j := range b {
+			b[j] = recoveryAlphabet[int(b[j])%len(recoveryAlphabet)]
+		}
+		hashes[i] = s.secret.HashPassword(string(b))
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+	}
+	if err := s.tfRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
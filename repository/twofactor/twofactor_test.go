@@ -0,0 +1,119 @@
+package twofactor_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/twofactor"
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	key, err := secret.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := secret.TOTPCode(key, secret.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sec := secret.New()
+	confirmed := time.Now()
+	lastStep := int64(0)
+	recovery := []model.RecoveryCode{{ID: 7, UserID: 1, CodeHash: sec.HashPassword("abcdefghjk")}}
+	repo := &mockdb.TwoFactor{
+		FindFn: func(id int) (*model.TwoFactor, error) {
+			switch id {
+			case 1:
+				return &model.TwoFactor{UserID: 1, Secret: key, ConfirmedAt: &confirmed}, nil
+			case 2:
+				return &model.TwoFactor{UserID: 2, Secret: key}, nil
+			}
+			return nil, apperr.New(http.StatusNotFound, "Two-factor authentication is not set up.")
+		},
+		UseStepFn: func(id int, step int64) (bool, error) {
+			if step <= lastStep {
+				return false, nil
+			}
+			lastStep = step
+			return true, nil
+		},
+		UnusedRecoveryCodesFn: func(int) ([]model.RecoveryCode, error) {
+			return recovery, nil
+		},
+		UseRecoveryCodeFn: func(id int) (bool, error) {
+			recovery = nil
+			return id == 7, nil
+		},
+	}
+	v := twofactor.NewVerifier(repo, sec)
+
+	enabled, err := v.Enabled(1)
+	assert.Nil(t, err)
+	assert.True(t, enabled)
+	enabled, err = v.Enabled(2)
+	assert.Nil(t, err)
+	assert.False(t, enabled, "pending setup")
+	enabled, err = v.Enabled(3)
+	assert.Nil(t, err)
+	assert.False(t, enabled, "not set up")
+
+	assert.NotNil(t, v.Verify(2, code), "pending setup")
+	assert.NotNil(t, v.Verify(1, "000000"))
+	assert.Nil(t, v.Verify(1, code))
+	assert.NotNil(t, v.Verify(1, code), "replayed code")
+
+	assert.NotNil(t, v.Verify(1, "zzzzz-zzzzz"))
+	assert.Nil(t, v.Verify(1, "ABCDE-FGHJK"))
+	assert.NotNil(t, v.Verify(1, "abcde-fghjk"), "used recovery code")
+}
+
+func TestStepUpThrottle(t *testing.T) {
+	var failed []string
+	locked := false
+	finds := 0
+	repo := &mockdb.TwoFactor{
+		FindFn: func(id int) (*model.TwoFactor, error) {
+			finds++
+			confirmed := time.Now()
+			return &model.TwoFactor{UserID: id, Secret: "JBSWY3DPEHPK3PXP", ConfirmedAt: &confirmed}, nil
+		},
+	}
+	throttler := &mock.Throttler{
+		CheckFn: func(keys ...string) error {
+			assert.Equal(t, []string{"otp:5", "ip:10.0.0.1"}, keys)
+			if locked {
+				return apperr.New(http.StatusTooManyRequests, "Too many attempts.")
+			}
+			return nil
+		},
+		FailFn: func(key string) (bool, error) {
+			failed = append(failed, key)
+			return false, nil
+		},
+	}
+	auth := &mock.Auth{
+		UserFn: func(*gin.Context) *model.AuthUser { return &model.AuthUser{ID: 5} },
+	}
+	s := twofactor.NewTwoFactorService(twofactor.NewVerifier(repo, secret.New()), nil, nil, auth, throttler, "Ribbit")
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/2fa/step-up", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+
+	assert.NotNil(t, s.StepUp(c, "000000"))
+	assert.ElementsMatch(t, []string{"otp:5", "ip:10.0.0.1"}, failed)
+
+	locked = true
+	err := s.StepUp(c, "000000")
+	assert.Equal(t, http.StatusTooManyRequests, err.(*apperr.APPError).Status)
+	assert.Equal(t, 1, finds, "codes aren't checked while locked")
+}
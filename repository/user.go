@@ -1,26 +1,69 @@
 package repository
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-pg/pg/v9/orm"
 	"go.uber.org/zap"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
 	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/secret"
 )
 
 const notDeleted = "deleted_at is null"
 
 // NewUserRepo returns a new UserRepo instance
-func NewUserRepo(db orm.DB, log *zap.Logger) *UserRepo {
-	return &UserRepo{db, log}
+func NewUserRepo(db orm.DB, log *zap.Logger, cipher secret.FieldCipher) *UserRepo {
+	return &UserRepo{db, log, cipher}
 }
 
 // UserRepo is the client for our user model
 type UserRepo struct {
-	db  orm.DB
-	log *zap.Logger
+	db     orm.DB
+	log    *zap.Logger
+	cipher secret.FieldCipher
+}
+
+// sensitiveFields returns the user's columns that are encrypted at rest
+func sensitiveFields(u *model.User) []*string {
+	return []*string{&u.TaxID, &u.DOB, &u.Address, &u.UnitApt}
+}
+
+// sealUser returns a copy of user with its sensitive fields encrypted, ready to be written
+func sealUser(cipher secret.FieldCipher, user *model.User) (*model.User, error) {
+	sealed := *user
+	for _, f := range sensitiveFields(&sealed) {
+		v, err := cipher.Encrypt(*f)
+		if err != nil {
+			return nil, err
+		}
+		*f = v
+	}
+	return &sealed, nil
+}
+
+// openUser decrypts the sensitive fields of a user read from the database in place
+func openUser(cipher secret.FieldCipher, user *model.User) error {
+	for _, f := range sensitiveFields(user) {
+		v, err := cipher.Decrypt(*f)
+		if err != nil {
+			return err
+		}
+		*f = v
+	}
+	return nil
+}
+
+func (u *UserRepo) open(user *model.User) (*model.User, error) {
+	if err := openUser(u.cipher, user); err != nil {
+		u.log.Error("UserRepo Error: failed to decrypt user", zap.Int("id", user.ID), zap.Error(err))
+		return nil, apperr.Generic
+	}
+	return user, nil
 }
 
 // View returns single user by ID
@@ -34,7 +77,7 @@ func (u *UserRepo) View(id int) (*model.User, error) {
 		u.log.Warn("UserRepo Error", zap.Error(err))
 		return nil, apperr.New(http.StatusNotFound, "400 not found")
 	}
-	return user, nil
+	return u.open(user)
 }
 
 // View returns single user by referral code
@@ -62,7 +105,7 @@ func (u *UserRepo) FindByUsername(username string) (*model.User, error) {
 		u.log.Warn("UserRepo Error", zap.String("Error:", err.Error()))
 		return nil, apperr.New(http.StatusNotFound, "400 not found")
 	}
-	return user, nil
+	return u.open(user)
 }
 
 // FindByEmail queries for a single user by email
@@ -76,7 +119,7 @@ func (u *UserRepo) FindByEmail(email string) (*model.User, error) {
 		u.log.Warn("UserRepo Error", zap.String("Error:", err.Error()))
 		return nil, apperr.New(http.StatusNotFound, "400 not found")
 	}
-	return user, nil
+	return u.open(user)
 }
 
 // FindByMobile queries for a single user by mobile (and country code)
@@ -90,7 +133,7 @@ func (u *UserRepo) FindByMobile(countryCode, mobile string) (*model.User, error)
 		u.log.Warn("UserRepo Error", zap.String("Error:", err.Error()))
 		return nil, apperr.New(http.StatusNotFound, "400 not found")
 	}
-	return user, nil
+	return u.open(user)
 }
 
 // FindByToken queries for single user by token
@@ -104,7 +147,7 @@ func (u *UserRepo) FindByToken(token string) (*model.User, error) {
 		u.log.Warn("UserRepo Error", zap.String("Error:", err.Error()))
 		return nil, apperr.New(http.StatusNotFound, "400 not found")
 	}
-	return user, nil
+	return u.open(user)
 }
 
 // UpdateLogin updates last login and refresh token for user
@@ -117,23 +160,148 @@ func (u *UserRepo) UpdateLogin(user *model.User) error {
 	return err
 }
 
-// List returns list of all users retreivable for the current user, depending on role
-func (u *UserRepo) List(qp *model.ListQuery, p *model.Pagination) ([]model.User, error) {
+// userSortExpressions are the SQL expressions of model.UserSortColumns. Text columns may be NULL.
+var userSortExpressions = map[string]string{
+	"id":         `"user"."id"`,
+	"created_at": `"user"."created_at"`,
+	"email":      `coalesce("user"."email", '')`,
+	"first_name": `coalesce("user"."first_name", '')`,
+	"last_name":  `coalesce("user"."last_name", '')`,
+}
+
+// List returns a page of the users matching lq, with the number of users matching lq on all pages.
+// Pages continue after lq.Cursor if set, or else start at p.Offset.
+func (u *UserRepo) List(lq *model.ListQuery, p *model.Pagination) ([]model.User, int, error) {
+	if lq == nil {
+		lq = &model.ListQuery{}
+	}
+	cursor, err := lq.ParseCursor()
+	if err != nil {
+		return nil, 0, err
+	}
+	where, params := userFilter(lq)
+
+	var count struct{ Count int }
+	countSQL := `SELECT count(*) AS count ` + usersWithRoles + ` WHERE ` + where
+	if _, err := u.db.QueryOne(&count, countSQL, params...); err != nil {
+		u.log.Warn("UserRepo Error", zap.Error(err))
+		return nil, 0, apperr.DB
+	}
+
+	sort, ok := userSortExpressions[lq.Sort]
+	desc := lq.Desc
+	if !ok { // newest users first
+		sort, desc = userSortExpressions["id"], true
+	}
+	dir, cmp := "ASC", ">"
+	if desc {
+		dir, cmp = "DESC", "<"
+	}
+	order := sort + " " + dir
+	if sort != userSortExpressions["id"] {
+		order += `, "user"."id" ` + dir
+	}
+	offset := p.Offset
+	if cursor != nil {
+		where += fmt.Sprintf(` AND (%s, "user"."id") %s (?, ?)`, sort, cmp)
+		params = append(params, cursor.Value, cursor.ID)
+		offset = 0
+	}
+	sql := `SELECT "user".*, "role"."id" AS "role__id", "role"."access_level" AS "role__access_level", "role"."name" AS "role__name"
+	` + usersWithRoles + `
+	WHERE ` + where + ` ORDER BY ` + order + ` LIMIT ? OFFSET ?`
+	params = append(params, p.Limit, offset)
+
 	var users []model.User
-	q := u.db.Model(&users).Column("user.*", "Role").Limit(p.Limit).Offset(p.Offset).Where(notDeleted).Order("user.id desc")
-	if qp != nil {
-		q.Where(qp.Query, qp.ID)
+	if _, err := u.db.Query(&users, sql, params...); err != nil {
+		u.log.Warn("UserRepo Error", zap.Error(err))
+		return nil, 0, apperr.DB
 	}
-	if err := q.Select(); err != nil {
-		u.log.Warn("UserDB Error", zap.Error(err))
-		return nil, err
+	for i := range users {
+		if _, err := u.open(&users[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return users, count.Count, nil
+}
+
+// usersWithRoles is the FROM clause of List, whose filters can refer to the roles of users
+const usersWithRoles = `FROM "users" AS "user" LEFT JOIN "roles" AS "role" ON "role"."id" = "user"."role_id"`
+
+// userFilter returns the SQL condition selecting the users lq filters for, and its parameters.
+// Roles are filtered by access level, as role IDs needn't equal access levels.
+func userFilter(lq *model.ListQuery) (string, []interface{}) {
+	conds := []string{`"user"."deleted_at" IS NULL`}
+	var params []interface{}
+	add := func(cond string, p ...interface{}) {
+		conds = append(conds, cond)
+		params = append(params, p...)
+	}
+	if lq.Role != 0 {
+		add(`"role"."access_level" = ?`, int(lq.Role))
+	}
+	if lq.Verified != nil {
+		add(`coalesce("user"."verified", false) = ?`, *lq.Verified)
+	}
+	if lq.Active != nil {
+		add(`coalesce("user"."active", false) = ?`, *lq.Active)
+	}
+	if lq.AccountStatus != "" {
+		add(`"user"."account_status" = ?`, lq.AccountStatus)
+	}
+	if !lq.CreatedFrom.IsZero() {
+		add(`"user"."created_at" >= ?`, lq.CreatedFrom)
+	}
+	if !lq.CreatedTo.IsZero() {
+		add(`"user"."created_at" < ?`, lq.CreatedTo)
+	}
+	if lq.Search != "" {
+		pattern := "%" + escapeLike(lq.Search) + "%"
+		add(`(coalesce("user"."first_name", '') || ' ' || coalesce("user"."last_name", '') ILIKE ? OR "user"."email" ILIKE ? OR "user"."country_code" || "user"."mobile" LIKE ?)`,
+			pattern, pattern, pattern)
+	}
+	return strings.Join(conds, " AND "), params
+}
+
+// Search returns the users whose email, mobile number or broker account number or ID match term.
+// Emails match by substring, the rest exactly, with or without the country code for mobile numbers.
+func (u *UserRepo) Search(term string, limit int) ([]model.User, error) {
+	var users []model.User
+	err := u.db.Model(&users).Column("user.*", "Role").Where(notDeleted).
+		WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			q = q.WhereOr("user.email ILIKE ?", "%"+escapeLike(term)+"%").
+				WhereOr("user.mobile = ?", term).
+				WhereOr("user.country_code || user.mobile = ?", term).
+				WhereOr("user.account_number = ?", term).
+				WhereOr("user.account_id = ?", term)
+			return q, nil
+		}).
+		Order("user.id desc").Limit(limit).Select()
+	if err != nil {
+		u.log.Warn("UserRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	for i := range users {
+		if _, err := u.open(&users[i]); err != nil {
+			return nil, err
+		}
 	}
 	return users, nil
 }
 
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 // Update updates user's contact info
 func (u *UserRepo) Update(user *model.User) (*model.User, error) {
-	_, err := u.db.Model(user).Column(
+	sealed, err := sealUser(u.cipher, user)
+	if err != nil {
+		u.log.Error("UserDB Error: failed to encrypt user", zap.Error(err))
+		return nil, apperr.Generic
+	}
+	_, err = u.db.Model(sealed).Column(
 		"first_name",
 		"last_name",
 		"username",
@@ -181,9 +349,53 @@ func (u *UserRepo) Update(user *model.User) (*model.User, error) {
 	if err != nil {
 		u.log.Warn("UserDB Error", zap.Error(err))
 	}
+	user.UpdatedAt = sealed.UpdatedAt
 	return user, err
 }
 
+// ReencryptPII re-encrypts the sensitive fields of every user, deleted or not, with the current key.
+// It works through the table in batches and returns the number of rows rewritten.
+func (u *UserRepo) ReencryptPII(batchSize int) (int, error) {
+	count, lastID := 0, 0
+	for {
+		var users []model.User
+		err := u.db.Model(&users).Column("id", "tax_id", "dob", "address", "unit_apt").
+			Where("id > ?", lastID).Order("id asc").Limit(batchSize).Select()
+		if err != nil {
+			u.log.Warn("UserDB Error", zap.Error(err))
+			return count, err
+		}
+		if len(users) == 0 {
+			return count, nil
+		}
+		for i := range users {
+			user := &users[i]
+			lastID = user.ID
+			if err := openUser(u.cipher, user); err != nil {
+				return count, fmt.Errorf("user %d: %v", user.ID, err)
+			}
+			sealed, err := sealUser(u.cipher, user)
+			if err != nil {
+				return count, fmt.Errorf("user %d: %v", user.ID, err)
+			}
+			if _, err := u.db.Model(sealed).Column("tax_id", "dob", "address", "unit_apt").WherePK().Update(); err != nil {
+				u.log.Warn("UserDB Error", zap.Error(err))
+				return count, err
+			}
+			count++
+		}
+	}
+}
+
+// CountByAvatar returns how many users, including deleted ones, reference the avatar key
+func (u *UserRepo) CountByAvatar(avatar string) (int, error) {
+	count, err := u.db.Model((*model.User)(nil)).Where("avatar = ?", avatar).Count()
+	if err != nil {
+		u.log.Warn("UserRepo Error", zap.Error(err))
+	}
+	return count, err
+}
+
 // Delete sets deleted_at for a user
 func (u *UserRepo) Delete(user *model.User) error {
 	user.Delete()
@@ -193,3 +405,64 @@ func (u *UserRepo) Delete(user *model.User) error {
 	}
 	return err
 }
+
+// Close marks the account of a user closed, deleting them and signing them out everywhere
+func (u *UserRepo) Close(user *model.User) error {
+	user.Close()
+	_, err := u.db.Model(user).Column("closed_at", "deleted_at", "active", "tokens_valid_after").WherePK().Update()
+	if err != nil {
+		u.log.Warn("UserRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// anonymizeSQL erases the personal data of the users deleted before a time, along with their sign-in
+// methods, sessions, linked bank accounts and support notes, in a single statement. The ID, role, broker
+// account and dates of a user are kept, as are their rewards and the audit log, for our records.
+const anonymizeSQL = `WITH anonymized AS (
+	SELECT id, avatar FROM users WHERE deleted_at < ? AND anonymized_at IS NULL FOR UPDATE
+), erased AS (
+	UPDATE users SET first_name = NULL, last_name = NULL, username = NULL, password = NULL, email = NULL,
+		mobile = NULL, country_code = NULL, address = NULL, last_login = NULL, token = NULL, dob = NULL,
+		city = NULL, state = NULL, country = NULL, tax_id_type = NULL, tax_id = NULL, funding_source = NULL,
+		employment_status = NULL, investing_experience = NULL, public_shareholder = NULL, another_brokerage = NULL,
+		device_id = NULL, bio = NULL, facebook_url = NULL, twitter_url = NULL, instagram_url = NULL,
+		public_portfolio = NULL, employer_name = NULL, occupation = NULL, unit_apt = NULL, zip_code = NULL,
+		stock_symbol = NULL, brokerage_firm_name = NULL, brokerage_firm_employee_name = NULL,
+		brokerage_firm_employee_relationship = NULL, shareholder_company_name = NULL, avatar = NULL,
+		referral_code = NULL, referred_by = NULL, agreements_ip = NULL, active = NULL, anonymized_at = now()
+	WHERE id IN (SELECT id FROM anonymized)
+), sessions AS (
+	DELETE FROM sessions WHERE user_id IN (SELECT id FROM anonymized)
+), refresh_tokens AS (
+	DELETE FROM refresh_tokens WHERE user_id IN (SELECT id FROM anonymized)
+), identities AS (
+	DELETE FROM user_identities WHERE user_id IN (SELECT id FROM anonymized)
+), passkeys AS (
+	DELETE FROM webauthn_credentials WHERE user_id IN (SELECT id FROM anonymized)
+), two_factors AS (
+	DELETE FROM two_factors WHERE user_id IN (SELECT id FROM anonymized)
+), recovery_codes AS (
+	DELETE FROM recovery_codes WHERE user_id IN (SELECT id FROM anonymized)
+), password_history AS (
+	DELETE FROM password_history WHERE user_id IN (SELECT id FROM anonymized)
+), verifications AS (
+	DELETE FROM verifications WHERE user_id IN (SELECT id FROM anonymized)
+), bank_accounts AS (
+	DELETE FROM bank_accounts WHERE user_id IN (SELECT id FROM anonymized)
+), notes AS (
+	DELETE FROM user_notes WHERE user_id IN (SELECT id FROM anonymized)
+)
+SELECT id, avatar FROM anonymized`
+
+// Anonymize erases the personal data of the users deleted, or closed, before t that weren't anonymized
+// yet. It returns the ID and former avatar of every anonymized user, so the avatars can be removed.
+func (u *UserRepo) Anonymize(before time.Time) ([]model.User, error) {
+	var users []model.User
+	if _, err := u.db.Query(&users, anonymizeSQL, before); err != nil {
+		u.log.Warn("UserRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return users, nil
+}
@@ -5,7 +5,6 @@ import (
 
 	"github.com/alpacahq/ribbit-backend/apperr"
 	"github.com/alpacahq/ribbit-backend/model"
-	"github.com/alpacahq/ribbit-backend/repository/platform/query"
 	"github.com/alpacahq/ribbit-backend/repository/platform/structs"
 
 	"github.com/gin-gonic/gin"
@@ -27,19 +26,26 @@ type Service struct {
 	rbac     model.RBACService
 }
 
-// List returns list of users
-func (s *Service) List(c *gin.Context, p *model.Pagination) ([]model.User, error) {
-	u := s.auth.User(c)
-	q, err := query.List(u)
-	if err != nil {
-		return nil, err
+// List returns a page of the users matching lq, with the number of users matching lq on all pages
+func (s *Service) List(c *gin.Context, lq *model.ListQuery, p *model.Pagination) ([]model.User, int, error) {
+	if !s.rbac.EnforcePermission(c, model.PermUsersRead) {
+		return nil, 0, apperr.New(http.StatusForbidden, "Forbidden")
 	}
-	return s.userRepo.List(q, p)
+	if lq == nil {
+		lq = &model.ListQuery{}
+	}
+	return s.userRepo.List(lq, p)
+}
+
+// enforce authorizes a request about the user id, which users may make about themselves
+// and staff only with permission
+func (s *Service) enforce(c *gin.Context, id int, permission string) bool {
+	return c.GetInt("id") == id || s.rbac.EnforcePermission(c, permission)
 }
 
 // View returns single user
 func (s *Service) View(c *gin.Context, id int) (*model.User, error) {
-	if !s.rbac.EnforceUser(c, id) {
+	if !s.enforce(c, id, model.PermUsersRead) {
 		return nil, apperr.New(http.StatusForbidden, "Forbidden")
 	}
 	return s.userRepo.View(id)
@@ -57,7 +63,7 @@ type Update struct {
 
 // Update updates user's contact information
 func (s *Service) Update(c *gin.Context, update *Update) (*model.User, error) {
-	if !s.rbac.EnforceUser(c, update.ID) {
+	if !s.enforce(c, update.ID, model.PermUsersWrite) {
 		return nil, apperr.New(http.StatusForbidden, "Forbidden")
 	}
 	u, err := s.userRepo.View(update.ID)
@@ -68,8 +74,11 @@ func (s *Service) Update(c *gin.Context, update *Update) (*model.User, error) {
 	return s.userRepo.Update(u)
 }
 
-// Delete deletes a user
+// Delete deletes a user with a lower role
 func (s *Service) Delete(c *gin.Context, id int) error {
+	if !s.rbac.EnforcePermission(c, model.PermUsersWrite) {
+		return apperr.New(http.StatusForbidden, "Forbidden")
+	}
 	u, err := s.userRepo.View(id)
 	if err != nil {
 		return err
@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
 	"github.com/alpacahq/ribbit-backend/model"
 	"github.com/alpacahq/ribbit-backend/repository"
 	"github.com/alpacahq/ribbit-backend/secret"
@@ -98,10 +100,10 @@ func (suite *UserTestSuite) TestUserView() {
 	for _, tt := range cases {
 		suite.T().Run(tt.name, func(t *testing.T) {
 			log, _ := zap.NewDevelopment()
-			userRepo := repository.NewUserRepo(tt.db, log)
+			userRepo := repository.NewUserRepo(tt.db, log, mock.Cipher())
 
 			if tt.create {
-				accountRepo := repository.NewAccountRepo(tt.db, log, secret.New())
+				accountRepo := repository.NewAccountRepo(tt.db, log, secret.New(), mock.Cipher())
 				_, err := accountRepo.Create(tt.user)
 				assert.Nil(t, err)
 				u, err := userRepo.View(tt.user.ID)
@@ -128,8 +130,9 @@ func (suite *UserTestSuite) TestUserView() {
 				assert.Nil(t, u)
 				assert.Error(t, apperr.NotFound)
 				pag := &model.Pagination{Limit: 10, Offset: 0}
-				users, err := userRepo.List(nil, pag)
+				users, total, err := userRepo.List(nil, pag)
 				assert.Equal(suite.T(), 0, len(users))
+				assert.Equal(suite.T(), 0, total)
 				assert.Nil(suite.T(), err)
 			} else {
 				u, err := userRepo.View(tt.user.ID)
@@ -158,7 +161,7 @@ func (suite *UserTestSuite) TestUserView() {
 func (suite *UserTestSuite) TestUpdateLoginFailure() {
 	u := suite.u
 	log, _ := zap.NewDevelopment()
-	userRepo := repository.NewUserRepo(suite.dbErr, log)
+	userRepo := repository.NewUserRepo(suite.dbErr, log, mock.Cipher())
 	err := userRepo.UpdateLogin(u)
 	assert.NotNil(suite.T(), err)
 }
@@ -166,7 +169,7 @@ func (suite *UserTestSuite) TestUpdateLoginFailure() {
 func (suite *UserTestSuite) TestUpdateFailure() {
 	u := suite.u
 	log, _ := zap.NewDevelopment()
-	userRepo := repository.NewUserRepo(suite.dbErr, log)
+	userRepo := repository.NewUserRepo(suite.dbErr, log, mock.Cipher())
 	u.Address = "some address"
 	user, err := userRepo.Update(u)
 	assert.NotNil(suite.T(), user)
@@ -176,20 +179,64 @@ func (suite *UserTestSuite) TestUpdateFailure() {
 func (suite *UserTestSuite) TestDeleteFailure() {
 	u := suite.u
 	log, _ := zap.NewDevelopment()
-	userRepo := repository.NewUserRepo(suite.dbErr, log)
+	userRepo := repository.NewUserRepo(suite.dbErr, log, mock.Cipher())
 	err := userRepo.Delete(u)
 	assert.NotNil(suite.T(), err)
 }
 
 func (suite *UserTestSuite) TestListFailure() {
 	log, _ := zap.NewDevelopment()
-	userRepo := repository.NewUserRepo(suite.dbErr, log)
+	userRepo := repository.NewUserRepo(suite.dbErr, log, mock.Cipher())
 	qp := &model.ListQuery{}
 	pag := &model.Pagination{Limit: 10, Offset: 0}
-	_, err := userRepo.List(qp, pag)
+	_, _, err := userRepo.List(qp, pag)
 	assert.NotNil(suite.T(), err)
 }
 
+func (suite *UserTestSuite) TestCloseAndAnonymize() {
+	t := suite.T()
+	createSchema(suite.db, &model.Session{}, &model.IssuedRefreshToken{}, &model.Identity{}, &model.Passkey{},
+		&model.TwoFactor{}, &model.RecoveryCode{}, &model.PasswordHistory{}, &model.BankAccount{}, &model.UserNote{})
+	log, _ := zap.NewDevelopment()
+	userRepo := repository.NewUserRepo(suite.db, log, mock.Cipher())
+	u := suite.u
+	u.AccountID = "acc-1"
+	u.Avatar = "avatars/abc.png"
+	u.MarkVerified()
+	assert.Nil(t, suite.db.Insert(u))
+	assert.Nil(t, suite.db.Insert(&model.BankAccount{UserID: u.ID, AccessToken: "access-token"}))
+
+	assert.Nil(t, userRepo.Close(u))
+	_, err := userRepo.View(u.ID)
+	assert.NotNil(t, err, "closed users are deleted")
+
+	users, err := userRepo.Anonymize(time.Now().Add(-time.Hour))
+	assert.Nil(t, err)
+	assert.Empty(t, users, "users closed within the retention period are kept")
+
+	users, err = userRepo.Anonymize(time.Now().Add(time.Minute))
+	assert.Nil(t, err)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, u.ID, users[0].ID)
+		assert.Equal(t, "avatars/abc.png", users[0].Avatar)
+	}
+	stored := &model.User{ID: u.ID}
+	assert.Nil(t, suite.db.Select(stored))
+	assert.Empty(t, stored.Email)
+	assert.Empty(t, stored.Mobile)
+	assert.Empty(t, stored.Avatar)
+	assert.Equal(t, "acc-1", stored.AccountID)
+	assert.NotNil(t, stored.ClosedAt)
+	assert.NotNil(t, stored.AnonymizedAt)
+	count, err := suite.db.Model((*model.BankAccount)(nil)).Where("user_id = ?", u.ID).Count()
+	assert.Nil(t, err)
+	assert.Zero(t, count)
+
+	users, err = userRepo.Anonymize(time.Now().Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Empty(t, users, "users are anonymized once")
+}
+
 func TestUserTestSuiteIntegration(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test")
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/go-pg/pg/v9/orm"
+	"go.uber.org/zap"
+)
+
+// NewUserRewardRepo returns a UserRewardRepo instance
+func NewUserRewardRepo(db orm.DB, log *zap.Logger) *UserRewardRepo {
+	return &UserRewardRepo{db, log}
+}
+
+// UserRewardRepo represents the client for the rewards of users
+type UserRewardRepo struct {
+	db  orm.DB
+	log *zap.Logger
+}
+
+// Create stores a reward of a user, to be paid out by journaling it to their broker account
+func (r *UserRewardRepo) Create(ur *model.UserReward) error {
+	if err := r.db.Insert(ur); err != nil {
+		r.log.Warn("UserRewardRepo Error", zap.Error(err))
+		return apperr.DB
+	}
+	return nil
+}
+
+// List returns the rewards of a user, newest first
+func (r *UserRewardRepo) List(userID int) ([]model.UserReward, error) {
+	var rewards []model.UserReward
+	if err := r.db.Model(&rewards).Where("user_id = ?", userID).Order("id DESC").Select(); err != nil {
+		r.log.Warn("UserRewardRepo Error", zap.Error(err))
+		return nil, apperr.DB
+	}
+	return rewards, nil
+}
@@ -1,8 +1,12 @@
 package repository_test
 
 import (
+	"net/http"
 	"testing"
+	"time"
 
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
 	"github.com/alpacahq/ribbit-backend/mockgopg"
 	"github.com/alpacahq/ribbit-backend/model"
 	"github.com/alpacahq/ribbit-backend/repository"
@@ -36,7 +40,7 @@ func (suite *UserUnitTestSuite) SetupTest() {
 	}
 
 	log, _ := zap.NewDevelopment()
-	suite.userRepo = repository.NewUserRepo(db, log)
+	suite.userRepo = repository.NewUserRepo(db, log, mock.Cipher())
 }
 
 func (suite *UserUnitTestSuite) TearDownTest() {
@@ -141,3 +145,80 @@ func (suite *UserUnitTestSuite) TestFindByTokenSuccess() {
 	assert.Equal(t, u.Token, user.Token)
 	assert.Nil(t, err)
 }
+
+func (suite *UserUnitTestSuite) TestListFilteredSuccess() {
+	userRepo := suite.userRepo
+	t := suite.T()
+	mock := suite.mock
+
+	verified := true
+	from := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	where := `"user"."deleted_at" IS NULL AND "role"."access_level" = ? AND coalesce("user"."verified", false) = ? AND "user"."created_at" >= ?
+	AND (coalesce("user"."first_name", '') || ' ' || coalesce("user"."last_name", '') ILIKE ? OR "user"."email" ILIKE ? OR "user"."country_code" || "user"."mobile" LIKE ?)`
+	mock.ExpectQueryOne(`SELECT count(*) AS count FROM "users" AS "user" LEFT JOIN "roles" AS "role" ON "role"."id" = "user"."role_id" WHERE `+where).
+		WithArgs(3, true, from, "%jo\\_e%", "%jo\\_e%", "%jo\\_e%").
+		Returns(mockgopg.NewResult(1, 1, &struct{ Count int }{42}), nil)
+	mock.ExpectQuery(`SELECT "user".*, "role"."id" AS "role__id", "role"."access_level" AS "role__access_level", "role"."name" AS "role__name"
+	FROM "users" AS "user" LEFT JOIN "roles" AS "role" ON "role"."id" = "user"."role_id"
+	WHERE `+where+` ORDER BY coalesce("user"."last_name", '') DESC, "user"."id" DESC LIMIT ? OFFSET ?`).
+		WithArgs(3, true, from, "%jo\\_e%", "%jo\\_e%", "%jo\\_e%", 2, 4).
+		Returns(mockgopg.NewResult(2, 2, []model.User{{ID: 7, LastName: "Zed"}, {ID: 9, LastName: "Doe"}}), nil)
+
+	lq := &model.ListQuery{Role: model.UserRole, Verified: &verified, CreatedFrom: from, Search: "jo_e", Sort: "last_name", Desc: true}
+	users, total, err := userRepo.List(lq, &model.Pagination{Limit: 2, Offset: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, 42, total)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, "Doe", users[1].LastName)
+	}
+}
+
+func (suite *UserUnitTestSuite) TestListCursorSuccess() {
+	userRepo := suite.userRepo
+	t := suite.T()
+	mock := suite.mock
+
+	created := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	lq := &model.ListQuery{Sort: "created_at"}
+	lq.Cursor = lq.NextCursor(&model.User{ID: 5, Base: model.Base{CreatedAt: created}})
+
+	mock.ExpectQueryOne(`SELECT count(*) AS count FROM "users" AS "user" LEFT JOIN "roles" AS "role" ON "role"."id" = "user"."role_id" WHERE "user"."deleted_at" IS NULL`).
+		Returns(mockgopg.NewResult(1, 1, &struct{ Count int }{6}), nil)
+	mock.ExpectQuery(`SELECT "user".*, "role"."id" AS "role__id", "role"."access_level" AS "role__access_level", "role"."name" AS "role__name"
+	FROM "users" AS "user" LEFT JOIN "roles" AS "role" ON "role"."id" = "user"."role_id"
+	WHERE "user"."deleted_at" IS NULL AND ("user"."created_at", "user"."id") > (?, ?)
+	ORDER BY "user"."created_at" ASC, "user"."id" ASC LIMIT ? OFFSET ?`).
+		WithArgs(created.Format(time.RFC3339Nano), 5, 10, 0).
+		Returns(mockgopg.NewResult(1, 1, []model.User{{ID: 6}}), nil)
+
+	users, total, err := userRepo.List(lq, &model.Pagination{Limit: 10, Offset: 30})
+	assert.Nil(t, err)
+	assert.Equal(t, 6, total)
+	if assert.Len(t, users, 1) {
+		assert.Equal(t, 6, users[0].ID)
+	}
+}
+
+func (suite *UserUnitTestSuite) TestListDefaultOrder() {
+	t := suite.T()
+	mock := suite.mock
+
+	mock.ExpectQueryOne(`SELECT count(*) AS count FROM "users" AS "user" LEFT JOIN "roles" AS "role" ON "role"."id" = "user"."role_id" WHERE "user"."deleted_at" IS NULL`).
+		Returns(mockgopg.NewResult(1, 1, &struct{ Count int }{2}), nil)
+	mock.ExpectQuery(`SELECT "user".*, "role"."id" AS "role__id", "role"."access_level" AS "role__access_level", "role"."name" AS "role__name"
+	FROM "users" AS "user" LEFT JOIN "roles" AS "role" ON "role"."id" = "user"."role_id"
+	WHERE "user"."deleted_at" IS NULL ORDER BY "user"."id" DESC LIMIT ? OFFSET ?`).
+		WithArgs(10, 0).
+		Returns(mockgopg.NewResult(2, 2, []model.User{{ID: 2}, {ID: 1}}), nil)
+
+	users, total, err := suite.userRepo.List(nil, &model.Pagination{Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+	assert.Len(t, users, 2)
+}
+
+func (suite *UserUnitTestSuite) TestListInvalidCursor() {
+	t := suite.T()
+	_, _, err := suite.userRepo.List(&model.ListQuery{Cursor: "not-a-cursor"}, &model.Pagination{Limit: 10})
+	assert.Equal(t, http.StatusBadRequest, err.(*apperr.APPError).Status)
+}
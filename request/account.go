@@ -89,7 +89,7 @@ func PasswordChange(c *gin.Context) (*Password, error) {
 	return &p, nil
 }
 
-// Update contains password change request
+// Update contains the profile fields the server may update on a user
 type Update struct {
 	ID                                int     `json:"id"`
 	FirstName                         *string `json:"first_name"`
@@ -116,7 +116,6 @@ type Update struct {
 	PublicShareholder                 *string `json:"public_shareholder"`
 	AnotherBrokerage                  *string `json:"another_brokerage"`
 	DeviceID                          *string `json:"device_id"`
-	ProfileCompletion                 *string `json:"profile_completion"`
 	BIO                               *string `json:"bio"`
 	FacebookURL                       *string `json:"facebook_url"`
 	TwitterURL                        *string `json:"twitter_url"`
@@ -135,15 +134,3 @@ type Update struct {
 	ReferredBy                        *string `json:"referred_by"`
 	WatchlistID                       *string `json:"watchlist_id"`
 }
-
-// UpdateProfile updates user's profile
-func UpdateProfile(c *gin.Context) (*Update, error) {
-	var p Update
-	id, _ := c.Get("id")
-	if err := c.ShouldBindJSON(&p); err != nil {
-		apperr.Response(c, err)
-		return nil, err
-	}
-	p.ID = id.(int)
-	return &p, nil
-}
@@ -0,0 +1,68 @@
+package request
+
+import (
+	"github.com/alpacahq/ribbit-backend/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminNote contains an internal note on a user
+type AdminNote struct {
+	Body string `json:"body" binding:"required,max=2000"`
+}
+
+// Note parses out the note in gin's request context, into AdminNote
+func Note(c *gin.Context) (*AdminNote, error) {
+	n := new(AdminNote)
+	if err := c.ShouldBindJSON(n); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return n, nil
+}
+
+// AdminReward contains a reward granted to a user, in USD
+type AdminReward struct {
+	Amount float64 `json:"amount" binding:"required,gt=0,lte=1000"`
+	Reason string  `json:"reason" binding:"required,max=500"`
+}
+
+// Reward parses out the reward in gin's request context, into AdminReward
+func Reward(c *gin.Context) (*AdminReward, error) {
+	r := new(AdminReward)
+	if err := c.ShouldBindJSON(r); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return r, nil
+}
+
+// AdminDeactivate contains why a user is deactivated
+type AdminDeactivate struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// Deactivate parses out the reason in gin's request context, into AdminDeactivate
+func Deactivate(c *gin.Context) (*AdminDeactivate, error) {
+	d := new(AdminDeactivate)
+	if err := c.ShouldBindJSON(d); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return d, nil
+}
+
+// AdminImpersonate contains why a staff member impersonates a user
+type AdminImpersonate struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// Impersonate parses out the reason in gin's request context, into AdminImpersonate
+func Impersonate(c *gin.Context) (*AdminImpersonate, error) {
+	i := new(AdminImpersonate)
+	if err := c.ShouldBindJSON(i); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return i, nil
+}
@@ -0,0 +1,39 @@
+package request
+
+import (
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditQuery contains the filters of an audit log query. Times are RFC 3339.
+type AuditQuery struct {
+	ActorID      int       `form:"actor_id" binding:"min=0"`
+	TargetUserID int       `form:"target_user_id" binding:"min=0"`
+	Action       string    `form:"action"`
+	From         time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To           time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Before       int64     `form:"before" binding:"min=0"`
+	Limit        int       `form:"limit" binding:"min=0"`
+}
+
+// Audit parses out the audit log filters in gin's request context, into an AuditFilter
+func Audit(c *gin.Context) (*model.AuditFilter, error) {
+	q := new(AuditQuery)
+	if err := c.ShouldBindQuery(q); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return &model.AuditFilter{
+		ActorID:      q.ActorID,
+		TargetUserID: q.TargetUserID,
+		Action:       q.Action,
+		From:         q.From,
+		To:           q.To,
+		BeforeID:     q.Before,
+		Limit:        q.Limit,
+	}, nil
+}
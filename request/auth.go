@@ -22,6 +22,52 @@ func Login(c *gin.Context) (*Credentials, error) {
 	return cred, nil
 }
 
+// RefreshPayload stores the refresh token provided in the request
+type RefreshPayload struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh parses out the refresh token in gin's request context, into RefreshPayload
+func Refresh(c *gin.Context) (*RefreshPayload, error) {
+	p := new(RefreshPayload)
+	if err := c.ShouldBindJSON(p); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return p, nil
+}
+
+// TwoFactorLoginPayload stores the challenge token returned by login and the second factor
+type TwoFactorLoginPayload struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorLogin parses out the challenge token and code in gin's request context, into TwoFactorLoginPayload
+func TwoFactorLogin(c *gin.Context) (*TwoFactorLoginPayload, error) {
+	p := new(TwoFactorLoginPayload)
+	if err := c.ShouldBindJSON(p); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return p, nil
+}
+
+// TwoFactorCodePayload stores a TOTP or recovery code
+type TwoFactorCodePayload struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorCode parses out the code in gin's request context, into TwoFactorCodePayload
+func TwoFactorCode(c *gin.Context) (*TwoFactorCodePayload, error) {
+	p := new(TwoFactorCodePayload)
+	if err := c.ShouldBindJSON(p); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return p, nil
+}
+
 // ForgotPayload stores the email provided in the request
 type ForgotPayload struct {
 	Email string `json:"email" binding:"required"`
@@ -37,6 +83,21 @@ func Forgot(c *gin.Context) (*ForgotPayload, error) {
 	return fgt, nil
 }
 
+// ResendVerificationPayload stores the email a new verification code is sent to
+type ResendVerificationPayload struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResendVerification parses out the email in gin's request context, into ResendVerificationPayload
+func ResendVerification(c *gin.Context) (*ResendVerificationPayload, error) {
+	p := new(ResendVerificationPayload)
+	if err := c.ShouldBindJSON(p); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return p, nil
+}
+
 // RecoverPasswordPayload stores the data provided in the request
 type RecoverPasswordPayload struct {
 	Email           string `json:"email" binding:"required"`
@@ -54,3 +115,19 @@ func RecoverPassword(c *gin.Context) (*RecoverPasswordPayload, error) {
 	}
 	return rpp, nil
 }
+
+// IDTokenPayload stores the ID token of an identity provider, and the nonce the client sent the provider
+type IDTokenPayload struct {
+	IDToken string `json:"id_token" binding:"required"`
+	Nonce   string `json:"nonce"`
+}
+
+// IDToken parses out the ID token in gin's request context, into IDTokenPayload
+func IDToken(c *gin.Context) (*IDTokenPayload, error) {
+	p := new(IDTokenPayload)
+	if err := c.ShouldBindJSON(p); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return p, nil
+}
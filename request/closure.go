@@ -0,0 +1,24 @@
+package request
+
+import (
+	"io"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountClosure contains why a user closes their account, which they may leave out
+type AccountClosure struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// CloseAccount parses out the optional reason in gin's request context, into AccountClosure
+func CloseAccount(c *gin.Context) (*AccountClosure, error) {
+	a := new(AccountClosure)
+	if err := c.ShouldBindJSON(a); err != nil && err != io.EOF {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return a, nil
+}
@@ -0,0 +1,35 @@
+package request
+
+import (
+	"github.com/alpacahq/ribbit-backend/apperr"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasskeyCredential is the PublicKeyCredential returned by navigator.credentials.create() or .get(),
+// with binary values base64url encoded
+type PasskeyCredential struct {
+	// ID is the credential ID
+	ID string `json:"id" binding:"required"`
+	// Name labels a new passkey in the list of the user's passkeys, e.g. "iPhone"
+	Name     string `json:"name"`
+	Response struct {
+		ClientDataJSON string `json:"clientDataJSON" binding:"required"`
+		// AttestationObject is returned on registration
+		AttestationObject string `json:"attestationObject"`
+		// AuthenticatorData, Signature and UserHandle are returned on login
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response" binding:"required"`
+}
+
+// Passkey parses out the credential in gin's request context, into PasskeyCredential
+func Passkey(c *gin.Context) (*PasskeyCredential, error) {
+	p := new(PasskeyCredential)
+	if err := c.ShouldBindJSON(p); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return p, nil
+}
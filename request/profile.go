@@ -0,0 +1,319 @@
+package request
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/platform/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	dobLayout = "2006-01-02"
+	minAge    = 18
+	maxAge    = 120
+)
+
+var (
+	ssnRe       = regexp.MustCompile(`^(\d{3})-?(\d{2})-?(\d{4})$`)
+	taxIDRe     = regexp.MustCompile(`^[A-Za-z0-9-]{4,40}$`)
+	taxIDTypeRe = regexp.MustCompile(`^([A-Z]{3}_[A-Z_]+|NOT_SPECIFIED)$`)
+	usZipRe     = regexp.MustCompile(`^\d{5}(-\d{4})?$`)
+	mobileRe    = regexp.MustCompile(`^\d{6,15}$`)
+	countryRe   = regexp.MustCompile(`^\+?\d{1,4}$`)
+	symbolRe    = regexp.MustCompile(`^[A-Z.]{1,10}$`)
+	usernameRe  = regexp.MustCompile(`^[A-Za-z0-9_.]{3,30}$`)
+
+	employmentStatuses = []string{"employed", "unemployed", "student", "retired"}
+	fundingSources     = []string{"employment_income", "investments", "inheritance", "business_income", "savings", "family"}
+	socialHosts        = map[string]string{
+		"facebook_url":  "facebook.com",
+		"twitter_url":   "twitter.com",
+		"instagram_url": "instagram.com",
+	}
+)
+
+// serverManagedFields are user attributes that are only ever set by the server,
+// e.g. from the broker's response when an account is opened
+var serverManagedFields = map[string]bool{
+	"id":                   true,
+	"email":                true,
+	"password":             true,
+	"role_id":              true,
+	"verified":             true,
+	"active":               true,
+	"account_id":           true,
+	"account_number":       true,
+	"account_currency":     true,
+	"account_status":       true,
+	"watchlist_id":         true,
+	"avatar":               true,
+	"profile_completion":   true,
+	"referral_code":        true,
+	"per_account_limit":    true,
+	"agreements_signed_at": true,
+}
+
+// Geography looks up the reference data used to validate addresses
+type Geography interface {
+	CountryExists(code string) bool
+	StateExists(country, state string) bool
+	ZipMatchesState(country, state, zip string) bool
+}
+
+// ProfileUpdate contains the profile fields users may edit on themselves
+type ProfileUpdate struct {
+	FirstName                         *string `json:"first_name"`
+	LastName                          *string `json:"last_name"`
+	Username                          *string `json:"username"`
+	Mobile                            *string `json:"mobile"`
+	CountryCode                       *string `json:"country_code"`
+	Address                           *string `json:"address"`
+	DOB                               *string `json:"dob"`
+	City                              *string `json:"city"`
+	State                             *string `json:"state"`
+	Country                           *string `json:"country"`
+	TaxIDType                         *string `json:"tax_id_type"`
+	TaxID                             *string `json:"tax_id"`
+	FundingSource                     *string `json:"funding_source"`
+	EmploymentStatus                  *string `json:"employment_status"`
+	InvestingExperience               *string `json:"investing_experience"`
+	PublicShareholder                 *string `json:"public_shareholder"`
+	AnotherBrokerage                  *string `json:"another_brokerage"`
+	DeviceID                          *string `json:"device_id"`
+	BIO                               *string `json:"bio"`
+	FacebookURL                       *string `json:"facebook_url"`
+	TwitterURL                        *string `json:"twitter_url"`
+	InstagramURL                      *string `json:"instagram_url"`
+	PublicPortfolio                   *string `json:"public_portfolio"`
+	EmployerName                      *string `json:"employer_name"`
+	Occupation                        *string `json:"occupation"`
+	UnitApt                           *string `json:"unit_apt"`
+	ZipCode                           *string `json:"zip_code"`
+	StockSymbol                       *string `json:"stock_symbol"`
+	BrokerageFirmName                 *string `json:"brokerage_firm_name"`
+	BrokerageFirmEmployeeName         *string `json:"brokerage_firm_employee_name"`
+	BrokerageFirmEmployeeRelationship *string `json:"brokerage_firm_employee_relationship"`
+	ShareholderCompanyName            *string `json:"shareholder_company_name"`
+	ReferredBy                        *string `json:"referred_by"`
+}
+
+// UpdateProfile parses and validates the user's own profile update.
+// Server managed fields and invalid values are rejected with a 422 listing each failing field.
+func UpdateProfile(c *gin.Context) (*Update, error) {
+	id, _ := c.Get("id")
+	body, err := c.GetRawData()
+	if err != nil {
+		apperr.Response(c, apperr.New(http.StatusBadRequest, "Invalid request body."))
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		e := apperr.New(http.StatusBadRequest, "Invalid request body.")
+		apperr.Response(c, e)
+		return nil, e
+	}
+	var fields []apperr.FieldError
+	for k := range raw {
+		if serverManagedFields[k] {
+			fields = append(fields, apperr.FieldError{Field: k, Message: "is managed by the server and cannot be updated"})
+		}
+	}
+	var p ProfileUpdate
+	if err := json.Unmarshal(body, &p); err != nil {
+		e := apperr.New(http.StatusBadRequest, "Invalid request body: every profile field must be a string.")
+		apperr.Response(c, e)
+		return nil, e
+	}
+	fields = append(fields, p.Validate(time.Now())...)
+	if len(fields) > 0 {
+		sort.SliceStable(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+		e := apperr.NewFields(http.StatusUnprocessableEntity, "Invalid profile update.", fields)
+		apperr.Response(c, e)
+		return nil, e
+	}
+	u := &Update{ID: id.(int)}
+	structs.Merge(u, &p)
+	return u, nil
+}
+
+// Validate checks the format of every field present in the update
+func (p *ProfileUpdate) Validate(now time.Time) []apperr.FieldError {
+	var v fieldValidator
+	v.length("first_name", p.FirstName, 1, 50)
+	v.length("last_name", p.LastName, 1, 50)
+	v.match("username", p.Username, usernameRe, "must be 3 to 30 letters, digits, '_' or '.'")
+	v.match("mobile", p.Mobile, mobileRe, "must be 6 to 15 digits")
+	v.match("country_code", p.CountryCode, countryRe, "must be a dialing code such as +1")
+	v.length("address", p.Address, 1, 200)
+	v.length("unit_apt", p.UnitApt, 0, 50)
+	v.length("city", p.City, 1, 100)
+	v.length("bio", p.BIO, 0, 500)
+	v.length("employer_name", p.EmployerName, 0, 100)
+	v.length("occupation", p.Occupation, 0, 100)
+	v.dob("dob", p.DOB, now)
+	v.match("tax_id_type", p.TaxIDType, taxIDTypeRe, "must be a broker tax id type such as USA_SSN")
+	v.match("tax_id", p.TaxID, taxIDRe, "must be 4 to 40 letters, digits or dashes")
+	v.oneOf("employment_status", p.EmploymentStatus, employmentStatuses)
+	v.listOf("funding_source", p.FundingSource, fundingSources)
+	v.yesNo("public_shareholder", p.PublicShareholder)
+	v.yesNo("another_brokerage", p.AnotherBrokerage)
+	v.match("stock_symbol", p.StockSymbol, symbolRe, "must be an upper case ticker symbol")
+	v.socialURL("facebook_url", p.FacebookURL)
+	v.socialURL("twitter_url", p.TwitterURL)
+	v.socialURL("instagram_url", p.InstagramURL)
+	return v.errs
+}
+
+// ValidateProfile checks the consistency of a user's merged profile: the country, state and
+// zip code must agree with our geography data, and the tax id must match its type
+func ValidateProfile(u *model.User, g Geography) []apperr.FieldError {
+	var v fieldValidator
+	country := u.Country
+	if country == "" {
+		country = "USA" // the broker account payload defaults to USA
+	} else if !g.CountryExists(country) {
+		v.add("country", "is not a known ISO-3 country code")
+	}
+	if u.State != "" && !g.StateExists(country, u.State) {
+		v.add("state", "is not a known state of "+country)
+	}
+	if u.ZipCode != "" && country == "USA" {
+		if !usZipRe.MatchString(u.ZipCode) {
+			v.add("zip_code", "must be a 5 digit or ZIP+4 code")
+		} else if u.State != "" && !g.ZipMatchesState(country, u.State, u.ZipCode) {
+			v.add("zip_code", "does not belong to state "+u.State)
+		}
+	}
+	if u.TaxID != "" && u.TaxIDType == "USA_SSN" && !validSSN(u.TaxID) {
+		v.add("tax_id", "is not a valid social security number")
+	}
+	return v.errs
+}
+
+func validSSN(s string) bool {
+	m := ssnRe.FindStringSubmatch(s)
+	if m == nil {
+		return false
+	}
+	area, group, serial := m[1], m[2], m[3]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+type fieldValidator struct {
+	errs []apperr.FieldError
+}
+
+func (v *fieldValidator) add(field, msg string) {
+	v.errs = append(v.errs, apperr.FieldError{Field: field, Message: msg})
+}
+
+func (v *fieldValidator) length(field string, s *string, min, max int) {
+	if s == nil {
+		return
+	}
+	n := len([]rune(strings.TrimSpace(*s)))
+	if n < min || n > max {
+		if min > 0 {
+			v.add(field, "must be between "+strconv.Itoa(min)+" and "+strconv.Itoa(max)+" characters")
+		} else {
+			v.add(field, "must be at most "+strconv.Itoa(max)+" characters")
+		}
+	}
+}
+
+func (v *fieldValidator) match(field string, s *string, re *regexp.Regexp, msg string) {
+	if s == nil || *s == "" {
+		return
+	}
+	if !re.MatchString(*s) {
+		v.add(field, msg)
+	}
+}
+
+func (v *fieldValidator) dob(field string, s *string, now time.Time) {
+	if s == nil || *s == "" {
+		return
+	}
+	dob, err := time.Parse(dobLayout, *s)
+	if err != nil {
+		v.add(field, "must be a date in the format YYYY-MM-DD")
+		return
+	}
+	age := now.Year() - dob.Year()
+	if now.Month() < dob.Month() || now.Month() == dob.Month() && now.Day() < dob.Day() {
+		age--
+	}
+	switch {
+	case dob.After(now):
+		v.add(field, "cannot be in the future")
+	case age < minAge:
+		v.add(field, "you must be at least "+strconv.Itoa(minAge)+" years old")
+	case age > maxAge:
+		v.add(field, "is too far in the past")
+	}
+}
+
+func (v *fieldValidator) oneOf(field string, s *string, allowed []string) {
+	if s == nil || *s == "" {
+		return
+	}
+	for _, a := range allowed {
+		if *s == a {
+			return
+		}
+	}
+	v.add(field, "must be one of "+strings.Join(allowed, ", "))
+}
+
+func (v *fieldValidator) listOf(field string, s *string, allowed []string) {
+	if s == nil || *s == "" {
+		return
+	}
+	for _, item := range strings.Split(*s, ",") {
+		item = strings.TrimSpace(item)
+		ok := false
+		for _, a := range allowed {
+			ok = ok || item == a
+		}
+		if !ok {
+			v.add(field, "must be a comma separated list of "+strings.Join(allowed, ", "))
+			return
+		}
+	}
+}
+
+func (v *fieldValidator) yesNo(field string, s *string) {
+	if s == nil || *s == "" {
+		return
+	}
+	switch strings.ToLower(*s) {
+	case "yes", "no", "true", "false":
+		return
+	}
+	v.add(field, "must be yes or no")
+}
+
+func (v *fieldValidator) socialURL(field string, s *string) {
+	if s == nil || *s == "" {
+		return
+	}
+	u, err := url.Parse(*s)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		v.add(field, "must be an http(s) URL")
+		return
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if want := socialHosts[field]; host != want {
+		v.add(field, "must be a "+want+" URL")
+	}
+}
@@ -0,0 +1,115 @@
+package request_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/geo"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/request"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func str(s string) *string { return &s }
+
+func TestProfileUpdateValidate(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		update request.ProfileUpdate
+		fields []string
+	}{
+		{
+			name: "valid",
+			update: request.ProfileUpdate{
+				DOB:              str("1990-05-31"),
+				TaxIDType:        str("USA_SSN"),
+				TaxID:            str("123-45-6789"),
+				EmploymentStatus: str("employed"),
+				FundingSource:    str("savings, investments"),
+				TwitterURL:       str("https://www.twitter.com/ribbit"),
+			},
+		},
+		{
+			name:   "bad dob format",
+			update: request.ProfileUpdate{DOB: str("31/05/1990")},
+			fields: []string{"dob"},
+		},
+		{
+			name:   "under age",
+			update: request.ProfileUpdate{DOB: str("2010-01-01")},
+			fields: []string{"dob"},
+		},
+		{
+			name:   "future dob",
+			update: request.ProfileUpdate{DOB: str("2030-01-01")},
+			fields: []string{"dob"},
+		},
+		{
+			name: "several invalid fields",
+			update: request.ProfileUpdate{
+				FacebookURL:       str("javascript:alert(1)"),
+				InstagramURL:      str("https://evil.com/ribbit"),
+				EmploymentStatus:  str("busy"),
+				PublicShareholder: str("maybe"),
+			},
+			fields: []string{"employment_status", "public_shareholder", "facebook_url", "instagram_url"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range tt.update.Validate(now) {
+				got = append(got, f.Field)
+			}
+			assert.Equal(t, tt.fields, got)
+		})
+	}
+}
+
+func TestProfileUpdateValidateBirthday(t *testing.T) {
+	// the day before an eighteenth birthday has the same day of the year as the birthday in leap years
+	dob := request.ProfileUpdate{DOB: str("2006-03-01")}
+	errs := dob.Validate(time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	assert.Len(t, errs, 1)
+	assert.Empty(t, dob.Validate(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestValidateProfile(t *testing.T) {
+	g := geo.New()
+	cases := []struct {
+		name   string
+		user   model.User
+		fields []string
+	}{
+		{
+			name: "valid",
+			user: model.User{State: "CA", ZipCode: "94105", TaxIDType: "USA_SSN", TaxID: "123456789"},
+		},
+		{
+			name:   "unknown state",
+			user:   model.User{State: "XX"},
+			fields: []string{"state"},
+		},
+		{
+			name:   "bad zip",
+			user:   model.User{State: "CA", ZipCode: "9410"},
+			fields: []string{"zip_code"},
+		},
+		{
+			name:   "invalid ssn",
+			user:   model.User{TaxIDType: "USA_SSN", TaxID: "666-12-3456"},
+			fields: []string{"tax_id"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, f := range request.ValidateProfile(&tt.user, g) {
+				got = append(got, f.Field)
+			}
+			assert.Equal(t, tt.fields, got)
+		})
+	}
+}
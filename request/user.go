@@ -1,11 +1,57 @@
 package request
 
 import (
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ListUsers contains the filters and sort order of a user list request. Sort is a column,
+// prefixed with - to sort descending. Times are RFC 3339.
+type ListUsers struct {
+	Role          int       `form:"role" binding:"min=0"`
+	Verified      *bool     `form:"verified"`
+	Active        *bool     `form:"active"`
+	AccountStatus string    `form:"account_status"`
+	CreatedFrom   time.Time `form:"created_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedTo     time.Time `form:"created_to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Search        string    `form:"q" binding:"max=100"`
+	Sort          string    `form:"sort"`
+	Cursor        string    `form:"cursor"`
+}
+
+// UserList parses out the user list filters in gin's request context, into a ListQuery
+func UserList(c *gin.Context) (*model.ListQuery, error) {
+	l := new(ListUsers)
+	if err := c.ShouldBindQuery(l); err != nil {
+		apperr.Response(c, err)
+		return nil, err
+	}
+	sort := strings.TrimPrefix(l.Sort, "-")
+	if _, ok := model.UserSortColumns[sort]; sort != "" && !ok {
+		err := apperr.New(http.StatusBadRequest, "Unknown sort column.")
+		apperr.Response(c, err)
+		return nil, err
+	}
+	return &model.ListQuery{
+		Role:          model.AccessRole(l.Role),
+		Verified:      l.Verified,
+		Active:        l.Active,
+		AccountStatus: l.AccountStatus,
+		CreatedFrom:   l.CreatedFrom,
+		CreatedTo:     l.CreatedTo,
+		Search:        strings.TrimSpace(l.Search),
+		Sort:          sort,
+		Desc:          strings.HasPrefix(l.Sort, "-"),
+		Cursor:        l.Cursor,
+	}, nil
+}
+
 // UpdateUser contains user update data from json request
 type UpdateUser struct {
 	ID                                int     `json:"-"`
@@ -2,21 +2,41 @@ package route
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/alpacahq/ribbit-backend/broker"
+	"github.com/alpacahq/ribbit-backend/config"
 	"github.com/alpacahq/ribbit-backend/docs"
+	"github.com/alpacahq/ribbit-backend/geo"
 	"github.com/alpacahq/ribbit-backend/magic"
 	"github.com/alpacahq/ribbit-backend/mail"
 	mw "github.com/alpacahq/ribbit-backend/middleware"
 	"github.com/alpacahq/ribbit-backend/mobile"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/oidc"
 	"github.com/alpacahq/ribbit-backend/repository"
 	"github.com/alpacahq/ribbit-backend/repository/account"
+	"github.com/alpacahq/ribbit-backend/repository/admin"
 	assets "github.com/alpacahq/ribbit-backend/repository/assets"
+	"github.com/alpacahq/ribbit-backend/repository/audit"
 	"github.com/alpacahq/ribbit-backend/repository/auth"
+	"github.com/alpacahq/ribbit-backend/repository/avatar"
+	"github.com/alpacahq/ribbit-backend/repository/closure"
+	"github.com/alpacahq/ribbit-backend/repository/password"
+	"github.com/alpacahq/ribbit-backend/repository/permission"
 	"github.com/alpacahq/ribbit-backend/repository/plaid"
+	"github.com/alpacahq/ribbit-backend/repository/revocation"
+	"github.com/alpacahq/ribbit-backend/repository/session"
+	"github.com/alpacahq/ribbit-backend/repository/social"
+	"github.com/alpacahq/ribbit-backend/repository/tenant"
+	"github.com/alpacahq/ribbit-backend/repository/throttle"
 	"github.com/alpacahq/ribbit-backend/repository/transfer"
+	"github.com/alpacahq/ribbit-backend/repository/twofactor"
 	"github.com/alpacahq/ribbit-backend/repository/user"
 	"github.com/alpacahq/ribbit-backend/secret"
 	"github.com/alpacahq/ribbit-backend/service"
+	"github.com/alpacahq/ribbit-backend/storage"
+	"github.com/alpacahq/ribbit-backend/webauthn"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-pg/pg/v9"
@@ -25,29 +45,53 @@ import (
 	"go.uber.org/zap"
 )
 
+// stepUpMaxAge is how long confirming a second factor unlocks sensitive actions
+const stepUpMaxAge = 10 * time.Minute
+
 // NewServices creates a new router services
-func NewServices(DB *pg.DB, Log *zap.Logger, JWT *mw.JWT, Mail mail.Service, Mobile mobile.Service, Magic magic.Service, R *gin.Engine) *Services {
-	return &Services{DB, Log, JWT, Mail, Mobile, Magic, R}
+func NewServices(DB *pg.DB, Log *zap.Logger, JWT *mw.JWT, Mail mail.Service, Mobile mobile.Service, Magic magic.Service, Cipher secret.FieldCipher, Geo *geo.Index, Storage storage.Service, R *gin.Engine) *Services {
+	return &Services{DB, Log, JWT, Mail, Mobile, Magic, Cipher, Geo, Storage, R}
 }
 
 // Services lets us bind specific services when setting up routes
 type Services struct {
-	DB     *pg.DB
-	Log    *zap.Logger
-	JWT    *mw.JWT
-	Mail   mail.Service
-	Mobile mobile.Service
-	Magic  magic.Service
-	R      *gin.Engine
+	DB      *pg.DB
+	Log     *zap.Logger
+	JWT     *mw.JWT
+	Mail    mail.Service
+	Mobile  mobile.Service
+	Magic   magic.Service
+	Cipher  secret.FieldCipher
+	Geo     *geo.Index
+	Storage storage.Service
+	R       *gin.Engine
 }
 
 // SetupV1Routes instances various repos and services and sets up the routers
 func (s *Services) SetupV1Routes() {
 	// database logic
-	userRepo := repository.NewUserRepo(s.DB, s.Log)
-	accountRepo := repository.NewAccountRepo(s.DB, s.Log, secret.New())
+	userRepo := repository.NewUserRepo(s.DB, s.Log, s.Cipher)
+	accountRepo := repository.NewAccountRepo(s.DB, s.Log, secret.New(), s.Cipher)
+	sessionRepo := repository.NewSessionRepo(s.DB, s.Log)
+	twoFactorRepo := repository.NewTwoFactorRepo(s.DB, s.Log, s.Cipher)
+	refreshTokenRepo := repository.NewRefreshTokenRepo(s.DB, s.Log)
+	throttleRepo := repository.NewThrottleRepo(s.DB, s.Log)
+	identityRepo := repository.NewIdentityRepo(s.DB, s.Log)
+	passkeyRepo := repository.NewPasskeyRepo(s.DB, s.Log)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepo(s.DB, s.Log)
+	userRewardRepo := repository.NewUserRewardRepo(s.DB, s.Log)
+	auditRepo := repository.NewAuditRepo(s.DB, s.Log)
+	auditService := audit.NewAuditService(auditRepo)
+	revocations := revocation.NewStore(repository.NewRevocationRepo(s.DB, s.Log), revocation.DefaultInterval)
 	assetRepo := repository.NewAssetRepo(s.DB, s.Log, secret.New())
-	rbac := repository.NewRBACService(userRepo)
+	permissionService := permission.NewPermissionService(repository.NewRoleRepo(s.DB, s.Log), auditService, permission.DefaultInterval)
+	rbac := repository.NewRBACService(userRepo, permissionService)
+	site := config.GetSiteConfig()
+	tenants := tenant.NewStore(repository.NewTenantRepo(s.DB, s.Log, s.Cipher),
+		tenant.Default(site, config.GetMailConfig(), config.GetBrokerConfig()), tenant.DefaultInterval)
+
+	// every request belongs to the tenant of its host, or of the user of its token
+	s.R.Use(mw.Tenant(tenants))
 
 	// s.R.Use(cors.New(cors.Config{
 	// 	AllowAllOrigins:  true,
@@ -62,24 +106,65 @@ func (s *Services) SetupV1Routes() {
 	// }))
 
 	// service logic
-	authService := auth.NewAuthService(userRepo, accountRepo, s.JWT, s.Mail, s.Mobile, s.Magic)
-	accountService := account.NewAccountService(userRepo, accountRepo, rbac, secret.New())
+	brk := broker.NewBroker(config.GetBrokerConfig())
+	verifier := twofactor.NewVerifier(twoFactorRepo, secret.New())
+	limiter := throttle.NewLimiter(throttleRepo, config.GetThrottleConfig())
+	verification := config.GetVerificationConfig()
+	impersonation := config.GetImpersonationConfig()
+	passwordPolicy := password.NewPolicy(passwordHistoryRepo, secret.New(), config.GetPasswordConfig())
+	authService := auth.NewAuthService(userRepo, accountRepo, sessionRepo, refreshTokenRepo, verifier, limiter, s.JWT, s.Mail, s.Mobile, s.Magic, verification.ResendCooldown, passkeyRepo, relyingParty(config.GetWebAuthnConfig()), passwordPolicy)
+	authService.Tenants = tenants
+	accountService := account.NewAccountService(userRepo, accountRepo, rbac, secret.New(), passwordPolicy, auditService)
 	userService := user.NewUserService(userRepo, authService, rbac)
+	sessionService := session.NewSessionService(sessionRepo, refreshTokenRepo, revocations, authService)
+	twoFactorService := twofactor.NewTwoFactorService(verifier, userRepo, sessionRepo, authService, limiter, site.Name)
 	plaidService := plaid.NewPlaidService(userRepo, accountRepo, s.JWT, s.DB, s.Log)
 	transferService := transfer.NewTransferService(userRepo, accountRepo, s.JWT, s.DB, s.Log)
 	assetsService := assets.NewAssetsService(userRepo, accountRepo, assetRepo, s.JWT, s.DB, s.Log)
+	socialService := social.NewSocialService(identityRepo, userRepo, accountRepo, authService, identityProviders(config.GetOAuthConfig()))
+	adminService := admin.NewAdminService(userRepo, accountRepo, repository.NewNoteRepo(s.DB, s.Log), userRewardRepo, auditService, brk, tenants, authService, s.JWT, impersonation.TTL)
+	closureService := closure.NewClosureService(userRepo, userRewardRepo, auditRepo, auditService, brk, tenants)
+	avatarService := avatar.NewAvatarService(userRepo, accountRepo, rbac, s.Storage, config.GetStorageConfig().URLExpiry, s.Log)
 
 	// no prefix, no jwt
 	service.AuthRouter(authService, s.R)
+	service.TenantRouter(s.R)
+	s.R.GET("/.well-known/jwks.json", s.JWT.JWKSHandler())
 
 	// prefixed with /v1 and protected by jwt
 	v1Router := s.R.Group("/v1")
-	v1Router.Use(s.JWT.MWFunc())
-	service.AccountRouter(accountService, s.DB, v1Router)
-	service.PlaidRouter(plaidService, accountService, v1Router)
-	service.TransferRouter(transferService, accountService, v1Router)
+	s.JWT.Sessions = sessionRepo
+	s.JWT.Revocations = revocations
+	s.JWT.Tenants = tenants
+	v1Router.Use(s.JWT.MWFunc(), mw.RequireVerified(authService, verification.UnverifiedRoutes),
+		mw.Impersonation(auditService, append(impersonation.WritableRoutes, "POST /v1/logout")))
+	recordAudit := func(action string, fields ...string) gin.HandlerFunc {
+		return mw.Audit(auditService, action, fields...)
+	}
+	service.AccountRouter(accountService, s.DB, s.Geo, avatarService, recordAudit, v1Router)
+	stepUp := mw.StepUp(twoFactorService, stepUpMaxAge)
+	service.PlaidRouter(plaidService, accountService, stepUp, recordAudit, v1Router)
+	service.TransferRouter(transferService, accountService, stepUp, recordAudit, v1Router)
 	service.AssetsRouter(assetsService, accountService, v1Router)
 	service.UserRouter(userService, v1Router)
+	service.SessionRouter(sessionService, v1Router)
+	service.TwoFactorRouter(twoFactorService, v1Router)
+	service.SocialRouter(socialService, stepUp, s.R, v1Router)
+	service.PasskeyRouter(authService, stepUp, s.R, v1Router)
+	service.ClosureRouter(closureService, stepUp, v1Router)
+	requirePermission := func(p string) gin.HandlerFunc { return mw.RequirePermission(permissionService, p) }
+	service.RoleRouter(permissionService, requirePermission(model.PermRolesManage), v1Router)
+
+	// back office for support staff, prefixed with /admin and protected by jwt and permissions
+	adminRouter := s.R.Group("/admin")
+	adminRouter.Use(s.JWT.MWFunc(), mw.Impersonation(auditService, nil))
+	service.AdminRouter(adminService, requirePermission, adminRouter)
+	service.AuditRouter(auditService, requirePermission(model.PermAuditRead), adminRouter)
+
+	// signed URLs to locally stored uploads
+	if local, ok := s.Storage.(*storage.Local); ok {
+		s.R.GET("/storage/*key", local.Handler())
+	}
 
 	// Routes for static files
 	s.R.StaticFS("/file", http.Dir("public"))
@@ -88,7 +173,7 @@ func (s *Services) SetupV1Routes() {
 	//Routes for swagger
 	swagger := s.R.Group("swagger")
 	{
-		docs.SwaggerInfo.Title = "Alpaca MVP"
+		docs.SwaggerInfo.Title = site.Name
 		docs.SwaggerInfo.Description = "Broker MVP that uses golang gin as webserver, and go-pg library for connecting with a PostgreSQL database"
 		docs.SwaggerInfo.Version = "1.0"
 
@@ -99,3 +184,19 @@ func (s *Services) SetupV1Routes() {
 		c.Redirect(http.StatusMovedPermanently, "/swagger/index.html")
 	})
 }
+
+// identityProviders returns the verifiers of the identity providers with configured client IDs
+func identityProviders(cfg *config.OAuthConfig) map[string]social.Verifier {
+	verifiers := map[string]social.Verifier{}
+	for _, p := range []oidc.Provider{oidc.AppleProvider(cfg.AppleClientIDs), oidc.GoogleProvider(cfg.GoogleClientIDs)} {
+		if len(p.ClientIDs) > 0 {
+			verifiers[p.Name] = oidc.NewVerifier(p, oidc.NewRemoteKeys(p.JWKSURL))
+		}
+	}
+	return verifiers
+}
+
+// relyingParty returns the relying party passkeys are registered with
+func relyingParty(cfg *config.WebAuthnConfig) *webauthn.RelyingParty {
+	return &webauthn.RelyingParty{ID: cfg.RPID, Name: cfg.RPName, Origins: cfg.Origins}
+}
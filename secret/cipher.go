@@ -0,0 +1,139 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// cipherPrefix marks a value produced by Cipher.Encrypt, so that plaintext rows written before
+// encryption was enabled can still be read
+const cipherPrefix = "enc:v1:"
+
+// KeyProvider supplies the keys used for field-level encryption.
+// The default implementation is KeyRing, which holds keys loaded from env;
+// a KMS-backed provider only needs to satisfy this interface.
+type KeyProvider interface {
+	// CurrentKey returns the id and the key used to encrypt new values
+	CurrentKey() (string, []byte, error)
+	// Key returns the key for id, including retired keys that are only used for decryption
+	Key(id string) ([]byte, error)
+}
+
+// NewKeyRing returns a KeyRing that encrypts with the key identified by currentID.
+// Every key must be 32 bytes long (AES-256).
+func NewKeyRing(currentID string, keys map[string][]byte) (*KeyRing, error) {
+	if _, ok := keys[currentID]; !ok {
+		return nil, fmt.Errorf("encryption key %q is not in the key ring", currentID)
+	}
+	for id, k := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid encryption key id %q", id)
+		}
+		if len(k) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be 32 bytes, got %d", id, len(k))
+		}
+	}
+	return &KeyRing{currentID, keys}, nil
+}
+
+// KeyRing is a static KeyProvider
+type KeyRing struct {
+	currentID string
+	keys      map[string][]byte
+}
+
+// CurrentKey returns the key new values are encrypted with
+func (r *KeyRing) CurrentKey() (string, []byte, error) {
+	return r.currentID, r.keys[r.currentID], nil
+}
+
+// Key looks up a key by id
+func (r *KeyRing) Key(id string) ([]byte, error) {
+	k, ok := r.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+	return k, nil
+}
+
+// NewCipher returns a field-level cipher backed by the given key provider
+func NewCipher(p KeyProvider) *Cipher {
+	return &Cipher{p}
+}
+
+// Cipher encrypts individual column values with AES-256-GCM.
+// Encrypted values have the form enc:v1:<key id>:<base64 nonce+ciphertext>.
+type Cipher struct {
+	keys KeyProvider
+}
+
+// Encrypt encrypts plaintext with the current key. Empty strings are stored as is.
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	id, key, err := c.keys.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := GenerateRandomBytes(aead.NonceSize())
+	if err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(id))
+	return cipherPrefix + id + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt. Values without the cipher prefix are
+// legacy plaintext and are returned unchanged.
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, cipherPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", errors.New("malformed encrypted value")
+	}
+	key, err := c.keys.Key(parts[0])
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(parts[0]))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether value was produced by Cipher.Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, cipherPrefix)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,62 @@
+package secret_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCipherRoundTrip(t *testing.T) {
+	ring, err := secret.NewKeyRing("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	assert.Nil(t, err)
+	c := secret.NewCipher(ring)
+
+	enc, err := c.Encrypt("123-45-6789")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(enc, "enc:v1:k1:"))
+	assert.NotContains(t, enc, "6789")
+
+	dec, err := c.Decrypt(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, "123-45-6789", dec)
+
+	empty, err := c.Encrypt("")
+	assert.Nil(t, err)
+	assert.Equal(t, "", empty)
+
+	legacy, err := c.Decrypt("plain value")
+	assert.Nil(t, err)
+	assert.Equal(t, "plain value", legacy)
+}
+
+func TestCipherKeyRotation(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	oldRing, _ := secret.NewKeyRing("old", map[string][]byte{"old": oldKey})
+	enc, err := secret.NewCipher(oldRing).Encrypt("1990-05-19")
+	assert.Nil(t, err)
+
+	rotated, _ := secret.NewKeyRing("new", map[string][]byte{"old": oldKey, "new": newKey})
+	c := secret.NewCipher(rotated)
+	dec, err := c.Decrypt(enc)
+	assert.Nil(t, err)
+	assert.Equal(t, "1990-05-19", dec)
+	reenc, _ := c.Encrypt(dec)
+	assert.True(t, strings.HasPrefix(reenc, "enc:v1:new:"))
+
+	newOnly, _ := secret.NewKeyRing("new", map[string][]byte{"new": newKey})
+	_, err = secret.NewCipher(newOnly).Decrypt(enc)
+	assert.NotNil(t, err)
+}
+
+func TestNewKeyRingValidation(t *testing.T) {
+	_, err := secret.NewKeyRing("missing", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	assert.NotNil(t, err)
+	_, err = secret.NewKeyRing("k1", map[string][]byte{"k1": []byte("short")})
+	assert.NotNil(t, err)
+	_, err = secret.NewKeyRing("a:b", map[string][]byte{"a:b": bytes.Repeat([]byte{1}, 32)})
+	assert.NotNil(t, err)
+}
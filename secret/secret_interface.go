@@ -6,3 +6,9 @@ type Service interface {
 	HashMatchesPassword(hash, password string) bool
 	HashRandomPassword() (string, error)
 }
+
+// FieldCipher is the interface to our field-level encryption service
+type FieldCipher interface {
+	Encrypt(plaintext string) (string, error)
+	Decrypt(value string) (string, error)
+}
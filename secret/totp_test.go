@@ -0,0 +1,56 @@
+package secret_test
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/secret"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B SHA1 vectors, truncated to 6 digits
+	key := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range cases {
+		code, err := secret.TOTPCode(key, secret.TOTPStep(time.Unix(unix, 0)))
+		assert.Nil(t, err)
+		assert.Equal(t, want, code, unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	s, err := secret.GenerateTOTPSecret()
+	assert.Nil(t, err)
+	now := time.Now()
+	step := secret.TOTPStep(now)
+
+	for _, drift := range []int64{-1, 0, 1} {
+		code, _ := secret.TOTPCode(s, step+drift)
+		got, ok := secret.ValidateTOTP(s, code, now)
+		assert.True(t, ok)
+		assert.Equal(t, step+drift, got)
+	}
+	code, _ := secret.TOTPCode(s, step+2)
+	_, ok := secret.ValidateTOTP(s, code, now)
+	assert.False(t, ok)
+	_, ok = secret.ValidateTOTP(s, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := secret.TOTPURI("Ribbit", "john@mail.com", "JBSWY3DPEHPK3PXP")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Ribbit:john@mail.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Ribbit")
+}
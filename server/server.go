@@ -4,9 +4,11 @@ import (
 	"os"
 
 	"github.com/alpacahq/ribbit-backend/config"
+	"github.com/alpacahq/ribbit-backend/geo"
 	"github.com/alpacahq/ribbit-backend/mail"
 	mw "github.com/alpacahq/ribbit-backend/middleware"
 	"github.com/alpacahq/ribbit-backend/mobile"
+	"github.com/alpacahq/ribbit-backend/repository"
 	"github.com/alpacahq/ribbit-backend/route"
 
 	"github.com/gin-gonic/gin"
@@ -23,7 +25,7 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Access-Control-Allow-Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Access-Control-Allow-Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH")
 
 		if c.Request.Method == "OPTIONS" {
@@ -45,22 +47,48 @@ func (server *Server) Run(env string) error {
 	r.LoadHTMLGlob("templates/*")
 
 	// middleware
-	mw.Add(r, CORSMiddleware())
+	mw.Add(r, CORSMiddleware(), mw.RequestID())
 	jwt := mw.NewJWT(j)
+	if j.KeysDir != "" {
+		keys, err := mw.LoadKeySet(j.KeysDir, j.SigningKeyID)
+		if err != nil {
+			return err
+		}
+		jwt.Keys = keys
+	}
 	m := mail.NewMail(config.GetMailConfig(), config.GetSiteConfig())
 	mobile := mobile.NewMobile(config.GetTwilioConfig())
 	db := config.GetConnection()
 	log, _ := zap.NewDevelopment()
 	defer log.Sync()
+	cipher, err := config.GetFieldCipher()
+	if err != nil {
+		return err
+	}
+	store, err := config.GetStorage()
+	if err != nil {
+		return err
+	}
+	g, err := repository.NewGeoRepo(db, log).Load()
+	if err != nil || len(g.Countries()) == 0 {
+		log.Warn("geography tables are empty, run import_geo; falling back to countries.csv")
+		wd, _ := os.Getwd()
+		if g, err = geo.LoadDir(wd); err != nil {
+			return err
+		}
+	}
 
 	// setup default routes
 	rsDefault := &route.Services{
-		DB:     db,
-		Log:    log,
-		JWT:    jwt,
-		Mail:   m,
-		Mobile: mobile,
-		R:      r}
+		DB:      db,
+		Log:     log,
+		JWT:     jwt,
+		Mail:    m,
+		Mobile:  mobile,
+		Cipher:  cipher,
+		Geo:     g,
+		Storage: store,
+		R:       r}
 	rsDefault.SetupV1Routes()
 
 	// setup all custom/user-defined route services
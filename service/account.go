@@ -2,7 +2,6 @@ package service
 
 import (
 	"bytes"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,17 +10,18 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/broker"
+	"github.com/alpacahq/ribbit-backend/geo"
 	"github.com/alpacahq/ribbit-backend/model"
 	"github.com/alpacahq/ribbit-backend/repository/account"
+	"github.com/alpacahq/ribbit-backend/repository/avatar"
 	"github.com/alpacahq/ribbit-backend/request"
 
-	"github.com/bradfitz/slice"
 	"github.com/gin-gonic/gin"
 	"github.com/go-pg/pg/v9/orm"
 	shortuuid "github.com/lithammer/shortuuid/v3"
@@ -29,27 +29,36 @@ import (
 
 // AccountService represents the account http service
 type AccountService struct {
-	svc *account.Service
-	db  orm.DB
+	svc     *account.Service
+	db      orm.DB
+	geo     *geo.Index
+	avatars *avatar.Service
 }
 
+// orderFields are the order request fields recorded in the audit log
+var orderFields = []string{"symbol", "qty", "notional", "side", "type", "time_in_force", "limit_price", "stop_price", "trail_price", "trail_percent", "extended_hours"}
+
 // AccountRouter sets up all the controller functions to our router
-func AccountRouter(svc *account.Service, db orm.DB, r *gin.RouterGroup) {
+func AccountRouter(svc *account.Service, db orm.DB, g *geo.Index, avatars *avatar.Service, audit func(string, ...string) gin.HandlerFunc, r *gin.RouterGroup) {
 	a := AccountService{
-		svc: svc,
-		db:  db,
+		svc:     svc,
+		db:      db,
+		geo:     g,
+		avatars: avatars,
 	}
 	pr := r.Group("/profile")
 	pr.GET("", a.profile)
 	pr.POST("/avatar", a.uploadAvatar)
 	pr.DELETE("/avatar", a.deleteAvatar)
 	pr.GET("/shareable-link", a.getShareableProfileLik)
+	pr.GET("/completion", a.profileCompletion)
 	pr.PATCH("", a.updateProfile)
 
 	cr := r.Group("/countries")
 	cr.GET("", a.countriesList)
 
 	cr1 := cr.Group("/:country_code")
+	cr1.GET("", a.countryDetails)
 	cr1.GET("/states", a.statesList)
 	cr1.GET("/states/:state_code/cities", a.citiesList)
 
@@ -58,6 +67,7 @@ func AccountRouter(svc *account.Service, db orm.DB, r *gin.RouterGroup) {
 
 	acr := r.Group("/account")
 	acr.GET("", a.getAccount)
+	acr.POST("/agreements", a.signAgreements)
 	acr.POST("/sign", a.sign)
 	acr.GET("/portfolio/history", a.portfolioHistory)
 	acr.GET("/trading-profile", a.tradingProfile)
@@ -72,17 +82,17 @@ func AccountRouter(svc *account.Service, db orm.DB, r *gin.RouterGroup) {
 
 	ac := r.Group("/orders")
 	ac.GET("", a.getOrders)
-	ac.POST("", a.createOrder)
+	ac.POST("", audit(model.AuditOrderCreate, orderFields...), a.createOrder)
 	ac.GET("/:order_id", a.getOrderDetails)
-	ac.PATCH("/:order_id", a.replaceOrder)
-	ac.DELETE("", a.cancelAllOrders)
-	ac.DELETE("/:order_id", a.cancelOrder)
+	ac.PATCH("/:order_id", audit(model.AuditOrderReplace, orderFields...), a.replaceOrder)
+	ac.DELETE("", audit(model.AuditOrderCancelAll), a.cancelAllOrders)
+	ac.DELETE("/:order_id", audit(model.AuditOrderCancel), a.cancelOrder)
 
 	pz := r.Group("/positions")
 	pz.GET("", a.getPositions)
 	pz.GET("/:symbol", a.getOneOpenPosition)
-	pz.DELETE("", a.closePositions)
-	pz.DELETE("/:symbol", a.closeOnePosition)
+	pz.DELETE("", audit(model.AuditPositionClose), a.closePositions)
+	pz.DELETE("/:symbol", audit(model.AuditPositionClose), a.closeOnePosition)
 
 	mrk := r.Group("/market")
 	mrk.GET("/tickers", a.getMarketTickers)
@@ -157,6 +167,7 @@ func (a *AccountService) profile(c *gin.Context) {
 	id, _ := c.Get("id")
 	user := a.svc.GetProfile(c, id.(int))
 	if user != nil {
+		user.AvatarURLs = a.avatars.URLs(user.Avatar)
 		c.JSON(http.StatusOK, user)
 		return
 	}
@@ -167,88 +178,36 @@ func (a *AccountService) profile(c *gin.Context) {
 
 func (a *AccountService) uploadAvatar(c *gin.Context) {
 	id, _ := c.Get("id")
-	user := a.svc.GetProfile(c, id.(int))
-	const MAX_UPLOAD_SIZE = 1024 * 1024 * 5 // 5MB
-	if user != nil {
-		file, header, err := c.Request.FormFile("file")
-		if err != nil {
-			apperr.Response(c, apperr.New(apperr.BadRequest.Status, fmt.Sprintf("file err : %s", err.Error())))
-			return
-		}
-		// Check size
-		if err := c.Request.ParseMultipartForm(MAX_UPLOAD_SIZE); err != nil {
-			apperr.Response(c, apperr.New(apperr.BadRequest.Status, "The uploaded file is too big. Please choose an file that's less than 5MB in size"))
-			return
-		}
-		// Check file type
-		// if strings.Split(header.Header.Get("Content-Type"), "/")[0] != "image" {
-		// 	apperr.Response(c, apperr.New(apperr.BadRequest.Status, "The uploaded file is not an image"))
-		// 	return
-		// }
-		buff := make([]byte, 512)
-		if _, err = file.Read(buff); err != nil {
-			apperr.Response(c, apperr.New(apperr.BadRequest.Status, fmt.Sprintf("%s", err.Error())))
-			return
-		}
-		if filetype := http.DetectContentType(buff); filetype != "image/jpg" && filetype != "image/jpeg" && filetype != "image/png" {
-			apperr.Response(c, apperr.New(apperr.BadRequest.Status, "The provided file format is not allowed. Please upload a JPEG or PNG image"))
-			return
-		}
-		if _, err := file.Seek(0, io.SeekStart); err != nil {
-			apperr.Response(c, apperr.New(apperr.BadRequest.Status, fmt.Sprintf("%s", err.Error())))
-			return
-		}
-
-		filename := filepath.Base(header.Filename)
-		if filename == "." {
-			apperr.Response(c, apperr.New(http.StatusUnprocessableEntity, "File has malformed file format"))
-		}
-		newFileName := strconv.Itoa(user.ID) + "-" + filename
-		// delete old file
-		if user.Avatar != "" {
-			if err := os.Remove("public/users/" + user.Avatar); err != nil {
-				log.Fatal(err)
-			}
-		}
-
-		out, err := os.Create("public/users/" + newFileName)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer out.Close()
-		_, err = io.Copy(out, file)
-		if err != nil {
-			log.Fatal(err)
-		}
-		if err := a.svc.UpdateAvatar(c, newFileName, user.ID); err != nil {
-			apperr.Response(c, err)
-			return
-		}
-		c.JSON(http.StatusOK, map[string]interface{}{
-			"avatar": newFileName,
-		})
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, avatar.MaxUploadSize+1<<20)
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		apperr.Response(c, apperr.New(apperr.BadRequest.Status, "Please upload a JPEG or PNG image of less than 5MB as file."))
+		return
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(io.LimitReader(file, avatar.MaxUploadSize+1))
+	if err != nil {
+		apperr.Response(c, apperr.New(apperr.BadRequest.Status, "Couldn't read the uploaded file."))
+		return
+	}
+	user, err := a.avatars.Upload(c, id.(int), data)
+	if err != nil {
+		apperr.Response(c, err)
 		return
 	}
-	apperr.Response(c, apperr.New(apperr.BadRequest.Status, "Internal Server Error, please try again."))
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"avatar":      user.Avatar,
+		"avatar_urls": user.AvatarURLs,
+	})
 }
 
 func (a *AccountService) deleteAvatar(c *gin.Context) {
 	id, _ := c.Get("id")
-	user := a.svc.GetProfile(c, id.(int))
-	if user != nil {
-		if user.Avatar != "" {
-			if err := os.Remove("public/users/" + user.Avatar); err != nil {
-				log.Fatal(err)
-			}
-		}
-		if err := a.svc.UpdateAvatar(c, "", user.ID); err != nil {
-			apperr.Response(c, err)
-			return
-		}
-		apperr.Response(c, apperr.New(http.StatusOK, "Congratulations! Your avatar has been deleted successfully."))
+	if err := a.avatars.Delete(c, id.(int)); err != nil {
+		apperr.Response(c, err)
 		return
 	}
-	apperr.Response(c, apperr.New(http.StatusBadRequest, "Internal Server Error, please try again."))
+	apperr.Response(c, apperr.New(http.StatusOK, "Congratulations! Your avatar has been deleted successfully."))
 }
 
 type ShareableProfileLink struct {
@@ -261,7 +220,7 @@ func (a *AccountService) getShareableProfileLik(c *gin.Context) {
 	user := a.svc.GetProfile(c, id.(int))
 	if user != nil {
 		c.JSON(http.StatusOK, ShareableProfileLink{
-			URL:  "https://alpaca.com/profile/" + user.ReferralCode,
+			URL:  appLink(c, "/profile/"+user.ReferralCode),
 			Code: user.ReferralCode,
 		})
 		return
@@ -276,7 +235,7 @@ func (a *AccountService) updateProfile(c *gin.Context) {
 	if err != nil {
 		return
 	}
-	user, err := a.svc.UpdateProfile(c, p)
+	user, err := a.svc.EditProfile(c, p, a.geo)
 	if err != nil {
 		apperr.Response(c, err)
 		return
@@ -284,171 +243,114 @@ func (a *AccountService) updateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
-type Country struct {
-	Name      string `json:"name"`
-	ShortCode string `json:"short_code"`
-}
-
-func countryAlreadyExists(a []Country, x Country) bool {
-	for _, n := range a {
-		if x == n {
-			return true
-		}
+func (a *AccountService) profileCompletion(c *gin.Context) {
+	id, _ := c.Get("id")
+	checklist, err := a.svc.ProfileChecklist(c, id.(int))
+	if err != nil {
+		apperr.Response(c, err)
+		return
 	}
-	return false
+	c.JSON(http.StatusOK, checklist)
 }
 
-func (a *AccountService) countriesList(c *gin.Context) {
-	path, _ := os.Getwd()
-	q := c.Query("q")
-
-	csvFile, err := os.Open(path + "/countries.csv")
+func (a *AccountService) signAgreements(c *gin.Context) {
+	id, _ := c.Get("id")
+	user, err := a.svc.SignAgreements(c, id.(int))
 	if err != nil {
-		fmt.Println(err)
+		apperr.Response(c, err)
+		return
 	}
-	fmt.Println("Successfully Opened CSV file")
-	defer csvFile.Close()
+	c.JSON(http.StatusOK, user)
+}
 
-	csvLines, err := csv.NewReader(csvFile).ReadAll()
+// geoPage returns the requested page of a geography list.
+// Without limit or page parameters the whole list is returned, as older clients expect.
+func geoPage(c *gin.Context) (offset, limit int, ok bool) {
+	if c.Query("limit") == "" && c.Query("page") == "" {
+		return 0, 0, true
+	}
+	p, err := request.Paginate(c)
 	if err != nil {
-		fmt.Println(err)
-	}
-	countries := []Country{}
-	for _, line := range csvLines {
-		country := Country{
-			ShortCode: line[0],
-			Name:      line[1],
-		}
-		if !countryAlreadyExists(countries, country) {
-			if len(q) > 0 {
-				if strings.Contains(strings.ToLower(country.Name), strings.ToLower(q)) {
-					countries = append(countries, country)
-				}
-			} else {
-				countries = append(countries, country)
-			}
-		}
+		return 0, 0, false
 	}
-
-	c.JSON(http.StatusOK, countries[1:])
-	return
-
-	// c.JSON(http.StatusOK, []Country{{
-	// 	ShortCode: "USA",
-	// 	Name:      "United States",
-	// }})
+	return p.Offset, p.Limit, true
 }
 
-type State struct {
-	Name      string `json:"name"`
-	ShortCode string `json:"short_code"`
-}
-
-func stateAlreadyExists(a []State, x State) bool {
-	for _, n := range a {
-		if x == n {
+// geoNotModified sets the caching headers of geography responses, which only change
+// when the reference data is re-imported, and answers 304 if the client's copy is current
+func (a *AccountService) geoNotModified(c *gin.Context) bool {
+	etag := `"` + a.geo.Version() + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "public, max-age=3600")
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		if tag = strings.TrimSpace(tag); tag == etag || tag == "W/"+etag || tag == "*" {
+			c.Status(http.StatusNotModified)
 			return true
 		}
 	}
 	return false
 }
 
-func (a *AccountService) statesList(c *gin.Context) {
-	path, _ := os.Getwd()
-	countryCode := c.Param("country_code")
-	q := c.Query("q")
-
-	if countryCode == "USA" {
-		csvFile, err := os.Open(path + "/uscities.csv")
-		if err != nil {
-			fmt.Println(err)
-		}
-		fmt.Println("Successfully Opened CSV file")
-		defer csvFile.Close()
-
-		csvLines, err := csv.NewReader(csvFile).ReadAll()
-		if err != nil {
-			fmt.Println(err)
-		}
-		states := []State{}
-		for _, line := range csvLines {
-			state := State{
-				ShortCode: line[2],
-				Name:      line[3],
-			}
-			if !stateAlreadyExists(states, state) {
-				if len(q) > 0 {
-					if strings.Contains(strings.ToLower(state.Name), strings.ToLower(q)) {
-						states = append(states, state)
-					}
-				} else {
-					states = append(states, state)
-				}
-			}
-		}
-		slice.Sort(states[:], func(i, j int) bool {
-			return states[i].Name < states[j].Name
-		})
+func (a *AccountService) countriesList(c *gin.Context) {
+	if a.geoNotModified(c) {
+		return
+	}
+	offset, limit, ok := geoPage(c)
+	if !ok {
+		return
+	}
+	countries, total := a.geo.SearchCountries(c.Query("q"), offset, limit)
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, countries)
+}
 
-		c.JSON(http.StatusOK, states[1:])
+func (a *AccountService) countryDetails(c *gin.Context) {
+	if a.geoNotModified(c) {
 		return
 	}
-	c.JSON(http.StatusNotFound, gin.H{
-		"message": "400 Not found",
-	})
+	country, ok := a.geo.Country(c.Param("country_code"))
+	if !ok {
+		apperr.Response(c, apperr.New(http.StatusNotFound, "Country not found."))
+		return
+	}
+	c.JSON(http.StatusOK, country)
 }
 
-type City struct {
-	Name  string `json:"name"`
-	ASCII string `json:"ascii"`
-	LAT   string `json:"lat"`
-	LNG   string `json:"lng"`
+func (a *AccountService) statesList(c *gin.Context) {
+	if a.geoNotModified(c) {
+		return
+	}
+	country, ok := a.geo.Country(c.Param("country_code"))
+	if !ok || len(a.geo.States(country.ShortCode)) == 0 {
+		apperr.Response(c, apperr.New(http.StatusNotFound, "Country not found."))
+		return
+	}
+	offset, limit, ok := geoPage(c)
+	if !ok {
+		return
+	}
+	states, total := a.geo.SearchStates(country.ShortCode, c.Query("q"), offset, limit)
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, states)
 }
 
 func (a *AccountService) citiesList(c *gin.Context) {
-	path, _ := os.Getwd()
-	countryCode := c.Param("country_code")
-	stateCode := c.Param("state_code")
-	q := c.Query("q")
-
-	if countryCode == "USA" {
-		csvFile, err := os.Open(path + "/uscities.csv")
-		if err != nil {
-			fmt.Println(err)
-		}
-		fmt.Println("Successfully Opened CSV file")
-		defer csvFile.Close()
-
-		csvLines, err := csv.NewReader(csvFile).ReadAll()
-		if err != nil {
-			fmt.Println(err)
-		}
-		citys := []City{}
-		for _, line := range csvLines {
-			city := City{
-				Name:  line[0],
-				ASCII: line[1],
-				LAT:   line[6],
-				LNG:   line[7],
-			}
-			if line[2] == stateCode {
-				if len(q) > 0 {
-					if strings.Contains(strings.ToLower(city.Name), strings.ToLower(q)) {
-						citys = append(citys, city)
-					}
-				} else {
-					citys = append(citys, city)
-				}
-
-			}
-		}
-
-		c.JSON(http.StatusOK, citys[1:])
+	if a.geoNotModified(c) {
 		return
 	}
-	c.JSON(http.StatusNotFound, gin.H{
-		"message": "400 Not found",
-	})
+	country, ok := a.geo.Country(c.Param("country_code"))
+	stateCode := strings.ToUpper(c.Param("state_code"))
+	if !ok || len(a.geo.States(country.ShortCode)) == 0 || !a.geo.StateExists(country.ShortCode, stateCode) {
+		apperr.Response(c, apperr.New(http.StatusNotFound, "State not found."))
+		return
+	}
+	offset, limit, ok := geoPage(c)
+	if !ok {
+		return
+	}
+	cities, total := a.geo.SearchCities(country.ShortCode, stateCode, c.Query("q"), offset, limit)
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, cities)
 }
 
 type Referral struct {
@@ -525,6 +427,7 @@ type BrokerAccountResponse struct {
 }
 
 func getBrokerAccount(u *model.User) BrokerAccount {
+	signedAt := u.AgreementsSignedAt.Format(time.RFC3339)
 	account := BrokerAccount{
 		Contact: BrokerContact{
 			Email:   u.Email,
@@ -554,18 +457,18 @@ func getBrokerAccount(u *model.User) BrokerAccount {
 		Agreements: []BrokerAgreement{
 			{
 				Agreement: "margin_agreement",
-				SignedAt:  time.Now().Format(time.RFC3339),
-				IPAddress: "127.0.0.1",
+				SignedAt:  signedAt,
+				IPAddress: u.AgreementsIP,
 			},
 			{
 				Agreement: "account_agreement",
-				SignedAt:  time.Now().Format(time.RFC3339),
-				IPAddress: "127.0.0.1",
+				SignedAt:  signedAt,
+				IPAddress: u.AgreementsIP,
 			},
 			{
 				Agreement: "customer_agreement",
-				SignedAt:  time.Now().Format(time.RFC3339),
-				IPAddress: "127.0.0.1",
+				SignedAt:  signedAt,
+				IPAddress: u.AgreementsIP,
 			},
 		},
 	}
@@ -578,17 +481,20 @@ func (a *AccountService) sign(c *gin.Context) {
 	id, _ := c.Get("id")
 	user := a.svc.GetProfile(c, id.(int))
 	if user != nil {
+		if err := account.EnsureProfileComplete(user); err != nil {
+			apperr.Response(c, err)
+			return
+		}
 		brokerAccount := getBrokerAccount(user)
 		requestBytes, _ := json.Marshal(brokerAccount)
-		fmt.Println(string(requestBytes))
 
 		client := &http.Client{}
-		req, err := http.NewRequest("POST", os.Getenv("BROKER_API_BASE")+"/v1/accounts", bytes.NewReader(requestBytes))
+		req, err := http.NewRequest("POST", broker.APIBase(c)+"/v1/accounts", bytes.NewReader(requestBytes))
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -600,7 +506,6 @@ func (a *AccountService) sign(c *gin.Context) {
 			log.Fatal(err)
 		}
 
-		fmt.Println(string(responseData))
 		if strings.Contains(string(responseData), "account_number") {
 			brokerResponse := BrokerAccountResponse{}
 			json.Unmarshal(responseData, &brokerResponse)
@@ -636,12 +541,12 @@ func (a *AccountService) sign(c *gin.Context) {
 func (a *AccountService) clock(c *gin.Context) {
 
 	client := &http.Client{}
-	req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/clock", nil)
+	req, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/clock", nil)
 	if err != nil {
 		fmt.Print(err.Error())
 	}
 
-	req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+	req.Header.Add("Authorization", broker.Token(c))
 	response, err := client.Do(req)
 
 	if err != nil {
@@ -666,12 +571,12 @@ func (a *AccountService) getOrders(c *gin.Context) {
 		client := &http.Client{}
 		accountID := user.AccountID
 
-		req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/orders", nil)
+		req, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/orders", nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -701,12 +606,12 @@ func (a *AccountService) createOrder(c *gin.Context) {
 		client := &http.Client{}
 		accountID := user.AccountID
 
-		req, err := http.NewRequest("POST", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/orders", c.Request.Body)
+		req, err := http.NewRequest("POST", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/orders", c.Request.Body)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -738,12 +643,12 @@ func (a *AccountService) getOrderDetails(c *gin.Context) {
 
 		orderID := c.Param("order_id")
 
-		req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/orders/"+orderID, nil)
+		req, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/orders/"+orderID, nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -774,12 +679,12 @@ func (a *AccountService) replaceOrder(c *gin.Context) {
 		accountID := user.AccountID
 		orderID := c.Param("order_id")
 
-		req, err := http.NewRequest("PATCH", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/orders/"+orderID, c.Request.Body)
+		req, err := http.NewRequest("PATCH", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/orders/"+orderID, c.Request.Body)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -809,12 +714,12 @@ func (a *AccountService) cancelAllOrders(c *gin.Context) {
 		client := &http.Client{}
 		accountID := user.AccountID
 
-		req, err := http.NewRequest("DELETE", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/orders", nil)
+		req, err := http.NewRequest("DELETE", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/orders", nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -845,12 +750,12 @@ func (a *AccountService) cancelOrder(c *gin.Context) {
 		accountID := user.AccountID
 		orderID := c.Param("order_id")
 
-		req, err := http.NewRequest("DELETE", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/orders/"+orderID, nil)
+		req, err := http.NewRequest("DELETE", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/orders/"+orderID, nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -880,12 +785,12 @@ func (a *AccountService) portfolioHistory(c *gin.Context) {
 		client := &http.Client{}
 		accountID := user.AccountID
 
-		req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/account/portfolio/history?period="+url.QueryEscape(c.Query("period"))+"&timeframe="+url.QueryEscape(c.Query("timeframe"))+"&date_end="+url.QueryEscape(c.Query("date_end"))+"&extended_hours="+url.QueryEscape(c.Query("extended_hours"))+"", nil)
+		req, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/account/portfolio/history?period="+url.QueryEscape(c.Query("period"))+"&timeframe="+url.QueryEscape(c.Query("timeframe"))+"&date_end="+url.QueryEscape(c.Query("date_end"))+"&extended_hours="+url.QueryEscape(c.Query("extended_hours"))+"", nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -920,8 +825,8 @@ func (a *AccountService) tradingProfile(c *gin.Context) {
 	client := &http.Client{}
 	accountID := user.AccountID
 
-	req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/account", nil)
-	req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+	req, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/account", nil)
+	req.Header.Add("Authorization", broker.Token(c))
 
 	response, _ := client.Do(req)
 	responseData, err := ioutil.ReadAll(response.Body)
@@ -1006,8 +911,8 @@ func (a *AccountService) getWatchList(c *gin.Context) {
 	client := &http.Client{}
 	accountID := user.AccountID
 
-	req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/watchlists/"+user.WatchlistID, nil)
-	req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+	req, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/watchlists/"+user.WatchlistID, nil)
+	req.Header.Add("Authorization", broker.Token(c))
 
 	response, _ := client.Do(req)
 	responseData, err := ioutil.ReadAll(response.Body)
@@ -1094,8 +999,8 @@ func (a *AccountService) addAssetInWatchList(c *gin.Context) {
 
 		fmt.Println(watchlistBody)
 
-		req, err := http.NewRequest("POST", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/watchlists", watchlistBody)
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req, err := http.NewRequest("POST", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/watchlists", watchlistBody)
+		req.Header.Add("Authorization", broker.Token(c))
 
 		response, _ := client.Do(req)
 		responseData, err := ioutil.ReadAll(response.Body)
@@ -1127,8 +1032,8 @@ func (a *AccountService) addAssetInWatchList(c *gin.Context) {
 		})
 		watchlistBody := bytes.NewBuffer(watchlistJson)
 
-		req, err := http.NewRequest("POST", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/watchlists/"+user.WatchlistID, watchlistBody)
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req, err := http.NewRequest("POST", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/watchlists/"+user.WatchlistID, watchlistBody)
+		req.Header.Add("Authorization", broker.Token(c))
 
 		response, _ := client.Do(req)
 		responseData, err := ioutil.ReadAll(response.Body)
@@ -1169,8 +1074,8 @@ func (a *AccountService) removeAssetFromWatchList(c *gin.Context) {
 	client := &http.Client{}
 	accountID := user.AccountID
 
-	req, err := http.NewRequest("DELETE", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/watchlists/"+user.WatchlistID+"/"+symbol, nil)
-	req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+	req, err := http.NewRequest("DELETE", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/watchlists/"+user.WatchlistID+"/"+symbol, nil)
+	req.Header.Add("Authorization", broker.Token(c))
 
 	response, _ := client.Do(req)
 	responseData, err := ioutil.ReadAll(response.Body)
@@ -1199,12 +1104,12 @@ func (a *AccountService) getPositions(c *gin.Context) {
 		client := &http.Client{}
 		accountID := user.AccountID
 
-		req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/positions", nil)
+		req, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/positions", nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -1266,8 +1171,8 @@ func (a *AccountService) getPositions(c *gin.Context) {
 
 			// Watchlisted flag
 			if user.WatchlistID != "" {
-				req2, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+user.AccountID+"/watchlists/"+user.WatchlistID, nil)
-				req2.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+				req2, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+user.AccountID+"/watchlists/"+user.WatchlistID, nil)
+				req2.Header.Add("Authorization", broker.Token(c))
 
 				response2, _ := client.Do(req2)
 				responseData2, err := ioutil.ReadAll(response2.Body)
@@ -1311,12 +1216,12 @@ func (a *AccountService) getOneOpenPosition(c *gin.Context) {
 
 		symbol := c.Param("symbol")
 
-		req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/positions/"+symbol, nil)
+		req, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/positions/"+symbol, nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -1346,12 +1251,12 @@ func (a *AccountService) closePositions(c *gin.Context) {
 		client := &http.Client{}
 		accountID := user.AccountID
 
-		req, err := http.NewRequest("DELETE", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/positions", nil)
+		req, err := http.NewRequest("DELETE", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/positions", nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -1382,12 +1287,12 @@ func (a *AccountService) closeOnePosition(c *gin.Context) {
 		accountID := user.AccountID
 		symbol := c.Param("symbol")
 
-		req, err := http.NewRequest("DELETE", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/positions/"+symbol, nil)
+		req, err := http.NewRequest("DELETE", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/positions/"+symbol, nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -1417,12 +1322,12 @@ func (a *AccountService) getCalendar(c *gin.Context) {
 		client := &http.Client{}
 		// accountID := user.AccountID
 
-		req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v2/calendar", nil)
+		req, err := http.NewRequest("GET", broker.APIBase(c)+"/v2/calendar", nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -1452,12 +1357,12 @@ func (a *AccountService) getAccount(c *gin.Context) {
 		client := &http.Client{}
 		accountID := user.AccountID
 
-		req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+accountID+"/account", nil)
+		req, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+accountID+"/account", nil)
 		if err != nil {
 			fmt.Print(err.Error())
 		}
 
-		req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+		req.Header.Add("Authorization", broker.Token(c))
 		response, err := client.Do(req)
 
 		if err != nil {
@@ -18,6 +18,11 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// noAudit stands in for the audit middleware of routes
+func noAudit(string, ...string) gin.HandlerFunc {
+	return func(c *gin.Context) { c.Next() }
+}
+
 func TestCreate(t *testing.T) {
 	cases := []struct {
 		name        string
@@ -29,7 +34,7 @@ func TestCreate(t *testing.T) {
 	}{
 		{
 			name:       "Invalid request",
-			req:        `{"first_name":"John","last_name":"Doe","username":"juzernejm","password":"hunter123","email":"johndoe@gmail.com","role_id":3}`,
+			req:        `{"first_name":"John","last_name":"Doe","username":"juzernejm","password":"hunter123","email":"johndoe@gmail.com","role_id":9}`,
 			wantStatus: http.StatusBadRequest,
 		},
 		{
@@ -79,8 +84,8 @@ func TestCreate(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			r := gin.New()
 			rg := r.Group("/v1")
-			accountService := account.NewAccountService(nil, tt.accountRepo, tt.rbac, secret.New())
-			service.AccountRouter(accountService, rg)
+			accountService := account.NewAccountService(nil, tt.accountRepo, tt.rbac, secret.New(), &mock.PasswordPolicy{}, &mock.AuditLog{})
+			service.AccountRouter(accountService, nil, nil, nil, noAudit, rg)
 			ts := httptest.NewServer(r)
 			defer ts.Close()
 			path := ts.URL + "/v1/users"
@@ -115,7 +120,7 @@ func TestChangePassword(t *testing.T) {
 			name:       "Invalid request",
 			req:        `{"new_password":"new_password","old_password":"my_old_password"}`,
 			wantStatus: http.StatusBadRequest,
-			id:         "1",
+			id:         "me",
 		},
 		{
 			name: "Fail on RBAC",
@@ -135,6 +140,9 @@ func TestChangePassword(t *testing.T) {
 				EnforceUserFn: func(c *gin.Context, id int) bool {
 					return true
 				},
+				ImpersonatorFn: func(c *gin.Context) int {
+					return 0
+				},
 			},
 			id: "1",
 			userRepo: &mockdb.User{
@@ -159,8 +167,8 @@ func TestChangePassword(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			r := gin.New()
 			rg := r.Group("/v1")
-			accountService := account.NewAccountService(tt.userRepo, tt.accountRepo, tt.rbac, secret.New())
-			service.AccountRouter(accountService, rg)
+			accountService := account.NewAccountService(tt.userRepo, tt.accountRepo, tt.rbac, secret.New(), &mock.PasswordPolicy{}, &mock.AuditLog{})
+			service.AccountRouter(accountService, nil, nil, nil, noAudit, rg)
 			ts := httptest.NewServer(r)
 			defer ts.Close()
 			path := ts.URL + "/v1/users/" + tt.id + "/password"
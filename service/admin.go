@@ -0,0 +1,181 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/admin"
+	"github.com/alpacahq/ribbit-backend/request"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin represents the back-office http service
+type Admin struct {
+	svc *admin.Service
+}
+
+// AdminRouter declares the back-office routes for support staff. require returns a handler
+// that lets through users whose role has a permission.
+func AdminRouter(svc *admin.Service, require func(string) gin.HandlerFunc, r *gin.RouterGroup) {
+	a := Admin{svc}
+	read, write := require(model.PermUsersRead), require(model.PermUsersWrite)
+
+	ur := r.Group("/users")
+	ur.GET("", read, a.search) // ?q= searches by email, mobile number or broker account number
+	ur.GET("/:id", read, a.view)
+	ur.GET("/:id/broker", require(model.PermKYCReview), a.brokerAccount) // broker account status and KYC results
+	ur.GET("/:id/orders", read, a.orders)
+	ur.GET("/:id/positions", read, a.positions)
+	ur.GET("/:id/transfers", read, a.transfers)
+	ur.POST("/:id/activate", write, a.activate)
+	ur.POST("/:id/deactivate", write, a.deactivate)
+	ur.POST("/:id/verification", write, a.resendVerification)
+	ur.POST("/:id/rewards", require(model.PermRewardsGrant), a.grantReward)
+	ur.POST("/:id/notes", write, a.addNote)
+	ur.POST("/:id/impersonate", require(model.PermUsersImpersonate), a.impersonate) // a short-lived, read-only token to act as the user
+}
+
+func (a *Admin) search(c *gin.Context) {
+	users, err := a.svc.Search(c, c.Query("q"))
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+func (a *Admin) view(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	d, err := a.svc.View(c, id)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+func (a *Admin) brokerAccount(c *gin.Context) {
+	a.fromBroker(c, a.svc.BrokerAccount)
+}
+
+func (a *Admin) orders(c *gin.Context) {
+	a.fromBroker(c, a.svc.Orders)
+}
+
+func (a *Admin) positions(c *gin.Context) {
+	a.fromBroker(c, a.svc.Positions)
+}
+
+func (a *Admin) transfers(c *gin.Context) {
+	a.fromBroker(c, a.svc.Transfers)
+}
+
+func (a *Admin) fromBroker(c *gin.Context, get func(*gin.Context, int) (json.RawMessage, error)) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	body, err := get(c, id)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func (a *Admin) activate(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	if err := a.svc.Activate(c, id); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (a *Admin) deactivate(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	d, err := request.Deactivate(c)
+	if err != nil {
+		return
+	}
+	if err := a.svc.Deactivate(c, id, d.Reason); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (a *Admin) resendVerification(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	if err := a.svc.ResendVerification(c, id); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Code sent."})
+}
+
+func (a *Admin) grantReward(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	r, err := request.Reward(c)
+	if err != nil {
+		return
+	}
+	reward, err := a.svc.GrantReward(c, id, r.Amount, r.Reason)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, reward)
+}
+
+func (a *Admin) addNote(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	n, err := request.Note(c)
+	if err != nil {
+		return
+	}
+	note, err := a.svc.AddNote(c, id, n.Body)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, note)
+}
+
+func (a *Admin) impersonate(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	p, err := request.Impersonate(c)
+	if err != nil {
+		return
+	}
+	imp, err := a.svc.Impersonate(c, id, p.Reason)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, imp)
+}
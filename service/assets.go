@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/broker"
 	account "github.com/alpacahq/ribbit-backend/repository/account"
 	"github.com/alpacahq/ribbit-backend/repository/assets"
 
@@ -362,8 +363,8 @@ func (a *Assets) getAssetsList(c *gin.Context) {
 
 		// Watchlisted flag
 		if user.AccountID != "" && user.WatchlistID != "" {
-			req2, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v1/trading/accounts/"+user.AccountID+"/watchlists/"+user.WatchlistID, nil)
-			req2.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+			req2, err := http.NewRequest("GET", broker.APIBase(c)+"/v1/trading/accounts/"+user.AccountID+"/watchlists/"+user.WatchlistID, nil)
+			req2.Header.Add("Authorization", broker.Token(c))
 
 			response2, _ := client.Do(req2)
 			responseData2, err := ioutil.ReadAll(response2.Body)
@@ -398,12 +399,12 @@ func (a *Assets) getAssetDetail(c *gin.Context) {
 			// fetch market data of assets
 			client := &http.Client{}
 
-			req, err := http.NewRequest("GET", os.Getenv("BROKER_API_BASE")+"/v2/stocks/snapshots?symbols="+url.QueryEscape(AssetsList[i].Symbol), nil)
+			req, err := http.NewRequest("GET", broker.APIBase(c)+"/v2/stocks/snapshots?symbols="+url.QueryEscape(AssetsList[i].Symbol), nil)
 			if err != nil {
 				fmt.Print(err.Error())
 			}
 
-			req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+			req.Header.Add("Authorization", broker.Token(c))
 			response, err := client.Do(req)
 
 			if err != nil {
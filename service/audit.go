@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/audit"
+	"github.com/alpacahq/ribbit-backend/request"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Audit represents the audit log http service
+type Audit struct {
+	svc *audit.Service
+}
+
+// AuditPage is a page of audit events, newest first. Next is the before parameter
+// of the next page, or 0 on the last page.
+type AuditPage struct {
+	Events []model.AuditEvent `json:"events"`
+	Next   int64              `json:"next"`
+}
+
+// AuditRouter declares the audit log routes, for users with the audit:read permission
+func AuditRouter(svc *audit.Service, read gin.HandlerFunc, r *gin.RouterGroup) {
+	a := Audit{svc}
+	r.GET("/audit", read, a.list) // filters by actor_id, target_user_id, action, from and to
+}
+
+func (a *Audit) list(c *gin.Context) {
+	f, err := request.Audit(c)
+	if err != nil {
+		return
+	}
+	events, err := a.svc.List(f)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	page := AuditPage{Events: events}
+	if len(events) == f.Limit {
+		page.Next = events[len(events)-1].ID
+	}
+	if page.Events == nil {
+		page.Events = []model.AuditEvent{}
+	}
+	c.JSON(http.StatusOK, page)
+}
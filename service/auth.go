@@ -7,7 +7,6 @@ import (
 	"encoding/base64"
 	"encoding/pem"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"net/http"
 
@@ -25,11 +24,14 @@ func AuthRouter(svc *auth.Service, r *gin.Engine) {
 	r.POST("/magic", a.magic)   // magic: magic link authentication which handles both the signup scenario and the login scenario
 	r.POST("/signup", a.signup) // email: creates user object
 	r.POST("/login", a.login)
+	r.POST("/login/2fa", a.loginTwoFactor) // second step of login for users with two-factor authentication
 	r.POST("/forgot-password", a.forgot)
 	r.POST("/recover-password", a.recoverPassword)
-	r.GET("/refresh/:token", a.refresh)
-	r.GET("/verification/:token", a.verify)                             // email: on verification token submission, mark user as verified and return jwt
+	r.POST("/refresh", a.refresh)                                       // exchanges a refresh token for a new access token and refresh token
+	r.GET("/verification/:token", a.verify)                             // email: on verification token submission, optionally with ?email=, mark user as verified
+	r.POST("/verification/resend", a.resendVerification)                // email: sends a new verification code, after a cooldown
 	r.POST("/mobile/verify", a.mobileVerify)                            // mobile: on sms code submission, either mark user as verified and return jwt, or update last_login and return jwt
+	r.POST("/mobile/resend", a.mobileResend)                            // mobile: sends a new sms code, up to the send limit of the number
 	r.GET("/referral_code/verify/:referral_code", a.referralCodeVerify) // verify referral code
 	r.GET("/terms-condition", a.termsCondition)
 }
@@ -91,9 +93,25 @@ func (a *Auth) login(c *gin.Context) {
 	c.JSON(http.StatusOK, r)
 }
 
+func (a *Auth) loginTwoFactor(c *gin.Context) {
+	p, err := request.TwoFactorLogin(c)
+	if err != nil {
+		return
+	}
+	r, err := a.svc.VerifyTwoFactor(c, p.ChallengeToken, p.Code)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
 func (a *Auth) refresh(c *gin.Context) {
-	refreshToken := c.Param("token")
-	r, err := a.svc.Refresh(c, refreshToken)
+	p, err := request.Refresh(c)
+	if err != nil {
+		return
+	}
+	r, err := a.svc.Refresh(c, p.RefreshToken)
 	if err != nil {
 		apperr.Response(c, err)
 		return
@@ -140,7 +158,7 @@ func (a *Auth) signup(c *gin.Context) {
 
 func (a *Auth) verify(c *gin.Context) {
 	token := c.Param("token")
-	err := a.svc.Verify(c, token)
+	err := a.svc.Verify(c, c.Query("email"), token)
 	if err != nil {
 		apperr.Response(c, err)
 		return
@@ -148,6 +166,20 @@ func (a *Auth) verify(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{})
 }
 
+func (a *Auth) resendVerification(c *gin.Context) {
+	p, err := request.ResendVerification(c)
+	if err != nil {
+		return
+	}
+	if err := a.svc.ResendVerification(c, p.Email); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the account still has to be verified, a new code has been sent.",
+	})
+}
+
 func (a *Auth) forgot(c *gin.Context) {
 	body, e := request.Forgot(c)
 	if e != nil {
@@ -263,13 +295,11 @@ func (a *Auth) mobile(c *gin.Context) {
 func (a *Auth) magic(c *gin.Context) {
 	m, err := request.Magic(c)
 	if err != nil {
-		fmt.Println(m)
 		apperr.Response(c, err)
 		return
 	}
 	user, err := a.svc.Magic(c, m)
 	if err != nil {
-		fmt.Println(user)
 		apperr.Response(c, err)
 		return
 	}
@@ -286,12 +316,27 @@ func (a *Auth) mobileVerify(c *gin.Context) {
 	}
 	r, err := a.svc.MobileVerify(c, m.CountryCode, m.Mobile, m.Code, m.Signup)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, nil)
+		apperr.Response(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, r)
 }
 
+// mobileResend sends a new sms code to a user created by /mobile, e.g. after the previous one expired
+func (a *Auth) mobileResend(c *gin.Context) {
+	m, err := request.Mobile(c)
+	if err != nil {
+		return
+	}
+	if err := a.svc.MobileResend(c, m.CountryCode, m.Mobile); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Code sent.",
+	})
+}
+
 func (a *Auth) referralCodeVerify(c *gin.Context) {
 	referralCode := c.Param("referral_code")
 	r, err := a.svc.RefVerify(c, referralCode)
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mail"
+	"github.com/alpacahq/ribbit-backend/mobile"
 	"github.com/alpacahq/ribbit-backend/mock"
 	"github.com/alpacahq/ribbit-backend/mock/mockdb"
 	"github.com/alpacahq/ribbit-backend/model"
@@ -29,6 +31,8 @@ func TestLogin(t *testing.T) {
 		wantResp    *model.AuthToken
 		userRepo    *mockdb.User
 		accountRepo *mockdb.Account
+		sessionRepo *mockdb.Session
+		tokenRepo   *mockdb.RefreshToken
 		jwt         *mock.JWT
 		m           *mock.Mail
 		mobile      *mock.Mobile
@@ -41,7 +45,7 @@ func TestLogin(t *testing.T) {
 		},
 		{
 			name:       "Fail on FindByUsername",
-			req:        `{"email":"juzernejm","password":"hunter123"}`,
+			req:        `{"email":"juzernejm","password":"` + encryptPassword("hunter123") + `"}`,
 			wantStatus: http.StatusUnauthorized,
 			userRepo: &mockdb.User{
 				FindByEmailFn: func(string) (*model.User, error) {
@@ -51,7 +55,7 @@ func TestLogin(t *testing.T) {
 		},
 		{
 			name:       "Success",
-			req:        `{"email":"juzernejm","password":"hunter123"}`,
+			req:        `{"email":"juzernejm","password":"` + encryptPassword("hunter123") + `"}`,
 			wantStatus: http.StatusOK,
 			userRepo: &mockdb.User{
 				FindByEmailFn: func(string) (*model.User, error) {
@@ -65,6 +69,16 @@ func TestLogin(t *testing.T) {
 					return nil
 				},
 			},
+			sessionRepo: &mockdb.Session{
+				CreateFn: func(*model.Session) error {
+					return nil
+				},
+			},
+			tokenRepo: &mockdb.RefreshToken{
+				CreateFn: func(*model.IssuedRefreshToken) error {
+					return nil
+				},
+			},
 			jwt: &mock.JWT{
 				GenerateTokenFn: func(*model.User) (string, string, error) {
 					return "jwttokenstring", mock.TestTime(2018).Format(time.RFC3339), nil
@@ -78,7 +92,7 @@ func TestLogin(t *testing.T) {
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			r := gin.New()
-			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.jwt, tt.m, tt.mobile, tt.magic)
+			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.sessionRepo, tt.tokenRepo, &mock.TwoFactor{}, &mock.Throttler{}, tt.jwt, tt.m, tt.mobile, tt.magic, time.Minute, nil, nil, &mock.PasswordPolicy{})
 			service.AuthRouter(authService, r)
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -101,43 +115,234 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+func TestLoginTwoFactor(t *testing.T) {
+	cases := []struct {
+		name       string
+		req        string
+		wantStatus int
+		wantStepUp bool
+		tfa        *mock.TwoFactor
+		throttle   *mock.Throttler
+	}{
+		{
+			name:       "Invalid request",
+			req:        `{"challenge_token":"challenge"}`,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Invalid challenge token",
+			req:        `{"challenge_token":"expired","code":"123456"}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Locked",
+			req:        `{"challenge_token":"challenge","code":"123456"}`,
+			wantStatus: http.StatusTooManyRequests,
+			throttle: &mock.Throttler{
+				CheckFn: func(...string) error {
+					return apperr.New(http.StatusTooManyRequests, "Too many failed attempts. Please try again in a minute.")
+				},
+			},
+		},
+		{
+			name:       "Wrong code",
+			req:        `{"challenge_token":"challenge","code":"000000"}`,
+			wantStatus: http.StatusUnauthorized,
+			tfa: &mock.TwoFactor{
+				VerifyFn: func(int, string) error {
+					return apperr.New(http.StatusUnauthorized, "Invalid two-factor code.")
+				},
+			},
+		},
+		{
+			name:       "Success",
+			req:        `{"challenge_token":"challenge","code":"123456"}`,
+			wantStatus: http.StatusOK,
+			wantStepUp: true,
+			tfa: &mock.TwoFactor{
+				VerifyFn: func(id int, code string) error {
+					if id != 1 || code != "123456" {
+						return apperr.New(http.StatusUnauthorized, "Invalid two-factor code.")
+					}
+					return nil
+				},
+			},
+		},
+	}
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			var session *model.Session
+			userRepo := &mockdb.User{
+				ViewFn: func(id int) (*model.User, error) {
+					return &model.User{ID: id, Active: true, Verified: true}, nil
+				},
+				UpdateLoginFn: func(*model.User) error {
+					return nil
+				},
+			}
+			sessionRepo := &mockdb.Session{
+				CreateFn: func(s *model.Session) error {
+					session = s
+					return nil
+				},
+			}
+			tokenRepo := &mockdb.RefreshToken{
+				CreateFn: func(*model.IssuedRefreshToken) error {
+					return nil
+				},
+			}
+			jwt := &mock.JWT{
+				GenerateTokenFn: func(*model.User) (string, string, error) {
+					return "jwttokenstring", mock.TestTime(2018).Format(time.RFC3339), nil
+				},
+				ParseChallengeTokenFn: func(token string) (int, error) {
+					if token != "challenge" {
+						return 0, apperr.New(http.StatusUnauthorized, "Invalid or expired challenge token. Please sign in again.")
+					}
+					return 1, nil
+				},
+			}
+			if tt.throttle == nil {
+				tt.throttle = &mock.Throttler{}
+			}
+			r := gin.New()
+			authService := auth.NewAuthService(userRepo, nil, sessionRepo, tokenRepo, tt.tfa, tt.throttle, jwt, nil, nil, nil, time.Minute, nil, nil, &mock.PasswordPolicy{})
+			service.AuthRouter(authService, r)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Post(ts.URL+"/login/2fa", "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			if tt.wantStepUp {
+				response := new(model.LoginResponseWithToken)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, "jwttokenstring", response.Token)
+				assert.NotEmpty(t, response.RefreshToken)
+				assert.NotNil(t, session.StepUpAt)
+			}
+		})
+	}
+}
+
 func TestRefresh(t *testing.T) {
+	issued := func() *model.IssuedRefreshToken {
+		return &model.IssuedRefreshToken{
+			UserID:          1,
+			FamilyID:        "family",
+			ExpiresAt:       time.Now().Add(time.Hour),
+			FamilyExpiresAt: time.Now().Add(24 * time.Hour),
+		}
+	}
+	used := time.Now().Add(-time.Minute)
 	cases := []struct {
 		name        string
 		req         string
 		wantStatus  int
 		wantResp    *model.RefreshToken
+		wantRevoked bool
 		userRepo    *mockdb.User
 		accountRepo *mockdb.Account
+		sessionRepo *mockdb.Session
+		tokenRepo   *mockdb.RefreshToken
 		jwt         *mock.JWT
 		m           *mock.Mail
 		mobile      *mock.Mobile
 		magic       *mock.Magic
 	}{
 		{
-			name:       "Fail on FindByToken",
-			req:        "refreshtoken",
+			name:       "Invalid request",
+			req:        `{}`,
 			wantStatus: http.StatusInternalServerError,
-			userRepo: &mockdb.User{
-				FindByTokenFn: func(string) (*model.User, error) {
-					return nil, apperr.DB
+		},
+		{
+			name:       "Fail on FindByHash",
+			req:        `{"refresh_token":"refreshtoken"}`,
+			wantStatus: http.StatusUnauthorized,
+			tokenRepo: &mockdb.RefreshToken{
+				FindByHashFn: func(string) (*model.IssuedRefreshToken, error) {
+					return nil, apperr.New(http.StatusUnauthorized, "Invalid refresh token.")
+				},
+			},
+		},
+		{
+			name:       "Fail on expired token",
+			req:        `{"refresh_token":"refreshtoken"}`,
+			wantStatus: http.StatusUnauthorized,
+			tokenRepo: &mockdb.RefreshToken{
+				FindByHashFn: func(string) (*model.IssuedRefreshToken, error) {
+					t := issued()
+					t.ExpiresAt = time.Now().Add(-time.Minute)
+					return t, nil
+				},
+			},
+		},
+		{
+			name:        "Reused token revokes its family",
+			req:         `{"refresh_token":"refreshtoken"}`,
+			wantStatus:  http.StatusUnauthorized,
+			wantRevoked: true,
+			sessionRepo: &mockdb.Session{
+				RevokeFn: func(string) error {
+					return nil
+				},
+			},
+			tokenRepo: &mockdb.RefreshToken{
+				FindByHashFn: func(string) (*model.IssuedRefreshToken, error) {
+					t := issued()
+					t.UsedAt = &used
+					return t, nil
 				},
 			},
 		},
 		{
 			name:       "Success",
-			req:        "refreshtoken",
+			req:        `{"refresh_token":"refreshtoken"}`,
 			wantStatus: http.StatusOK,
 			userRepo: &mockdb.User{
-				FindByTokenFn: func(string) (*model.User, error) {
+				ViewFn: func(int) (*model.User, error) {
 					return &model.User{
 						Username: "johndoe",
 						Active:   true,
 					}, nil
 				},
 			},
+			sessionRepo: &mockdb.Session{
+				ViewFn: func(id string) (*model.Session, error) {
+					return &model.Session{ID: id, UserID: 1, ExpiresAt: time.Now().Add(24 * time.Hour)}, nil
+				},
+				TouchFn: func(*model.Session) error {
+					return nil
+				},
+			},
+			tokenRepo: &mockdb.RefreshToken{
+				FindByHashFn: func(hash string) (*model.IssuedRefreshToken, error) {
+					if hash != model.HashRefreshToken("refreshtoken") {
+						return nil, apperr.New(http.StatusUnauthorized, "Invalid refresh token.")
+					}
+					return issued(), nil
+				},
+				MarkUsedFn: func(*model.IssuedRefreshToken) (bool, error) {
+					return true, nil
+				},
+				CreateFn: func(t *model.IssuedRefreshToken) error {
+					if t.FamilyID != "family" {
+						return apperr.DB
+					}
+					return nil
+				},
+			},
 			jwt: &mock.JWT{
-				GenerateTokenFn: func(*model.User) (string, string, error) {
+				GenerateSessionTokenFn: func(_ *model.User, sid string) (string, string, error) {
+					if sid != "family" {
+						return "", "", apperr.Generic
+					}
 					return "jwttokenstring", mock.TestTime(2018).Format(time.RFC3339), nil
 				},
 			},
@@ -148,13 +353,20 @@ func TestRefresh(t *testing.T) {
 
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
+			revoked := false
+			if tt.tokenRepo != nil {
+				tt.tokenRepo.RevokeFamilyFn = func(string) error {
+					revoked = true
+					return nil
+				}
+			}
 			r := gin.New()
-			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.jwt, tt.m, tt.mobile, tt.magic)
+			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.sessionRepo, tt.tokenRepo, &mock.TwoFactor{}, &mock.Throttler{}, tt.jwt, tt.m, tt.mobile, tt.magic, time.Minute, nil, nil, &mock.PasswordPolicy{})
 			service.AuthRouter(authService, r)
 			ts := httptest.NewServer(r)
 			defer ts.Close()
-			path := ts.URL + "/refresh/" + tt.req
-			res, err := http.Get(path)
+			path := ts.URL + "/refresh"
+			res, err := http.Post(path, "application/json", bytes.NewBufferString(tt.req))
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -164,9 +376,13 @@ func TestRefresh(t *testing.T) {
 				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
 					t.Fatal(err)
 				}
+				assert.NotEmpty(t, response.RefreshToken)
+				assert.NotEqual(t, "refreshtoken", response.RefreshToken)
+				tt.wantResp.RefreshToken = response.RefreshToken
 				assert.Equal(t, tt.wantResp, response)
 			}
 			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			assert.Equal(t, tt.wantRevoked, revoked)
 		})
 	}
 }
@@ -178,6 +394,8 @@ func TestSignup(t *testing.T) {
 		wantStatus  int
 		userRepo    *mockdb.User
 		accountRepo *mockdb.Account
+		sessionRepo *mockdb.Session
+		tokenRepo   *mockdb.RefreshToken
 		jwt         *mock.JWT
 		m           *mock.Mail
 		mobile      *mock.Mobile
@@ -185,12 +403,18 @@ func TestSignup(t *testing.T) {
 	}{
 		{
 			name:       "Success",
-			req:        `{"email":"juzernejm@example.org","password":"hunter123"}`,
+			req:        `{"email":"juzernejm@example.org","password":"` + encryptPassword("hunter123") + `"}`,
 			wantStatus: http.StatusCreated,
 			userRepo: &mockdb.User{ // no such user, so create
 				FindByEmailFn: func(string) (*model.User, error) {
 					return nil, apperr.DB
 				},
+				ViewFn: func(id int) (*model.User, error) {
+					return &model.User{ID: id, Email: "juzernejm@example.org"}, nil
+				},
+				UpdateLoginFn: func(*model.User) error {
+					return nil
+				},
 			},
 			accountRepo: &mockdb.Account{
 				CreateAndVerifyFn: func(*model.User) (*model.Verification, error) {
@@ -200,6 +424,21 @@ func TestSignup(t *testing.T) {
 					}, nil
 				},
 			},
+			sessionRepo: &mockdb.Session{
+				CreateFn: func(*model.Session) error {
+					return nil
+				},
+			},
+			tokenRepo: &mockdb.RefreshToken{
+				CreateFn: func(*model.IssuedRefreshToken) error {
+					return nil
+				},
+			},
+			jwt: &mock.JWT{
+				GenerateTokenFn: func(*model.User) (string, string, error) {
+					return "jwttokenstring", mock.TestTime(2018).Format(time.RFC3339), nil
+				},
+			},
 			m: &mock.Mail{
 				SendVerificationEmailFn: func(string, *model.Verification) error {
 					return nil
@@ -231,7 +470,7 @@ func TestSignup(t *testing.T) {
 		},
 		{
 			name:       "Failure because user already exists",
-			req:        `{"email":"calvin@example.org","password":"whatever123"}`,
+			req:        `{"email":"calvin@example.org","password":"` + encryptPassword("whatever123") + `"}`,
 			wantStatus: http.StatusConflict,
 			userRepo: &mockdb.User{ // user already exists
 				FindByEmailFn: func(string) (*model.User, error) {
@@ -262,7 +501,7 @@ func TestSignup(t *testing.T) {
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			r := gin.New()
-			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.jwt, tt.m, tt.mobile, tt.magic)
+			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.sessionRepo, tt.tokenRepo, &mock.TwoFactor{}, &mock.Throttler{}, tt.jwt, tt.m, tt.mobile, tt.magic, time.Minute, nil, nil, &mock.PasswordPolicy{})
 			service.AuthRouter(authService, r)
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -279,12 +518,22 @@ func TestSignup(t *testing.T) {
 }
 
 func TestVerification(t *testing.T) {
+	userRepo := &mockdb.User{
+		FindByEmailFn: func(email string) (*model.User, error) {
+			if email != "juzernejm@mail.com" {
+				return nil, apperr.NotFound
+			}
+			return &model.User{ID: 1, Email: email}, nil
+		},
+	}
 	cases := []struct {
 		name        string
 		req         string
 		wantStatus  int
 		userRepo    *mockdb.User
 		accountRepo *mockdb.Account
+		sessionRepo *mockdb.Session
+		tokenRepo   *mockdb.RefreshToken
 		jwt         *mock.JWT
 		m           *mock.Mail
 		mobile      *mock.Mobile
@@ -292,42 +541,85 @@ func TestVerification(t *testing.T) {
 	}{
 		{
 			name:       "Success",
-			req:        "some-random-verification-token",
+			req:        "123456?email=juzernejm@mail.com",
 			wantStatus: http.StatusOK,
+			userRepo:   userRepo,
 			accountRepo: &mockdb.Account{
-				FindVerificationTokenFn: func(string) (*model.Verification, error) {
-					return &model.Verification{
-						Token:  "some-random-token-for-verification",
-						UserID: 1,
-					}, nil
+				UseVerificationTokenFn: func(u *model.User, purpose, token string) (*model.Verification, error) {
+					if u.ID != 1 || purpose != model.PurposeEmailVerify || token != "123456" {
+						return nil, apperr.NotFound
+					}
+					return model.NewVerification(u.ID, purpose, token), nil
 				},
-				DeleteVerificationTokenFn: func(*model.Verification) error {
+				ActivateFn: func(u *model.User) error {
+					if !u.Active || !u.Verified {
+						return apperr.DB
+					}
 					return nil
 				},
 			},
 		},
+		{
+			name:       "Success without email",
+			req:        "123456",
+			wantStatus: http.StatusOK,
+			userRepo: &mockdb.User{
+				ViewFn: func(id int) (*model.User, error) {
+					return &model.User{ID: id, Email: "juzernejm@mail.com"}, nil
+				},
+			},
+			accountRepo: &mockdb.Account{
+				RedeemVerificationTokenFn: func(purpose, token string) (*model.Verification, error) {
+					if purpose != model.PurposeEmailVerify || token != "123456" {
+						return nil, apperr.NotFound
+					}
+					return model.NewVerification(1, purpose, token), nil
+				},
+				ActivateFn: func(u *model.User) error {
+					if u.ID != 1 || !u.Verified {
+						return apperr.DB
+					}
+					return nil
+				},
+			},
+		},
+		{
+			name:       "Failed without email",
+			req:        "654321",
+			wantStatus: http.StatusNotFound,
+			accountRepo: &mockdb.Account{
+				RedeemVerificationTokenFn: func(string, string) (*model.Verification, error) {
+					return nil, apperr.New(http.StatusNotFound, "Invalid or expired OTP")
+				},
+			},
+		},
+		{
+			name:       "Failed for unknown email",
+			req:        "123456?email=someone@mail.com",
+			wantStatus: http.StatusNotFound,
+			userRepo:   userRepo,
+		},
 		{
 			name:       "Failed",
-			req:        "some-random-verification-token",
+			req:        "123456?email=juzernejm@mail.com",
 			wantStatus: http.StatusNotFound,
+			userRepo:   userRepo,
 			accountRepo: &mockdb.Account{
-				FindVerificationTokenFn: func(string) (*model.Verification, error) {
+				UseVerificationTokenFn: func(*model.User, string, string) (*model.Verification, error) {
 					return nil, apperr.NotFound
 				},
 			},
 		},
 		{
 			name:       "Failed",
-			req:        "some-random-verification-token",
+			req:        "123456?email=juzernejm@mail.com",
 			wantStatus: http.StatusInternalServerError,
+			userRepo:   userRepo,
 			accountRepo: &mockdb.Account{
-				FindVerificationTokenFn: func(string) (*model.Verification, error) {
-					return &model.Verification{
-						Token:  "some-random-token-for-verification",
-						UserID: 1,
-					}, nil
+				UseVerificationTokenFn: func(u *model.User, purpose, token string) (*model.Verification, error) {
+					return model.NewVerification(u.ID, purpose, token), nil
 				},
-				DeleteVerificationTokenFn: func(*model.Verification) error {
+				ActivateFn: func(*model.User) error {
 					return apperr.DB
 				},
 			},
@@ -339,7 +631,7 @@ func TestVerification(t *testing.T) {
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			r := gin.New()
-			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.jwt, tt.m, tt.mobile, tt.magic)
+			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.sessionRepo, tt.tokenRepo, &mock.TwoFactor{}, &mock.Throttler{}, tt.jwt, tt.m, tt.mobile, tt.magic, time.Minute, nil, nil, &mock.PasswordPolicy{})
 			service.AuthRouter(authService, r)
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -355,6 +647,143 @@ func TestVerification(t *testing.T) {
 	}
 }
 
+func TestResendVerification(t *testing.T) {
+	userRepo := &mockdb.User{
+		FindByEmailFn: func(email string) (*model.User, error) {
+			switch email {
+			case "juzernejm@mail.com":
+				return &model.User{ID: 1, Email: email}, nil
+			case "verified@mail.com":
+				return &model.User{ID: 2, Email: email, Verified: true, Active: true}, nil
+			}
+			return nil, apperr.NotFound
+		},
+	}
+	sent := &mock.Mail{
+		SendVerificationEmailFn: func(string, *model.Verification) error {
+			return nil
+		},
+	}
+	cases := []struct {
+		name        string
+		req         string
+		wantStatus  int
+		accountRepo *mockdb.Account
+		m           *mock.Mail
+	}{
+		{
+			name:       "Fail on binding",
+			req:        `{"mail":"juzernejm@mail.com"}`,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Unknown email",
+			req:        `{"email":"someone@mail.com"}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "Already verified",
+			req:        `{"email":"verified@mail.com"}`,
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "Within cooldown",
+			req:        `{"email":"juzernejm@mail.com"}`,
+			wantStatus: http.StatusTooManyRequests,
+			accountRepo: &mockdb.Account{
+				FindVerificationTokenByUserFn: func(u *model.User, purpose string) (*model.Verification, error) {
+					v := model.NewVerification(u.ID, purpose, "123456")
+					v.CreatedAt = time.Now()
+					return v, nil
+				},
+			},
+		},
+		{
+			name:       "Success",
+			req:        `{"email":"juzernejm@mail.com"}`,
+			wantStatus: http.StatusAccepted,
+			accountRepo: &mockdb.Account{
+				FindVerificationTokenByUserFn: func(u *model.User, purpose string) (*model.Verification, error) {
+					v := model.NewVerification(u.ID, purpose, "123456")
+					v.CreatedAt = time.Now().Add(-2 * time.Minute)
+					return v, nil
+				},
+				CreateVerificationTokenFn: func(u *model.User, purpose string) (*model.Verification, error) {
+					return model.NewVerification(u.ID, purpose, "654321"), nil
+				},
+			},
+			m: sent,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			authService := auth.NewAuthService(userRepo, tt.accountRepo, nil, nil, &mock.TwoFactor{}, &mock.Throttler{}, nil, tt.m, nil, nil, time.Minute, nil, nil, &mock.PasswordPolicy{})
+			service.AuthRouter(authService, r)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			path := ts.URL + "/verification/resend"
+			res, err := http.Post(path, "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestResendVerificationTenant(t *testing.T) {
+	acme := &model.Tenant{ID: 1, Name: "Acme", Hosts: []string{"acme.test"}}
+	var sentFor *model.Tenant
+	sent := &mock.Mail{
+		SendVerificationEmailFn: func(string, *model.Verification) error {
+			return nil
+		},
+	}
+	sent.ForFn = func(t *model.Tenant) mail.Service {
+		sentFor = t
+		return sent
+	}
+	userRepo := &mockdb.User{
+		FindByEmailFn: func(email string) (*model.User, error) {
+			return &model.User{ID: 1, Email: email, TenantID: 2}, nil
+		},
+	}
+	accountRepo := &mockdb.Account{
+		FindVerificationTokenByUserFn: func(*model.User, string) (*model.Verification, error) {
+			return nil, apperr.NotFound
+		},
+		CreateVerificationTokenFn: func(u *model.User, purpose string) (*model.Verification, error) {
+			return model.NewVerification(u.ID, purpose, "654321"), nil
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("tenant", acme)
+	})
+	authService := auth.NewAuthService(userRepo, accountRepo, nil, nil, &mock.TwoFactor{}, &mock.Throttler{}, nil, sent, nil, nil, time.Minute, nil, nil, &mock.PasswordPolicy{})
+	service.AuthRouter(authService, r)
+	resend := func() int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/verification/resend", bytes.NewBufferString(`{"email":"juzernejm@mail.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusAccepted, resend())
+	assert.Equal(t, acme, sentFor, "emails are sent as the tenant of the request without a tenant store")
+
+	authService.Tenants = &mock.Tenants{}
+	assert.Equal(t, http.StatusAccepted, resend())
+	assert.Equal(t, 2, sentFor.ID, "emails are sent as the tenant of the user")
+}
+
 func TestMobile(t *testing.T) {
 	cases := []struct {
 		name        string
@@ -362,6 +791,8 @@ func TestMobile(t *testing.T) {
 		wantStatus  int
 		userRepo    *mockdb.User
 		accountRepo *mockdb.Account
+		sessionRepo *mockdb.Session
+		tokenRepo   *mockdb.RefreshToken
 		jwt         *mock.JWT
 		m           *mock.Mail
 		mobile      *mock.Mobile
@@ -437,7 +868,7 @@ func TestMobile(t *testing.T) {
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			r := gin.New()
-			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.jwt, tt.m, tt.mobile, tt.magic)
+			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.sessionRepo, tt.tokenRepo, &mock.TwoFactor{}, &mock.Throttler{}, tt.jwt, tt.m, tt.mobile, tt.magic, time.Minute, nil, nil, &mock.PasswordPolicy{})
 			service.AuthRouter(authService, r)
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -460,6 +891,8 @@ func TestMobileVerify(t *testing.T) {
 		wantStatus  int
 		userRepo    *mockdb.User
 		accountRepo *mockdb.Account
+		sessionRepo *mockdb.Session
+		tokenRepo   *mockdb.RefreshToken
 		jwt         *mock.JWT
 		m           *mock.Mail
 		mobile      *mock.Mobile
@@ -497,6 +930,16 @@ func TestMobileVerify(t *testing.T) {
 					}, nil
 				},
 			},
+			sessionRepo: &mockdb.Session{
+				CreateFn: func(*model.Session) error {
+					return nil
+				},
+			},
+			tokenRepo: &mockdb.RefreshToken{
+				CreateFn: func(*model.IssuedRefreshToken) error {
+					return nil
+				},
+			},
 		},
 		{
 			name: "Failure: no country code",
@@ -529,7 +972,7 @@ func TestMobileVerify(t *testing.T) {
 			},
 			mobile: &mock.Mobile{
 				CheckCodeFn: func(string, string, string) error {
-					return apperr.NewStatus(http.StatusNotFound)
+					return mobile.ErrInvalidCode
 				},
 			},
 		},
@@ -540,7 +983,7 @@ func TestMobileVerify(t *testing.T) {
 	for _, tt := range cases {
 		t.Run(tt.name, func(t *testing.T) {
 			r := gin.New()
-			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.jwt, tt.m, tt.mobile, tt.magic)
+			authService := auth.NewAuthService(tt.userRepo, tt.accountRepo, tt.sessionRepo, tt.tokenRepo, &mock.TwoFactor{}, &mock.Throttler{}, tt.jwt, tt.m, tt.mobile, tt.magic, time.Minute, nil, nil, &mock.PasswordPolicy{})
 			service.AuthRouter(authService, r)
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -555,3 +998,264 @@ func TestMobileVerify(t *testing.T) {
 		})
 	}
 }
+
+func TestMobileVerifyOutcomes(t *testing.T) {
+	userRepo := &mockdb.User{
+		FindByMobileFn: func(countryCode, number string) (*model.User, error) {
+			return &model.User{ID: 1, CountryCode: countryCode, Mobile: number}, nil
+		},
+		UpdateFn: func(u *model.User) (*model.User, error) {
+			return u, nil
+		},
+		UpdateLoginFn: func(*model.User) error {
+			return nil
+		},
+	}
+	jwt := &mock.JWT{
+		GenerateTokenFn: func(*model.User) (string, string, error) {
+			return "jwttokenstring", mock.TestTime(2018).Format(time.RFC3339), nil
+		},
+	}
+	sessionRepo := &mockdb.Session{
+		CreateFn: func(*model.Session) error {
+			return nil
+		},
+	}
+	tokenRepo := &mockdb.RefreshToken{
+		CreateFn: func(*model.IssuedRefreshToken) error {
+			return nil
+		},
+	}
+	cases := []struct {
+		name       string
+		send       bool
+		code       func(f *mock.FakeMobile) string
+		before     func(f *mock.FakeMobile)
+		wantStatus int
+	}{
+		{
+			name: "Approved",
+			send: true,
+			code: func(f *mock.FakeMobile) string {
+				code, _ := f.Code("+65", "91919191")
+				return code
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Wrong code",
+			send:       true,
+			code:       func(*mock.FakeMobile) string { return "000000" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "Expired code",
+			send: true,
+			before: func(f *mock.FakeMobile) {
+				f.Expire("+65", "91919191")
+			},
+			code:       func(*mock.FakeMobile) string { return "000001" },
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "No code sent",
+			code:       func(*mock.FakeMobile) string { return "000001" },
+			wantStatus: http.StatusGone,
+		},
+		{
+			name: "Max attempts",
+			send: true,
+			before: func(f *mock.FakeMobile) {
+				f.MaxAttempts = 1
+			},
+			code:       func(*mock.FakeMobile) string { return "000000" },
+			wantStatus: http.StatusTooManyRequests,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := mock.NewFakeMobile()
+			if tt.send {
+				if err := fake.GenerateSMSToken("+65", "91919191"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.before != nil {
+				tt.before(fake)
+			}
+			r := gin.New()
+			authService := auth.NewAuthService(userRepo, nil, sessionRepo, tokenRepo, &mock.TwoFactor{}, &mock.Throttler{}, jwt, nil, fake, nil, time.Minute, nil, nil, &mock.PasswordPolicy{})
+			service.AuthRouter(authService, r)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			req := `{"country_code":"+65","mobile":"91919191","code":"` + tt.code(fake) + `","signup":true}`
+			res, err := http.Post(ts.URL+"/mobile/verify", "application/json", bytes.NewBufferString(req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+		})
+	}
+}
+
+func TestMobileVerifyDisabled(t *testing.T) {
+	userRepo := &mockdb.User{
+		FindByMobileFn: func(countryCode, number string) (*model.User, error) {
+			return &model.User{ID: 1, CountryCode: countryCode, Mobile: number, Verified: true}, nil
+		},
+		UpdateFn: func(u *model.User) (*model.User, error) {
+			return nil, apperr.DB
+		},
+	}
+	fake := mock.NewFakeMobile()
+	if err := fake.GenerateSMSToken("+65", "91919191"); err != nil {
+		t.Fatal(err)
+	}
+	code, _ := fake.Code("+65", "91919191")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	authService := auth.NewAuthService(userRepo, nil, nil, nil, &mock.TwoFactor{}, &mock.Throttler{}, &mock.JWT{}, nil, fake, nil, time.Minute, nil, nil, &mock.PasswordPolicy{})
+	service.AuthRouter(authService, r)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	req := `{"country_code":"+65","mobile":"91919191","code":"` + code + `","signup":true}`
+	res, err := http.Post(ts.URL+"/mobile/verify", "application/json", bytes.NewBufferString(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
+
+func TestMobileVerifyTwoFactor(t *testing.T) {
+	userRepo := &mockdb.User{
+		FindByMobileFn: func(countryCode, number string) (*model.User, error) {
+			return &model.User{ID: 1, CountryCode: countryCode, Mobile: number, Verified: true, Active: true}, nil
+		},
+		UpdateFn: func(u *model.User) (*model.User, error) {
+			return u, nil
+		},
+	}
+	tfa := &mock.TwoFactor{
+		EnabledFn: func(int) (bool, error) { return true, nil },
+	}
+	jwt := &mock.JWT{
+		GenerateChallengeTokenFn: func(*model.User) (string, error) { return "challenge", nil },
+	}
+	fake := mock.NewFakeMobile()
+	if err := fake.GenerateSMSToken("+65", "91919191"); err != nil {
+		t.Fatal(err)
+	}
+	code, _ := fake.Code("+65", "91919191")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	authService := auth.NewAuthService(userRepo, nil, nil, nil, tfa, &mock.Throttler{}, jwt, nil, fake, nil, time.Minute, nil, nil, &mock.PasswordPolicy{})
+	service.AuthRouter(authService, r)
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+	req := `{"country_code":"+65","mobile":"91919191","code":"` + code + `","signup":true}`
+	res, err := http.Post(ts.URL+"/mobile/verify", "application/json", bytes.NewBufferString(req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	response := new(model.LoginResponseWithToken)
+	if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, response.TwoFactorRequired)
+	assert.Equal(t, "challenge", response.ChallengeToken)
+	assert.Empty(t, response.Token, "no session before the second factor")
+}
+
+func TestMobileResend(t *testing.T) {
+	userRepo := &mockdb.User{
+		FindByMobileFn: func(countryCode, number string) (*model.User, error) {
+			if number != "91919191" {
+				return nil, apperr.NotFound
+			}
+			return &model.User{ID: 1, CountryCode: countryCode, Mobile: number}, nil
+		},
+	}
+	cases := []struct {
+		name       string
+		req        string
+		fake       *mock.FakeMobile
+		throttle   *mock.Throttler
+		wantStatus int
+		wantSent   bool
+	}{
+		{
+			name:       "Fail on binding",
+			req:        `{"mobile":"91919191"}`,
+			fake:       mock.NewFakeMobile(),
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "Unknown number",
+			req:        `{"country_code":"+65","mobile":"81818181"}`,
+			fake:       mock.NewFakeMobile(),
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "Success",
+			req:        `{"country_code":"+65","mobile":"91919191"}`,
+			fake:       mock.NewFakeMobile(),
+			wantStatus: http.StatusAccepted,
+			wantSent:   true,
+		},
+		{
+			name: "Send limit reached",
+			req:  `{"country_code":"+65","mobile":"91919191"}`,
+			fake: mock.NewFakeMobile(),
+			throttle: &mock.Throttler{
+				CheckFn: func(keys ...string) error {
+					if keys[0] == model.ThrottleKey(model.ThrottleSMS, "+6591919191") {
+						return apperr.New(http.StatusTooManyRequests, "Too many failed attempts. Please try again in a minute.")
+					}
+					return nil
+				},
+			},
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "Provider refuses to send",
+			req:        `{"country_code":"+65","mobile":"91919191"}`,
+			fake:       &mock.FakeMobile{SendErr: mobile.ErrTooManySends},
+			wantStatus: http.StatusTooManyRequests,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle := tt.throttle
+			if throttle == nil {
+				throttle = &mock.Throttler{}
+			}
+			r := gin.New()
+			authService := auth.NewAuthService(userRepo, nil, nil, nil, &mock.TwoFactor{}, throttle, nil, nil, tt.fake, nil, time.Minute, nil, nil, &mock.PasswordPolicy{})
+			service.AuthRouter(authService, r)
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+			res, err := http.Post(ts.URL+"/mobile/resend", "application/json", bytes.NewBufferString(tt.req))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			if tt.wantStatus == http.StatusAccepted {
+				_, sent := tt.fake.Code("+65", "91919191")
+				assert.Equal(t, tt.wantSent, sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/repository/closure"
+	"github.com/alpacahq/ribbit-backend/request"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Closure represents the account closure and data export http service
+type Closure struct {
+	svc *closure.Service
+}
+
+// ClosureRouter declares the routes to close the current user's account and export their data.
+// stepUp guards both.
+func ClosureRouter(svc *closure.Service, stepUp gin.HandlerFunc, r *gin.RouterGroup) {
+	a := Closure{svc}
+	r.POST("/account/close", stepUp, a.close)
+	r.GET("/account/export", stepUp, a.export) // a ZIP archive of the user's data
+}
+
+func (a *Closure) close(c *gin.Context) {
+	p, err := request.CloseAccount(c)
+	if err != nil {
+		return
+	}
+	if err := a.svc.Close(c, p.Reason); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Your account is closed."})
+}
+
+func (a *Closure) export(c *gin.Context) {
+	data, err := a.svc.Export(c)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	name := fmt.Sprintf("account-export-%s.zip", time.Now().UTC().Format("2006-01-02"))
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Data(http.StatusOK, "application/zip", data)
+}
@@ -0,0 +1,55 @@
+package service_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// passwordKey is the key clients encrypt passwords with, read by the handlers from private_key.pem
+var passwordKey *rsa.PrivateKey
+
+// TestMain runs the tests in a temporary directory with a private_key.pem, as the login and
+// signup handlers read it from the working directory
+func TestMain(m *testing.M) {
+	os.Exit(runInKeyDir(m))
+}
+
+func runInKeyDir(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "service")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	if passwordKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		panic(err)
+	}
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(passwordKey)}
+	if err := ioutil.WriteFile(filepath.Join(dir, "private_key.pem"), pem.EncodeToMemory(block), 0600); err != nil {
+		panic(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	defer os.Chdir(wd)
+	return m.Run()
+}
+
+// encryptPassword encrypts a password the way clients send it to the login and signup handlers
+func encryptPassword(password string) string {
+	b, err := rsa.EncryptPKCS1v15(rand.Reader, &passwordKey.PublicKey, []byte(password))
+	if err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
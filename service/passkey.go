@@ -0,0 +1,94 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/repository/auth"
+	"github.com/alpacahq/ribbit-backend/request"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Passkey represents the passkey http service
+type Passkey struct {
+	svc *auth.Service
+}
+
+// PasskeyRouter declares the routes for signing in with a passkey, and for the passkeys of the current user
+func PasskeyRouter(svc *auth.Service, stepUp gin.HandlerFunc, r *gin.Engine, v1 *gin.RouterGroup) {
+	p := Passkey{svc}
+	r.POST("/passkeys/login/begin", p.beginLogin)   // returns the options for navigator.credentials.get()
+	r.POST("/passkeys/login/finish", p.finishLogin) // signs in with the assertion of the authenticator
+
+	pr := v1.Group("/passkeys")
+	pr.GET("", p.list)
+	pr.POST("/register/begin", stepUp, p.beginRegistration) // returns the options for navigator.credentials.create()
+	pr.POST("/register/finish", p.finishRegistration)       // stores the credential the authenticator created
+	pr.DELETE("/:id", stepUp, p.delete)
+}
+
+func (p *Passkey) beginLogin(c *gin.Context) {
+	o, err := p.svc.BeginPasskeyLogin(c)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+func (p *Passkey) finishLogin(c *gin.Context) {
+	cred, err := request.Passkey(c)
+	if err != nil {
+		return
+	}
+	r, err := p.svc.FinishPasskeyLogin(c, cred)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+func (p *Passkey) list(c *gin.Context) {
+	passkeys, err := p.svc.Passkeys(c)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, passkeys)
+}
+
+func (p *Passkey) beginRegistration(c *gin.Context) {
+	o, err := p.svc.BeginPasskeyRegistration(c)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+func (p *Passkey) finishRegistration(c *gin.Context) {
+	cred, err := request.Passkey(c)
+	if err != nil {
+		return
+	}
+	passkey, err := p.svc.FinishPasskeyRegistration(c, cred)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, passkey)
+}
+
+func (p *Passkey) delete(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	if err := p.svc.DeletePasskey(c, id); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
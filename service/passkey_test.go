@@ -0,0 +1,256 @@
+package service_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/mock"
+	"github.com/alpacahq/ribbit-backend/mock/mockdb"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/auth"
+	"github.com/alpacahq/ribbit-backend/service"
+	"github.com/alpacahq/ribbit-backend/webauthn"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+// passkeyAssertion signs challenge like a platform authenticator holding key for example.com
+func passkeyAssertion(t *testing.T, key *ecdsa.PrivateKey, count uint32, challenge string) (string, string, string) {
+	cd, _ := json.Marshal(map[string]string{"type": "webauthn.get", "challenge": challenge, "origin": "https://example.com"})
+	rpHash := sha256.Sum256([]byte("example.com"))
+	ad := append(rpHash[:], 0x05, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(ad[33:], count)
+	cdHash := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(append([]byte{}, ad...), cdHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webauthn.EncodeID(cd), webauthn.EncodeID(ad), webauthn.EncodeID(sig)
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pad := func(b []byte) []byte { return append(make([]byte, 32-len(b)), b...) }
+	var publicKey []byte
+	if err := codec.NewEncoderBytes(&publicKey, &codec.CborHandle{}).Encode(map[int64]interface{}{1: 2, 3: -7, -1: 1, -2: pad(key.X.Bytes()), -3: pad(key.Y.Bytes())}); err != nil {
+		t.Fatal(err)
+	}
+	credentialID := webauthn.EncodeID([]byte("credential-1"))
+	rp := &webauthn.RelyingParty{ID: "example.com", Name: "Example", Origins: []string{"https://example.com"}}
+	jwt := &mock.JWT{
+		GenerateTokenFn: func(*model.User) (string, string, error) {
+			return "jwttokenstring", mock.TestTime(2018).Format(time.RFC3339), nil
+		},
+	}
+	sessionRepo := &mockdb.Session{
+		CreateFn: func(*model.Session) error {
+			return nil
+		},
+	}
+	tokenRepo := &mockdb.RefreshToken{
+		CreateFn: func(*model.IssuedRefreshToken) error {
+			return nil
+		},
+	}
+	cases := []struct {
+		name         string
+		credentialID string
+		userHandle   string
+		signCount    uint32
+		user         *model.User
+		replay       bool
+		wantStatus   int
+		wantCount    int64
+	}{
+		{
+			name:       "Success",
+			userHandle: webauthn.EncodeID([]byte("1")),
+			signCount:  6,
+			user:       &model.User{ID: 1, Active: true, Verified: true},
+			wantStatus: http.StatusOK,
+			wantCount:  6,
+		},
+		{
+			name:         "Unknown credential",
+			credentialID: webauthn.EncodeID([]byte("credential-2")),
+			wantStatus:   http.StatusUnauthorized,
+		},
+		{
+			name:       "Other user handle",
+			userHandle: webauthn.EncodeID([]byte("2")),
+			signCount:  6,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Cloned authenticator",
+			signCount:  4,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Replayed challenge",
+			signCount:  6,
+			user:       &model.User{ID: 1, Active: true, Verified: true},
+			replay:     true,
+			wantStatus: http.StatusUnauthorized,
+			wantCount:  6,
+		},
+		{
+			name:       "Disabled user",
+			signCount:  6,
+			user:       &model.User{ID: 1, Active: false, Verified: true},
+			wantStatus: http.StatusForbidden,
+			wantCount:  6,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			challenges := map[string]bool{}
+			var usedCount int64
+			passkeys := &mockdb.Passkey{
+				CreateChallengeFn: func(c *model.PasskeyChallenge) error {
+					assert.Equal(t, model.PasskeyLogin, c.Ceremony)
+					challenges[c.Challenge] = true
+					return nil
+				},
+				UseChallengeFn: func(challenge, ceremony string) (*model.PasskeyChallenge, error) {
+					if !challenges[challenge] || ceremony != model.PasskeyLogin {
+						return nil, apperr.New(http.StatusUnauthorized, "Invalid or expired challenge.")
+					}
+					delete(challenges, challenge)
+					return &model.PasskeyChallenge{Challenge: challenge, Ceremony: ceremony}, nil
+				},
+				FindByCredentialIDFn: func(id string) (*model.Passkey, error) {
+					if id != credentialID {
+						return nil, apperr.NotFound
+					}
+					return &model.Passkey{ID: 1, UserID: 1, CredentialID: id, PublicKey: publicKey, SignCount: 5}, nil
+				},
+				UsedFn: func(p *model.Passkey) error {
+					usedCount = p.SignCount
+					return nil
+				},
+			}
+			userRepo := &mockdb.User{
+				ViewFn: func(int) (*model.User, error) {
+					return tt.user, nil
+				},
+				UpdateLoginFn: func(*model.User) error {
+					return nil
+				},
+			}
+			r := gin.New()
+			authService := auth.NewAuthService(userRepo, nil, sessionRepo, tokenRepo, &mock.TwoFactor{}, &mock.Throttler{}, jwt, nil, nil, nil, time.Minute, passkeys, rp, &mock.PasswordPolicy{})
+			service.PasskeyRouter(authService, nil, r, r.Group("/v1"))
+			ts := httptest.NewServer(r)
+			defer ts.Close()
+
+			res, err := http.Post(ts.URL+"/passkeys/login/begin", "application/json", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			options := new(webauthn.RequestOptions)
+			if err := json.NewDecoder(res.Body).Decode(options); err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			assert.Equal(t, "example.com", options.RPID)
+
+			id := tt.credentialID
+			if id == "" {
+				id = credentialID
+			}
+			cd, ad, sig := passkeyAssertion(t, key, tt.signCount, options.Challenge)
+			body, _ := json.Marshal(map[string]interface{}{
+				"id": id,
+				"response": map[string]string{
+					"clientDataJSON":    cd,
+					"authenticatorData": ad,
+					"signature":         sig,
+					"userHandle":        tt.userHandle,
+				},
+			})
+			if tt.replay {
+				res, err = http.Post(ts.URL+"/passkeys/login/finish", "application/json", bytes.NewBuffer(body))
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Body.Close()
+			}
+			res, err = http.Post(ts.URL+"/passkeys/login/finish", "application/json", bytes.NewBuffer(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			assert.Equal(t, tt.wantStatus, res.StatusCode)
+			if tt.wantStatus == http.StatusOK {
+				response := new(model.LoginResponseWithToken)
+				if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, "jwttokenstring", response.Token)
+				assert.NotEmpty(t, response.RefreshToken)
+			}
+			assert.Equal(t, tt.wantCount, usedCount)
+		})
+	}
+}
+
+func TestPasskeyLoginBeginLimit(t *testing.T) {
+	stored := 0
+	passkeys := &mockdb.Passkey{
+		CreateChallengeFn: func(*model.PasskeyChallenge) error {
+			stored++
+			return nil
+		},
+	}
+	begun := map[string]int{}
+	throttle := &mock.Throttler{
+		CheckFn: func(keys ...string) error {
+			for _, k := range keys {
+				if begun[k] >= 2 {
+					return apperr.New(http.StatusTooManyRequests, "Too many failed attempts.")
+				}
+			}
+			return nil
+		},
+		FailFn: func(key string) (bool, error) {
+			begun[key]++
+			return begun[key] >= 2, nil
+		},
+	}
+	rp := &webauthn.RelyingParty{ID: "example.com", Name: "Example", Origins: []string{"https://example.com"}}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	authService := auth.NewAuthService(nil, nil, nil, nil, &mock.TwoFactor{}, throttle, nil, nil, nil, nil, time.Minute, passkeys, rp, &mock.PasswordPolicy{})
+	service.PasskeyRouter(authService, nil, r, r.Group("/v1"))
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	for _, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		res, err := http.Post(ts.URL+"/passkeys/login/begin", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		assert.Equal(t, want, res.StatusCode)
+	}
+	assert.Equal(t, 2, stored, "refused begins store no challenge")
+	assert.Equal(t, 2, begun[model.ThrottleKey(model.ThrottlePasskey, "127.0.0.1")])
+}
@@ -5,9 +5,10 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
-	"os"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/broker"
+	"github.com/alpacahq/ribbit-backend/model"
 	"github.com/alpacahq/ribbit-backend/repository/account"
 	"github.com/alpacahq/ribbit-backend/repository/plaid"
 	"github.com/alpacahq/ribbit-backend/request"
@@ -15,14 +16,16 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func PlaidRouter(svc *plaid.Service, acc *account.Service, r *gin.RouterGroup) {
+// PlaidRouter declares the bank linking routes. stepUp guards linking and unlinking banks,
+// and audit returns a handler that records them in the audit log.
+func PlaidRouter(svc *plaid.Service, acc *account.Service, stepUp gin.HandlerFunc, audit func(string, ...string) gin.HandlerFunc, r *gin.RouterGroup) {
 	a := Plaid{svc, acc}
 
 	ar := r.Group("/plaid")
 	ar.GET("/create_link_token", a.createLinkToken)
-	ar.POST("/set_access_token", a.setAccessToken)
+	ar.POST("/set_access_token", stepUp, audit(model.AuditBankLink, "account_id"), a.setAccessToken)
 	ar.GET("/recipient_banks", a.accountsList)
-	ar.DELETE("/recipient_banks/:bank_id", a.detachAccount)
+	ar.DELETE("/recipient_banks/:bank_id", stepUp, audit(model.AuditBankUnlink), a.detachAccount)
 }
 
 // Auth represents auth http service
@@ -84,10 +87,10 @@ func (a *Plaid) accountsList(c *gin.Context) {
 	})
 	accountStatuses := bytes.NewBuffer(acountStatus)
 
-	getAchAccountsList := os.Getenv("BROKER_API_BASE") + "/v1/accounts/" + accountID + "/ach_relationships"
+	getAchAccountsList := broker.APIBase(c) + "/v1/accounts/" + accountID + "/ach_relationships"
 
 	req, _ := http.NewRequest("GET", getAchAccountsList, accountStatuses)
-	req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+	req.Header.Add("Authorization", broker.Token(c))
 
 	response, _ := client.Do(req)
 	responseData, err := ioutil.ReadAll(response.Body)
@@ -113,11 +116,11 @@ func (a *Plaid) detachAccount(c *gin.Context) {
 		return
 	}
 
-	deleteAccountAPIURL := os.Getenv("BROKER_API_BASE") + "/v1/accounts/" + accountID + "/ach_relationships/" + bankID
+	deleteAccountAPIURL := broker.APIBase(c) + "/v1/accounts/" + accountID + "/ach_relationships/" + bankID
 
 	client := &http.Client{}
 	req, _ := http.NewRequest("DELETE", deleteAccountAPIURL, nil)
-	req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+	req.Header.Add("Authorization", broker.Token(c))
 	response, _ := client.Do(req)
 
 	responseData, err := ioutil.ReadAll(response.Body)
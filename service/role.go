@@ -0,0 +1,65 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/model"
+	"github.com/alpacahq/ribbit-backend/repository/permission"
+	"github.com/alpacahq/ribbit-backend/request"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Role represents the role http service
+type Role struct {
+	svc *permission.Service
+}
+
+// RoleRouter declares the routes to manage the permissions of roles, for users with the roles:manage permission
+func RoleRouter(svc *permission.Service, manage gin.HandlerFunc, r *gin.RouterGroup) {
+	ro := Role{svc}
+	r.GET("/permissions", manage, ro.permissions) // lists the permissions roles can be granted
+
+	rr := r.Group("/roles", manage)
+	rr.GET("", ro.list)
+	rr.PUT("/:id/permissions/:permission", ro.grant)
+	rr.DELETE("/:id/permissions/:permission", ro.revoke)
+}
+
+func (ro *Role) permissions(c *gin.Context) {
+	c.JSON(http.StatusOK, model.Permissions)
+}
+
+func (ro *Role) list(c *gin.Context) {
+	roles, err := ro.svc.Roles()
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+func (ro *Role) grant(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	if err := ro.svc.Grant(c, id, c.Param("permission")); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (ro *Role) revoke(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	if err := ro.svc.Revoke(c, id, c.Param("permission")); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/repository/session"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Session represents the session http service
+type Session struct {
+	svc *session.Service
+}
+
+// SessionRouter declares the routes for the sessions of the current user
+func SessionRouter(svc *session.Service, r *gin.RouterGroup) {
+	s := Session{
+		svc: svc,
+	}
+	sr := r.Group("/sessions")
+	sr.GET("", s.list)
+	sr.DELETE("", s.revokeAll) // log out everywhere
+	sr.DELETE("/:id", s.revoke)
+	r.POST("/logout", s.logout)
+}
+
+func (s *Session) list(c *gin.Context) {
+	result, err := s.svc.List(c)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": result})
+}
+
+func (s *Session) revoke(c *gin.Context) {
+	if err := s.svc.Revoke(c, c.Param("id")); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (s *Session) revokeAll(c *gin.Context) {
+	if err := s.svc.RevokeAll(c); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (s *Session) logout(c *gin.Context) {
+	if err := s.svc.Logout(c); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/repository/social"
+	"github.com/alpacahq/ribbit-backend/request"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Social represents the social login http service
+type Social struct {
+	svc *social.Service
+}
+
+// SocialRouter declares the routes for signing in with Apple or Google, and for the identities linked to the current user
+func SocialRouter(svc *social.Service, stepUp gin.HandlerFunc, r *gin.Engine, v1 *gin.RouterGroup) {
+	s := Social{svc}
+	r.POST("/oauth/:provider", s.login) // signs in or signs up with an ID token of the provider
+
+	ir := v1.Group("/identities")
+	ir.GET("", s.list)
+	ir.POST("/:provider", stepUp, s.link) // links another identity to the current user
+	ir.DELETE("/:id", stepUp, s.unlink)
+}
+
+func (s *Social) login(c *gin.Context) {
+	p, err := request.IDToken(c)
+	if err != nil {
+		return
+	}
+	r, err := s.svc.Login(c, c.Param("provider"), p.IDToken, p.Nonce)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+func (s *Social) list(c *gin.Context) {
+	identities, err := s.svc.Identities(c)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, identities)
+}
+
+func (s *Social) link(c *gin.Context) {
+	p, err := request.IDToken(c)
+	if err != nil {
+		return
+	}
+	i, err := s.svc.Link(c, c.Param("provider"), p.IDToken, p.Nonce)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, i)
+}
+
+func (s *Social) unlink(c *gin.Context) {
+	id, err := request.ID(c)
+	if err != nil {
+		return
+	}
+	if err := s.svc.Unlink(c, id); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/alpacahq/ribbit-backend/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultLinkDomain is the base URL of links outside of requests resolved to a tenant
+const defaultLinkDomain = "https://alpaca.com"
+
+// TenantRouter declares the route apps get the branding of the tenant of their host from
+func TenantRouter(r *gin.Engine) {
+	r.GET("/tenant", tenant)
+}
+
+func tenant(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, model.TenantOf(c))
+}
+
+// appLink returns the link to path, e.g. /profile/abc, in the app of the current tenant
+func appLink(c *gin.Context, path string) string {
+	domain := defaultLinkDomain
+	if t := model.TenantOf(c); t != nil && t.LinkDomain != "" {
+		domain = t.LinkDomain
+	}
+	return strings.TrimSuffix(domain, "/") + path
+}
@@ -5,10 +5,11 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"strconv"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/broker"
+	"github.com/alpacahq/ribbit-backend/model"
 	"github.com/alpacahq/ribbit-backend/repository/account"
 	"github.com/alpacahq/ribbit-backend/repository/transfer"
 
@@ -20,14 +21,16 @@ type ErrorBody struct {
 	Message string `json:"message"`
 }
 
-func TransferRouter(svc *transfer.Service, acc *account.Service, r *gin.RouterGroup) {
+// TransferRouter declares the transfer routes. stepUp guards moving money, and audit returns
+// a handler that records it in the audit log.
+func TransferRouter(svc *transfer.Service, acc *account.Service, stepUp gin.HandlerFunc, audit func(string, ...string) gin.HandlerFunc, r *gin.RouterGroup) {
 	a := Transfer{svc, acc}
 
 	ar := r.Group("/transfer")
 	ar.GET("", a.transfer)
 	ar.GET("/history", a.transfer)
-	ar.POST("/bank/:bank_id/deposit", a.createNewTransfer)
-	ar.DELETE("/:transfer_id/delete", a.deleteTransfer)
+	ar.POST("/bank/:bank_id/deposit", stepUp, audit(model.AuditTransferCreate, "amount"), a.createNewTransfer)
+	ar.DELETE("/:transfer_id/delete", audit(model.AuditTransferCancel), a.deleteTransfer)
 }
 
 // Auth represents auth http service
@@ -49,11 +52,11 @@ func (a *Transfer) transfer(c *gin.Context) {
 	offset := c.DefaultQuery("offset", "0")
 	direction := c.DefaultQuery("direction", "")
 
-	transferListURL := os.Getenv("BROKER_API_BASE") + "/v1/accounts/" + user.AccountID + "/transfers?limit=" + limit + "&offset=" + offset + "&direction=" + direction
+	transferListURL := broker.APIBase(c) + "/v1/accounts/" + user.AccountID + "/transfers?limit=" + limit + "&offset=" + offset + "&direction=" + direction
 
 	client := &http.Client{}
 	transferListRequest, _ := http.NewRequest("GET", transferListURL, nil)
-	transferListRequest.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+	transferListRequest.Header.Add("Authorization", broker.Token(c))
 
 	transferList, _ := client.Do(transferListRequest)
 	transferListBody, err := ioutil.ReadAll(transferList.Body)
@@ -95,7 +98,7 @@ func (a *Transfer) createNewTransfer(c *gin.Context) {
 		return
 	}
 
-	createNewTransactionURL := os.Getenv("BROKER_API_BASE") + "/v1/accounts/" + user.AccountID + "/transfers"
+	createNewTransactionURL := broker.APIBase(c) + "/v1/accounts/" + user.AccountID + "/transfers"
 
 	client := &http.Client{}
 	createNewTransfer, _ := json.Marshal(map[string]interface{}{
@@ -107,7 +110,7 @@ func (a *Transfer) createNewTransfer(c *gin.Context) {
 	tranferBody := bytes.NewBuffer(createNewTransfer)
 
 	req, err := http.NewRequest("POST", createNewTransactionURL, tranferBody)
-	req.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+	req.Header.Add("Authorization", broker.Token(c))
 
 	createTransfer, err := client.Do(req)
 	responseData, err := ioutil.ReadAll(createTransfer.Body)
@@ -139,11 +142,11 @@ func (a *Transfer) deleteTransfer(c *gin.Context) {
 	}
 	transferID := c.Param("transfer_id")
 
-	deleteTransfersListURL := os.Getenv("BROKER_API_BASE") + "/v1/accounts/" + user.AccountID + "/transfers/" + transferID
+	deleteTransfersListURL := broker.APIBase(c) + "/v1/accounts/" + user.AccountID + "/transfers/" + transferID
 
 	client := &http.Client{}
 	transferDeleteRequest, _ := http.NewRequest("DELETE", deleteTransfersListURL, nil)
-	transferDeleteRequest.Header.Add("Authorization", os.Getenv("BROKER_TOKEN"))
+	transferDeleteRequest.Header.Add("Authorization", broker.Token(c))
 
 	transferDeleteResponse, _ := client.Do(transferDeleteRequest)
 	transferDeleteBody, err := ioutil.ReadAll(transferDeleteResponse.Body)
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/alpacahq/ribbit-backend/apperr"
+	"github.com/alpacahq/ribbit-backend/repository/twofactor"
+	"github.com/alpacahq/ribbit-backend/request"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactor represents the two-factor authentication http service
+type TwoFactor struct {
+	svc *twofactor.Service
+}
+
+// TwoFactorRouter declares the routes for managing two-factor authentication of the current user
+func TwoFactorRouter(svc *twofactor.Service, r *gin.RouterGroup) {
+	t := TwoFactor{
+		svc: svc,
+	}
+	tr := r.Group("/2fa")
+	tr.POST("/totp", t.enroll)                  // returns a new secret and its otpauth:// URI
+	tr.POST("/totp/confirm", t.confirm)         // enables two-factor authentication and returns recovery codes
+	tr.POST("/totp/disable", t.disable)         // turns two-factor authentication off
+	tr.POST("/recovery-codes", t.recoveryCodes) // replaces the recovery codes
+	tr.POST("/step-up", t.stepUp)               // unlocks sensitive actions in the current session
+}
+
+func (t *TwoFactor) enroll(c *gin.Context) {
+	result, err := t.svc.Enroll(c)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (t *TwoFactor) confirm(c *gin.Context) {
+	p, err := request.TwoFactorCode(c)
+	if err != nil {
+		return
+	}
+	codes, err := t.svc.Confirm(c, p.Code)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (t *TwoFactor) disable(c *gin.Context) {
+	p, err := request.TwoFactorCode(c)
+	if err != nil {
+		return
+	}
+	if err := t.svc.Disable(c, p.Code); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (t *TwoFactor) recoveryCodes(c *gin.Context) {
+	p, err := request.TwoFactorCode(c)
+	if err != nil {
+		return
+	}
+	codes, err := t.svc.RegenerateRecoveryCodes(c, p.Code)
+	if err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func (t *TwoFactor) stepUp(c *gin.Context) {
+	p, err := request.TwoFactorCode(c)
+	if err != nil {
+		return
+	}
+	if err := t.svc.StepUp(c, p.Code); err != nil {
+		apperr.Response(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{})
+}
@@ -22,7 +22,7 @@ func UserRouter(svc *user.Service, r *gin.RouterGroup) {
 		svc: svc,
 	}
 	ur := r.Group("/users")
-	ur.GET("", u.list)
+	ur.GET("", u.list) // filters by role, verified, active, account_status, created_from, created_to and q, sorts by sort and pages by page or cursor
 	ur.GET("/:id", u.view)
 	ur.PATCH("/:id", u.update)
 	ur.DELETE("/:id", u.delete)
@@ -31,6 +31,10 @@ func UserRouter(svc *user.Service, r *gin.RouterGroup) {
 type listResponse struct {
 	Users []model.User `json:"users"`
 	Page  int          `json:"page"`
+	// Total is the number of users matching the filters on all pages
+	Total int `json:"total"`
+	// NextCursor continues the list after this page, or is empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func (u *User) list(c *gin.Context) {
@@ -38,17 +42,26 @@ func (u *User) list(c *gin.Context) {
 	if err != nil {
 		return
 	}
-	result, err := u.svc.List(c, &model.Pagination{
+	lq, err := request.UserList(c)
+	if err != nil {
+		return
+	}
+	result, total, err := u.svc.List(c, lq, &model.Pagination{
 		Limit: p.Limit, Offset: p.Offset,
 	})
 	if err != nil {
 		apperr.Response(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, listResponse{
+	resp := listResponse{
 		Users: result,
 		Page:  p.Page,
-	})
+		Total: total,
+	}
+	if len(result) == p.Limit {
+		resp.NextCursor = lq.NextCursor(&result[len(result)-1])
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 func (u *User) view(c *gin.Context) {
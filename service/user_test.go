@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/alpacahq/ribbit-backend/apperr"
 	"github.com/alpacahq/ribbit-backend/mock"
@@ -20,8 +21,15 @@ import (
 
 func TestListUsers(t *testing.T) {
 	type listResponse struct {
-		Users []model.User `json:"users"`
-		Page  int          `json:"page"`
+		Users      []model.User `json:"users"`
+		Page       int          `json:"page"`
+		Total      int          `json:"total"`
+		NextCursor string       `json:"next_cursor"`
+	}
+	staff := &mock.RBAC{
+		EnforcePermissionFn: func(_ *gin.Context, p string) bool {
+			return p == model.PermUsersRead
+		},
 	}
 	cases := []struct {
 		name       string
@@ -30,7 +38,6 @@ func TestListUsers(t *testing.T) {
 		wantResp   *listResponse
 		userRepo   *mockdb.User
 		rbac       *mock.RBAC
-		auth       *mock.Auth
 	}{
 		{
 			name:       "Invalid request",
@@ -38,20 +45,15 @@ func TestListUsers(t *testing.T) {
 			wantStatus: http.StatusInternalServerError,
 		},
 		{
-			name: "Fail on query list",
+			name: "Fail on permission",
 
 			req: `?limit=100&page=1`,
 
-			auth: &mock.Auth{
-				UserFn: func(c *gin.Context) *model.AuthUser {
-
-					return &model.AuthUser{
-
-						ID:    1,
-						Role:  model.UserRole,
-						Email: "john@mail.com",
-					}
-				}},
+			rbac: &mock.RBAC{
+				EnforcePermissionFn: func(*gin.Context, string) bool {
+					return false
+				},
+			},
 			wantStatus: http.StatusForbidden,
 		},
 		{
@@ -59,18 +61,9 @@ func TestListUsers(t *testing.T) {
 
 			req: `?limit=100&page=1`,
 
-			auth: &mock.Auth{
-				UserFn: func(c *gin.Context) *model.AuthUser {
-
-					return &model.AuthUser{
-
-						ID:    1,
-						Role:  model.SuperAdminRole,
-						Email: "john@mail.com",
-					}
-				}},
+			rbac: staff,
 			userRepo: &mockdb.User{
-				ListFn: func(q *model.ListQuery, p *model.Pagination) ([]model.User, error) {
+				ListFn: func(q *model.ListQuery, p *model.Pagination) ([]model.User, int, error) {
 
 					if p.Limit == 100 && p.Offset == 100 {
 
@@ -153,10 +146,10 @@ func TestListUsers(t *testing.T) {
 									Name: "ADMIN",
 								},
 							},
-						}, nil
+						}, 102, nil
 
 					}
-					return nil, apperr.DB
+					return nil, 0, apperr.DB
 
 				},
 			},
@@ -241,7 +234,41 @@ func TestListUsers(t *testing.T) {
 							Name: "ADMIN",
 						},
 					},
-				}, Page: 1},
+				}, Page: 1, Total: 102},
+		},
+		{
+			name: "Filters and sorts",
+			req:  `?limit=1&role=3&verified=true&q=+jo+&sort=-created_at&created_from=2021-06-01T00:00:00Z`,
+			rbac: staff,
+			userRepo: &mockdb.User{
+				ListFn: func(q *model.ListQuery, p *model.Pagination) ([]model.User, int, error) {
+					verified := true
+					want := &model.ListQuery{
+						Role:        model.UserRole,
+						Verified:    &verified,
+						Search:      "jo",
+						Sort:        "created_at",
+						Desc:        true,
+						CreatedFrom: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+					}
+					if !assert.Equal(t, want, q) {
+						return nil, 0, apperr.DB
+					}
+					return []model.User{{ID: 12, Base: model.Base{CreatedAt: mock.TestTime(2021)}}}, 3, nil
+				},
+			},
+			wantStatus: http.StatusOK,
+			wantResp: &listResponse{
+				Users:      []model.User{{ID: 12, Base: model.Base{CreatedAt: mock.TestTime(2021)}}},
+				Total:      3,
+				NextCursor: (&model.ListQuery{Sort: "created_at"}).NextCursor(&model.User{ID: 12, Base: model.Base{CreatedAt: mock.TestTime(2021)}}),
+			},
+		},
+		{
+			name:       "Unknown sort column",
+			req:        `?sort=password`,
+			rbac:       staff,
+			wantStatus: http.StatusBadRequest,
 		},
 	}
 	gin.SetMode(gin.TestMode)
@@ -250,7 +277,7 @@ func TestListUsers(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			r := gin.New()
 			rg := r.Group("/v1")
-			userService := user.NewUserService(tt.userRepo, tt.auth, tt.rbac)
+			userService := user.NewUserService(tt.userRepo, nil, tt.rbac)
 			service.UserRouter(userService, rg)
 			ts := httptest.NewServer(r)
 			defer ts.Close()
@@ -291,7 +318,7 @@ func TestViewUser(t *testing.T) {
 			name: "Fail on RBAC",
 			req:  `1`,
 			rbac: &mock.RBAC{
-				EnforceUserFn: func(*gin.Context, int) bool {
+				EnforcePermissionFn: func(*gin.Context, string) bool {
 					return false
 				},
 			},
@@ -301,7 +328,7 @@ func TestViewUser(t *testing.T) {
 			name: "Success",
 			req:  `1`,
 			rbac: &mock.RBAC{
-				EnforceUserFn: func(*gin.Context, int) bool {
+				EnforcePermissionFn: func(*gin.Context, string) bool {
 					return true
 				},
 			},
@@ -381,7 +408,7 @@ func TestUpdateUser(t *testing.T) {
 			id:   `1`,
 			req:  `{"first_name":"jj","last_name":"okocha","mobile":"123456","phone":"321321","address":"home"}`,
 			rbac: &mock.RBAC{
-				EnforceUserFn: func(*gin.Context, int) bool {
+				EnforcePermissionFn: func(*gin.Context, string) bool {
 					return false
 				},
 			},
@@ -392,7 +419,7 @@ func TestUpdateUser(t *testing.T) {
 			id:   `1`,
 			req:  `{"first_name":"jj","last_name":"okocha","phone":"321321","address":"home"}`,
 			rbac: &mock.RBAC{
-				EnforceUserFn: func(*gin.Context, int) bool {
+				EnforcePermissionFn: func(*gin.Context, string) bool {
 					return true
 				},
 			},
@@ -476,6 +503,16 @@ func TestDeleteUser(t *testing.T) {
 			id:         `a`,
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "Fail on permission",
+			id:   `1`,
+			rbac: &mock.RBAC{
+				EnforcePermissionFn: func(*gin.Context, string) bool {
+					return false
+				},
+			},
+			wantStatus: http.StatusForbidden,
+		},
 		{
 			name: "Fail on RBAC",
 			id:   `1`,
@@ -489,6 +526,9 @@ func TestDeleteUser(t *testing.T) {
 				},
 			},
 			rbac: &mock.RBAC{
+				EnforcePermissionFn: func(*gin.Context, string) bool {
+					return true
+				},
 				IsLowerRoleFn: func(*gin.Context, model.AccessRole) bool {
 					return false
 				},
@@ -511,6 +551,9 @@ func TestDeleteUser(t *testing.T) {
 				},
 			},
 			rbac: &mock.RBAC{
+				EnforcePermissionFn: func(_ *gin.Context, p string) bool {
+					return p == model.PermUsersWrite
+				},
 				IsLowerRoleFn: func(*gin.Context, model.AccessRole) bool {
 					return true
 				},
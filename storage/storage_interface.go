@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("storage: object not found")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or escape the storage root
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Service is the interface to our object storage for user uploads
+type Service interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	SignedURL(key string, expiry time.Duration) (string, error)
+}
+
+// ValidKey reports whether key is a clean relative path such as avatars/abc.jpg
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	if path.Clean(key) != key {
+		return false
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == ".." || seg == "." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ugorji/go/codec"
+)
+
+// COSE key parameters, see https://www.iana.org/assignments/cose/cose.xhtml
+const (
+	coseKty = 1
+	coseAlg = 3
+	// EC2 keys
+	coseCrv = -1
+	coseX   = -2
+	coseY   = -3
+	// RSA keys
+	coseN = -1
+	coseE = -2
+
+	coseKtyEC2  = 2
+	coseKtyRSA  = 3
+	coseCrvP256 = 1
+)
+
+// COSE algorithms we accept, offered to authenticators in this order of preference
+const (
+	AlgES256 = -7
+	AlgRS256 = -257
+)
+
+// publicKey is a credential public key
+type publicKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// verify checks an ES256 or RS256 signature over data
+func (k *publicKey) verify(data, sig []byte) bool {
+	hash := sha256.Sum256(data)
+	switch pub := k.key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(pub, hash[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig) == nil
+	}
+	return false
+}
+
+// parsePublicKey parses a COSE encoded ES256 or RS256 key
+func parsePublicKey(data []byte) (*publicKey, error) {
+	var raw map[int64]interface{}
+	if err := codec.NewDecoderBytes(data, cbor).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("webauthn: credential public key: %v", err)
+	}
+	kty, _ := coseInt(raw[coseKty])
+	alg, _ := coseInt(raw[coseAlg])
+	switch {
+	case kty == coseKtyEC2 && alg == AlgES256:
+		crv, _ := coseInt(raw[coseCrv])
+		x, _ := raw[coseX].([]byte)
+		y, _ := raw[coseY].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("webauthn: invalid P-256 key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("webauthn: invalid P-256 key")
+		}
+		return &publicKey{alg, pub}, nil
+	case kty == coseKtyRSA && alg == AlgRS256:
+		n, _ := raw[coseN].([]byte)
+		e, _ := raw[coseE].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("webauthn: invalid RSA key")
+		}
+		return &publicKey{alg, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}}, nil
+	}
+	return nil, fmt.Errorf("webauthn: unsupported key type %d with algorithm %d", kty, alg)
+}
+
+// coseInt returns a CBOR integer, which decodes as int64 or uint64
+func coseInt(v interface{}) (int64, bool) {
+	switch i := v.(type) {
+	case int64:
+		return i, true
+	case uint64:
+		return int64(i), true
+	}
+	return 0, false
+}
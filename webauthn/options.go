@@ -0,0 +1,81 @@
+package webauthn
+
+import (
+	"time"
+)
+
+// CredentialDescriptor identifies a credential
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// CredentialParameter is a key type the relying party accepts
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// CreationOptions are the options a client passes to navigator.credentials.create(), with binary values base64url encoded
+type CreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey        string `json:"residentKey"`
+		RequireResidentKey bool   `json:"requireResidentKey"`
+		UserVerification   string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// RequestOptions are the options a client passes to navigator.credentials.get(), with the challenge base64url encoded
+type RequestOptions struct {
+	Challenge        string `json:"challenge"`
+	RPID             string `json:"rpId"`
+	Timeout          int    `json:"timeout"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions asks for a discoverable credential, so users can later sign in without typing a name,
+// for the user with handle userID. Authenticators refuse to create a second credential for exclude.
+func (rp *RelyingParty) CreationOptions(challenge string, userID []byte, name, displayName string, exclude [][]byte, timeout time.Duration) *CreationOptions {
+	o := &CreationOptions{
+		Challenge:   challenge,
+		Timeout:     int(timeout / time.Millisecond),
+		Attestation: "none",
+	}
+	o.RP.ID, o.RP.Name = rp.ID, rp.Name
+	o.User.ID, o.User.Name, o.User.DisplayName = EncodeID(userID), name, displayName
+	for _, alg := range []int{AlgES256, AlgRS256} {
+		o.PubKeyCredParams = append(o.PubKeyCredParams, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	o.ExcludeCredentials = []CredentialDescriptor{}
+	for _, id := range exclude {
+		o.ExcludeCredentials = append(o.ExcludeCredentials, CredentialDescriptor{Type: "public-key", ID: EncodeID(id)})
+	}
+	o.AuthenticatorSelection.ResidentKey = "required"
+	o.AuthenticatorSelection.RequireResidentKey = true
+	o.AuthenticatorSelection.UserVerification = "required"
+	return o
+}
+
+// RequestOptions asks for any discoverable credential of the relying party
+func (rp *RelyingParty) RequestOptions(challenge string, timeout time.Duration) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID,
+		Timeout:          int(timeout / time.Millisecond),
+		UserVerification: "required",
+	}
+}
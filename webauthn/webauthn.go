@@ -0,0 +1,211 @@
+// Package webauthn registers passkeys and verifies assertions of them, as specified by
+// https://www.w3.org/TR/webauthn-2/. We ask authenticators for no attestation, so attestation
+// statements are not checked: a credential is trusted because the signed-in user registered it.
+package webauthn
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ugorji/go/codec"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+	flagExtensionData    = 0x80
+)
+
+// Errors returned when a ceremony fails verification
+var (
+	ErrChallenge     = errors.New("webauthn: challenge mismatch")
+	ErrOrigin        = errors.New("webauthn: origin not allowed")
+	ErrRelyingParty  = errors.New("webauthn: credential is scoped to another relying party")
+	ErrUserVerified  = errors.New("webauthn: user was not verified")
+	ErrSignature     = errors.New("webauthn: invalid signature")
+	ErrClonedCounter = errors.New("webauthn: signature counter went backwards, the authenticator may be cloned")
+)
+
+var cbor = &codec.CborHandle{}
+
+// RelyingParty is our side of WebAuthn ceremonies
+type RelyingParty struct {
+	// ID is the domain credentials are scoped to, e.g. example.com
+	ID   string
+	Name string
+	// Origins are the origins ceremonies are accepted from, e.g. https://example.com
+	// or android:apk-key-hash:<hash> for the Android app
+	Origins []string
+}
+
+// Credential is a public key credential created by an authenticator
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE encoded public key
+	PublicKey []byte
+	SignCount uint32
+}
+
+// clientData is the JSON the client signs over, with the challenge base64url encoded
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// authData is the parsed authenticator data
+type authData struct {
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+	// credential is only set during registration
+	credential *Credential
+}
+
+// Challenge returns the challenge of clientDataJSON, which the server has to look up before verifying a ceremony
+func Challenge(clientDataJSON []byte) (string, error) {
+	var cd clientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return "", fmt.Errorf("webauthn: client data: %v", err)
+	}
+	return cd.Challenge, nil
+}
+
+// VerifyRegistration checks the response of navigator.credentials.create() to challenge and returns the new credential.
+// The user must have been verified by the authenticator, e.g. with a fingerprint.
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte) (*Credential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+	var att struct {
+		Fmt      string `codec:"fmt"`
+		AuthData []byte `codec:"authData"`
+	}
+	if err := codec.NewDecoderBytes(attestationObject, cbor).Decode(&att); err != nil {
+		return nil, fmt.Errorf("webauthn: attestation object: %v", err)
+	}
+	ad, err := rp.verifyAuthData(att.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if ad.credential == nil {
+		return nil, errors.New("webauthn: no attested credential data")
+	}
+	if _, err := parsePublicKey(ad.credential.PublicKey); err != nil {
+		return nil, err
+	}
+	return ad.credential, nil
+}
+
+// VerifyAssertion checks the response of navigator.credentials.get() to challenge against a stored credential
+// and returns the new signature counter, which must be stored to detect cloned authenticators.
+func (rp *RelyingParty) VerifyAssertion(c *Credential, challenge string, clientDataJSON, authenticatorData, signature []byte) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	ad, err := rp.verifyAuthData(authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	key, err := parsePublicKey(c.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	hash := sha256.Sum256(clientDataJSON)
+	if !key.verify(append(append([]byte{}, authenticatorData...), hash[:]...), signature) {
+		return 0, ErrSignature
+	}
+	// authenticators without a counter always send 0
+	if (ad.signCount != 0 || c.SignCount != 0) && ad.signCount <= c.SignCount {
+		return 0, ErrClonedCounter
+	}
+	return ad.signCount, nil
+}
+
+func (rp *RelyingParty) verifyClientData(data []byte, typ, challenge string) error {
+	var cd clientData
+	if err := json.Unmarshal(data, &cd); err != nil {
+		return fmt.Errorf("webauthn: client data: %v", err)
+	}
+	if cd.Type != typ {
+		return fmt.Errorf("webauthn: unexpected ceremony %q", cd.Type)
+	}
+	if subtle.ConstantTimeCompare([]byte(cd.Challenge), []byte(challenge)) != 1 {
+		return ErrChallenge
+	}
+	for _, o := range rp.Origins {
+		if cd.Origin == o {
+			return nil
+		}
+	}
+	return ErrOrigin
+}
+
+func (rp *RelyingParty) verifyAuthData(data []byte) (*authData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("webauthn: authenticator data too short")
+	}
+	ad := &authData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	want := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(ad.rpIDHash, want[:]) != 1 {
+		return nil, ErrRelyingParty
+	}
+	if ad.flags&flagUserPresent == 0 || ad.flags&flagUserVerified == 0 {
+		return nil, ErrUserVerified
+	}
+	if ad.flags&flagAttestedCredData == 0 {
+		return ad, nil
+	}
+	// attested credential data: aaguid (16), credential ID length (2), credential ID, COSE key
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, errors.New("webauthn: attested credential data too short")
+	}
+	n := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < n {
+		return nil, errors.New("webauthn: credential ID too short")
+	}
+	id := rest[:n]
+	rest = rest[n:]
+	keyLen := len(rest)
+	if ad.flags&flagExtensionData != 0 {
+		// the key is followed by extensions, so find where it ends
+		dec := codec.NewDecoderBytes(rest, cbor)
+		var key interface{}
+		if err := dec.Decode(&key); err != nil {
+			return nil, fmt.Errorf("webauthn: credential public key: %v", err)
+		}
+		keyLen = dec.NumBytesRead()
+	}
+	ad.credential = &Credential{
+		ID:        append([]byte{}, id...),
+		PublicKey: append([]byte{}, rest[:keyLen]...),
+		SignCount: ad.signCount,
+	}
+	return ad, nil
+}
+
+// EncodeID returns the base64url encoding used for challenges and credential IDs
+func EncodeID(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeID decodes a base64url value, with or without padding
+func DecodeID(s string) ([]byte, error) {
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
@@ -0,0 +1,144 @@
+package webauthn_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/alpacahq/ribbit-backend/webauthn"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+var rp = &webauthn.RelyingParty{ID: "example.com", Name: "Example", Origins: []string{"https://example.com"}}
+
+// authenticator simulates a platform authenticator holding one P-256 credential
+type authenticator struct {
+	id    []byte
+	key   *ecdsa.PrivateKey
+	count uint32
+	flags byte
+	rpID  string
+}
+
+func newAuthenticator() *authenticator {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	return &authenticator{id: []byte("credential-1"), key: key, flags: 0x05, rpID: rp.ID}
+}
+
+func encode(t *testing.T, v interface{}) []byte {
+	var b []byte
+	if err := codec.NewEncoderBytes(&b, &codec.CborHandle{}).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func clientDataJSON(typ, challenge, origin string) []byte {
+	b, _ := json.Marshal(map[string]string{"type": typ, "challenge": challenge, "origin": origin})
+	return b
+}
+
+func (a *authenticator) authData(attested []byte) []byte {
+	hash := sha256.Sum256([]byte(a.rpID))
+	flags := a.flags
+	if attested != nil {
+		flags |= 0x40
+	}
+	d := append(hash[:], flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(d[33:], a.count)
+	return append(d, attested...)
+}
+
+func (a *authenticator) create(t *testing.T, challenge, origin string) ([]byte, []byte) {
+	pad := func(b []byte) []byte { return append(make([]byte, 32-len(b)), b...) }
+	key := encode(t, map[int64]interface{}{1: 2, 3: -7, -1: 1, -2: pad(a.key.X.Bytes()), -3: pad(a.key.Y.Bytes())})
+	attested := make([]byte, 18)
+	binary.BigEndian.PutUint16(attested[16:], uint16(len(a.id)))
+	attested = append(append(attested, a.id...), key...)
+	att := encode(t, map[string]interface{}{"fmt": "none", "attStmt": map[string]interface{}{}, "authData": a.authData(attested)})
+	return clientDataJSON("webauthn.create", challenge, origin), att
+}
+
+func (a *authenticator) get(t *testing.T, challenge string) ([]byte, []byte, []byte) {
+	cd := clientDataJSON("webauthn.get", challenge, "https://example.com")
+	ad := a.authData(nil)
+	hash := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(append([]byte{}, ad...), hash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cd, ad, sig
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	cases := []struct {
+		name    string
+		change  func(a *authenticator)
+		origin  string
+		wantErr error
+	}{
+		{name: "Success"},
+		{name: "Other origin", origin: "https://evil.com", wantErr: webauthn.ErrOrigin},
+		{name: "Other relying party", change: func(a *authenticator) { a.rpID = "evil.com" }, wantErr: webauthn.ErrRelyingParty},
+		{name: "User not verified", change: func(a *authenticator) { a.flags = 0x01 }, wantErr: webauthn.ErrUserVerified},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAuthenticator()
+			if tt.change != nil {
+				tt.change(a)
+			}
+			origin := tt.origin
+			if origin == "" {
+				origin = "https://example.com"
+			}
+			cd, att := a.create(t, "Y2hhbGxlbmdl", origin)
+			c, err := rp.VerifyRegistration("Y2hhbGxlbmdl", cd, att)
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				assert.Equal(t, a.id, c.ID)
+			}
+		})
+	}
+
+	t.Run("Other challenge", func(t *testing.T) {
+		cd, att := newAuthenticator().create(t, "b3RoZXI", "https://example.com")
+		_, err := rp.VerifyRegistration("Y2hhbGxlbmdl", cd, att)
+		assert.Equal(t, webauthn.ErrChallenge, err)
+	})
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	a := newAuthenticator()
+	cd, att := a.create(t, "cmVn", "https://example.com")
+	cred, err := rp.VerifyRegistration("cmVn", cd, att)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.count = 1
+	cd, ad, sig := a.get(t, "bG9naW4")
+	count, err := rp.VerifyAssertion(cred, "bG9naW4", cd, ad, sig)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), count)
+	cred.SignCount = count
+
+	challenge, err := webauthn.Challenge(cd)
+	assert.NoError(t, err)
+	assert.Equal(t, "bG9naW4", challenge)
+
+	sig[len(sig)-1] ^= 0xff
+	_, err = rp.VerifyAssertion(cred, "bG9naW4", cd, ad, sig)
+	assert.Equal(t, webauthn.ErrSignature, err)
+
+	cd, ad, sig = a.get(t, "YWdhaW4")
+	_, err = rp.VerifyAssertion(cred, "YWdhaW4", cd, ad, sig)
+	assert.Equal(t, webauthn.ErrClonedCounter, err, "replayed counter")
+}